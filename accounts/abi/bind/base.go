@@ -249,6 +249,12 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 // FilterLogs filters contract logs for past blocks, returning the necessary
 // channels to construct a strongly typed bound iterator on top of them.
 func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	return c.filterLogs(opts, []common.Address{c.address}, name, query...)
+}
+
+// filterLogs filters the logs of the given addresses for past blocks, see
+// FilterLogs.
+func (c *BoundContract) filterLogs(opts *FilterOpts, addresses []common.Address, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
 	// Don't crash on a lazy user
 	if opts == nil {
 		opts = new(FilterOpts)
@@ -264,7 +270,7 @@ func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]int
 	logs := make(chan types.Log, 128)
 
 	config := ethereum.FilterQuery{
-		Addresses: []common.Address{c.address},
+		Addresses: addresses,
 		Topics:    topics,
 		FromBlock: new(big.Int).SetUint64(opts.Start),
 	}
@@ -298,6 +304,19 @@ func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]int
 // WatchLogs filters subscribes to contract logs for future blocks, returning a
 // subscription object that can be used to tear down the watcher.
 func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	// Start the background filtering
+	logs := make(chan types.Log, 128)
+
+	sub, err := c.watchLogs(opts, []common.Address{c.address}, logs, name, query...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logs, sub, nil
+}
+
+// watchLogs subscribes to the logs of the given addresses for future blocks,
+// delivering them into the given channel, see WatchLogs.
+func (c *BoundContract) watchLogs(opts *WatchOpts, addresses []common.Address, logs chan<- types.Log, name string, query ...[]interface{}) (event.Subscription, error) {
 	// Don't crash on a lazy user
 	if opts == nil {
 		opts = new(WatchOpts)
@@ -307,23 +326,16 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 
 	topics, err := makeTopics(query...)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	// Start the background filtering
-	logs := make(chan types.Log, 128)
-
 	config := ethereum.FilterQuery{
-		Addresses: []common.Address{c.address},
+		Addresses: addresses,
 		Topics:    topics,
 	}
 	if opts.Start != nil {
 		config.FromBlock = new(big.Int).SetUint64(*opts.Start)
 	}
-	sub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
-	if err != nil {
-		return nil, nil, err
-	}
-	return logs, sub, nil
+	return c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
 }
 
 // UnpackLog unpacks a retrieved log into the provided output structure.
@@ -23,6 +23,7 @@ package bind
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/format"
 	"regexp"
@@ -46,7 +47,14 @@ const (
 // to be used as is in client code, but rather as an intermediate struct which
 // enforces compile time type safety and naming convention opposed to having to
 // manually maintain hard coded strings that break on runtime.
-func Bind(types []string, abis []string, bytecodes []string, runtimecodes []string, pkg string, lang Lang) (string, error) {
+//
+// If governedProxy is set, the contracts are bound as reached through a Range
+// governed proxy: the events are filtered from the implementations behind the
+// proxy and the upgrade operations of the proxy are exposed.
+func Bind(types []string, abis []string, bytecodes []string, runtimecodes []string, pkg string, lang Lang, governedProxy bool) (string, error) {
+	if governedProxy && lang != LangGo {
+		return "", errors.New("governed proxy bindings are only supported for Go")
+	}
 	// Process each individual contract requested binding
 	contracts := make(map[string]*tmplContract)
 
@@ -127,6 +135,8 @@ func Bind(types []string, abis []string, bytecodes []string, runtimecodes []stri
 			Calls:       calls,
 			Transacts:   transacts,
 			Events:      events,
+
+			GovernedProxy: governedProxy,
 		}
 	}
 	// Generate the contract template data content and render it
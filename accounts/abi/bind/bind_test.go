@@ -953,7 +953,7 @@ func TestBindings(t *testing.T) {
 	// Generate the test suite for all the contracts
 	for i, tt := range bindTests {
 		// Generate the binding and create a Go source file in the workspace
-		bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, []string{tt.runtimecodes}, "bindtest", LangGo)
+		bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, []string{tt.runtimecodes}, "bindtest", LangGo, false)
 		if err != nil {
 			t.Fatalf("test %d: failed to generate binding: %v", i, err)
 		}
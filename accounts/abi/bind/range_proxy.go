@@ -0,0 +1,289 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"range/core/gen3/accounts/abi"
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/event"
+)
+
+// governedProxyABI is the part of the IGovernedProxy interface the governed
+// proxy bindings are built on.
+const governedProxyABI = `[
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"impl","type":"address"},{"indexed":false,"name":"proposal","type":"address"}],"name":"Upgraded","type":"event"},
+	{"constant":false,"inputs":[{"name":"_proposal","type":"address"}],"name":"collectUpgradeProposal","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},
+	{"constant":true,"inputs":[],"name":"impl","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},
+	{"constant":true,"inputs":[],"name":"listUpgradeProposals","outputs":[{"name":"proposals","type":"address[]"}],"payable":false,"stateMutability":"view","type":"function"},
+	{"constant":false,"inputs":[{"name":"_newImpl","type":"address"},{"name":"_period","type":"uint256"}],"name":"proposeUpgrade","outputs":[{"name":"","type":"address"}],"payable":true,"stateMutability":"payable","type":"function"},
+	{"constant":false,"inputs":[{"name":"_proposal","type":"address"}],"name":"upgrade","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},
+	{"constant":true,"inputs":[{"name":"_proposal","type":"address"}],"name":"upgradeProposalImpl","outputs":[{"name":"new_impl","type":"address"}],"payable":false,"stateMutability":"view","type":"function"}
+]`
+
+// ErrNoProxyCaller is returned by the governed proxy bindings if the
+// implementation behind the proxy cannot be resolved for lack of a backend
+// able to call contracts.
+var ErrNoProxyCaller = errors.New("no contract caller to resolve the governed proxy implementation with")
+
+var parsedGovernedProxyABI abi.ABI
+
+func init() {
+	var err error
+	if parsedGovernedProxyABI, err = abi.JSON(strings.NewReader(governedProxyABI)); err != nil {
+		panic(err)
+	}
+}
+
+// proxyUpgraded is the Upgraded event of a governed proxy.
+type proxyUpgraded struct {
+	Impl     common.Address
+	Proposal common.Address
+}
+
+// GovernedProxy binds the IGovernedProxy interface at the address of the
+// contract. If the contract was bound without a caller, the filterer or the
+// transactor is used to call the proxy if either of them is capable of it.
+func (c *BoundContract) GovernedProxy() *BoundContract {
+	caller := c.caller
+	if caller == nil {
+		if fc, ok := c.filterer.(ContractCaller); ok {
+			caller = fc
+		} else if tc, ok := c.transactor.(ContractCaller); ok {
+			caller = tc
+		}
+	}
+	return NewBoundContract(c.address, parsedGovernedProxyABI, caller, c.transactor, c.filterer)
+}
+
+// ProxyImpl returns the implementation behind the governed proxy the contract
+// is reached through.
+func (c *BoundContract) ProxyImpl(opts *CallOpts) (common.Address, error) {
+	proxy := c.GovernedProxy()
+	if proxy.caller == nil {
+		return common.Address{}, ErrNoProxyCaller
+	}
+	var impl common.Address
+	err := proxy.Call(opts, &impl, "impl")
+	return impl, err
+}
+
+// FilterProxiedLogs filters contract logs for past blocks like FilterLogs, but
+// for a contract reached through a governed proxy: the events are emitted by
+// the implementations behind the proxy, so the logs of the one active at the
+// start of the range and of all the ones upgraded to within it are included.
+//
+// If the state at the start of the range is not available anymore, the
+// implementation active at the start is derived from the upgrades before it.
+func (c *BoundContract) FilterProxiedLogs(opts *FilterOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(FilterOpts)
+	}
+	proxy := c.GovernedProxy()
+
+	impl, err := c.ProxyImpl(&CallOpts{BlockNumber: new(big.Int).SetUint64(opts.Start), Context: opts.Context})
+	if err == ErrNoProxyCaller {
+		return nil, nil, err
+	} else if err != nil {
+		if impl, err = c.upgradedImpl(proxy, opts, err); err != nil {
+			return nil, nil, err
+		}
+	}
+	addresses := []common.Address{c.address, impl}
+
+	upgrades, sub, err := proxy.FilterLogs(opts, "Upgraded")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sub.Unsubscribe()
+
+	err = drainLogs(upgrades, sub, func(log types.Log) error {
+		upgraded := new(proxyUpgraded)
+		if err := proxy.UnpackLog(upgraded, "Upgraded", log); err != nil {
+			return err
+		}
+		addresses = appendAddress(addresses, upgraded.Impl)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.filterLogs(opts, addresses, name, query...)
+}
+
+// upgradedImpl derives the implementation active at the start of the range
+// from the upgrades of the proxy up to it. The implementation the proxy was
+// deployed with is only known if it was never upgraded since, otherwise the
+// error of the state lookup is returned.
+func (c *BoundContract) upgradedImpl(proxy *BoundContract, opts *FilterOpts, stateErr error) (common.Address, error) {
+	upgrades, sub, err := proxy.FilterLogs(&FilterOpts{Context: opts.Context}, "Upgraded")
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		impl     common.Address
+		upgraded bool
+		later    bool
+	)
+	err = drainLogs(upgrades, sub, func(log types.Log) error {
+		if log.BlockNumber > opts.Start {
+			later = true
+			return nil
+		}
+		upgrade := new(proxyUpgraded)
+		if err := proxy.UnpackLog(upgrade, "Upgraded", log); err != nil {
+			return err
+		}
+		impl, upgraded = upgrade.Impl, true
+		return nil
+	})
+	switch {
+	case err != nil:
+		return common.Address{}, err
+	case upgraded:
+		return impl, nil
+	case later:
+		return common.Address{}, stateErr
+	}
+	return c.ProxyImpl(&CallOpts{Context: opts.Context})
+}
+
+// WatchProxiedLogs subscribes to contract logs for future blocks like
+// WatchLogs, but for a contract reached through a governed proxy: the logs of
+// the current implementation behind the proxy are delivered, switching over
+// to the new one on upgrades.
+func (c *BoundContract) WatchProxiedLogs(opts *WatchOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(WatchOpts)
+	}
+	proxy := c.GovernedProxy()
+
+	impl, err := c.ProxyImpl(&CallOpts{Context: opts.Context})
+	if err != nil {
+		return nil, nil, err
+	}
+	upgrades, upgradeSub, err := proxy.WatchLogs(opts, "Upgraded")
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		addresses = []common.Address{c.address, impl}
+		logs      = make(chan types.Log, 128)
+	)
+	sub, err := c.watchLogs(opts, addresses, logs, name, query...)
+	if err != nil {
+		upgradeSub.Unsubscribe()
+		return nil, nil, err
+	}
+	return logs, event.NewSubscription(func(quit <-chan struct{}) error {
+		defer upgradeSub.Unsubscribe()
+		defer func() { sub.Unsubscribe() }()
+
+		for {
+			select {
+			case log := <-upgrades:
+				upgraded := new(proxyUpgraded)
+				if err := proxy.UnpackLog(upgraded, "Upgraded", log); err != nil {
+					return err
+				}
+				addresses = appendAddress(addresses, upgraded.Impl)
+
+				// Resubscribe with the new implementation, the logs it emitted in
+				// the block of the upgrade are already past the subscription.
+				sub.Unsubscribe()
+				if sub, err = c.watchLogs(&WatchOpts{Context: opts.Context}, addresses, logs, name, query...); err != nil {
+					return err
+				}
+				block := log.BlockNumber
+				past, pastSub, err := c.filterLogs(&FilterOpts{Start: block, End: &block, Context: opts.Context}, []common.Address{upgraded.Impl}, name, query...)
+				if err != nil {
+					return err
+				}
+				err = drainLogs(past, pastSub, func(log types.Log) error {
+					select {
+					case logs <- log:
+						return nil
+					case <-quit:
+						return errWatchQuit
+					}
+				})
+				pastSub.Unsubscribe()
+				if err == errWatchQuit {
+					return nil
+				} else if err != nil {
+					return err
+				}
+
+			case err := <-sub.Err():
+				return err
+			case err := <-upgradeSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// errWatchQuit signals the termination of a watch while delivering logs.
+var errWatchQuit = errors.New("watch terminated")
+
+// drainLogs feeds the logs of a completing filter to the given function until
+// the filter is done delivering them.
+func drainLogs(logs chan types.Log, sub event.Subscription, fn func(types.Log) error) error {
+	for {
+		select {
+		case log := <-logs:
+			if err := fn(log); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			if err != nil {
+				return err
+			}
+			// Deliver whatever was buffered before completion
+			for {
+				select {
+				case log := <-logs:
+					if err := fn(log); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// appendAddress appends an address to the list unless already present.
+func appendAddress(addresses []common.Address, address common.Address) []common.Address {
+	for _, known := range addresses {
+		if known == address {
+			return addresses
+		}
+	}
+	return append(addresses, address)
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ethereum "range/core/gen3"
+	"range/core/gen3/accounts/abi"
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/event"
+)
+
+var (
+	proxyAddr   = common.HexToAddress("0x306")
+	implAtStart = common.HexToAddress("0x1001")
+	implLatest  = common.HexToAddress("0x1002")
+	implInRange = common.HexToAddress("0x1003")
+	implBefore  = common.HexToAddress("0x1004")
+
+	upgradedTopic = crypto.Keccak256Hash([]byte("Upgraded(address,address)"))
+)
+
+// mockFilterer records the queries filtering and watching logs, the proxy
+// being upgraded at the given blocks.
+type mockFilterer struct {
+	queries  []ethereum.FilterQuery
+	upgrades map[uint64]common.Address
+}
+
+func (mf *mockFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	mf.queries = append(mf.queries, query)
+	if query.Topics[0][0] != upgradedTopic {
+		return nil, nil
+	}
+	upgrades := mf.upgrades
+	if upgrades == nil {
+		upgrades = map[uint64]common.Address{15: implInRange}
+	}
+	var logs []types.Log
+	for number := uint64(0); number <= 30; number++ {
+		impl, ok := upgrades[number]
+		if !ok || number < query.FromBlock.Uint64() || (query.ToBlock != nil && number > query.ToBlock.Uint64()) {
+			continue
+		}
+		logs = append(logs, types.Log{
+			Address:     proxyAddr,
+			Topics:      []common.Hash{upgradedTopic, impl.Hash()},
+			Data:        make([]byte, 32),
+			BlockNumber: number,
+		})
+	}
+	return logs, nil
+}
+
+func (mf *mockFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	mf.queries = append(mf.queries, query)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+// mockProxyBackend serves the implementation behind the proxy, the historical
+// state being available unless pruned.
+type mockProxyBackend struct {
+	mockFilterer
+	pruned bool
+}
+
+func (mb *mockProxyBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (mb *mockProxyBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber == nil {
+		return implLatest.Hash().Bytes(), nil
+	}
+	if mb.pruned {
+		return nil, errors.New("missing trie node")
+	}
+	return implAtStart.Hash().Bytes(), nil
+}
+
+func newProxiedContract(t *testing.T, backend bind.ContractFilterer) *bind.BoundContract {
+	parsed, err := abi.JSON(strings.NewReader(`[{"anonymous":false,"inputs":[{"indexed":true,"name":"number","type":"uint256"}],"name":"Checkpoint","type":"event"}]`))
+	assert.Empty(t, err)
+	return bind.NewBoundContract(proxyAddr, parsed, nil, nil, backend)
+}
+
+func TestFilterProxiedLogs(t *testing.T) {
+	end := uint64(20)
+	opts := &bind.FilterOpts{Start: 10, End: &end}
+
+	// The implementation at the start and the upgrades within the range
+	backend := new(mockProxyBackend)
+	_, sub, err := newProxiedContract(t, backend).FilterProxiedLogs(opts, "Checkpoint")
+	assert.Empty(t, err)
+	sub.Unsubscribe()
+
+	assert.Len(t, backend.queries, 2)
+	assert.Equal(t, []common.Address{proxyAddr}, backend.queries[0].Addresses)
+	assert.Equal(t, []common.Address{proxyAddr, implAtStart, implInRange}, backend.queries[1].Addresses)
+	assert.Equal(t, big.NewInt(10), backend.queries[1].FromBlock)
+	assert.Equal(t, big.NewInt(20), backend.queries[1].ToBlock)
+
+	// The last upgrade before the start if the start state is pruned
+	backend = &mockProxyBackend{pruned: true}
+	backend.upgrades = map[uint64]common.Address{3: implBefore, 5: implAtStart, 15: implInRange}
+	_, sub, err = newProxiedContract(t, backend).FilterProxiedLogs(opts, "Checkpoint")
+	assert.Empty(t, err)
+	sub.Unsubscribe()
+
+	assert.Len(t, backend.queries, 3)
+	assert.Equal(t, []common.Address{proxyAddr, implAtStart, implInRange}, backend.queries[2].Addresses)
+
+	// The current implementation if never upgraded
+	backend = &mockProxyBackend{pruned: true}
+	backend.upgrades = map[uint64]common.Address{}
+	_, sub, err = newProxiedContract(t, backend).FilterProxiedLogs(opts, "Checkpoint")
+	assert.Empty(t, err)
+	sub.Unsubscribe()
+
+	assert.Equal(t, []common.Address{proxyAddr, implLatest}, backend.queries[2].Addresses)
+
+	// The deployed implementation is unknown if upgraded since
+	backend = &mockProxyBackend{pruned: true}
+	_, _, err = newProxiedContract(t, backend).FilterProxiedLogs(opts, "Checkpoint")
+	assert.EqualError(t, err, "missing trie node")
+
+	// No way to resolve the implementation
+	_, _, err = newProxiedContract(t, new(mockFilterer)).FilterProxiedLogs(opts, "Checkpoint")
+	assert.Equal(t, bind.ErrNoProxyCaller, err)
+}
+
+func TestWatchProxiedLogs(t *testing.T) {
+	backend := new(mockProxyBackend)
+	_, sub, err := newProxiedContract(t, backend).WatchProxiedLogs(nil, "Checkpoint")
+	assert.Empty(t, err)
+	sub.Unsubscribe()
+
+	assert.Len(t, backend.queries, 2)
+	assert.Equal(t, []common.Address{proxyAddr}, backend.queries[0].Addresses)
+	assert.Equal(t, upgradedTopic, backend.queries[0].Topics[0][0])
+	assert.Equal(t, []common.Address{proxyAddr, implLatest}, backend.queries[1].Addresses)
+}
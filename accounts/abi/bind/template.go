@@ -35,6 +35,8 @@ type tmplContract struct {
 	Calls       map[string]*tmplMethod // Contract calls that only read state data
 	Transacts   map[string]*tmplMethod // Contract calls that write state data
 	Events      map[string]*tmplEvent  // Contract events accessors
+
+	GovernedProxy bool // Whether the contract is reached through a Range governed proxy
 }
 
 // tmplMethod is a wrapper around an abi.Method that contains a few preprocessed
@@ -259,6 +261,48 @@ var (
 		return _{{$contract.Type}}.Contract.contract.Transact(opts, method, params...)
 	}
 
+	{{if .GovernedProxy}}
+		// UpgradeImpl is a free data retrieval call returning the implementation
+		// currently behind the governed proxy of {{.Type}}.
+		func (_{{$contract.Type}} *{{$contract.Type}}Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+			return _{{$contract.Type}}.contract.ProxyImpl(opts)
+		}
+
+		// UpgradeProposals is a free data retrieval call listing the pending upgrade
+		// proposals of the governed proxy of {{.Type}}.
+		func (_{{$contract.Type}} *{{$contract.Type}}Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+			var ret []common.Address
+			err := _{{$contract.Type}}.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+			return ret, err
+		}
+
+		// UpgradeProposalImpl is a free data retrieval call returning the implementation
+		// an upgrade proposal of the governed proxy of {{.Type}} switches to.
+		func (_{{$contract.Type}} *{{$contract.Type}}Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+			var ret common.Address
+			err := _{{$contract.Type}}.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+			return ret, err
+		}
+
+		// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+		// proxy of {{.Type}} to a new implementation, the proposal fee being the value.
+		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+			return _{{$contract.Type}}.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+		}
+
+		// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+		// {{.Type}} to the implementation of an accepted proposal.
+		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+			return _{{$contract.Type}}.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+		}
+
+		// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+		// proposal of the governed proxy of {{.Type}}.
+		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+			return _{{$contract.Type}}.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+		}
+	{{end}}
+
 	{{range .Calls}}
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
@@ -398,7 +442,7 @@ var (
 				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
 			}{{end}}{{end}}
 
-			logs, sub, err := _{{$contract.Type}}.contract.FilterLogs(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			logs, sub, err := _{{$contract.Type}}.contract.{{if $contract.GovernedProxy}}FilterProxiedLogs{{else}}FilterLogs{{end}}(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
 			if err != nil {
 				return nil, err
 			}
@@ -415,7 +459,7 @@ var (
 				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
 			}{{end}}{{end}}
 
-			logs, sub, err := _{{$contract.Type}}.contract.WatchLogs(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			logs, sub, err := _{{$contract.Type}}.contract.{{if $contract.GovernedProxy}}WatchProxiedLogs{{else}}WatchLogs{{end}}(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
 			if err != nil {
 				return nil, err
 			}
@@ -42,6 +42,8 @@ var (
 	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
 	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
 	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc)")
+
+	proxyFlag = flag.Bool("governed-proxy", false, "Bind the contracts as reached through a Range governed proxy")
 )
 
 func main() {
@@ -153,7 +155,7 @@ func main() {
 		types = append(types, kind)
 	}
 	// Generate the contract binding
-	code, err := bind.Bind(types, abis, bins, runs, *pkgFlag, lang)
+	code, err := bind.Bind(types, abis, bins, runs, *pkgFlag, lang, *proxyFlag)
 	if err != nil {
 		fmt.Printf("Failed to generate ABI binding: %v\n", err)
 		os.Exit(-1)
@@ -0,0 +1,356 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/common/math"
+	"range/core/gen3/consensus"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"range/core/gen3/rlp"
+	"range/core/gen3/tests"
+	"golang.org/x/crypto/sha3"
+
+	energi_consensus "range/core/gen3/energi/consensus"
+	energi_params "range/core/gen3/energi/params"
+)
+
+var (
+	errNoRangeRules     = errors.New("finalization requires a Range ruleset")
+	errNoAncestorState  = errors.New("state of the ancestors is not available")
+	errConsensusTxOrder = errors.New("consensus transactions must be at the end")
+
+	consensusSigner = energi_consensus.NewConsensusSigner()
+)
+
+// Prestate is the state the transactions are applied to: the accounts and the
+// environment of the block.
+type Prestate struct {
+	Env stEnv             `json:"env"`
+	Pre core.GenesisAlloc `json:"pre"`
+}
+
+// ExecutionResult is the outcome of the transition: the roots of the block,
+// the receipts of the included transactions and the rejected ones.
+type ExecutionResult struct {
+	StateRoot    common.Hash        `json:"stateRoot"`
+	TxRoot       common.Hash        `json:"txRoot"`
+	ReceiptRoot  common.Hash        `json:"receiptRoot"`
+	LogsHash     common.Hash        `json:"logsHash"`
+	Bloom        types.Bloom        `json:"logsBloom"`
+	Receipts     types.Receipts     `json:"receipts"`
+	Rejected     []*rejectedTx      `json:"rejected,omitempty"`
+	ConsensusTxs types.Transactions `json:"consensusTxs,omitempty"` // Created by the finalization
+	GasUsed      hexutil.Uint64     `json:"gasUsed"`
+}
+
+// rejectedTx is a transaction that could not be included in the block.
+type rejectedTx struct {
+	Index int    `json:"index"`
+	Err   string `json:"error"`
+}
+
+// stEnv is the environment of the block. The ancestors are only known by
+// their hashes, serving BLOCKHASH.
+type stEnv struct {
+	Coinbase    common.Address                      `json:"currentCoinbase"`
+	Difficulty  *math.HexOrDecimal256               `json:"currentDifficulty"`
+	GasLimit    math.HexOrDecimal64                 `json:"currentGasLimit"`
+	Number      math.HexOrDecimal64                 `json:"currentNumber"`
+	Timestamp   math.HexOrDecimal64                 `json:"currentTimestamp"`
+	ParentHash  common.Hash                         `json:"parentHash"`
+	BlockHashes map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
+}
+
+// blockHash returns the hash of an ancestor of the block, if known.
+func (env *stEnv) blockHash(number uint64) common.Hash {
+	if number+1 == uint64(env.Number) && (env.ParentHash != common.Hash{}) {
+		return env.ParentHash
+	}
+	return env.BlockHashes[math.HexOrDecimal64(number)]
+}
+
+// header assembles the header of the block from the environment.
+func (env *stEnv) header() *types.Header {
+	header := &types.Header{
+		Coinbase:   env.Coinbase,
+		Difficulty: new(big.Int),
+		GasLimit:   uint64(env.GasLimit),
+		Number:     new(big.Int).SetUint64(uint64(env.Number)),
+		Time:       uint64(env.Timestamp),
+	}
+	if env.Difficulty != nil {
+		header.Difficulty = (*big.Int)(env.Difficulty)
+	}
+	if env.Number > 0 {
+		header.ParentHash = env.blockHash(uint64(env.Number) - 1)
+	}
+	return header
+}
+
+// Apply applies the transactions to the pre-state, rejecting the invalid
+// ones, and runs the Range consensus finalization over the result if
+// requested. Consensus transactions among the input are checked against the
+// ones created by the finalization, or applied as the tracers replay them
+// otherwise.
+func (pre *Prestate) Apply(
+	vmConfig vm.Config,
+	chainConfig *params.ChainConfig,
+	txs types.Transactions,
+	finalize bool,
+	getTracerFn func(txIndex int, txHash common.Hash) (vm.Tracer, error),
+) (*state.StateDB, *ExecutionResult, error) {
+	if finalize && chainConfig.Range == nil {
+		return nil, nil, errNoRangeRules
+	}
+	var (
+		db      = ethdb.NewMemDatabase()
+		statedb = tests.MakePreState(db, pre.Pre)
+		header  = pre.Env.header()
+		chain   = &chainContext{config: chainConfig, env: &pre.Env}
+		signer  = types.MakeSigner(chainConfig, header.Number)
+		gaspool = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas uint64
+
+		seenConsensus bool
+		included      types.Transactions
+		consensusTxs  types.Transactions
+		receipts      types.Receipts
+		rejected      []*rejectedTx
+	)
+	if chainConfig.Range != nil {
+		chain.engine = energi_consensus.New(chainConfig.Range, db)
+	} else {
+		chain.engine = ethash.NewFaker()
+	}
+	for i, tx := range txs {
+		// Range-specific: consensus transactions must be at the end
+		if tx.IsConsensus() {
+			seenConsensus = true
+			if finalize {
+				consensusTxs = append(consensusTxs, tx)
+				continue
+			}
+		} else {
+			if seenConsensus {
+				rejected = append(rejected, &rejectedTx{i, errConsensusTxOrder.Error()})
+				continue
+			}
+			if _, err := types.Sender(signer, tx); err != nil {
+				rejected = append(rejected, &rejectedTx{i, err.Error()})
+				continue
+			}
+		}
+		vmConfig := vmConfig
+		if getTracerFn != nil {
+			tracer, err := getTracerFn(len(included), tx.Hash())
+			if err != nil {
+				return nil, nil, err
+			}
+			vmConfig.Tracer, vmConfig.Debug = tracer, true
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+
+		var (
+			snapshot = statedb.Snapshot()
+			prevGas  = *gaspool
+			receipt  *types.Receipt
+			err      error
+		)
+		if tx.IsConsensus() {
+			receipt, err = applyConsensusTx(chain, statedb, header, tx, usedGas, vmConfig)
+		} else {
+			receipt, _, err = core.ApplyTransaction(chainConfig, chain, nil, gaspool, statedb, header, tx, &usedGas, vmConfig)
+		}
+		if err != nil {
+			statedb.RevertToSnapshot(snapshot)
+			*gaspool = prevGas
+			rejected = append(rejected, &rejectedTx{i, err.Error()})
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+	header.GasUsed = usedGas
+
+	var created types.Transactions
+	if finalize {
+		var (
+			engine = chain.engine.(*energi_consensus.Range)
+			block  *types.Block
+			err    error
+		)
+		if len(consensusTxs) > 0 {
+			block, receipts, err = engine.Finalize(chain, header, statedb, append(included, consensusTxs...), nil, receipts)
+		} else {
+			block, receipts, err = engine.FinalizeTransactions(chain, header, statedb, included, receipts)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not finalize block: %v", err)
+		}
+		created = block.Transactions()[len(included):]
+		included = block.Transactions()
+	}
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	root, err := statedb.Commit(chainConfig.IsEIP158(header.Number))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not commit state: %v", err)
+	}
+	result := &ExecutionResult{
+		StateRoot:    root,
+		TxRoot:       types.DeriveSha(included),
+		ReceiptRoot:  types.DeriveSha(receipts),
+		LogsHash:     rlpHash(logs),
+		Bloom:        types.CreateBloom(receipts),
+		Receipts:     receipts,
+		Rejected:     rejected,
+		ConsensusTxs: created,
+		GasUsed:      hexutil.Uint64(usedGas),
+	}
+	statedb, _ = state.New(root, statedb.Database())
+	return statedb, result, nil
+}
+
+// applyConsensusTx applies a consensus transaction without the finalization
+// creating it, preparing the state the same way the tracers replay it: the
+// system faucet is funded with the value and drained accounts are removed
+// from the blacklist. The gas is not accounted to the block.
+func applyConsensusTx(
+	chain *chainContext,
+	statedb *state.StateDB,
+	header *types.Header,
+	tx *types.Transaction,
+	usedGas uint64,
+	vmConfig vm.Config,
+) (*types.Receipt, error) {
+	if addr := tx.ConsensusSender(); addr == energi_params.Range_SystemFaucet {
+		statedb.SetBalance(energi_params.Range_SystemFaucet, tx.Value())
+	} else if (statedb.GetState(energi_params.Range_Blacklist, addr.Hash()) != common.Hash{}) {
+		statedb.SetState(energi_params.Range_Blacklist, addr.Hash(), common.Hash{})
+	}
+	msg, err := tx.AsMessage(consensusSigner)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		vmctx = core.NewEVMContext(msg, header, chain, nil)
+		evm   = vm.NewEVM(vmctx, statedb, chain.config, vmConfig)
+		gp    = core.GasPool(msg.Gas())
+	)
+	_, gas, failed, err := core.ApplyMessage(evm, msg, &gp)
+	if err != nil {
+		return nil, err
+	}
+	root := statedb.IntermediateRoot(chain.config.IsEIP158(header.Number))
+	receipt := types.NewReceipt(root.Bytes(), failed, usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = gas
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, nil
+}
+
+// chainContext serves the chain configuration and the consensus engine to the
+// EVM and the finalization. The ancestors only carry their number and parent
+// hash, all BLOCKHASH needs.
+type chainContext struct {
+	config *params.ChainConfig
+	engine consensus.Engine
+	env    *stEnv
+}
+
+// Config implements consensus.ChainReader.
+func (c *chainContext) Config() *params.ChainConfig {
+	return c.config
+}
+
+// Engine implements core.ChainContext.
+func (c *chainContext) Engine() consensus.Engine {
+	return c.engine
+}
+
+// CurrentHeader implements consensus.ChainReader, returning the parent.
+func (c *chainContext) CurrentHeader() *types.Header {
+	if c.env.Number == 0 {
+		return nil
+	}
+	return c.GetHeaderByNumber(uint64(c.env.Number) - 1)
+}
+
+// GetHeader implements consensus.ChainReader.
+func (c *chainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && c.env.blockHash(number) == hash {
+		return header
+	}
+	return nil
+}
+
+// GetHeaderByNumber implements consensus.ChainReader.
+func (c *chainContext) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(c.env.Number) || (c.env.blockHash(number) == common.Hash{}) {
+		return nil
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(number)}
+	if number > 0 {
+		header.ParentHash = c.env.blockHash(number - 1)
+	}
+	return header
+}
+
+// GetHeaderByHash implements consensus.ChainReader.
+func (c *chainContext) GetHeaderByHash(hash common.Hash) *types.Header {
+	if number := uint64(c.env.Number); number > 0 && c.env.blockHash(number-1) == hash {
+		return c.GetHeaderByNumber(number - 1)
+	}
+	for number, h := range c.env.BlockHashes {
+		if h == hash {
+			return c.GetHeaderByNumber(uint64(number))
+		}
+	}
+	return nil
+}
+
+// GetBlock implements consensus.ChainReader, the ancestor bodies are unknown.
+func (c *chainContext) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return nil
+}
+
+// CalculateBlockState implements consensus.ChainReader.
+func (c *chainContext) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	return nil, errNoAncestorState
+}
+
+func rlpHash(x interface{}) (h common.Hash) {
+	hw := sha3.NewLegacyKeccak256()
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
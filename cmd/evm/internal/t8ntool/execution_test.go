@@ -0,0 +1,186 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/math"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	energi_consensus "range/core/gen3/energi/consensus"
+	energi_params "range/core/gen3/energi/params"
+)
+
+const transitionPrestate = `{
+	"env": {
+		"currentCoinbase": "0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x750a163df65e8a",
+		"currentNumber": "1",
+		"currentTimestamp": "1000",
+		"parentHash": "0xe4e2a30b340bec696242b67584264f878600dce98354ae0b6328740fd4ff18da"
+	},
+	"pre": {
+		"0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+			"balance": "0x5ffd4878be161d74",
+			"nonce": "0xac"
+		},
+		"0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192": {
+			"balance": "0xfeedbead"
+		}
+	}
+}`
+
+func TestApply(t *testing.T) {
+	var pre Prestate
+	assert.Empty(t, json.Unmarshal([]byte(transitionPrestate), &pre))
+
+	var (
+		key, _   = crypto.HexToECDSA("45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192")
+		config   = params.TestChainConfig
+		signer   = types.MakeSigner(config, big.NewInt(1))
+		sign     = func(tx *types.Transaction) *types.Transaction {
+			tx, err := types.SignTx(tx, signer, key)
+			assert.Empty(t, err)
+			return tx
+		}
+	)
+	txs := types.Transactions{
+		// A plain transfer and a zero-fee one
+		sign(types.NewTransaction(0xac, receiver, big.NewInt(1), 21000, big.NewInt(1), nil)),
+		sign(types.NewTransaction(0xad, receiver, big.NewInt(1), 21000, common.Big0, nil)),
+		// Nonce too high
+		sign(types.NewTransaction(0xaf, receiver, big.NewInt(1), 21000, big.NewInt(1), nil)),
+		// Funded by the system faucet
+		types.NewTransaction(0, receiver, big.NewInt(5), 21000, common.Big0, nil).WithConsensusSender(energi_params.Range_SystemFaucet),
+		// Regular transactions are not allowed after the consensus ones
+		sign(types.NewTransaction(0xae, receiver, big.NewInt(1), 21000, big.NewInt(1), nil)),
+	}
+	statedb, result, err := pre.Apply(vm.Config{}, config, txs, false, nil)
+	assert.Empty(t, err)
+
+	assert.Len(t, result.Receipts, 3)
+	assert.Equal(t, []*rejectedTx{
+		{2, "nonce too high"},
+		{4, errConsensusTxOrder.Error()},
+	}, result.Rejected)
+	assert.Equal(t, uint64(42000), uint64(result.GasUsed))
+	assert.Equal(t, types.DeriveSha(types.Transactions{txs[0], txs[1], txs[3]}), result.TxRoot)
+
+	assert.Equal(t, uint64(0xae), statedb.GetNonce(sender))
+	assert.Equal(t, big.NewInt(0xfeedbead+7), statedb.GetBalance(receiver))
+	assert.Equal(t, common.Big0, statedb.GetBalance(energi_params.Range_SystemFaucet))
+
+	// The finalization requires the Range consensus rules
+	_, _, err = pre.Apply(vm.Config{}, config, txs[:2], true, nil)
+	assert.Equal(t, errNoRangeRules, err)
+}
+
+// Tests that the finalization creates the same consensus transactions and
+// state root as the consensus engine processing the block on a chain.
+func TestApplyFinalize(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0x8a8eafb1cf62bfbeb1741769dae1a9dd47996192")
+		coinbase = common.HexToAddress("0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba")
+		db       = ethdb.NewMemDatabase()
+		config   = *params.RangeTestnetChainConfig
+	)
+	config.Range = &params.RangeConfig{MigrationSigner: sender}
+	gspec := &core.Genesis{
+		Config:     &config,
+		GasLimit:   8000000,
+		Timestamp:  1000,
+		Difficulty: big.NewInt(1),
+		Coinbase:   energi_params.Range_Treasury,
+		Alloc:      core.GenesisAlloc{sender: {Balance: big.NewInt(1e18)}},
+		Xfers:      core.DeployRangeGovernance(&config),
+	}
+	genesis := gspec.MustCommit(db)
+
+	engine := energi_consensus.New(config.Range, db)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+
+	signer := types.MakeSigner(&config, big.NewInt(1))
+	tx, err := types.SignTx(types.NewTransaction(0, receiver, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+	assert.Empty(t, err)
+
+	// The block processed by the engine on top of the genesis
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Coinbase:   coinbase,
+		Difficulty: big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Number:     big.NewInt(1),
+		Time:       genesis.Time() + 30,
+	}
+	statedb, err := chain.StateAt(genesis.Root())
+	assert.Empty(t, err)
+	pre := dumpAlloc(statedb)
+
+	statedb.Prepare(tx.Hash(), common.Hash{}, 0)
+	receipt, _, err := core.ApplyTransaction(&config, chain, nil, new(core.GasPool).AddGas(header.GasLimit),
+		statedb, header, tx, &header.GasUsed, vm.Config{})
+	assert.Empty(t, err)
+	block, _, err := engine.FinalizeTransactions(chain, header, statedb, types.Transactions{tx}, types.Receipts{receipt})
+	assert.Empty(t, err)
+	root := statedb.IntermediateRoot(config.IsEIP158(header.Number))
+
+	prestate := &Prestate{
+		Env: stEnv{
+			Coinbase:   coinbase,
+			Difficulty: (*math.HexOrDecimal256)(header.Difficulty),
+			GasLimit:   math.HexOrDecimal64(header.GasLimit),
+			Number:     1,
+			Timestamp:  math.HexOrDecimal64(header.Time),
+			ParentHash: genesis.Hash(),
+		},
+		Pre: pre,
+	}
+	_, result, err := prestate.Apply(vm.Config{}, &config, types.Transactions{tx}, true, nil)
+	assert.Empty(t, err)
+	assert.Empty(t, result.Rejected)
+	assert.NotEmpty(t, result.ConsensusTxs)
+	assert.Equal(t, block.Transactions()[1:], result.ConsensusTxs)
+	assert.Equal(t, root, result.StateRoot)
+	assert.Equal(t, block.TxHash(), result.TxRoot)
+
+	// The consensus transactions of the input are checked against the created ones
+	txs := append(types.Transactions{tx}, result.ConsensusTxs...)
+	_, checked, err := prestate.Apply(vm.Config{}, &config, txs, true, nil)
+	assert.Empty(t, err)
+	assert.Equal(t, root, checked.StateRoot)
+
+	_, _, err = prestate.Apply(vm.Config{}, &config, append(txs, result.ConsensusTxs...), true, nil)
+	assert.NotEmpty(t, err)
+}
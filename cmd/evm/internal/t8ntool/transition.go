@@ -0,0 +1,341 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+// Package t8ntool implements the state transition tool of the evm command,
+// applying a set of transactions over a pre-state and reporting the result.
+package t8ntool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/common/math"
+	"range/core/gen3/core"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/log"
+	"range/core/gen3/params"
+	"range/core/gen3/rlp"
+	"range/core/gen3/tests"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	rangeMainnet = "RangeMainnet"
+	rangeTestnet = "RangeTestnet"
+
+	stdinSelector = "stdin"
+)
+
+// input is the combined input read from stdin.
+type input struct {
+	Alloc core.GenesisAlloc `json:"alloc,omitempty"`
+	Env   *stEnv            `json:"env,omitempty"`
+	Txs   []*txWithKey      `json:"txs,omitempty"`
+}
+
+// txWithKey is a transaction of the input. It is signed with the secret key
+// if given, turned into a consensus transaction of the given sender or taken
+// with its signature as is otherwise. Zero-fee transactions are simply ones
+// with a zero gas price.
+type txWithKey struct {
+	Nonce           math.HexOrDecimal64   `json:"nonce"`
+	GasPrice        *math.HexOrDecimal256 `json:"gasPrice"`
+	Gas             math.HexOrDecimal64   `json:"gas"`
+	To              *common.Address       `json:"to"`
+	Value           *math.HexOrDecimal256 `json:"value"`
+	Input           hexutil.Bytes         `json:"input"`
+	V               *math.HexOrDecimal256 `json:"v"`
+	R               *math.HexOrDecimal256 `json:"r"`
+	S               *math.HexOrDecimal256 `json:"s"`
+	SecretKey       *common.Hash          `json:"secretKey"`
+	ConsensusSender *common.Address       `json:"consensusSender"`
+}
+
+// transaction assembles the transaction, signing it if needed.
+func (t *txWithKey) transaction(signer types.Signer) (*types.Transaction, error) {
+	var (
+		price = bigOrZero(t.GasPrice)
+		value = bigOrZero(t.Value)
+		tx    *types.Transaction
+	)
+	if t.To == nil {
+		tx = types.NewContractCreation(uint64(t.Nonce), value, uint64(t.Gas), price, t.Input)
+	} else {
+		tx = types.NewTransaction(uint64(t.Nonce), *t.To, value, uint64(t.Gas), price, t.Input)
+	}
+	switch {
+	case t.ConsensusSender != nil:
+		return tx.WithConsensusSender(*t.ConsensusSender), nil
+
+	case t.SecretKey != nil:
+		key, err := crypto.ToECDSA(t.SecretKey[:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key: %v", err)
+		}
+		return types.SignTx(tx, signer, key)
+
+	default:
+		v, r, s := bigOrZero(t.V), bigOrZero(t.R), bigOrZero(t.S)
+		if v.Sign() == 0 && r.Sign() == 0 && s.Sign() == 0 {
+			return tx, nil
+		}
+		// Swap the signature values in through the JSON form, which
+		// validates them
+		enc := make(map[string]interface{})
+		if err := remarshal(tx, &enc); err != nil {
+			return nil, err
+		}
+		enc["v"], enc["r"], enc["s"] = (*hexutil.Big)(v), (*hexutil.Big)(r), (*hexutil.Big)(s)
+
+		signed := new(types.Transaction)
+		if err := remarshal(enc, signed); err != nil {
+			return nil, err
+		}
+		return signed, nil
+	}
+}
+
+// remarshal converts between two JSON representations.
+func remarshal(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// Transition implements the transition command of the evm.
+func Transition(ctx *cli.Context) error {
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.GlobalInt("verbosity")))
+	log.Root().SetHandler(glogger)
+
+	baseDir, err := createBasedir(ctx)
+	if err != nil {
+		return err
+	}
+	// Read the pre-state, the environment and the transactions
+	var (
+		prestate Prestate
+		txsIn    []*txWithKey
+		inStdin  *input
+
+		allocStr = ctx.String(InputAllocFlag.Name)
+		envStr   = ctx.String(InputEnvFlag.Name)
+		txStr    = ctx.String(InputTxsFlag.Name)
+	)
+	if allocStr == stdinSelector || envStr == stdinSelector || txStr == stdinSelector {
+		inStdin = new(input)
+		if err := json.NewDecoder(os.Stdin).Decode(inStdin); err != nil {
+			return fmt.Errorf("failed unmarshaling stdin: %v", err)
+		}
+	}
+	if allocStr == stdinSelector {
+		prestate.Pre = inStdin.Alloc
+	} else if err := readFile(allocStr, "alloc", &prestate.Pre); err != nil {
+		return err
+	}
+	if envStr == stdinSelector {
+		if inStdin.Env == nil {
+			return fmt.Errorf("no env in stdin")
+		}
+		prestate.Env = *inStdin.Env
+	} else if err := readFile(envStr, "env", &prestate.Env); err != nil {
+		return err
+	}
+	if txStr == stdinSelector {
+		txsIn = inStdin.Txs
+	} else if err := readFile(txStr, "txs", &txsIn); err != nil {
+		return err
+	}
+	// Pick the ruleset and assemble the transactions
+	chainConfig, err := ruleset(ctx.String(ForknameFlag.Name), ctx.Uint64(ChainIDFlag.Name))
+	if err != nil {
+		return err
+	}
+	signer := types.MakeSigner(chainConfig, new(big.Int).SetUint64(uint64(prestate.Env.Number)))
+
+	txs := make(types.Transactions, len(txsIn))
+	for i, in := range txsIn {
+		if txs[i], err = in.transaction(signer); err != nil {
+			return fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	// Configure the tracers, writing a file per transaction
+	var (
+		getTracerFn func(txIndex int, txHash common.Hash) (vm.Tracer, error)
+		traceFiles  []*os.File
+	)
+	if ctx.Bool(TraceFlag.Name) {
+		logConfig := &vm.LogConfig{
+			DisableMemory: ctx.Bool(TraceDisableMemoryFlag.Name),
+			DisableStack:  ctx.Bool(TraceDisableStackFlag.Name),
+		}
+		getTracerFn = func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+			name := filepath.Join(baseDir, fmt.Sprintf("trace-%d-%v.jsonl", txIndex, txHash.String()))
+			file, err := os.Create(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed creating trace-file: %v", err)
+			}
+			traceFiles = append(traceFiles, file)
+			return vm.NewJSONLogger(logConfig, file), nil
+		}
+	}
+	statedb, result, err := prestate.Apply(vm.Config{}, chainConfig, txs, ctx.Bool(FinalizeFlag.Name), getTracerFn)
+	for _, file := range traceFiles {
+		file.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return dispatchOutput(ctx, baseDir, result, dumpAlloc(statedb))
+}
+
+// ruleset returns the chain configuration of the given name, overriding the
+// chain ID if non-zero.
+func ruleset(name string, chainID uint64) (*params.ChainConfig, error) {
+	var config params.ChainConfig
+	switch name {
+	case rangeMainnet:
+		config = *params.RangeMainnetChainConfig
+	case rangeTestnet:
+		config = *params.RangeTestnetChainConfig
+	default:
+		forked, ok := tests.Forks[name]
+		if !ok {
+			return nil, tests.UnsupportedForkError{Name: name}
+		}
+		config = *forked
+	}
+	if chainID != 0 {
+		config.ChainID = new(big.Int).SetUint64(chainID)
+	}
+	return &config, nil
+}
+
+// dumpAlloc converts the post-state into the alloc format of the input.
+func dumpAlloc(statedb *state.StateDB) core.GenesisAlloc {
+	dump := statedb.RawDump()
+
+	alloc := make(core.GenesisAlloc, len(dump.Accounts))
+	for addr, account := range dump.Accounts {
+		balance, _ := new(big.Int).SetString(account.Balance, 10)
+		genAccount := core.GenesisAccount{
+			Code:    common.FromHex(account.Code),
+			Balance: balance,
+			Nonce:   account.Nonce,
+		}
+		if len(account.Storage) > 0 {
+			genAccount.Storage = make(map[common.Hash]common.Hash, len(account.Storage))
+		}
+		for key, value := range account.Storage {
+			var content []byte
+			rlp.DecodeBytes(common.FromHex(value), &content)
+			genAccount.Storage[common.HexToHash(key)] = common.BytesToHash(content)
+		}
+		alloc[common.HexToAddress(addr)] = genAccount
+	}
+	return alloc
+}
+
+// dispatchOutput writes the result and the post-state alloc to their files,
+// or combined to stdout or stderr.
+func dispatchOutput(ctx *cli.Context, baseDir string, result *ExecutionResult, alloc core.GenesisAlloc) error {
+	var (
+		stdOutObject = make(map[string]interface{})
+		stdErrObject = make(map[string]interface{})
+	)
+	dispatch := func(fName, name string, obj interface{}) error {
+		switch fName {
+		case "stdout":
+			stdOutObject[name] = obj
+		case "stderr":
+			stdErrObject[name] = obj
+		default:
+			b, err := json.MarshalIndent(obj, "", " ")
+			if err != nil {
+				return fmt.Errorf("failed marshalling output: %v", err)
+			}
+			if err = ioutil.WriteFile(filepath.Join(baseDir, fName), b, 0644); err != nil {
+				return fmt.Errorf("failed writing output: %v", err)
+			}
+		}
+		return nil
+	}
+	if err := dispatch(ctx.String(OutputAllocFlag.Name), "alloc", alloc); err != nil {
+		return err
+	}
+	if err := dispatch(ctx.String(OutputResultFlag.Name), "result", result); err != nil {
+		return err
+	}
+	if len(stdOutObject) > 0 {
+		b, err := json.MarshalIndent(stdOutObject, "", " ")
+		if err != nil {
+			return fmt.Errorf("failed marshalling output: %v", err)
+		}
+		os.Stdout.Write(append(b, '\n'))
+	}
+	if len(stdErrObject) > 0 {
+		b, err := json.MarshalIndent(stdErrObject, "", " ")
+		if err != nil {
+			return fmt.Errorf("failed marshalling output: %v", err)
+		}
+		os.Stderr.Write(append(b, '\n'))
+	}
+	return nil
+}
+
+// createBasedir creates the output directory if needed.
+func createBasedir(ctx *cli.Context) (string, error) {
+	baseDir := ctx.String(OutputBasedir.Name)
+	if baseDir != "" {
+		if err := os.MkdirAll(baseDir, 0755); err != nil {
+			return "", fmt.Errorf("failed creating output basedir: %v", err)
+		}
+	}
+	return baseDir, nil
+}
+
+// readFile decodes the JSON content of the named input file.
+func readFile(path, desc string, dest interface{}) error {
+	inFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed reading %s file: %v", desc, err)
+	}
+	defer inFile.Close()
+
+	if err := json.NewDecoder(inFile).Decode(dest); err != nil {
+		return fmt.Errorf("failed unmarshaling %s file: %v", desc, err)
+	}
+	return nil
+}
+
+func bigOrZero(v *math.HexOrDecimal256) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return (*big.Int)(v)
+}
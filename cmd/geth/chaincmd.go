@@ -33,10 +33,12 @@ import (
 	"range/core/gen3/core/state"
 	"range/core/gen3/core/types"
 	"range/core/gen3/eth/downloader"
+	"range/core/gen3/internal/era"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/event"
 	"range/core/gen3/log"
 	"range/core/gen3/trie"
+	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -65,11 +67,15 @@ It expects the genesis file as argument.`,
 		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.SnapshotFlag,
+			utils.AncientDepthFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -86,6 +92,8 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -96,6 +104,84 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	eraSizeFlag = cli.Uint64Flag{
+		Name:  "era.size",
+		Usage: "Number of blocks in an epoch archive",
+		Value: era.DefaultEpochSize,
+	}
+	eraStateFlag = cli.BoolFlag{
+		Name:  "era.state",
+		Usage: "Dump the state at the end of the export, for offline bootstrapping",
+	}
+	eraNoExecFlag = cli.BoolFlag{
+		Name:  "era.noexec",
+		Usage: "Import the blocks with their verified receipts, without execution",
+	}
+	eraTrustedFlag = cli.StringFlag{
+		Name:  "era.trusted",
+		Usage: "Hash of the last archived block, trusted by --era.noexec along with the blocks below it",
+	}
+	exportHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportHistory),
+		Name:      "export-history",
+		Usage:     "Export blockchain history into era archives",
+		ArgsUsage: "<dir> <blockNumFirst> <blockNumLast>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			eraSizeFlag,
+			eraStateFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes the blocks in the range into the directory as era archives, one per
+epoch of --era.size blocks. Each archive holds the blocks with their receipts
+and total difficulties, along with an index and the accumulator root naming
+the file, so that it can be verified on its own.
+
+With --era.state the state is dumped too, a stake window below the last block,
+for new nodes to be bootstrapped offline by import-history --era.noexec.`,
+	}
+	importHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(importHistory),
+		Name:      "import-history",
+		Usage:     "Import blockchain history from era archives",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.GCModeFlag,
+			utils.CacheDatabaseFlag,
+			utils.CacheGCFlag,
+			eraNoExecFlag,
+			eraTrustedFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Imports the era archives of the network in the directory in epoch order, every
+archive is verified first. The blocks are executed, unless --era.noexec is set:
+the blocks are then written along with their receipts, as matched against their
+headers. Their seals can't be verified without the state, so they are trusted by
+their hash link to a validated checkpoint or to the last archived block, whose
+hash is given by --era.trusted. The head block is set on top of the dumped
+state, if present, else the state is to be completed by a sync.`,
+	}
+	verifyHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyHistory),
+		Name:      "verify-history",
+		Usage:     "Verify era archives on their own",
+		ArgsUsage: "<file> (<file 2> ... <file N>)",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+Checks the bodies and receipts of the archived blocks against their headers,
+their hash links, total difficulties and the accumulator root of each archive.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -104,6 +190,8 @@ be gzipped.`,
 		ArgsUsage: "<datafile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -118,6 +206,8 @@ be gzipped.`,
 		ArgsUsage: "<dumpfile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -132,6 +222,8 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
@@ -148,6 +240,8 @@ The first argument must be the directory containing the blockchain to download f
 		ArgsUsage: " ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -160,6 +254,8 @@ Remove blockchain and state databases`,
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.FreezerFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -246,7 +342,9 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
+	db := chainDb.(interface {
+		LDB() *leveldb.DB
+	})
 
 	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
@@ -331,13 +429,68 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires three arguments.")
+	}
+	first, ferr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	last, lerr := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	start := time.Now()
+	if err := utils.ExportHistory(chain, ctx.Args().First(), first, last, ctx.Uint64(eraSizeFlag.Name), ctx.Bool(eraStateFlag.Name)); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	var trusted common.Hash
+	if ctx.IsSet(eraTrustedFlag.Name) {
+		if err := trusted.UnmarshalText([]byte(ctx.String(eraTrustedFlag.Name))); err != nil {
+			utils.Fatalf("Invalid trusted block hash: %v", err)
+		}
+	}
+	start := time.Now()
+	if err := utils.ImportHistory(chain, chainDb, ctx.Args().First(), ctx.Bool(eraNoExecFlag.Name), trusted); err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+func verifyHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	if err := utils.VerifyHistory(ctx.Args()); err != nil {
+		utils.Fatalf("Verification error: %v\n", err)
+	}
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -353,7 +506,7 @@ func exportPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -403,7 +556,10 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	ldb := chainDb.(interface {
+		LDB() *leveldb.DB
+	})
+	if err = ldb.LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -414,7 +570,11 @@ func copyDb(ctx *cli.Context) error {
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
-	for _, name := range []string{"chaindata", "lightchaindata"} {
+	names := []string{"chaindata", "lightchaindata"}
+	if ancient := ctx.GlobalString(utils.AncientFlag.Name); ancient != "" {
+		names = append(names, ancient)
+	}
+	for _, name := range names {
 		// Ensure the database exists in the first place
 		logger := log.New("database", name)
 
@@ -157,6 +157,9 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if ctx.GlobalIsSet(utils.ConstantinopleOverrideFlag.Name) {
 		cfg.Eth.ConstantinopleOverride = new(big.Int).SetUint64(ctx.GlobalUint64(utils.ConstantinopleOverrideFlag.Name))
 	}
+	if ctx.GlobalIsSet(utils.IstanbulOverrideFlag.Name) {
+		cfg.Eth.IstanbulOverride = new(big.Int).SetUint64(ctx.GlobalUint64(utils.IstanbulOverrideFlag.Name))
+	}
 	utils.RegisterEthService(stack, &cfg.Eth)
 
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
@@ -178,13 +181,13 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
-	// Add the Ethereum Stats daemon if requested.
-	if cfg.Ethstats.URL != "" {
-		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
-	}
-
 	utils.RegisterDynamicCheckpointService(stack)
 
+	// Add the GraphQL service if requested, it requires a full node
+	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts, cfg.Node.RPCPolicy)
+	}
+
 	if ctx.GlobalBool(utils.MasternodeFlag.Name) {
 		var owner common.Address
 		if ownerStr := ctx.GlobalString(utils.MasternodeOwnerFlag.Name); ownerStr != "" {
@@ -196,6 +199,12 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterMasternodeService(stack, owner)
 	}
 
+	// Add the Ethereum Stats daemon if requested. It must be registered after
+	// the masternode service to be able to report the masternode duties.
+	if cfg.Ethstats.URL != "" {
+		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
+	}
+
 	return stack
 }
 
@@ -0,0 +1,188 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"range/core/gen3/cmd/utils"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/rawdb"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Inspect and repair the database of a stopped node",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(inspectDatabase),
+				Name:      "inspect",
+				Usage:     "Report the number and the size of the entries per category",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.FreezerFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    range3 db inspect
+
+Iterates over the whole database and sums up the entries per key prefix:
+headers, bodies, receipts, transaction lookups, bloombits, trie nodes,
+preimages, light client CHT and bloom tries, Range metadata and the rest,
+followed by the ancient store.`,
+			},
+			{
+				Action:    utils.MigrateFlags(checkState),
+				Name:      "check-state",
+				Usage:     "Walk the state of the given root and report the missing entries",
+				ArgsUsage: "<root>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.FreezerFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    range3 db check-state <root>
+
+Walks the account trie of the state root, along with every storage trie and
+contract code, and reports all the missing trie nodes and codes. The state
+of the head block is checked if no root is given.`,
+			},
+			{
+				Action:    utils.MigrateFlags(repairDatabase),
+				Name:      "repair",
+				Usage:     "Rebuild the chain indexes and roll back to a complete state",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.FreezerFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    range3 db repair
+
+Rebuilds the canonical hash mappings along the parent hashes of the head
+header and the transaction lookup indexes from the stored bodies. The heads
+are rolled back to the highest canonical block whose state is complete, the
+blocks above it are synced again once the node is started.`,
+			},
+		},
+	}
+)
+
+func inspectDatabase(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	stats, err := rawdb.InspectDatabase(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to inspect database: %v", err)
+	}
+	var (
+		rows  [][]string
+		count uint64
+		total common.StorageSize
+	)
+	for _, stat := range stats {
+		rows = append(rows, []string{stat.Category, fmt.Sprint(stat.Count), stat.Size.String()})
+		count += stat.Count
+		total += stat.Size
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Category", "Items", "Size"})
+	table.SetFooter([]string{"Total", fmt.Sprint(count), total.String()})
+	table.AppendBulk(rows)
+	table.Render()
+	return nil
+}
+
+func checkState(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command requires at most one argument.")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	var root common.Hash
+	if len(ctx.Args()) == 1 {
+		enc, err := hexutil.Decode(ctx.Args().First())
+		if err != nil || len(enc) != common.HashLength {
+			utils.Fatalf("Invalid state root: %s", ctx.Args().First())
+		}
+		root = common.BytesToHash(enc)
+	} else {
+		head := rawdb.ReadHeadBlockHash(chainDb)
+		number := rawdb.ReadHeaderNumber(chainDb, head)
+		if number == nil {
+			utils.Fatalf("Head block is not found")
+		}
+		header := rawdb.ReadHeader(chainDb, head, *number)
+		if header == nil {
+			utils.Fatalf("Head block %x is not found", head)
+		}
+		root = header.Root
+	}
+	start := time.Now()
+	nodes, missing, err := core.CheckState(chainDb, root, func(entry *core.MissingStateEntry) {
+		fmt.Printf("Missing %v\n", entry)
+	})
+	if err != nil {
+		utils.Fatalf("Failed to check state: %v", err)
+	}
+	fmt.Printf("Root:    %x\n", root)
+	fmt.Printf("Nodes:   %d\n", nodes)
+	fmt.Printf("Missing: %d\n", missing)
+	fmt.Printf("Elapsed: %v\n", common.PrettyDuration(time.Since(start)))
+
+	if missing > 0 {
+		utils.Fatalf("State %x is incomplete", root)
+	}
+	return nil
+}
+
+func repairDatabase(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	report, err := core.RepairDatabase(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to repair database: %v", err)
+	}
+	fmt.Printf("Head header:                %d\n", report.Head)
+	fmt.Printf("Head with complete state:   %d\n", report.StateHead)
+	fmt.Printf("Canonical hashes rewritten: %d\n", report.CanonicalFixed)
+	fmt.Printf("Canonical hashes deleted:   %d\n", report.CanonicalPruned)
+	fmt.Printf("Tx lookups rewritten:       %d\n", report.LookupsFixed)
+	fmt.Printf("Tx lookups deleted:         %d\n", report.LookupsPruned)
+	fmt.Printf("Elapsed:                    %v\n", common.PrettyDuration(report.Elapsed))
+	return nil
+}
@@ -65,6 +65,11 @@ var customGenesisTests = []struct {
 					"ebiSigner"      : "0x0000000000000000000000000000000000400003",
 					"cppSigner"      : "0x0000000000000000000000000000000000400004"
 				},
+				"homesteadBlock"     : 0,
+				"eip150Block"        : 0,
+				"eip155Block"        : 0,
+				"eip158Block"        : 0,
+				"byzantiumBlock"     : 0,
 				"constantinopleBlock": 0,
 				"superblockCycle"    : 10,
 				"mnRequireValidation": 5,
@@ -97,14 +102,19 @@ var customGenesisTests = []struct {
 					"ebiSigner"      : "0x0000000000000000000000000000000000500003",
 					"cppSigner"      : "0x0000000000000000000000000000000000500004"
 				},
+				"homesteadBlock"     : 0,
+				"eip150Block"        : 0,
+				"eip155Block"        : 0,
+				"eip158Block"        : 0,
+				"byzantiumBlock"     : 0,
 				"constantinopleBlock": 0,
+				"istanbulBlock"      : 314,
 				"superblockCycle"    : 10,
 				"mnRequireValidation": 5,
 				"mnValidationPeriod" : 5,
 				"mnCleanupPeriod"    : 1209600,
 				"mnEverCollateral"   : 30000000000000000000000,
 				"mnRewardsPerBlock"  : 10,
-				"homesteadBlock"     : 314,
 				"daoForkBlock"       : 141,
 				"daoForkSupport"     : true
 			}
@@ -0,0 +1,722 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ethereum "range/core/gen3"
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/accounts/keystore"
+	"range/core/gen3/cmd/utils"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/common/math"
+	"range/core/gen3/core/types"
+	"range/core/gen3/ethclient"
+	"range/core/gen3/node"
+	"range/core/gen3/params"
+	"range/core/gen3/rlp"
+	"range/core/gen3/rpc"
+	"gopkg.in/urfave/cli.v1"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi_params "range/core/gen3/energi/params"
+)
+
+const (
+	// Gas limits of offline signed transactions, they must match the ones
+	// used by the node APIs.
+	govProposalGas uint64 = 3000000
+	govTokenGas    uint64 = energi_params.MasternodeCallGas
+
+	// Interval between the transaction receipt polls.
+	govReceiptPollInterval = 3 * time.Second
+)
+
+var errGovOffline = errors.New("not available in offline mode")
+
+var (
+	govAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Usage: "IPC endpoint of the running node (default: <datadir>/range3.ipc)",
+	}
+	govAccountFlag = cli.StringFlag{
+		Name:  "account",
+		Usage: "Address of the owner or the payer account",
+	}
+	govNoWaitFlag = cli.BoolFlag{
+		Name:  "nowait",
+		Usage: "Do not wait for the transaction receipt",
+	}
+	govTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Maximum time to wait for the transaction receipt",
+		Value: 10 * time.Minute,
+	}
+	govOfflineFlag = cli.BoolFlag{
+		Name:  "offline",
+		Usage: "Sign the transaction with the local keystore without connecting to a node",
+	}
+	govNonceFlag = cli.Uint64Flag{
+		Name:  "offline.nonce",
+		Usage: "Account nonce of the offline signed transaction",
+	}
+	govGasPriceFlag = cli.StringFlag{
+		Name:  "offline.gasprice",
+		Usage: "Gas price in wei of the offline signed transaction",
+	}
+	govGasFlag = cli.Uint64Flag{
+		Name:  "offline.gas",
+		Usage: "Gas limit of the offline signed transaction (default: method specific)",
+	}
+	govChainIDFlag = cli.Uint64Flag{
+		Name:  "offline.chainid",
+		Usage: "Chain ID of the offline signed transaction (default: by network)",
+	}
+	govOutFlag = cli.StringFlag{
+		Name:  "offline.out",
+		Usage: "File to write the offline signed transaction to (default: stdout)",
+	}
+
+	govOnlineFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.TestnetFlag,
+		utils.PasswordFileFlag,
+		govAttachFlag,
+		govAccountFlag,
+		govNoWaitFlag,
+		govTimeoutFlag,
+	}
+
+	govOfflineFlags = append([]cli.Flag{
+		utils.KeyStoreDirFlag,
+		utils.LightKDFFlag,
+		govOfflineFlag,
+		govNonceFlag,
+		govGasPriceFlag,
+		govGasFlag,
+		govChainIDFlag,
+		govOutFlag,
+	}, govOnlineFlags...)
+
+	govBroadcastCommand = cli.Command{
+		Action:    utils.MigrateFlags(govBroadcast),
+		Name:      "broadcast",
+		Usage:     "Broadcast an offline signed transaction",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+			govAttachFlag,
+			govNoWaitFlag,
+			govTimeoutFlag,
+		},
+		Description: `
+Sends a transaction produced with --offline to the network. The file may
+contain either the JSON output of the offline signing or the raw transaction
+in hex. Use "-" to read from the standard input.`,
+	}
+
+	govCommand = cli.Command{
+		Name:     "gov",
+		Usage:    "Manage governance proposals of a running node",
+		Category: "GOVERNANCE COMMANDS",
+		Description: `
+The gov commands attach to a running node over IPC and submit governance
+transactions on behalf of a keystore account. The account password is taken
+from --password or prompted for. The result is printed as JSON after the
+transaction receipt is available, unless --nowait is given.
+
+Voting and fee withdrawal may be signed with --offline on an air-gapped
+machine. The signed transaction is sent later with "range3 gov broadcast".`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(govVoteAccept),
+				Name:      "vote-accept",
+				Usage:     "Vote to accept a proposal",
+				ArgsUsage: "<proposal>",
+				Flags:     govOfflineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govVoteReject),
+				Name:      "vote-reject",
+				Usage:     "Vote to reject a proposal",
+				ArgsUsage: "<proposal>",
+				Flags:     govOfflineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govWithdrawFee),
+				Name:      "withdraw-fee",
+				Usage:     "Withdraw the fee of an accepted proposal",
+				ArgsUsage: "<proposal>",
+				Flags:     govOfflineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govUpgradePropose),
+				Name:      "upgrade-propose",
+				Usage:     "Propose an upgrade of a governed proxy",
+				ArgsUsage: "<proxy> <implementation> <period> <fee>",
+				Flags:     govOnlineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govUpgradePerform),
+				Name:      "upgrade-perform",
+				Usage:     "Perform an accepted upgrade of a governed proxy",
+				ArgsUsage: "<proxy> <proposal>",
+				Flags:     govOnlineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govUpgradeCollect),
+				Name:      "upgrade-collect",
+				Usage:     "Collect a finished upgrade proposal",
+				ArgsUsage: "<proxy> <proposal>",
+				Flags:     govOnlineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(govBudgetPropose),
+				Name:      "budget-propose",
+				Usage:     "Propose a treasury budget",
+				ArgsUsage: "<amount> <uuid> <period> <fee>",
+				Flags:     govOnlineFlags,
+			},
+			govBroadcastCommand,
+		},
+	}
+
+	masternodeCommand = cli.Command{
+		Name:     "masternode",
+		Usage:    "Manage masternodes of a running node",
+		Category: "GOVERNANCE COMMANDS",
+		Description: `
+The masternode commands attach to a running node over IPC and submit
+masternode transactions on behalf of the owner keystore account. All amounts
+are in wei.
+
+Collateral deposit and withdrawal may be signed with --offline on an
+air-gapped machine. The signed transaction is sent later with
+"range3 masternode broadcast".`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(masternodeAnnounce),
+				Name:      "announce",
+				Usage:     "Announce a masternode",
+				ArgsUsage: "<enode>",
+				Flags:     govOnlineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(masternodeDenounce),
+				Name:      "denounce",
+				Usage:     "Denounce the masternode of the owner",
+				ArgsUsage: " ",
+				Flags:     govOnlineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(masternodeDeposit),
+				Name:      "deposit",
+				Usage:     "Deposit masternode collateral",
+				ArgsUsage: "<amount>",
+				Flags:     govOfflineFlags,
+			},
+			{
+				Action:    utils.MigrateFlags(masternodeWithdraw),
+				Name:      "withdraw",
+				Usage:     "Withdraw masternode collateral",
+				ArgsUsage: "<amount>",
+				Flags:     govOfflineFlags,
+			},
+			govBroadcastCommand,
+		},
+	}
+)
+
+// govOfflineFn builds a transaction with the given options and backend.
+type govOfflineFn func(bind.ContractTransactor, *bind.TransactOpts) (*types.Transaction, error)
+
+// govRequest describes a governance transaction. It is submitted through the
+// node API or signed locally, if the offline mode is supported.
+type govRequest struct {
+	method  string
+	params  []interface{}
+	gas     uint64
+	offline govOfflineFn
+}
+
+type govResult struct {
+	Tx      common.Hash    `json:"tx"`
+	Receipt *types.Receipt `json:"receipt,omitempty"`
+}
+
+type govOfflineTx struct {
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Nonce hexutil.Uint64  `json:"nonce"`
+	Hash  common.Hash     `json:"hash"`
+	Raw   hexutil.Bytes   `json:"raw"`
+}
+
+func govVoteAccept(ctx *cli.Context) error {
+	proposal := govAddressArg(ctx, 0, "proposal")
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_voteAccept",
+		params: []interface{}{proposal, owner},
+		gas:    govProposalGas,
+		offline: func(b bind.ContractTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			contract, err := energi_abi.NewIProposalTransactor(proposal, b)
+			if err != nil {
+				return nil, err
+			}
+			return contract.VoteAccept(opts)
+		},
+	})
+}
+
+func govVoteReject(ctx *cli.Context) error {
+	proposal := govAddressArg(ctx, 0, "proposal")
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_voteReject",
+		params: []interface{}{proposal, owner},
+		gas:    govProposalGas,
+		offline: func(b bind.ContractTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			contract, err := energi_abi.NewIProposalTransactor(proposal, b)
+			if err != nil {
+				return nil, err
+			}
+			return contract.VoteReject(opts)
+		},
+	})
+}
+
+func govWithdrawFee(ctx *cli.Context) error {
+	proposal := govAddressArg(ctx, 0, "proposal")
+	payer := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_withdrawFee",
+		params: []interface{}{proposal, payer},
+		gas:    govProposalGas,
+		offline: func(b bind.ContractTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			contract, err := energi_abi.NewIProposalTransactor(proposal, b)
+			if err != nil {
+				return nil, err
+			}
+			return contract.Withdraw(opts)
+		},
+	})
+}
+
+func govUpgradePropose(ctx *cli.Context) error {
+	proxy := govAddressArg(ctx, 0, "proxy")
+	impl := govAddressArg(ctx, 1, "implementation")
+	period := govUintArg(ctx, 2, "period")
+	fee := govAmountArg(ctx, 3, "fee")
+	payer := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_upgradePropose",
+		params: []interface{}{proxy, impl, period, fee, payer},
+	})
+}
+
+func govUpgradePerform(ctx *cli.Context) error {
+	proxy := govAddressArg(ctx, 0, "proxy")
+	proposal := govAddressArg(ctx, 1, "proposal")
+	payer := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_upgradePerform",
+		params: []interface{}{proxy, proposal, payer},
+	})
+}
+
+func govUpgradeCollect(ctx *cli.Context) error {
+	proxy := govAddressArg(ctx, 0, "proxy")
+	proposal := govAddressArg(ctx, 1, "proposal")
+	payer := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_upgradeCollect",
+		params: []interface{}{proxy, proposal, payer},
+	})
+}
+
+func govBudgetPropose(ctx *cli.Context) error {
+	amount := govAmountArg(ctx, 0, "amount")
+	ref_uuid := govStringArg(ctx, 1, "uuid")
+	period := govUintArg(ctx, 2, "period")
+	fee := govAmountArg(ctx, 3, "fee")
+	payer := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "energi_budgetPropose",
+		params: []interface{}{amount, ref_uuid, period, fee, payer},
+	})
+}
+
+func masternodeAnnounce(ctx *cli.Context) error {
+	enode := govStringArg(ctx, 0, "enode")
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "masternode_announce",
+		params: []interface{}{owner, enode},
+	})
+}
+
+func masternodeDenounce(ctx *cli.Context) error {
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "masternode_denounce",
+		params: []interface{}{owner},
+	})
+}
+
+func masternodeDeposit(ctx *cli.Context) error {
+	amount := govAmountArg(ctx, 0, "amount")
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "masternode_depositCollateral",
+		params: []interface{}{owner, amount},
+		gas:    govTokenGas,
+		offline: func(b bind.ContractTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			token, err := energi_abi.NewIMasternodeTokenTransactor(
+				energi_params.Range_MasternodeToken, b)
+			if err != nil {
+				return nil, err
+			}
+			opts.Value = amount.ToInt()
+			return token.DepositCollateral(opts)
+		},
+	})
+}
+
+func masternodeWithdraw(ctx *cli.Context) error {
+	amount := govAmountArg(ctx, 0, "amount")
+	owner := govAccount(ctx)
+
+	return govSubmit(ctx, &govRequest{
+		method: "masternode_withdrawCollateral",
+		params: []interface{}{owner, amount},
+		gas:    govTokenGas,
+		offline: func(b bind.ContractTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+			token, err := energi_abi.NewIMasternodeTokenTransactor(
+				energi_params.Range_MasternodeToken, b)
+			if err != nil {
+				return nil, err
+			}
+			return token.WithdrawCollateral(opts, amount.ToInt())
+		},
+	})
+}
+
+// govSubmit either signs the request offline or submits it through the API
+// of the running node and waits for the receipt.
+func govSubmit(ctx *cli.Context, req *govRequest) error {
+	owner := govAccount(ctx)
+
+	if ctx.Bool(govOfflineFlag.Name) {
+		if req.offline == nil {
+			utils.Fatalf("Offline signing is not supported by this command")
+		}
+		return govSignOffline(ctx, owner, req)
+	}
+
+	client := govDial(ctx)
+	defer client.Close()
+
+	prompt := fmt.Sprintf("Unlocking account %s", owner.Hex())
+	password := getPassPhrase(prompt, false, 0, utils.MakePasswordList(ctx))
+
+	var txhash common.Hash
+	args := append(req.params, password)
+	if err := client.Call(&txhash, req.method, args...); err != nil {
+		utils.Fatalf("Failed to submit transaction: %v", err)
+	}
+
+	return govWaitAndPrint(ctx, client, txhash)
+}
+
+// govSignOffline signs the request with the local keystore. The nonce and
+// the gas price must be given explicitly as there is no node to query.
+func govSignOffline(ctx *cli.Context, owner common.Address, req *govRequest) error {
+	if !ctx.IsSet(govNonceFlag.Name) {
+		utils.Fatalf("The --%s flag is required for offline signing", govNonceFlag.Name)
+	}
+	if !ctx.IsSet(govGasPriceFlag.Name) {
+		utils.Fatalf("The --%s flag is required for offline signing", govGasPriceFlag.Name)
+	}
+	gasPrice, ok := math.ParseBig256(ctx.String(govGasPriceFlag.Name))
+	if !ok {
+		utils.Fatalf("Invalid gas price: %s", ctx.String(govGasPriceFlag.Name))
+	}
+
+	chainID := params.RangeMainnetChainConfig.ChainID
+	if ctx.GlobalBool(utils.TestnetFlag.Name) {
+		chainID = params.RangeTestnetChainConfig.ChainID
+	}
+	if ctx.IsSet(govChainIDFlag.Name) {
+		chainID = new(big.Int).SetUint64(ctx.Uint64(govChainIDFlag.Name))
+	}
+
+	gas := req.gas
+	if ctx.IsSet(govGasFlag.Name) {
+		gas = ctx.Uint64(govGasFlag.Name)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account, _ := unlockAccount(ctx, ks, owner.Hex(), 0, utils.MakePasswordList(ctx), false)
+	defer ks.Lock(account.Address)
+
+	backend := &govOfflineBackend{}
+	opts := &bind.TransactOpts{
+		From:     account.Address,
+		Nonce:    new(big.Int).SetUint64(ctx.Uint64(govNonceFlag.Name)),
+		GasPrice: gasPrice,
+		GasLimit: gas,
+		Signer: func(signer types.Signer, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return ks.SignTx(account, tx, chainID)
+		},
+	}
+	if _, err := req.offline(backend, opts); err != nil {
+		utils.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	raw, err := rlp.EncodeToBytes(backend.tx)
+	if err != nil {
+		utils.Fatalf("Failed to encode transaction: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(&govOfflineTx{
+		From:  account.Address,
+		To:    backend.tx.To(),
+		Nonce: hexutil.Uint64(backend.tx.Nonce()),
+		Hash:  backend.tx.Hash(),
+		Raw:   raw,
+	}, "", "  ")
+
+	if path := ctx.String(govOutFlag.Name); path != "" {
+		if err := ioutil.WriteFile(path, append(out, '\n'), 0600); err != nil {
+			utils.Fatalf("Failed to write transaction: %v", err)
+		}
+		return nil
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
+
+// govBroadcast sends an offline signed transaction and waits for the receipt.
+func govBroadcast(ctx *cli.Context) error {
+	path := govStringArg(ctx, 0, "file")
+
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to read transaction: %v", err)
+	}
+
+	var raw hexutil.Bytes
+	if input := strings.TrimSpace(string(data)); strings.HasPrefix(input, "{") {
+		var signed govOfflineTx
+		if err := json.Unmarshal([]byte(input), &signed); err != nil {
+			utils.Fatalf("Failed to parse transaction: %v", err)
+		}
+		raw = signed.Raw
+	} else if raw, err = hexutil.Decode(input); err != nil {
+		utils.Fatalf("Failed to parse transaction: %v", err)
+	}
+
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		utils.Fatalf("Failed to decode transaction: %v", err)
+	}
+
+	client := govDial(ctx)
+	defer client.Close()
+
+	if err := ethclient.NewClient(client).SendTransaction(context.Background(), tx); err != nil {
+		utils.Fatalf("Failed to send transaction: %v", err)
+	}
+
+	return govWaitAndPrint(ctx, client, tx.Hash())
+}
+
+// govWaitAndPrint waits for the transaction receipt, unless disabled, and
+// prints the result. A failed transaction is reported as an error.
+func govWaitAndPrint(ctx *cli.Context, client *rpc.Client, txhash common.Hash) error {
+	res := &govResult{Tx: txhash}
+
+	if !ctx.Bool(govNoWaitFlag.Name) {
+		receipt, err := govWaitReceipt(client, txhash, ctx.Duration(govTimeoutFlag.Name))
+		if err != nil {
+			utils.Fatalf("Failed to wait for transaction %s: %v", txhash.Hex(), err)
+		}
+		res.Receipt = receipt
+	}
+
+	out, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Println(string(out))
+
+	if res.Receipt != nil && res.Receipt.Status == types.ReceiptStatusFailed {
+		return fmt.Errorf("transaction %s failed", txhash.Hex())
+	}
+	return nil
+}
+
+func govWaitReceipt(
+	client *rpc.Client,
+	txhash common.Hash,
+	timeout time.Duration,
+) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ec := ethclient.NewClient(client)
+	ticker := time.NewTicker(govReceiptPollInterval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := ec.TransactionReceipt(ctx, txhash)
+		if receipt != nil {
+			return receipt, nil
+		}
+		if err != nil && err != ethereum.NotFound {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// govDial attaches to the node given by --attach or the default IPC endpoint
+// of the data directory.
+func govDial(ctx *cli.Context) *rpc.Client {
+	endpoint := ctx.String(govAttachFlag.Name)
+	if endpoint == "" {
+		path := node.DefaultDataDir()
+		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+			path = ctx.GlobalString(utils.DataDirFlag.Name)
+		}
+		if path != "" && ctx.GlobalBool(utils.TestnetFlag.Name) {
+			path = filepath.Join(path, "testnet")
+		}
+		endpoint = fmt.Sprintf("%s/range3.ipc", path)
+	}
+
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to remote range3: %v", err)
+	}
+	return client
+}
+
+func govAccount(ctx *cli.Context) common.Address {
+	account := ctx.String(govAccountFlag.Name)
+	if !common.IsHexAddress(account) {
+		utils.Fatalf("A valid --%s address is required", govAccountFlag.Name)
+	}
+	return common.HexToAddress(account)
+}
+
+func govStringArg(ctx *cli.Context, i int, name string) string {
+	if len(ctx.Args()) <= i {
+		utils.Fatalf("Missing <%s> argument", name)
+	}
+	return ctx.Args().Get(i)
+}
+
+func govAddressArg(ctx *cli.Context, i int, name string) common.Address {
+	arg := govStringArg(ctx, i, name)
+	if !common.IsHexAddress(arg) {
+		utils.Fatalf("Invalid <%s> address: %s", name, arg)
+	}
+	return common.HexToAddress(arg)
+}
+
+func govUintArg(ctx *cli.Context, i int, name string) uint64 {
+	arg := govStringArg(ctx, i, name)
+	res, err := strconv.ParseUint(arg, 0, 64)
+	if err != nil {
+		utils.Fatalf("Invalid <%s>: %v", name, err)
+	}
+	return res
+}
+
+func govAmountArg(ctx *cli.Context, i int, name string) *hexutil.Big {
+	arg := govStringArg(ctx, i, name)
+	res, ok := math.ParseBig256(arg)
+	if !ok || res.Sign() < 0 {
+		utils.Fatalf("Invalid <%s> amount: %s", name, arg)
+	}
+	return (*hexutil.Big)(res)
+}
+
+// govOfflineBackend is a contract backend of the offline signing. It has no
+// access to the chain, so all the transaction parameters must be set in the
+// options. The signed transaction is captured instead of being sent.
+type govOfflineBackend struct {
+	tx *types.Transaction
+}
+
+func (b *govOfflineBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return nil, errGovOffline
+}
+
+func (b *govOfflineBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, errGovOffline
+}
+
+func (b *govOfflineBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return nil, errGovOffline
+}
+
+func (b *govOfflineBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 0, errGovOffline
+}
+
+func (b *govOfflineBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.tx = tx
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+
+	energi_abi "range/core/gen3/energi/abi"
+)
+
+func TestGovOfflineBackend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	proposal := common.HexToAddress("0x1234")
+	chainID := params.RangeTestnetChainConfig.ChainID
+
+	backend := &govOfflineBackend{}
+	contract, err := energi_abi.NewIProposalTransactor(proposal, backend)
+	assert.Empty(t, err)
+
+	opts := &bind.TransactOpts{
+		From:     owner,
+		Nonce:    big.NewInt(7),
+		GasPrice: big.NewInt(100),
+		GasLimit: govProposalGas,
+		Signer: func(signer types.Signer, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+		},
+	}
+
+	// All the parameters are given, the signed transaction is captured
+	tx, err := contract.VoteAccept(opts)
+	assert.Empty(t, err)
+	assert.Equal(t, tx, backend.tx)
+	assert.Equal(t, &proposal, tx.To())
+	assert.Equal(t, uint64(7), tx.Nonce())
+	assert.Equal(t, big.NewInt(100), tx.GasPrice())
+	assert.Equal(t, govProposalGas, tx.Gas())
+
+	from, err := types.Sender(types.NewEIP155Signer(chainID), tx)
+	assert.Empty(t, err)
+	assert.Equal(t, owner, from)
+
+	// Nothing can be queried from the chain
+	backend.tx = nil
+	opts.Nonce = nil
+	_, err = contract.VoteAccept(opts)
+	assert.Contains(t, err.Error(), errGovOffline.Error())
+	assert.Nil(t, backend.tx)
+
+	opts.Nonce = big.NewInt(7)
+	opts.GasPrice = nil
+	_, err = contract.VoteAccept(opts)
+	assert.Contains(t, err.Error(), errGovOffline.Error())
+
+	opts.GasPrice = big.NewInt(100)
+	opts.GasLimit = 0
+	_, err = contract.VoteAccept(opts)
+	assert.Contains(t, err.Error(), errGovOffline.Error())
+	assert.Nil(t, backend.tx)
+}
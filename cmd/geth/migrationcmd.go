@@ -0,0 +1,183 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"range/core/gen3/cmd/utils"
+	"range/core/gen3/log"
+	"range/core/gen3/rpc"
+	"gopkg.in/urfave/cli.v1"
+
+	energi_api "range/core/gen3/energi/api"
+)
+
+var (
+	migrationFollowFlag = cli.BoolFlag{
+		Name:  "follow",
+		Usage: "Report the progress until the batch claim is finished",
+	}
+	migrationIntervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Usage: "Progress report interval of --follow",
+		Value: time.Minute,
+	}
+
+	migrationCommand = cli.Command{
+		Name:     "migration",
+		Usage:    "Claim Gen2 coins through a running node",
+		Category: "GOVERNANCE COMMANDS",
+		Description: `
+The migration commands attach to a running node over IPC and manage a batch
+claim of Gen2 coins to the --account destination. Claims are sent by the node
+in the background at the pace allowed by the zero-fee protection. The progress
+is kept by the node, so an interrupted batch is resumed by running the claim
+command again.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(migrationClaim),
+				Name:      "claim",
+				Usage:     "Start or resume a batch claim of a Gen2 dump file",
+				ArgsUsage: "<dumpfile>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					utils.PasswordFileFlag,
+					govAttachFlag,
+					govAccountFlag,
+					migrationFollowFlag,
+					migrationIntervalFlag,
+				},
+			},
+			{
+				Action:    utils.MigrateFlags(migrationStatus),
+				Name:      "status",
+				Usage:     "Show the per-coin status of a batch claim",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					govAttachFlag,
+					govAccountFlag,
+					migrationFollowFlag,
+					migrationIntervalFlag,
+				},
+			},
+			{
+				Action:    utils.MigrateFlags(migrationCancel),
+				Name:      "cancel",
+				Usage:     "Stop a running batch claim",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					govAttachFlag,
+					govAccountFlag,
+				},
+			},
+		},
+	}
+)
+
+func migrationClaim(ctx *cli.Context) error {
+	// The dump file is read by the node
+	file, err := filepath.Abs(govStringArg(ctx, 0, "dumpfile"))
+	if err != nil {
+		utils.Fatalf("Invalid dump file: %v", err)
+	}
+	dst := govAccount(ctx)
+
+	client := govDial(ctx)
+	defer client.Close()
+
+	prompt := fmt.Sprintf("Unlocking account %s", dst.Hex())
+	password := getPassPhrase(prompt, false, 0, utils.MakePasswordList(ctx))
+
+	var status energi_api.Gen2BatchStatus
+	if err := client.Call(&status, "energi_claimGen2CoinsBatch", password, dst, file); err != nil {
+		utils.Fatalf("Failed to start batch claim: %v", err)
+	}
+
+	return migrationReport(ctx, client, &status)
+}
+
+func migrationStatus(ctx *cli.Context) error {
+	dst := govAccount(ctx)
+
+	client := govDial(ctx)
+	defer client.Close()
+
+	var status energi_api.Gen2BatchStatus
+	if err := client.Call(&status, "energi_claimGen2BatchStatus", dst); err != nil {
+		utils.Fatalf("Failed to get batch claim status: %v", err)
+	}
+
+	return migrationReport(ctx, client, &status)
+}
+
+func migrationCancel(ctx *cli.Context) error {
+	dst := govAccount(ctx)
+
+	client := govDial(ctx)
+	defer client.Close()
+
+	var cancelled bool
+	if err := client.Call(&cancelled, "energi_claimGen2BatchCancel", dst); err != nil {
+		utils.Fatalf("Failed to cancel batch claim: %v", err)
+	}
+
+	out, _ := json.MarshalIndent(cancelled, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
+
+// migrationReport prints the batch status. With --follow, it logs the
+// progress until the batch is finished and prints the final status.
+func migrationReport(
+	ctx *cli.Context,
+	client *rpc.Client,
+	status *energi_api.Gen2BatchStatus,
+) error {
+	if ctx.Bool(migrationFollowFlag.Name) {
+		dst := status.Destination
+
+		for status.Running {
+			log.Info("Batch claim progress",
+				"unclaimed", status.Unclaimed, "pending", status.Pending,
+				"claimed", status.Claimed, "failed", status.Failed)
+
+			time.Sleep(ctx.Duration(migrationIntervalFlag.Name))
+
+			status = new(energi_api.Gen2BatchStatus)
+			if err := client.Call(status, "energi_claimGen2BatchStatus", dst); err != nil {
+				utils.Fatalf("Failed to get batch claim status: %v", err)
+			}
+		}
+	}
+
+	out, _ := json.MarshalIndent(status, "", "  ")
+	fmt.Println(string(out))
+
+	if !status.Running && status.Failed > 0 {
+		return fmt.Errorf("%d coin(s) failed to be claimed", status.Failed)
+	}
+	return nil
+}
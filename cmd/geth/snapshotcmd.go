@@ -0,0 +1,222 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"range/core/gen3/cmd/utils"
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state/pruner"
+	"range/core/gen3/ethdb"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"gopkg.in/urfave/cli.v1"
+
+	energi_params "range/core/gen3/energi/params"
+)
+
+// minPruneBlocks is the least number of recent states to retain, covering the
+// maturity period looked up for the PoS stake weights.
+var minPruneBlocks = energi_params.StakeWindow
+
+var (
+	pruneBlocksFlag = cli.Uint64Flag{
+		Name:  "blocks",
+		Usage: "Number of recent block states to retain",
+		Value: 128,
+	}
+	pruneDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Report the stale state without deleting it",
+	}
+	pruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the retained state",
+		Value: 2048,
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Manage the state of a stopped node",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(pruneState),
+				Name:      "prune-state",
+				Usage:     "Delete the state not referenced by the recent blocks",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.FreezerFlag,
+					utils.TestnetFlag,
+					pruneBlocksFlag,
+					pruneDryRunFlag,
+					pruneBloomSizeFlag,
+				},
+				Description: `
+    range3 snapshot prune-state --blocks 128
+
+Deletes every state trie node and contract code which is not referenced by
+the states of the last --blocks blocks or of the latest hardcoded checkpoint.
+At least MaturityPeriod worth of blocks is always retained, as the stake
+weights are looked up over it. The states not flushed to disk are replaced by
+the nearest older one. The retained states are verified afterwards.
+
+The reachable state is marked in a bloom filter of --bloomfilter.size
+megabytes, a false positive only leaves some stale state on disk. With
+--dry-run the amount of stale state is reported and nothing is deleted.
+
+The node must be stopped and the state of its head block must be on disk.`,
+			},
+		},
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	blocks := ctx.Uint64(pruneBlocksFlag.Name)
+	if blocks < minPruneBlocks {
+		utils.Fatalf("At least %d blocks must be retained to cover the maturity period", minPruneBlocks)
+	}
+	dryRun := ctx.Bool(pruneDryRunFlag.Name)
+
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	roots := pruneRoots(chainDb, blocks)
+
+	p, err := pruner.NewPruner(chainDb, ctx.Uint64(pruneBloomSizeFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to create pruner: %v", err)
+	}
+	report, err := p.Prune(roots, dryRun)
+	if err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+
+	fmt.Printf("Retained states:     %d\n", len(report.Retained))
+	fmt.Printf("Missing states:      %d\n", len(report.Missing))
+	fmt.Printf("Marked entries:      %d\n", report.Marked)
+	fmt.Printf("Scanned entries:     %d\n", report.Scanned)
+	fmt.Printf("Stale entries:       %d\n", report.Deleted)
+	fmt.Printf("Stale size:          %v\n", report.Freed)
+	fmt.Printf("False positive rate: %.6f\n", report.FalsePositiveRate)
+	fmt.Printf("Elapsed:             %v\n\n", common.PrettyDuration(report.Elapsed))
+
+	if dryRun {
+		return nil
+	}
+
+	start := time.Now()
+	fmt.Println("Verifying retained state...")
+	if err := p.Verify(report.Retained); err != nil {
+		utils.Fatalf("Retained state is corrupted: %v", err)
+	}
+	fmt.Printf("Verification done in %v\n", time.Since(start))
+
+	// Compact the entire database to release the freed space
+	if ldb, ok := rawdb.KeyValueStore(chainDb).(interface {
+		LDB() *leveldb.DB
+	}); ok {
+		start = time.Now()
+		fmt.Println("Compacting entire database...")
+		if err := ldb.LDB().CompactRange(util.Range{}); err != nil {
+			utils.Fatalf("Compaction failed: %v", err)
+		}
+		fmt.Printf("Compaction done in %v.\n", time.Since(start))
+	}
+	return nil
+}
+
+// pruneRoots collects the state roots to retain in ascending block order: the
+// latest hardcoded checkpoint and the recent blocks.
+func pruneRoots(db ethdb.Database, blocks uint64) []common.Hash {
+	headHash := rawdb.ReadHeadBlockHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		utils.Fatalf("Head block is not found")
+	}
+	head := rawdb.ReadHeader(db, headHash, *headNumber)
+	if head == nil {
+		utils.Fatalf("Head block %x is not found", headHash)
+	}
+	if has, _ := db.Has(head.Root[:]); !has {
+		utils.Fatalf("State of the head block %d is not on disk", *headNumber)
+	}
+
+	var (
+		roots []common.Hash
+		first uint64
+	)
+	if *headNumber >= blocks {
+		first = *headNumber - blocks + 1
+	}
+
+	// The latest hardcoded checkpoint below the retained blocks
+	var checkpoint uint64
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	for number := range energi_params.RangeCheckpoints[genesis] {
+		if number > checkpoint && number < first {
+			checkpoint = number
+		}
+	}
+	if checkpoint > 0 {
+		if header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, checkpoint), checkpoint); header != nil {
+			roots = append(roots, header.Root)
+		}
+	}
+
+	// The states not flushed to disk are regenerated from the nearest older
+	// one, which is retained in their place
+	var base *common.Hash
+	for number := first; number <= *headNumber; number++ {
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		if header == nil {
+			utils.Fatalf("Canonical block %d is not found", number)
+		}
+		root := header.Root
+		if has, _ := db.Has(root[:]); has {
+			base = &root
+		} else if base == nil {
+			base = pruneBase(db, number)
+		}
+		if len(roots) == 0 || roots[len(roots)-1] != *base {
+			roots = append(roots, *base)
+		}
+	}
+	return roots
+}
+
+// pruneBase returns the root of the nearest state on disk below the block.
+func pruneBase(db ethdb.Database, number uint64) *common.Hash {
+	for parent := number; parent > 0; {
+		parent--
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, parent), parent)
+		if header == nil {
+			utils.Fatalf("Canonical block %d is not found", parent)
+		}
+		if has, _ := db.Has(header.Root[:]); has {
+			return &header.Root
+		}
+	}
+	utils.Fatalf("No state on disk below block %d", number)
+	return nil
+}
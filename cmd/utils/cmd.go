@@ -18,12 +18,16 @@
 package utils
 
 import (
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 
@@ -34,9 +38,13 @@ import (
 	"range/core/gen3/crypto"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/internal/debug"
+	"range/core/gen3/internal/era"
 	"range/core/gen3/log"
 	"range/core/gen3/node"
 	"range/core/gen3/rlp"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+
+	energi_params "range/core/gen3/energi/params"
 )
 
 const (
@@ -237,8 +245,351 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// HistoryNetwork returns the network name of the history archives of the chain.
+func HistoryNetwork(chain *core.BlockChain) string {
+	return fmt.Sprintf("%x", chain.Genesis().Hash().Bytes()[:4])
+}
+
+// ExportHistory exports the blocks in the range into the directory, as era
+// archives of the epochs of the given size. With the state requested, the
+// state is dumped a stake window below the last block, for the importer to
+// be able to verify the next blocks.
+func ExportHistory(chain *core.BlockChain, dir string, first, last, epochSize uint64, withState bool) error {
+	if first > last {
+		return fmt.Errorf("first (%d) is greater than last (%d)", first, last)
+	}
+	if head := chain.CurrentBlock().NumberU64(); last > head {
+		return fmt.Errorf("last (%d) is above the head (%d)", last, head)
+	}
+	if epochSize == 0 {
+		return fmt.Errorf("invalid epoch size")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	network := HistoryNetwork(chain)
+	log.Info("Exporting history", "dir", dir, "first", first, "last", last, "epoch", epochSize)
+
+	for epoch := first / epochSize; epoch <= last/epochSize; epoch++ {
+		start, end := epoch*epochSize, (epoch+1)*epochSize-1
+		if start < first {
+			start = first
+		}
+		if end > last {
+			end = last
+		}
+		// Write into a temporary file, the name depends on the accumulator
+		tmp := filepath.Join(dir, fmt.Sprintf("%s-%05d.tmp", network, epoch))
+		root, err := exportEpoch(chain, tmp, start, end)
+		if err != nil {
+			return err
+		}
+		fn := filepath.Join(dir, era.Filename(network, epoch, root))
+		if err := os.Rename(tmp, fn); err != nil {
+			return err
+		}
+		log.Info("Exported history epoch", "file", fn, "first", start, "last", end, "accumulator", root)
+	}
+	log.Info("Exported history", "last", last, "hash", chain.GetHeaderByNumber(last).Hash())
+	if !withState {
+		return nil
+	}
+	number := first
+	if last-first > energi_params.StakeWindow {
+		number = last - energi_params.StakeWindow
+	}
+	block := chain.GetBlockByNumber(number)
+	if _, err := chain.CalculateBlockState(block.Hash(), number); err != nil {
+		return err
+	}
+	fn := filepath.Join(dir, era.StateFilename(network, last/epochSize, block.Hash()))
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	count, err := era.WriteState(fh, block.Header(), chain.StateCache().TrieDB())
+	if err != nil {
+		return err
+	}
+	log.Info("Exported state", "file", fn, "number", number, "root", block.Root(), "blobs", count)
+	return nil
+}
+
+// exportEpoch writes the blocks in the range as an era archive.
+func exportEpoch(chain *core.BlockChain, fn string, first, last uint64) (common.Hash, error) {
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer fh.Close()
+
+	writer := bufio.NewWriter(fh)
+	builder := era.NewBuilder(writer)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return common.Hash{}, fmt.Errorf("block #%d not found", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if receipts == nil && len(block.Transactions()) > 0 {
+			return common.Hash{}, fmt.Errorf("receipts of block #%d not found", number)
+		}
+		if err := builder.Add(block, receipts, chain.GetTd(block.Hash(), number)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return root, writer.Flush()
+}
+
+// ImportHistory imports the era archives of the directory in epoch order,
+// verifying each one before. The blocks are executed unless noexec is set, in
+// which case they are written along with their receipts, as verified against
+// their headers. Without execution, the seals can't be verified either, as
+// they are checked against the state: the blocks are trusted by their hash
+// link to a trusted one instead, a validated checkpoint or the given last
+// archived block. The chain head is then set on top of the exported state, if
+// any.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, noexec bool, trusted common.Hash) error {
+	network := HistoryNetwork(chain)
+	files, err := era.ReadDir(dir, network)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no history archives of network %s in %s", network, dir)
+	}
+	var anchor uint64
+	if noexec {
+		if anchor, err = trustedHistory(chain, files, trusted); err != nil {
+			return err
+		}
+	}
+	for _, fn := range files {
+		log.Info("Importing history", "file", fn)
+		if err := importEpoch(chain, fn, noexec, anchor); err != nil {
+			return err
+		}
+	}
+	if !noexec {
+		return nil
+	}
+	states, err := filepath.Glob(filepath.Join(dir, network+"-*"+era.StateExtension))
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		log.Warn("Imported history without state, sync to complete it", "head", chain.CurrentFastBlock().Number())
+		return nil
+	}
+	sort.Strings(states)
+	return importState(chain, db, states[len(states)-1])
+}
+
+// trustedHistory verifies the era archives and their hash links to each other,
+// returning the highest archived block vouched for by a trusted hash: either a
+// validated checkpoint or the given hash of the last archived block.
+func trustedHistory(chain *core.BlockChain, files []string, trusted common.Hash) (uint64, error) {
+	checkpoints := make(map[uint64]common.Hash)
+	for _, cp := range chain.ListCheckpoints() {
+		checkpoints[cp.Number] = cp.Hash
+	}
+	var (
+		anchor uint64
+		found  bool
+		last   *types.Block
+	)
+	for _, fn := range files {
+		archive, err := era.Open(fn)
+		if err != nil {
+			return 0, err
+		}
+		last, err = verifyTrustedEpoch(archive, checkpoints, last)
+		archive.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", fn, err)
+		}
+		for number := range checkpoints {
+			if number >= archive.Start() && number <= last.NumberU64() && number >= anchor {
+				anchor, found = number, true
+			}
+		}
+	}
+	if last.Hash() == trusted {
+		anchor, found = last.NumberU64(), true
+	}
+	if !found {
+		return 0, fmt.Errorf("no trusted block in the history, set the hash of the last archived block #%d", last.NumberU64())
+	}
+	if anchor < last.NumberU64() {
+		log.Warn("Archived blocks above the trusted one left to sync", "trusted", anchor, "last", last.NumberU64())
+	}
+	return anchor, nil
+}
+
+// verifyTrustedEpoch verifies an era archive, its link to the last block of
+// the previous one and the archived checkpoints. It returns its last block.
+func verifyTrustedEpoch(archive *era.Era, checkpoints map[uint64]common.Hash, prev *types.Block) (*types.Block, error) {
+	if err := archive.Verify(); err != nil {
+		return nil, err
+	}
+	first, err := archive.GetEntry(archive.Start())
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && (first.Block.NumberU64() != prev.NumberU64()+1 || first.Block.ParentHash() != prev.Hash()) {
+		return nil, fmt.Errorf("block #%d not linked to the previous archive", first.Block.NumberU64())
+	}
+	for number, hash := range checkpoints {
+		if number < archive.Start() || number >= archive.Start()+archive.Count() {
+			continue
+		}
+		entry, err := archive.GetEntry(number)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Block.Hash() != hash {
+			return nil, fmt.Errorf("block #%d: %v", number, core.ErrCheckpointMismatch)
+		}
+	}
+	last, err := archive.GetEntry(archive.Start() + archive.Count() - 1)
+	if err != nil {
+		return nil, err
+	}
+	return last.Block, nil
+}
+
+// importEpoch imports a single era archive. With execution, it is verified
+// first, else it was verified along with the others and only the blocks up to
+// the trusted one are imported.
+func importEpoch(chain *core.BlockChain, fn string, noexec bool, trusted uint64) error {
+	archive, err := era.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if !noexec {
+		if err := archive.Verify(); err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+	}
+	first, last := archive.Start(), archive.Start()+archive.Count()-1
+	if noexec && last > trusted {
+		if first > trusted {
+			return nil
+		}
+		last = trusted
+	}
+	for start := first; start <= last; start += importBatchSize {
+		end := start + importBatchSize - 1
+		if end > last {
+			end = last
+		}
+		var (
+			blocks   types.Blocks
+			receipts []types.Receipts
+			headers  []*types.Header
+			tds      []*big.Int
+		)
+		for number := start; number <= end; number++ {
+			entry, err := archive.GetEntry(number)
+			if err != nil {
+				return err
+			}
+			if number == 0 {
+				if entry.Block.Hash() != chain.Genesis().Hash() {
+					return fmt.Errorf("%s: genesis mismatch", fn)
+				}
+				continue
+			}
+			blocks = append(blocks, entry.Block)
+			receipts = append(receipts, entry.ConsensusReceipts())
+			headers = append(headers, entry.Block.Header())
+			tds = append(tds, entry.TD)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if noexec {
+			// The seals need the state, the trusted block vouches for them
+			if _, err := chain.InsertHeaderChain(headers, 0); err != nil {
+				return err
+			}
+			if _, err := chain.InsertReceiptChain(blocks, receipts); err != nil {
+				return err
+			}
+		} else if missing := missingBlocks(chain, blocks); len(missing) > 0 {
+			if _, err := chain.InsertChain(missing); err != nil {
+				return err
+			}
+		}
+		for i, block := range blocks {
+			if td := chain.GetTd(block.Hash(), block.NumberU64()); td == nil || td.Cmp(tds[i]) != 0 {
+				return fmt.Errorf("block #%d: total difficulty mismatch: have %v, want %v", block.NumberU64(), td, tds[i])
+			}
+		}
+	}
+	log.Info("Imported history epoch", "file", fn, "first", first, "last", last)
+	return nil
+}
+
+// importState loads a state dump and sets the head block on top of it.
+func importState(chain *core.BlockChain, db ethdb.Database, fn string) error {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	header, count, err := era.ReadState(bufio.NewReader(fh), db)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	log.Info("Imported state", "file", fn, "number", header.Number, "root", header.Root, "blobs", count)
+
+	if block := chain.GetBlockByHash(header.Hash); block == nil || block.Root() != header.Root {
+		return fmt.Errorf("%s: state of unknown block #%d [%x…]", fn, header.Number, header.Hash[:4])
+	}
+	if !chain.HasState(header.Root) {
+		return fmt.Errorf("%s: incomplete state", fn)
+	}
+	head := chain.CurrentFastBlock()
+	if head.Hash() == header.Hash {
+		if err := chain.FastSyncCommitHead(head.Hash()); err != nil {
+			return err
+		}
+		rawdb.WriteHeadBlockHash(db, head.Hash())
+		return nil
+	}
+	_, err = chain.PivotCommitHead(header.Hash, head.Hash())
+	return err
+}
+
+// VerifyHistory checks the era archives on their own.
+func VerifyHistory(files []string) error {
+	for _, fn := range files {
+		archive, err := era.Open(fn)
+		if err != nil {
+			return err
+		}
+		err = archive.Verify()
+		archive.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+		log.Info("Verified history archive", "file", fn, "first", archive.Start(), "count", archive.Count(), "accumulator", archive.Accumulator())
+	}
+	return nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
-func ImportPreimages(db *ethdb.LDBDatabase, fn string) error {
+func ImportPreimages(db ethdb.Database, fn string) error {
 	log.Info("Importing preimages", "file", fn)
 
 	// Open the file handle and potentially unwrap the gzip stream
@@ -285,7 +636,7 @@ func ImportPreimages(db *ethdb.LDBDatabase, fn string) error {
 
 // ExportPreimages exports all known hash preimages into the specified file,
 // truncating any data already present in the file.
-func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
+func ExportPreimages(db ethdb.Database, fn string) error {
 	log.Info("Exporting preimages", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -301,7 +652,13 @@ func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the preimages and export them
-	it := db.NewIteratorWithPrefix([]byte("secure-key-"))
+	iteratee, ok := db.(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		return fmt.Errorf("database does not support iteration")
+	}
+	it := iteratee.NewIteratorWithPrefix([]byte("secure-key-"))
 	for it.Next() {
 		if err := rlp.Encode(writer, it.Value()); err != nil {
 			return err
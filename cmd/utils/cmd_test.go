@@ -0,0 +1,117 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of Range Core.
+//
+// Range Core is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Range Core is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Range Core. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	historyKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	historyAddress = crypto.PubkeyToAddress(historyKey.PublicKey)
+	historyGenesis = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{historyAddress: {Balance: big.NewInt(1000000000000000000)}},
+	}
+)
+
+// newHistoryChain creates an empty chain of the test genesis.
+func newHistoryChain(t *testing.T, engine consensus.Engine) (*core.BlockChain, ethdb.Database) {
+	db := ethdb.NewMemDatabase()
+	historyGenesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, historyGenesis.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain, db
+}
+
+// Tests that the exported era archives are imported back, with execution or
+// without, in which case the blocks are trusted by the hash of the last one
+// instead of their seals.
+func TestHistoryRoundTrip(t *testing.T) {
+	src, srcdb := newHistoryChain(t, ethash.NewFaker())
+	defer src.Stop()
+
+	blocks, _ := core.GenerateChain(historyGenesis.Config, src.Genesis(), ethash.NewFaker(), srcdb, 40, func(i int, block *core.BlockGen) {
+		if i%4 == 0 {
+			signer := types.MakeSigner(historyGenesis.Config, block.Number())
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(historyAddress), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, historyKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	if _, err := src.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "era")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assert.Empty(t, ExportHistory(src, dir, 0, 40, 16, true))
+
+	head := blocks[len(blocks)-1]
+	check := func(chain *core.BlockChain) {
+		t.Helper()
+
+		assert.Equal(t, head.Hash(), chain.CurrentBlock().Hash())
+		assert.True(t, chain.HasState(head.Root()))
+		for _, block := range blocks {
+			assert.Equal(t, block.Hash(), chain.GetHeaderByNumber(block.NumberU64()).Hash())
+			assert.NotNil(t, chain.GetBlockByHash(block.Hash()), "block #%d", block.NumberU64())
+			assert.Equal(t, len(block.Transactions()), len(chain.GetReceiptsByHash(block.Hash())), "block #%d", block.NumberU64())
+			assert.Equal(t, src.GetTd(block.Hash(), block.NumberU64()), chain.GetTd(block.Hash(), block.NumberU64()))
+		}
+	}
+	// The blocks are executed
+	exec, execdb := newHistoryChain(t, ethash.NewFaker())
+	defer exec.Stop()
+
+	assert.Empty(t, ImportHistory(exec, execdb, dir, false, common.Hash{}))
+	check(exec)
+
+	// Without execution, the seals are not verified, as seen with a failing
+	// one, the blocks are trusted by the hash of the last one
+	noexec, noexecdb := newHistoryChain(t, ethash.NewFakeFailer(20))
+	defer noexec.Stop()
+
+	assert.NotEmpty(t, ImportHistory(noexec, noexecdb, dir, true, common.Hash{}))
+	assert.NotEmpty(t, ImportHistory(noexec, noexecdb, dir, true, blocks[0].Hash()))
+	assert.Equal(t, uint64(0), noexec.CurrentFastBlock().NumberU64())
+
+	assert.Empty(t, ImportHistory(noexec, noexecdb, dir, true, head.Hash()))
+	check(noexec)
+}
@@ -37,6 +37,7 @@ import (
 	"range/core/gen3/consensus/clique"
 	"range/core/gen3/consensus/ethash"
 	"range/core/gen3/core"
+	"range/core/gen3/core/rawdb"
 	"range/core/gen3/core/state"
 	"range/core/gen3/core/vm"
 	"range/core/gen3/crypto"
@@ -46,6 +47,7 @@ import (
 	"range/core/gen3/eth/gasprice"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/ethstats"
+	"range/core/gen3/graphql"
 	"range/core/gen3/les"
 	"range/core/gen3/log"
 	"range/core/gen3/metrics"
@@ -57,6 +59,7 @@ import (
 	"range/core/gen3/p2p/nat"
 	"range/core/gen3/p2p/netutil"
 	"range/core/gen3/params"
+	"range/core/gen3/rpc"
 	whisper "range/core/gen3/whisper/whisperv6"
 	cli "gopkg.in/urfave/cli.v1"
 
@@ -123,6 +126,19 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
+	FreezerFlag = cli.BoolFlag{
+		Name:  "freezer",
+		Usage: "Move the blocks finalized by a checkpoint into the ancient store (always on once it holds blocks)",
+	}
+	AncientDepthFlag = cli.Uint64Flag{
+		Name:  "datadir.ancient.depth",
+		Usage: "Number of blocks behind the latest checkpoint kept out of the ancient store",
+		Value: eth.DefaultConfig.DatabaseFreezerDepth,
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -148,6 +164,10 @@ var (
 		Name:  "override.constantinople",
 		Usage: "Manually specify constantinople fork-block, overriding the bundled setting",
 	}
+	IstanbulOverrideFlag = cli.Uint64Flag{
+		Name:  "override.istanbul",
+		Usage: "Manually specify istanbul fork-block, overriding the bundled setting",
+	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
 		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
@@ -168,7 +188,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "checkpoint")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -263,6 +283,21 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteJournalSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournalsize",
+		Usage: "Maximum number of remote transactions to journal",
+		Value: core.DefaultTxPoolConfig.RemoteJournalSize,
+	}
+	TxPoolRemoteJournalLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.remotejournallifetime",
+		Usage: "Maximum age of the journaled remote transactions to reload",
+		Value: core.DefaultTxPoolConfig.RemoteJournalLifetime,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -293,6 +328,11 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: eth.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolConsensusSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.consensusslots",
+		Usage: "Maximum number of zero-fee consensus transaction slots reserved for all accounts",
+		Value: eth.DefaultConfig.TxPool.ConsensusSlots,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
@@ -319,6 +359,14 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat state snapshot for faster state reads (regenerated in the background)",
+	}
+	FinalityDepthFlag = cli.Uint64Flag{
+		Name:  "finalitydepth",
+		Usage: "Maximum number of canonical blocks a reorg may drop without admin approval (0 = unlimited)",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -437,6 +485,10 @@ var (
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 	}
+	TraceJobsDirFlag = DirectoryFlag{
+		Name:  "tracejobs.dir",
+		Usage: "Directory of the batch tracing jobs (default = inside the datadir)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -512,6 +564,30 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -684,7 +760,7 @@ var (
 	}
 	PublicServiceFlag = cli.BoolFlag{
 		Name:  "publicservice",
-		Usage: "Enable security restrictions and tweaks to operate as a public service",
+		Usage: "Enable security restrictions, RPC rate limits and tweaks to operate as a public service",
 	}
 )
 
@@ -845,6 +921,29 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setGraphQL creates the GraphQL listener interface string from the set
+// command line flags, returning empty if the GraphQL endpoint is disabled.
+func setGraphQL(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) && cfg.GraphQLHost == "" {
+		cfg.GraphQLHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+			cfg.GraphQLHost = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.GraphQLPort = ctx.GlobalInt(GraphQLPortFlag.Name)
+	} else if ctx.GlobalIsSet(TestnetFlag.Name) {
+		cfg.GraphQLPort = 49794
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -1048,6 +1147,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
+	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
@@ -1068,6 +1168,11 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info("Enforcing NoUSB and enabled Ephemeral account")
 		cfg.NoEphemeral = false
 		cfg.NoUSB = true
+
+		if cfg.RPCPolicy == nil {
+			log.Info("Enforcing RPC rate limits")
+			cfg.RPCPolicy = rpc.DefaultPublicPolicy()
+		}
 	}
 }
 
@@ -1111,6 +1216,15 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.RemoteJournalSize = ctx.GlobalUint64(TxPoolRemoteJournalSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalLifetimeFlag.Name) {
+		cfg.RemoteJournalLifetime = ctx.GlobalDuration(TxPoolRemoteJournalLifetimeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1129,6 +1243,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolConsensusSlotsFlag.Name) {
+		cfg.ConsensusSlots = ctx.GlobalUint64(TxPoolConsensusSlotsFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
@@ -1266,6 +1383,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(FreezerFlag.Name) {
+		cfg.DatabaseFreezerEnabled = ctx.GlobalBool(FreezerFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientDepthFlag.Name) {
+		cfg.DatabaseFreezerDepth = ctx.GlobalUint64(AncientDepthFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1283,6 +1409,11 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	cfg.Snapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	if ctx.GlobalIsSet(FinalityDepthFlag.Name) {
+		cfg.FinalityDepth = ctx.GlobalUint64(FinalityDepthFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
 		cfg.MinerNotify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
 	}
@@ -1337,6 +1468,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
+	if ctx.GlobalIsSet(TraceJobsDirFlag.Name) {
+		cfg.TraceJobsDir = ctx.GlobalString(TraceJobsDirFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(PublicServiceFlag.Name) {
 		cfg.PublicService = ctx.GlobalBool(PublicServiceFlag.Name)
@@ -1447,7 +1581,11 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 		var lesServ *les.LightEthereum
 		ctx.Service(&lesServ)
 
-		return ethstats.New(url, ethServ, lesServ)
+		// The masternode service is optional
+		var mnServ *energi_svc.MasternodeService
+		ctx.Service(&mnServ)
+
+		return ethstats.New(url, ethServ, lesServ, mnServ)
 	}); err != nil {
 		Fatalf("Failed to register the Range Stats service: %v", err)
 	}
@@ -1465,6 +1603,21 @@ func RegisterDynamicCheckpointService(stack *node.Node) {
 	}
 }
 
+// RegisterGraphQLService is a utility function to construct a new service and
+// register it against a node.
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts, policy *rpc.Policy) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Try to construct the GraphQL service backed by a full node
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, err
+		}
+		return graphql.New(ethServ, endpoint, cors, vhosts, timeouts, policy)
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // RegisterMasternodeService configures Range Masternode service. It also accepts
 // the owner parameter which is an optional user set cmd argument.
 func RegisterMasternodeService(stack *node.Node, owner common.Address) {
@@ -1522,11 +1675,23 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = makeDatabaseHandles()
 	)
-	name := "chaindata"
-	if ctx.GlobalString(SyncModeFlag.Name) == "light" {
-		name = "lightchaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	switch {
+	case ctx.GlobalString(SyncModeFlag.Name) == "light":
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles)
+	case ctx.GlobalBool(FreezerFlag.Name):
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), "")
+	case stack.HasAncients("chaindata", ctx.GlobalString(AncientFlag.Name)):
+		log.Warn("Enabling the freezer of the existing ancient store")
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), "")
+	default:
+		if chainDb, err = stack.OpenDatabase("chaindata", cache, handles); err == nil {
+			err = rawdb.CheckNoAncients(chainDb)
+		}
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1579,6 +1744,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		TrieDirtyLimit: eth.DefaultConfig.TrieDirtyCache,
 		TrieTimeLimit:  eth.DefaultConfig.TrieTimeout,
 		TrieRapidLimit: eth.DefaultConfig.TrieRapidTime,
+		Snapshot:       ctx.GlobalBool(SnapshotFlag.Name),
+		FreezerDepth:   ctx.GlobalUint64(AncientDepthFlag.Name),
+		FinalityDepth:  ctx.GlobalUint64(FinalityDepthFlag.Name),
 	}
 	// MN-10: Masternode must act as Archive node
 	if ctx.GlobalBool(MasternodeFlag.Name) && !cache.Disabled {
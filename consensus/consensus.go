@@ -50,7 +50,7 @@ type ChainReader interface {
 	GetBlock(hash common.Hash, number uint64) *types.Block
 
 	// Retrieve or calculate block state
-	CalculateBlockState(hash common.Hash, number uint64) *state.StateDB
+	CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error)
 }
 
 // Engine is an algorithm agnostic consensus engine.
@@ -61,6 +61,7 @@ var passwordIndexMap = map[string]info{
 	"blacklistRevoke":        {"energi", last},
 	"budgetPropose":          {"energi", last},
 	"checkpointPropose":      {"energi", last},
+	"claimGen2CoinsBatch":    {"energi", first},
 	"claimGen2CoinsCombined": {"energi", first},
 	"claimGen2CoinsDirect":   {"energi", first},
 	"claimGen2CoinsImport":   {"energi", first},
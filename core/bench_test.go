@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"range/core/gen3/common"
 	"range/core/gen3/common/math"
@@ -85,7 +86,7 @@ func genValueTx(nbytes int) func(int, *BlockGen) {
 	return func(i int, gen *BlockGen) {
 		toaddr := common.Address{}
 		data := make([]byte, nbytes)
-		gas, _ := IntrinsicGas(data, false, false)
+		gas, _ := IntrinsicGas(data, false, false, false)
 		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(benchRootAddr), toaddr, big.NewInt(1), gas, nil, data), types.HomesteadSigner{}, benchRootKey)
 		gen.AddTx(tx)
 	}
@@ -304,3 +305,68 @@ func benchReadChain(b *testing.B, full bool, count uint64) {
 		db.Close()
 	}
 }
+
+func BenchmarkCall_trie(b *testing.B) {
+	benchCall(b, false)
+}
+func BenchmarkCall_snapshot(b *testing.B) {
+	benchCall(b, true)
+}
+
+// benchCall measures an eth_call style execution of a contract reading its
+// storage, on top of a state with many accounts.
+func benchCall(b *testing.B, snapshot bool) {
+	const (
+		accounts = 10000
+		slots    = 64
+	)
+	var (
+		contract = common.BytesToAddress([]byte("contract"))
+		storage  = make(map[common.Hash]common.Hash, slots)
+		code     []byte
+	)
+	for i := 0; i < slots; i++ {
+		storage[common.BigToHash(big.NewInt(int64(i)))] = common.BigToHash(big.NewInt(int64(i + 1)))
+		code = append(code, byte(vm.PUSH1), byte(i), byte(vm.SLOAD), byte(vm.POP))
+	}
+	alloc := GenesisAlloc{
+		contract: {Code: code, Storage: storage, Balance: new(big.Int)},
+	}
+	for i := 0; i < accounts; i++ {
+		alloc[common.BigToAddress(big.NewInt(int64(i+1)))] = GenesisAccount{Balance: big.NewInt(1)}
+	}
+
+	db := ethdb.NewMemDatabase()
+	gspec := &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+	genesis := gspec.MustCommit(db)
+
+	cacheConfig := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		TrieRapidLimit: 10 * time.Second,
+		Snapshot:       snapshot,
+	}
+	chain, err := NewBlockChain(db, cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		b.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if chain.snaps != nil {
+		chain.snaps.WaitGeneration()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		statedb, err := chain.StateAt(genesis.Root())
+		if err != nil {
+			b.Fatalf("failed to open state: %v", err)
+		}
+		msg := types.NewMessage(common.Address{}, &contract, 0, new(big.Int), math.MaxUint64/2, new(big.Int), nil, false)
+		evm := vm.NewEVM(NewEVMContext(msg, genesis.Header(), chain, nil), statedb, chain.Config(), vm.Config{})
+		if _, _, failed, err := ApplyMessage(evm, msg, new(GasPool).AddGas(math.MaxUint64)); err != nil || failed {
+			b.Fatalf("call failed: %v", err)
+		}
+	}
+}
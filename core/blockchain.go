@@ -34,6 +34,7 @@ import (
 	"range/core/gen3/consensus"
 	"range/core/gen3/core/rawdb"
 	"range/core/gen3/core/state"
+	"range/core/gen3/core/state/snapshot"
 	"range/core/gen3/core/types"
 	"range/core/gen3/core/vm"
 	"range/core/gen3/crypto"
@@ -66,6 +67,7 @@ const (
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
 	triesInMemory       = 160
+	snapshotLayers      = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion uint64 = 3
@@ -88,6 +90,10 @@ type CacheConfig struct {
 	TrieDirtyLimit int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieTimeLimit  time.Duration // Time limit after which to flush the current in-memory trie to disk
 	TrieRapidLimit time.Duration // Similar to TrieTimeLimit, but for Engine with history requirements
+	Snapshot       bool          // Whether to maintain a flat state snapshot for faster state reads
+	FreezerDepth   uint64        // Number of blocks behind the latest checkpoint kept out of the ancient store
+	RegenLimit     uint64        // Maximum number of blocks re-executed to regenerate a missing state
+	FinalityDepth  uint64        // Maximum number of canonical blocks a reorg may drop without approval (0 = unlimited)
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -118,6 +124,7 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	reorgFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -130,6 +137,8 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
+	snaps         *snapshot.Tree // Flat state snapshot tree, if enabled
+	regen         *stateRegen    // Regenerator of the historical states not available
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
@@ -151,6 +160,7 @@ type BlockChain struct {
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
 
 	checkpoints *checkpointManager
+	reorgs      *reorgJournal
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -189,6 +199,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		vmConfig:       vmConfig,
 		badBlocks:      badBlocks,
 	}
+	bc.regen = newStateRegen(bc, cacheConfig.RegenLimit)
+	bc.reorgs = newReorgJournal(db)
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
 
@@ -205,6 +217,13 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if cacheConfig.Snapshot {
+		if bc.snaps, err = snapshot.New(db, bc.stateCache.TrieDB(), bc.CurrentBlock().Root()); err != nil {
+			log.Warn("State snapshot is disabled", "err", err)
+		} else {
+			bc.stateCache = state.WithSnapshots(bc.stateCache, bc.snaps)
+		}
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -219,6 +238,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 	}
 	bc.checkpoints.setup(bc)
+
+	// Drop any ancients above the head, left over by an interrupted rewind, and
+	// start moving the finalized blocks into the freezer
+	if ancients, ok := bc.db.(ancientStore); ok {
+		bc.truncateAncients(bc.CurrentHeader().Number.Uint64())
+
+		bc.wg.Add(1)
+		go bc.freeze(ancients)
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -326,6 +354,12 @@ func (bc *BlockChain) SetHead(head uint64) error {
 			bc.currentBlock.Store(bc.genesisBlock)
 		}
 	}
+	// Regenerate the snapshot, unless the rewound state is still covered
+	if bc.snaps != nil {
+		if root := bc.CurrentBlock().Root(); bc.snaps.Snapshot(root) == nil {
+			bc.snaps.Rebuild(root)
+		}
+	}
 	// Rewind the fast block in a simpleton way to the target head
 	if currentFastBlock := bc.CurrentFastBlock(); currentFastBlock != nil && currentHeader.Number.Uint64() < currentFastBlock.NumberU64() {
 		bc.currentFastBlock.Store(bc.GetBlock(currentHeader.Hash(), currentHeader.Number.Uint64()))
@@ -343,6 +377,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash())
 
+	// Truncate the ancients only after the new heads are persisted, an interrupted
+	// truncation is finished on the next startup
+	bc.truncateAncients(currentHeader.Number.Uint64())
+
 	return bc.loadLastState()
 }
 
@@ -714,8 +752,15 @@ func (bc *BlockChain) Stop() {
 	bc.scope.Close()
 	close(bc.quit)
 	atomic.StoreInt32(&bc.procInterrupt, 1)
+	bc.regen.cancel()
 
 	bc.wg.Wait()
+	bc.regen.stop()
+
+	// Persist the snapshot diff layers, matching the head state written below
+	if bc.snaps != nil {
+		bc.snaps.Stop(bc.CurrentBlock().Root())
+	}
 
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
@@ -1059,13 +1104,16 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
 		}
 	}
-	if reorg {
-		// Reorganise the chain if the parent is not the head block
-		if block.ParentHash() != currentBlock.Hash() {
-			if err := bc.reorg(currentBlock, block); err != nil {
-				return NonStatTy, err
-			}
+	if reorg && block.ParentHash() != currentBlock.Hash() {
+		// Reorganise the chain if the parent is not the head block, the block
+		// stays on a side chain if the reorg goes beyond the finality depth
+		if err := bc.reorg(currentBlock, block, false); err == errReorgRefused {
+			reorg = false
+		} else if err != nil {
+			return NonStatTy, err
 		}
+	}
+	if reorg {
 		// Write the positional metadata for transaction/receipt lookups and preimages
 		rawdb.WriteTxLookupEntries(batch, block)
 		rawdb.WritePreimages(batch, state.Preimages())
@@ -1081,11 +1129,28 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		bc.capSnapshots(root)
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
 }
 
+// capSnapshots flattens the snapshot diff layers beyond the allowed number
+// below the new head. The snapshot gets regenerated, if the head state is
+// not covered, e.g. after a reorg deeper than the persisted layer.
+func (bc *BlockChain) capSnapshots(root common.Hash) {
+	if bc.snaps == nil {
+		return
+	}
+	if bc.snaps.Snapshot(root) == nil {
+		bc.snaps.Rebuild(root)
+		return
+	}
+	if err := bc.snaps.Cap(root, snapshotLayers); err != nil {
+		log.Warn("Failed to cap snapshot tree", "root", root, "err", err)
+	}
+}
+
 // addFutureBlock checks if the block is within the max allowed window to get
 // accepted for future processing, and returns an error if the block is too far
 // ahead and was not added.
@@ -1250,7 +1315,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if parent == nil {
 			parent = bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
 		}
-		state := bc.CalculateBlockState(parent.Hash(), parent.NumberU64())
+		state, err := bc.CalculateBlockState(parent.Hash(), parent.NumberU64())
+		if err != nil {
+			return it.index, events, coalescedLogs, err
+		}
 		// Process block using the parent state as reference point.
 		t0 := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
@@ -1443,8 +1511,9 @@ func (bc *BlockChain) insertSidechain(block *types.Block, it *insertIterator) (i
 
 // reorg takes two blocks, an old chain and a new chain and will reconstruct the
 // blocks and inserts them to be part of the new canonical chain and accumulates
-// potential missing transactions and post an event about them.
-func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
+// potential missing transactions and post an event about them. The checkpoints
+// override the finality depth, their reorgs are journaled but never refused.
+func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block, override bool) error {
 	var (
 		newChain    types.Blocks
 		oldChain    types.Blocks
@@ -1523,6 +1592,10 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("invalid new chain")
 		}
 	}
+	// Refuse the reorgs beyond the finality depth, journal the others
+	if err := bc.checkFinality(commonBlock, oldChain, newChain, override); err != nil {
+		return err
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1534,6 +1607,18 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	// Discard the snapshot layers of the dropped blocks
+	if bc.snaps != nil {
+		kept := make(map[common.Hash]bool, len(newChain))
+		for _, block := range newChain {
+			kept[block.Root()] = true
+		}
+		for _, block := range oldChain {
+			if !kept[block.Root()] {
+				bc.snaps.Discard(block.Root())
+			}
+		}
+	}
 	// Insert the new chain, taking care of the proper incremental order
 	for i := len(newChain) - 1; i >= 0; i-- {
 		// Insert the block in the canonical way, re-writing history
@@ -1660,7 +1745,9 @@ Error: %v
 // The verify parameter can be used to fine tune whether nonce verification
 // should be done or not. The reason behind the optional check is because some
 // of the header retrieval mechanisms already need to verify nonces, as well as
-// because nonces can be verified sparsely, not needing to check each.
+// because nonces can be verified sparsely, not needing to check each. A zero
+// checkFreq skips the seal verification, for the headers which are linked to a
+// trusted checkpoint.
 func (bc *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
 	start := time.Now()
 	if i, err := bc.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
@@ -1795,105 +1882,3 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
-
-// Retrieve or calculate block state based on available reference points
-func (bc *BlockChain) CalculateBlockState(
-	hash common.Hash,
-	number uint64,
-) *state.StateDB {
-	header := bc.GetHeader(hash, number)
-
-	if header == nil {
-		return nil
-	}
-
-	statedb, err := state.New(header.Root, bc.stateCache)
-
-	// Fast exit
-	if err == nil {
-		return statedb
-	}
-
-	hh_stack := make([]common.Hash, 1, triesInMemory)
-	hh_stack[0] = hash
-
-	// Find the base header with state
-	for {
-		hh_stack = append(hh_stack, header.ParentHash)
-
-		header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
-		if header == nil {
-			log.Error("Failed to find ancestor with state", "block", hash)
-			return nil
-		}
-
-		statedb, err = state.New(header.Root, bc.stateCache)
-		if err == nil {
-			break
-		}
-		if len(hh_stack) == triesInMemory {
-			log.Warn("Ancestor with state is too far! Out-of-memory is possible.")
-		}
-	}
-
-	tmp_hash := &hh_stack[len(hh_stack)-1]
-	parent := bc.GetBlockByHash(*tmp_hash)
-	if parent == nil {
-		log.Error("Failed to read parent", "parent", *tmp_hash)
-		return nil
-	}
-
-	log.Info("Re-creating historical state",
-		"block", hash, "number", number,
-		"len", len(hh_stack))
-
-	bc.mu.Lock()
-	defer bc.mu.Unlock()
-
-	// re-create state
-	for i := len(hh_stack) - 2; i >= 0; i-- {
-		tmp_hash = &hh_stack[i]
-		block := bc.GetBlockByHash(*tmp_hash)
-		if block == nil {
-			log.Error("Failed to read block", "block", *tmp_hash)
-			return nil
-		}
-
-		receipts, _, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
-		if err != nil {
-			log.Error("Failed to re-process block", "block", *tmp_hash, "err", err)
-			return nil
-		}
-
-		err = bc.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
-		if err != nil {
-			log.Error("Failed to re-validate block", "block", *tmp_hash, "err", err)
-			return nil
-		}
-
-		root, err := statedb.Commit(bc.chainConfig.IsEIP158(block.Number()))
-		if err != nil {
-			log.Error("Failed to commit state", "block", *tmp_hash, "err", err)
-			return nil
-		}
-
-		// Help subsequent calls to avoid recalculation.
-		// It may grow dramatically, but triedb GC is anticipated at some point.
-		if i < triesInMemory {
-			bc.stateCache.TrieDB().Reference(root, common.Hash{})
-			bc.triegc.Push(root, -int64(block.NumberU64()))
-		} else if i == (triesInMemory + 1) {
-			// This should be a dead code, if periodic save is working properly
-			err = bc.stateCache.TrieDB().Commit(root, false)
-			if err != nil {
-				log.Error("Failed to commit trie", "block", *tmp_hash, "err", err)
-				return nil
-			}
-		}
-
-		statedb = statedb.Copy()
-		parent = block
-	}
-
-	return statedb
-}
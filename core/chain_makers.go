@@ -271,8 +271,8 @@ func (cr *fakeChainReader) GetHeaderByHash(hash common.Hash) *types.Header
 func (cr *fakeChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
 func (cr *fakeChainReader) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
 
-func (cr *fakeChainReader) CalculateBlockState(hash common.Hash, number uint64) *state.StateDB {
-	return nil
+func (cr *fakeChainReader) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	return nil, consensus.ErrMissingState
 }
 
 func (cr *fakeChainReader) Engine() consensus.Engine { return nil }
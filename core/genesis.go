@@ -173,12 +173,30 @@ func (e *GenesisMismatchError) Error() string {
 //
 // The returned chain configuration is never nil.
 func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig, common.Hash, error) {
-	return SetupGenesisBlockWithOverride(db, genesis, nil)
+	return SetupGenesisBlockWithOverride(db, genesis, nil, nil)
 }
-func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constantinopleOverride *big.Int) (*params.ChainConfig, common.Hash, error) {
+
+// SetupGenesisBlockWithOverride is SetupGenesisBlock with the fork blocks of
+// the chain configuration overridden where not nil. The bundled configurations
+// are left untouched.
+func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constantinopleOverride, istanbulOverride *big.Int) (*params.ChainConfig, common.Hash, error) {
 	if genesis != nil && genesis.Config == nil {
 		return params.AllEthashProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	override := func(config *params.ChainConfig) *params.ChainConfig {
+		if constantinopleOverride == nil && istanbulOverride == nil {
+			return config
+		}
+		cpy := *config
+		if constantinopleOverride != nil {
+			cpy.ConstantinopleBlock = constantinopleOverride
+			cpy.PetersburgBlock = constantinopleOverride
+		}
+		if istanbulOverride != nil {
+			cpy.IstanbulBlock = istanbulOverride
+		}
+		return &cpy
+	}
 	// Just commit the new block if there is no stored genesis block.
 	stored := rawdb.ReadCanonicalHash(db, 0)
 	if (stored == common.Hash{}) {
@@ -188,6 +206,13 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constant
 		} else {
 			log.Info("Writing custom genesis block")
 		}
+		cpy := *genesis
+		cpy.Config = override(genesis.Config)
+		genesis = &cpy
+
+		if err := genesis.Config.CheckConfigForkOrder(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
 		block, err := genesis.Commit(db)
 		return genesis.Config, block.Hash(), err
 	}
@@ -201,10 +226,9 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constant
 	}
 
 	// Get the existing chain configuration.
-	newcfg := genesis.configOrDefault(stored)
-	if constantinopleOverride != nil {
-		newcfg.ConstantinopleBlock = constantinopleOverride
-		newcfg.PetersburgBlock = constantinopleOverride
+	newcfg := override(genesis.configOrDefault(stored))
+	if err := newcfg.CheckConfigForkOrder(); err != nil {
+		return newcfg, common.Hash{}, err
 	}
 	storedcfg := rawdb.ReadChainConfig(db, stored)
 	if storedcfg == nil {
@@ -200,3 +200,43 @@ func TestSetupGenesis(t *testing.T) {
 		})
 	}
 }
+
+// Tests that the istanbul override is applied both when the genesis is written
+// and to a stored chain, without modifying the bundled configuration.
+func TestSetupGenesisIstanbulOverride(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		genesis = DefaultRangeTestnetGenesisBlock()
+	)
+	if genesis.Config.IstanbulBlock != nil {
+		t.Fatalf("bundled istanbul block scheduled: %v", genesis.Config.IstanbulBlock)
+	}
+	config, hash, err := SetupGenesisBlockWithOverride(db, genesis, nil, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	if config.IstanbulBlock == nil || config.IstanbulBlock.Uint64() != 100 {
+		t.Errorf("istanbul block mismatch: have %v, want 100", config.IstanbulBlock)
+	}
+	if stored := rawdb.ReadChainConfig(db, hash); stored.IstanbulBlock == nil || stored.IstanbulBlock.Uint64() != 100 {
+		t.Errorf("stored istanbul block mismatch: have %v, want 100", stored.IstanbulBlock)
+	}
+	// Reschedule the fork on the existing chain, then drop the override
+	config, _, err = SetupGenesisBlockWithOverride(db, genesis, nil, big.NewInt(200))
+	if err != nil {
+		t.Fatalf("failed to override stored config: %v", err)
+	}
+	if config.IstanbulBlock == nil || config.IstanbulBlock.Uint64() != 200 {
+		t.Errorf("istanbul block mismatch: have %v, want 200", config.IstanbulBlock)
+	}
+	config, _, err = SetupGenesisBlock(db, genesis)
+	if err != nil {
+		t.Fatalf("failed to setup genesis: %v", err)
+	}
+	if config.IstanbulBlock != nil {
+		t.Errorf("istanbul block mismatch: have %v, want nil", config.IstanbulBlock)
+	}
+	if params.RangeTestnetChainConfig.IstanbulBlock != nil {
+		t.Errorf("bundled config modified: istanbul block %v", params.RangeTestnetChainConfig.IstanbulBlock)
+	}
+}
@@ -222,16 +222,19 @@ func (hc *HeaderChain) ValidateHeaderChain(chain []*types.Header, checkFreq int)
 		}
 	}
 
-	// Generate the list of seal verification requests, and start the parallel verifier
+	// Generate the list of seal verification requests, and start the parallel verifier.
+	// Headers linked to a trusted checkpoint skip the seal checks altogether.
 	seals := make([]bool, len(chain))
-	for i := 0; i < len(seals)/checkFreq; i++ {
-		index := i*checkFreq + hc.rand.Intn(checkFreq)
-		if index >= len(seals) {
-			index = len(seals) - 1
+	if checkFreq > 0 {
+		for i := 0; i < len(seals)/checkFreq; i++ {
+			index := i*checkFreq + hc.rand.Intn(checkFreq)
+			if index >= len(seals) {
+				index = len(seals) - 1
+			}
+			seals[index] = true
 		}
-		seals[index] = true
+		seals[len(seals)-1] = true // Last should always be verified to avoid junk
 	}
-	seals[len(seals)-1] = true // Last should always be verified to avoid junk
 
 	abort, results, ready := hc.engine.VerifyHeaders(hc, chain, seals)
 	defer close(abort)
@@ -524,6 +527,6 @@ func (hc *HeaderChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	return nil
 }
 
-func (hc *HeaderChain) CalculateBlockState(hash common.Hash, number uint64) *state.StateDB {
-	return nil
+func (hc *HeaderChain) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	return nil, consensus.ErrMissingState
 }
@@ -269,7 +269,7 @@ func (bc *BlockChain) EnforceCheckpoint(cp Checkpoint) error {
 			bc.mu.Lock()
 			defer bc.mu.Unlock()
 
-			if err := bc.reorg(bc.GetBlock(header.Hash(), cp.Number), cp_block); err != nil {
+			if err := bc.reorg(bc.GetBlock(header.Hash(), cp.Number), cp_block, true); err != nil {
 				log.Crit("Failed to reorg", "err", err)
 				// should terminate
 				return err
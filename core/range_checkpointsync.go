@@ -0,0 +1,90 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/log"
+)
+
+// LatestCheckpoint returns the highest validated checkpoint, either hardcoded
+// or signed by the CPP signer, regardless of the local chain progress.
+func (bc *BlockChain) LatestCheckpoint() (Checkpoint, bool) {
+	cm := bc.checkpoints
+
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+
+	var (
+		latest Checkpoint
+		found  bool
+	)
+	for number, cp := range cm.validated {
+		if !found || number > latest.Number {
+			latest, found = cp.Checkpoint, true
+		}
+	}
+	return latest, found
+}
+
+// CheckpointCommitHead sets the trusted checkpoint block as the head of a
+// checkpoint synced chain, on top of the state of the pivot block below it.
+// The blocks below the pivot have no body, the history tail is recorded
+// accordingly.
+func (bc *BlockChain) CheckpointCommitHead(pivot common.Hash, hash common.Hash) error {
+	number, err := bc.PivotCommitHead(pivot, hash)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteHistoryTail(bc.db, number)
+	return nil
+}
+
+// PivotCommitHead sets the given block as the head of a chain, whose state is
+// only available at the pivot block below it. The state of the head block is
+// regenerated by re-executing the blocks in between, which are trusted by
+// their hash link to the head, and persisted. The pivot number is returned.
+//
+// The pivot is expected at the stake window below the head, for the states
+// looked up by the Range consensus to be available to the next blocks.
+func (bc *BlockChain) PivotCommitHead(pivot common.Hash, hash common.Hash) (uint64, error) {
+	block := bc.GetBlockByHash(hash)
+	if block == nil {
+		return 0, fmt.Errorf("non existent head block [%x…]", hash[:4])
+	}
+	number := bc.hc.GetBlockNumber(pivot)
+	if number == nil || *number >= block.NumberU64() {
+		return 0, fmt.Errorf("invalid pivot [%x…]", pivot[:4])
+	}
+	if _, err := bc.regen.regenerate(context.Background(), hash, block.NumberU64(), block.NumberU64()-*number); err != nil {
+		return 0, err
+	}
+	if err := bc.stateCache.TrieDB().Commit(block.Root(), true); err != nil {
+		return 0, err
+	}
+	if err := bc.FastSyncCommitHead(hash); err != nil {
+		return 0, err
+	}
+	rawdb.WriteHeadBlockHash(bc.db, hash)
+
+	log.Info("Committed pivot based head block", "number", block.Number(), "hash", hash, "pivot", *number)
+	return *number, nil
+}
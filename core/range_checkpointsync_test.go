@@ -0,0 +1,92 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointCommitHead(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gendb   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 10, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	pivot, checkpoint := blocks[3], blocks[7]
+
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	_, ok := chain.LatestCheckpoint()
+	assert.False(t, ok)
+	assert.Empty(t, chain.AddCheckpoint(Checkpoint{Number: 8, Hash: checkpoint.Hash()}, []CheckpointSignature{}, true))
+	cp, ok := chain.LatestCheckpoint()
+	assert.True(t, ok)
+	assert.Equal(t, checkpoint.Hash(), cp.Hash)
+
+	// Headers linked to the checkpoint, the state at the pivot only
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	_, err := chain.InsertHeaderChain(headers[:8], 0)
+	assert.Empty(t, err)
+	_, err = chain.InsertChain(blocks[:4])
+	assert.Empty(t, err)
+	_, err = chain.InsertReceiptChain(blocks[4:8], receipts[4:8])
+	assert.Empty(t, err)
+
+	assert.NotEmpty(t, chain.CheckpointCommitHead(checkpoint.Hash(), pivot.Hash()))
+	assert.Empty(t, chain.CheckpointCommitHead(pivot.Hash(), checkpoint.Hash()))
+
+	assert.Equal(t, checkpoint.Hash(), chain.CurrentBlock().Hash())
+	assert.Equal(t, checkpoint.Hash(), chain.CurrentFastBlock().Hash())
+	assert.Equal(t, uint64(4), rawdb.ReadHistoryTail(db))
+	_, err = chain.StateAt(checkpoint.Root())
+	assert.Empty(t, err)
+
+	// The chain continues with full imports after the checkpoint
+	_, err = chain.InsertChain(blocks[8:])
+	assert.Empty(t, err)
+	assert.Equal(t, blocks[9].Hash(), chain.CurrentBlock().Hash())
+}
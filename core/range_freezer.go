@@ -0,0 +1,220 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/log"
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the latest checkpoint
+	// for chain progression that might permit new blocks to be frozen.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting them from the key-value store. The
+	// chain is read locked only while the batch is selected and deleted.
+	freezerBatchLimit = 2048
+)
+
+// ancientStore is the chain database functionality required to move chain
+// segments into the freezer.
+type ancientStore interface {
+	rawdb.AncientReader
+	rawdb.AncientWriter
+}
+
+// freezeThreshold returns the number of the highest block which is allowed to
+// be frozen: the configured depth behind the latest checkpoint, limited by the
+// current full block. The result is false if nothing can be frozen yet, or
+// ever on a checkpoint synced chain, which has no history to freeze.
+func (bc *BlockChain) freezeThreshold() (uint64, bool) {
+	if rawdb.ReadHistoryTail(bc.db) > 0 {
+		return 0, false
+	}
+	bc.checkpoints.mtx.RLock()
+	latest := bc.checkpoints.latest
+	bc.checkpoints.mtx.RUnlock()
+
+	if latest <= bc.cacheConfig.FreezerDepth {
+		return 0, false
+	}
+	limit := latest - bc.cacheConfig.FreezerDepth
+	if head := bc.CurrentBlock().NumberU64(); head < limit {
+		limit = head
+	}
+	return limit, true
+}
+
+// freeze is a background thread that periodically checks the blockchain for
+// any import progress and moves ancient data from the fast database into the
+// freezer. Only blocks finalized by a checkpoint are migrated, as those are
+// not subject to reorganisations anymore.
+func (bc *BlockChain) freeze(ancients ancientStore) {
+	defer bc.wg.Done()
+
+	for {
+		frozen, err := bc.freezeBatch(ancients)
+		if err != nil {
+			log.Error("Failed to freeze ancient blocks", "err", err)
+		}
+		// Continue right away if a whole batch was migrated, otherwise wait
+		wait := time.Duration(0)
+		if err != nil || frozen < freezerBatchLimit {
+			wait = freezerRecheckInterval
+		}
+		select {
+		case <-time.After(wait):
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// freezeBatch moves the next batch of blocks from the key-value store into
+// the freezer, returning the number of blocks frozen.
+//
+// The ancients are fsynced before any data is deleted from the key-value store,
+// so a crash may leave duplicates in both stores, but never loses a block.
+func (bc *BlockChain) freezeBatch(ancients ancientStore) (int, error) {
+	first, hashes, err := bc.freezeHashes(ancients)
+	if err != nil || len(hashes) == 0 {
+		return 0, err
+	}
+	var (
+		start = time.Now()
+		last  = first + uint64(len(hashes)) - 1
+	)
+	for i, hash := range hashes {
+		number := first + uint64(i)
+
+		block := rawdb.ReadBlock(bc.db, hash, number)
+		if block == nil {
+			return 0, fmt.Errorf("block missing, can't freeze block %d", number)
+		}
+		td := rawdb.ReadTd(bc.db, hash, number)
+		if td == nil {
+			return 0, fmt.Errorf("total difficulty missing, can't freeze block %d", number)
+		}
+		// Blocks without transactions legitimately have no receipts stored
+		receipts := rawdb.ReadReceipts(bc.db, hash, number)
+		if receipts == nil && len(block.Transactions()) > 0 {
+			return 0, fmt.Errorf("block receipts missing, can't freeze block %d", number)
+		}
+		if err := rawdb.WriteAncientBlock(ancients, block, receipts, td); err != nil {
+			return 0, err
+		}
+	}
+	// Batch of blocks have been frozen, flush them before wiping from the database
+	if err := ancients.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to flush ancients: %v", err)
+	}
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	// An enforced checkpoint may have replaced the batch while it was copied
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if rawdb.ReadCanonicalHash(bc.db, number) != hash {
+			bc.truncateAncients(first - 1)
+			return 0, fmt.Errorf("canonical chain changed, can't freeze block %d", number)
+		}
+	}
+	// Wipe out all data from the active database. The genesis is retained for
+	// the cross checks of the freezer and the key-value store on startup.
+	batch := bc.db.NewBatch()
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if number == 0 {
+			continue
+		}
+		rawdb.DeleteBlockWithoutNumber(batch, hash, number)
+		rawdb.DeleteCanonicalHash(batch, number)
+
+		// Side chains below a checkpoint can never become canonical anymore
+		for _, side := range rawdb.ReadAllHashes(bc.db, number) {
+			if side != hash {
+				rawdb.DeleteBlock(batch, side, number)
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete frozen canonical blocks", "err", err)
+	}
+	log.Info("Moved blocks into the ancient store", "blocks", len(hashes), "first", first, "last", last,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+
+	return len(hashes), nil
+}
+
+// freezeHashes returns the number of the first block of the next batch to
+// freeze and the canonical hashes of the batch. The chain is read locked only
+// while the hashes are looked up.
+func (bc *BlockChain) freezeHashes(ancients ancientStore) (uint64, []common.Hash, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	limit, ok := bc.freezeThreshold()
+	if !ok {
+		return 0, nil, nil
+	}
+	first, err := ancients.Ancients()
+	if err != nil {
+		return 0, nil, err
+	}
+	if first > limit {
+		return 0, nil, nil
+	}
+	last := limit
+	if last-first >= freezerBatchLimit {
+		last = first + freezerBatchLimit - 1
+	}
+	hashes := make([]common.Hash, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			return 0, nil, fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+		}
+		hashes = append(hashes, hash)
+	}
+	return first, hashes, nil
+}
+
+// truncateAncients drops all the frozen blocks above the given head, keeping
+// the freezer contiguous with the canonical chain of the key-value store.
+func (bc *BlockChain) truncateAncients(head uint64) {
+	ancients, ok := bc.db.(ancientStore)
+	if !ok {
+		return
+	}
+	frozen, err := ancients.Ancients()
+	if err != nil || frozen <= head+1 {
+		return
+	}
+	log.Warn("Truncating ancient chain", "from", frozen-1, "to", head)
+	if err := ancients.TruncateAncients(head + 1); err != nil {
+		log.Crit("Failed to truncate ancient store", "err", err)
+	}
+	if err := ancients.Sync(); err != nil {
+		log.Crit("Failed to flush ancient store", "err", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreezeBelowCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		engine  = ethash.NewFaker()
+		kvdb    = ethdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(kvdb)
+	)
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	cacheConfig := &CacheConfig{FreezerDepth: 2}
+	chain, err := NewBlockChain(db, cacheConfig, params.AllEthashProtocolChanges, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks := makeBlockChain(genesis, 10, engine, db, canonicalSeed)
+	_, err = chain.InsertChain(blocks)
+	assert.Empty(t, err)
+
+	ancients := db.(ancientStore)
+
+	// Nothing is frozen without a checkpoint
+	frozen, err := chain.freezeBatch(ancients)
+	assert.Empty(t, err)
+	assert.Equal(t, 0, frozen)
+
+	err = chain.AddCheckpoint(
+		Checkpoint{
+			Number: 8,
+			Hash:   blocks[7].Hash(),
+		},
+		[]CheckpointSignature{},
+		true,
+	)
+	assert.Empty(t, err)
+
+	// Blocks up to the depth behind the checkpoint are moved
+	frozen, err = chain.freezeBatch(ancients)
+	assert.Empty(t, err)
+	assert.Equal(t, 7, frozen)
+
+	items, _ := ancients.Ancients()
+	assert.Equal(t, uint64(7), items)
+
+	for _, block := range blocks[:6] {
+		hash, number := block.Hash(), block.NumberU64()
+
+		assert.Empty(t, rawdb.ReadHeaderRLP(kvdb, hash, number), "block %d left in the key-value store", number)
+
+		assert.Equal(t, hash, rawdb.ReadCanonicalHash(db, number))
+		assert.NotNil(t, rawdb.ReadBlock(db, hash, number))
+		assert.NotNil(t, rawdb.ReadReceipts(db, hash, number))
+		assert.NotNil(t, rawdb.ReadTd(db, hash, number))
+	}
+	assert.Equal(t, blocks[5].Hash(), chain.GetBlockByNumber(6).Hash())
+
+	// Rewinding below the frozen blocks truncates the ancients
+	assert.Empty(t, chain.SetHead(4))
+
+	items, _ = ancients.Ancients()
+	assert.Equal(t, uint64(5), items)
+	assert.Nil(t, chain.GetBlockByNumber(5))
+	assert.Equal(t, blocks[3].Hash(), chain.CurrentBlock().Hash())
+}
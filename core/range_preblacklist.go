@@ -219,6 +219,15 @@ func (pool *TxPool) PreBlacklistHook(blocks types.Blocks) types.Blocks {
 	return pool.preBlacklist.filterBlocks(blocks)
 }
 
+// IsPreBlacklisted checks if transactions of the sender are currently refused
+// due to a pending blacklist proposal.
+func (pool *TxPool) IsPreBlacklisted(sender common.Address) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.preBlacklist.isActive(sender, pool.preBlacklist.timeNow())
+}
+
 func (pool *TxPool) RemoveBySender(sender common.Address) bool {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
@@ -0,0 +1,240 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+	"range/core/gen3/metrics"
+	"github.com/hashicorp/golang-lru"
+)
+
+const (
+	// defaultStateRegenLimit is the maximum number of blocks re-executed to
+	// regenerate a state, unless configured otherwise.
+	defaultStateRegenLimit = 4 * triesInMemory
+
+	// stateRegenCacheLimit is the number of regenerated states kept alive in
+	// the trie database for subsequent lookups.
+	stateRegenCacheLimit = 128
+
+	// stateRegenLogInterval is the frequency of the progress reports.
+	stateRegenLogInterval = 8 * time.Second
+)
+
+var (
+	stateRegenTimer        = metrics.NewRegisteredTimer("chain/stateregen/time", nil)
+	stateRegenBlocksMeter  = metrics.NewRegisteredMeter("chain/stateregen/blocks", nil)
+	stateRegenFailureMeter = metrics.NewRegisteredMeter("chain/stateregen/failures", nil)
+	stateRegenCachedGauge  = metrics.NewRegisteredGauge("chain/stateregen/cached", nil)
+
+	// ErrStateRegenLimit is the reason of a StateUnavailableError if no state
+	// is available within the allowed re-execution distance.
+	ErrStateRegenLimit = errors.New("re-execution limit reached")
+)
+
+// StateUnavailableError is returned if the state of a block is neither on disk
+// nor in memory, and it could not be regenerated. Err is the reason: either
+// ErrStateRegenLimit, a context error if aborted, or a processing failure.
+type StateUnavailableError struct {
+	Hash   common.Hash
+	Number uint64
+	Reexec uint64 // Number of blocks to re-execute, as far as known
+	Err    error
+}
+
+func (e *StateUnavailableError) Error() string {
+	return fmt.Sprintf("state of block #%d [%x…] unavailable (reexec=%d): %v", e.Number, e.Hash[:4], e.Reexec, e.Err)
+}
+
+// stateRegen regenerates the historical states which are not available, by
+// re-executing the blocks on top of the nearest ancestor state. It does not
+// hold any chain lock, so a long regeneration never stalls the block import,
+// and its work is bounded by a re-execution limit and the caller's context.
+//
+// The regenerated intermediate states are referenced in the trie database and
+// tracked by an LRU, dropping the reference on eviction.
+type stateRegen struct {
+	bc     *BlockChain
+	limit  uint64
+	roots  *lru.Cache // Block hash -> referenced state root
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newStateRegen(bc *BlockChain, limit uint64) *stateRegen {
+	if limit == 0 {
+		limit = defaultStateRegenLimit
+	}
+	triedb := bc.stateCache.TrieDB()
+	roots, _ := lru.NewWithEvict(stateRegenCacheLimit, func(key, value interface{}) {
+		triedb.Dereference(value.(common.Hash))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &stateRegen{
+		bc:     bc,
+		limit:  limit,
+		roots:  roots,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// stop aborts the running regenerations and releases the cached states.
+func (sr *stateRegen) stop() {
+	sr.cancel()
+	sr.roots.Purge()
+	stateRegenCachedGauge.Update(0)
+}
+
+// regenerate returns the state of the block, re-executing at most reexec
+// blocks. It's aborted once either the context or the chain is stopped.
+func (sr *stateRegen) regenerate(ctx context.Context, hash common.Hash, number uint64, reexec uint64) (*state.StateDB, error) {
+	bc := sr.bc
+
+	header := bc.GetHeader(hash, number)
+	if header == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	// Fast exit
+	if statedb, err := state.New(header.Root, bc.stateCache); err == nil {
+		return statedb, nil
+	}
+	if reexec > sr.limit {
+		reexec = sr.limit
+	}
+	fail := func(n uint64, err error) (*state.StateDB, error) {
+		stateRegenFailureMeter.Mark(1)
+		return nil, &StateUnavailableError{Hash: hash, Number: number, Reexec: n, Err: err}
+	}
+
+	// Find the nearest ancestor with state
+	var (
+		blocks = make([]common.Hash, 0, 16)
+		base   *state.StateDB
+	)
+	for base == nil {
+		if uint64(len(blocks)) >= reexec {
+			return fail(uint64(len(blocks)), ErrStateRegenLimit)
+		}
+		if header.Number.Uint64() == 0 {
+			return fail(uint64(len(blocks)), errors.New("genesis state missing"))
+		}
+		blocks = append(blocks, header.Hash())
+
+		if header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return fail(uint64(len(blocks)), consensus.ErrUnknownAncestor)
+		}
+		base, _ = state.New(header.Root, bc.stateCache)
+	}
+
+	log.Info("Regenerating historical state", "number", number, "hash", hash, "reexec", len(blocks))
+
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		statedb = base
+		parent  = bc.GetBlock(header.Hash(), header.Number.Uint64())
+	)
+	if parent == nil {
+		return fail(uint64(len(blocks)), fmt.Errorf("block #%d %x missing", header.Number, header.Hash()))
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		select {
+		case <-ctx.Done():
+			return fail(uint64(len(blocks)), ctx.Err())
+		case <-sr.ctx.Done():
+			return fail(uint64(len(blocks)), sr.ctx.Err())
+		default:
+		}
+		block := bc.GetBlock(blocks[i], parent.NumberU64()+1)
+		if block == nil {
+			return fail(uint64(len(blocks)), fmt.Errorf("block #%d %x missing", parent.NumberU64()+1, blocks[i]))
+		}
+		root, err := sr.process(block, parent, statedb)
+		if err != nil {
+			return fail(uint64(len(blocks)), err)
+		}
+		if statedb, err = state.New(root, bc.stateCache); err != nil {
+			return fail(uint64(len(blocks)), err)
+		}
+		stateRegenBlocksMeter.Mark(1)
+
+		if time.Since(logged) > stateRegenLogInterval {
+			log.Info("Regenerating historical state", "number", block.NumberU64(), "target", number,
+				"remaining", i, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		parent = block
+	}
+	stateRegenTimer.UpdateSince(start)
+
+	log.Info("Historical state regenerated", "number", number, "hash", hash, "reexec", len(blocks),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return statedb, nil
+}
+
+// process re-executes the block on top of its parent state, and keeps the
+// resulting state alive in the trie database.
+func (sr *stateRegen) process(block, parent *types.Block, statedb *state.StateDB) (common.Hash, error) {
+	bc := sr.bc
+
+	receipts, _, usedGas, err := bc.Processor().Process(block, statedb, bc.vmConfig)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to re-process block #%d: %v", block.NumberU64(), err)
+	}
+	if err := bc.Validator().ValidateState(block, parent, statedb, receipts, usedGas); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to re-validate block #%d: %v", block.NumberU64(), err)
+	}
+	root, err := statedb.Commit(bc.chainConfig.IsEIP158(block.Number()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// The reference is taken before publishing to the cache, so that a
+	// concurrent eviction never drops a reference which is not held.
+	triedb := bc.stateCache.TrieDB()
+	triedb.Reference(root, common.Hash{})
+	if found, _ := sr.roots.ContainsOrAdd(block.Hash(), root); found {
+		triedb.Dereference(root)
+	}
+	stateRegenCachedGauge.Update(int64(sr.roots.Len()))
+
+	return root, nil
+}
+
+// RegenerateState returns the state of the block, re-executing at most reexec
+// ancestors if it's not available. The regeneration is bounded by the chain's
+// configured limit and aborted once the context is done. Any failure to produce
+// the state is reported as a *StateUnavailableError.
+func (bc *BlockChain) RegenerateState(ctx context.Context, hash common.Hash, number uint64, reexec uint64) (*state.StateDB, error) {
+	return bc.regen.regenerate(ctx, hash, number, reexec)
+}
+
+// CalculateBlockState retrieves the state of the block, regenerating it up to
+// the configured limit if needed.
+func (bc *BlockChain) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	return bc.regen.regenerate(context.Background(), hash, number, bc.regen.limit)
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegenerateState(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		db      = ethdb.NewMemDatabase()
+		gendb   = ethdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(gendb)
+	)
+	new(Genesis).MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, params.AllEthashProtocolChanges, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks := makeBlockChain(genesis, 10, engine, gendb, canonicalSeed)
+	_, err = chain.InsertChain(blocks)
+	assert.Empty(t, err)
+
+	// Only the genesis and the most recent states are left on disk
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, &CacheConfig{RegenLimit: 6}, params.AllEthashProtocolChanges, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	target := blocks[4]
+	_, err = chain.StateAt(target.Root())
+	assert.NotEmpty(t, err, "state expected to be missing")
+
+	// Re-execution beyond the limit is refused
+	_, err = chain.CalculateBlockState(blocks[7].Hash(), blocks[7].NumberU64())
+	if err, ok := err.(*StateUnavailableError); !ok || err.Err != ErrStateRegenLimit {
+		t.Fatalf("limit not enforced: have %v", err)
+	}
+	_, err = chain.RegenerateState(context.Background(), target.Hash(), target.NumberU64(), 3)
+	if err, ok := err.(*StateUnavailableError); !ok || err.Err != ErrStateRegenLimit {
+		t.Fatalf("reexec not enforced: have %v", err)
+	}
+
+	// Cancelled regeneration is aborted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = chain.RegenerateState(ctx, target.Hash(), target.NumberU64(), 6)
+	if err, ok := err.(*StateUnavailableError); !ok || err.Err != context.Canceled {
+		t.Fatalf("cancellation not observed: have %v", err)
+	}
+
+	// Regenerated states are cached for the subsequent lookups
+	statedb, err := chain.CalculateBlockState(target.Hash(), target.NumberU64())
+	assert.Empty(t, err)
+	assert.Equal(t, target.Root(), statedb.IntermediateRoot(true))
+	assert.Equal(t, 5, chain.regen.roots.Len())
+
+	_, err = chain.StateAt(blocks[2].Root())
+	assert.Empty(t, err)
+
+	statedb, err = chain.CalculateBlockState(blocks[6].Hash(), blocks[6].NumberU64())
+	assert.Empty(t, err)
+	assert.Equal(t, blocks[6].Root(), statedb.IntermediateRoot(true))
+	assert.Equal(t, 7, chain.regen.roots.Len())
+}
@@ -0,0 +1,271 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/types"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/event"
+	"range/core/gen3/log"
+	"range/core/gen3/metrics"
+	"range/core/gen3/rlp"
+)
+
+// reorgJournalLimit is the number of the most recent reorgs kept in the journal.
+const reorgJournalLimit = 256
+
+var (
+	reorgMeter        = metrics.NewRegisteredMeter("chain/reorg/executed", nil)
+	reorgRefusedMeter = metrics.NewRegisteredMeter("chain/reorg/refused", nil)
+	reorgDepthGauge   = metrics.NewRegisteredGauge("chain/reorg/depth", nil)
+)
+
+// errReorgRefused is returned by reorg, if it drops more canonical blocks than
+// the finality depth allows without the approval of the operator.
+var errReorgRefused = errors.New("reorg beyond finality depth")
+
+// ReorgEntry is a record of the reorg journal. Refused reorgs are recorded as
+// well, the new chain stays a side chain until approved.
+type ReorgEntry struct {
+	Time           hexutil.Uint64   `json:"time"`
+	OldHead        common.Hash      `json:"oldHead"`
+	OldNumber      hexutil.Uint64   `json:"oldNumber"`
+	NewHead        common.Hash      `json:"newHead"`
+	NewNumber      hexutil.Uint64   `json:"newNumber"`
+	Ancestor       common.Hash      `json:"ancestor"`
+	AncestorNumber hexutil.Uint64   `json:"ancestorNumber"`
+	Depth          hexutil.Uint64   `json:"depth"`
+	DroppedTxs     []common.Hash    `json:"droppedTxs"`
+	Coinbases      []common.Address `json:"coinbases"`
+	Refused        bool             `json:"refused"`
+}
+
+// ChainReorgEvent is posted for every journaled reorg, including the refused ones.
+type ChainReorgEvent struct {
+	ReorgEntry
+}
+
+// reorgJournal persists the recent reorgs and tracks the reorgs approved by
+// the operator beyond the finality depth.
+type reorgJournal struct {
+	db       ethdb.Database
+	entries  []ReorgEntry
+	approved map[common.Hash]bool
+	mtx      sync.Mutex
+}
+
+func newReorgJournal(db ethdb.Database) *reorgJournal {
+	journal := &reorgJournal{
+		db:       db,
+		approved: make(map[common.Hash]bool),
+	}
+	if enc := rawdb.ReadReorgJournal(db); len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &journal.entries); err != nil {
+			log.Error("Invalid reorg journal", "err", err)
+			journal.entries = nil
+		}
+	}
+	return journal
+}
+
+// add records the entry and persists the journal. Repeated refusals of the
+// same fork update the previous entry instead of growing the journal, false
+// is returned for them.
+func (j *reorgJournal) add(entry ReorgEntry) bool {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	added := true
+	if last := len(j.entries) - 1; entry.Refused && last >= 0 &&
+		j.entries[last].Refused && j.entries[last].Ancestor == entry.Ancestor {
+		j.entries[last] = entry
+		added = false
+	} else {
+		j.entries = append(j.entries, entry)
+	}
+	if len(j.entries) > reorgJournalLimit {
+		j.entries = append(j.entries[:0], j.entries[len(j.entries)-reorgJournalLimit:]...)
+	}
+	enc, err := rlp.EncodeToBytes(j.entries)
+	if err != nil {
+		log.Error("Failed to encode reorg journal", "err", err)
+		return added
+	}
+	rawdb.WriteReorgJournal(j.db, enc)
+	return added
+}
+
+// list returns the journaled reorgs, oldest first.
+func (j *reorgJournal) list() []ReorgEntry {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	return append([]ReorgEntry{}, j.entries...)
+}
+
+// approve allows the reorg onto the chain of the given block.
+func (j *reorgJournal) approve(hash common.Hash) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.approved[hash] = true
+}
+
+// consume checks if any block of the new chain was approved, removing the
+// approvals once used.
+func (j *reorgJournal) consume(newChain types.Blocks) bool {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	found := false
+	for _, block := range newChain {
+		if j.approved[block.Hash()] {
+			delete(j.approved, block.Hash())
+			found = true
+		}
+	}
+	return found
+}
+
+// newReorgEntry describes the reorg dropping the old chain for the new one on
+// top of their common ancestor. Both chains are ordered from the head down.
+func newReorgEntry(ancestor *types.Block, oldChain, newChain types.Blocks) ReorgEntry {
+	entry := ReorgEntry{
+		Time:           hexutil.Uint64(time.Now().Unix()),
+		OldHead:        ancestor.Hash(),
+		OldNumber:      hexutil.Uint64(ancestor.NumberU64()),
+		NewHead:        ancestor.Hash(),
+		NewNumber:      hexutil.Uint64(ancestor.NumberU64()),
+		Ancestor:       ancestor.Hash(),
+		AncestorNumber: hexutil.Uint64(ancestor.NumberU64()),
+		Depth:          hexutil.Uint64(len(oldChain)),
+		DroppedTxs:     []common.Hash{},
+		Coinbases:      []common.Address{},
+	}
+	if len(oldChain) > 0 {
+		entry.OldHead, entry.OldNumber = oldChain[0].Hash(), hexutil.Uint64(oldChain[0].NumberU64())
+	}
+	if len(newChain) > 0 {
+		entry.NewHead, entry.NewNumber = newChain[0].Hash(), hexutil.Uint64(newChain[0].NumberU64())
+	}
+	var deleted, added types.Transactions
+	seen := make(map[common.Address]bool)
+	for _, block := range oldChain {
+		deleted = append(deleted, block.Transactions()...)
+		if coinbase := block.Coinbase(); !seen[coinbase] {
+			seen[coinbase] = true
+			entry.Coinbases = append(entry.Coinbases, coinbase)
+		}
+	}
+	for _, block := range newChain {
+		added = append(added, block.Transactions()...)
+	}
+	for _, tx := range types.TxDifference(deleted, added) {
+		entry.DroppedTxs = append(entry.DroppedTxs, tx.Hash())
+	}
+	return entry
+}
+
+// checkFinality refuses the reorg if it drops more canonical blocks than the
+// finality depth, unless the operator approved the new chain or a checkpoint
+// overrides the local finality. Reorgs below the latest checkpoint never get
+// this far, so the finality depth only applies between the checkpoints.
+func (bc *BlockChain) checkFinality(ancestor *types.Block, oldChain, newChain types.Blocks, override bool) error {
+	if len(oldChain) == 0 || len(newChain) == 0 {
+		return nil
+	}
+	entry := newReorgEntry(ancestor, oldChain, newChain)
+
+	if depth := bc.cacheConfig.FinalityDepth; !override && depth > 0 && uint64(len(oldChain)) > depth && !bc.reorgs.consume(newChain) {
+		log.Error("Reorg beyond finality depth refused", "number", ancestor.Number(), "hash", ancestor.Hash(),
+			"depth", len(oldChain), "finality", depth, "newhead", entry.NewHead, "approve", "admin.approveReorg")
+		reorgRefusedMeter.Mark(1)
+
+		entry.Refused = true
+		bc.recordReorg(entry)
+		return errReorgRefused
+	}
+	reorgMeter.Mark(1)
+	reorgDepthGauge.Update(int64(len(oldChain)))
+
+	bc.recordReorg(entry)
+	return nil
+}
+
+// recordReorg journals the reorg and notifies the subscribers, once per
+// refused fork.
+func (bc *BlockChain) recordReorg(entry ReorgEntry) {
+	if bc.reorgs.add(entry) {
+		go bc.reorgFeed.Send(ChainReorgEvent{entry})
+	}
+}
+
+// Reorgs returns the journal of the recent reorgs, oldest first.
+func (bc *BlockChain) Reorgs() []ReorgEntry {
+	return bc.reorgs.list()
+}
+
+// ApproveReorg lets the chain of the given block replace the canonical one,
+// regardless of the finality depth. The reorg happens right away if it is
+// the heavier chain already, otherwise once it becomes the heavier one.
+func (bc *BlockChain) ApproveReorg(hash common.Hash) error {
+	block := bc.GetBlockByHash(hash)
+	if block == nil {
+		return fmt.Errorf("unknown block [%x…]", hash[:4])
+	}
+	bc.reorgs.approve(hash)
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if rawdb.ReadCanonicalHash(bc.db, block.NumberU64()) == hash {
+		return nil
+	}
+	current := bc.CurrentBlock()
+	externTd := bc.GetTd(hash, block.NumberU64())
+	localTd := bc.GetTd(current.Hash(), current.NumberU64())
+	if externTd == nil || externTd.Cmp(localTd) <= 0 {
+		log.Info("Approved reorg pending", "number", block.Number(), "hash", hash)
+		return nil
+	}
+	if !bc.HasState(block.Root()) {
+		return fmt.Errorf("missing state of block [%x…]", hash[:4])
+	}
+	if err := bc.reorg(current, block, false); err != nil {
+		return err
+	}
+	bc.capSnapshots(block.Root())
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+
+	log.Warn("Approved reorg done", "number", block.Number(), "hash", hash)
+	return nil
+}
+
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
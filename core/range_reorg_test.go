@@ -0,0 +1,141 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorgFinalityDepth(t *testing.T) {
+	t.Parallel()
+
+	var (
+		engine  = ethash.NewFaker()
+		db      = ethdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(db)
+	)
+	chain, err := NewBlockChain(db, &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		TrieRapidLimit: 10 * time.Second,
+		FinalityDepth:  2,
+	}, params.AllEthashProtocolChanges, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+
+	reorgs := make(chan ChainReorgEvent, 4)
+	sub := chain.SubscribeChainReorgEvent(reorgs)
+	defer sub.Unsubscribe()
+
+	canonical := makeBlockChain(genesis, 6, engine, db, canonicalSeed)
+	_, err = chain.InsertChain(canonical)
+	assert.Empty(t, err)
+
+	// Shallow reorgs go through
+	shallow := makeBlockChain(canonical[3], 3, engine, db, canonicalSeed+1)
+	_, err = chain.InsertChain(shallow)
+	assert.Empty(t, err)
+	assert.Equal(t, shallow[2].Hash(), chain.CurrentBlock().Hash())
+
+	ev := <-reorgs
+	assert.False(t, ev.Refused)
+	assert.Equal(t, uint64(2), uint64(ev.Depth))
+	assert.Equal(t, canonical[3].Hash(), ev.Ancestor)
+
+	// Deeper reorgs are refused, the fork is kept as a side chain
+	deep := makeBlockChain(canonical[1], 8, engine, db, canonicalSeed+2)
+	_, err = chain.InsertChain(deep)
+	assert.Empty(t, err)
+	assert.Equal(t, shallow[2].Hash(), chain.CurrentBlock().Hash())
+
+	ev = <-reorgs
+	assert.True(t, ev.Refused)
+	assert.Equal(t, canonical[1].Hash(), ev.Ancestor)
+
+	journal := chain.Reorgs()
+	assert.Len(t, journal, 2)
+	assert.True(t, journal[1].Refused)
+	assert.Equal(t, deep[7].Hash(), journal[1].NewHead)
+
+	// Until the operator approves them
+	assert.Empty(t, chain.ApproveReorg(deep[7].Hash()))
+	assert.Equal(t, deep[7].Hash(), chain.CurrentBlock().Hash())
+	assert.Equal(t, deep[0].Hash(), chain.GetHeaderByNumber(3).Hash())
+
+	ev = <-reorgs
+	assert.False(t, ev.Refused)
+	assert.Equal(t, uint64(5), uint64(ev.Depth))
+
+	// The journal is persisted
+	assert.Equal(t, chain.Reorgs(), newReorgJournal(db).list())
+	assert.Len(t, chain.Reorgs(), 3)
+}
+
+func TestCheckpointOverridesFinality(t *testing.T) {
+	t.Parallel()
+
+	var (
+		engine  = ethash.NewFaker()
+		db      = ethdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(db)
+	)
+	chain, err := NewBlockChain(db, &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		TrieRapidLimit: 10 * time.Second,
+		FinalityDepth:  2,
+	}, params.AllEthashProtocolChanges, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+
+	canonical := makeBlockChain(genesis, 6, engine, db, canonicalSeed)
+	_, err = chain.InsertChain(canonical)
+	assert.Empty(t, err)
+
+	// The lighter fork stays a side chain
+	fork := makeBlockChain(genesis, 3, engine, db, canonicalSeed+1)
+	_, err = chain.InsertChain(fork)
+	assert.Empty(t, err)
+	assert.Equal(t, canonical[2].Hash(), chain.GetHeaderByNumber(3).Hash())
+
+	// The checkpoint deeper than the finality depth is still enforced
+	err = chain.AddCheckpoint(
+		Checkpoint{
+			Number: 3,
+			Hash:   fork[2].Hash(),
+		},
+		[]CheckpointSignature{},
+		true,
+	)
+	assert.Empty(t, err)
+	assert.Equal(t, fork[2].Hash(), chain.GetHeaderByNumber(3).Hash())
+
+	journal := chain.Reorgs()
+	assert.Len(t, journal, 1)
+	assert.False(t, journal[0].Refused)
+	assert.Equal(t, uint64(3), uint64(journal[0].Depth))
+}
@@ -0,0 +1,276 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"range/core/gen3/rlp"
+	"range/core/gen3/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// MissingStateEntry is a trie node or a contract code missing from a state.
+type MissingStateEntry struct {
+	Account common.Hash // Hashed address of the account, zero for the account trie
+	Path    []byte      // Hex-encoded path of the missing node within its trie
+	Hash    common.Hash // Hash of the missing node or code
+	Code    bool        // Whether the contract code is missing
+}
+
+func (e *MissingStateEntry) String() string {
+	switch {
+	case e.Code:
+		return fmt.Sprintf("code %x of account %x", e.Hash, e.Account)
+	case e.Account == (common.Hash{}):
+		return fmt.Sprintf("account trie node %x at path %x", e.Hash, e.Path)
+	default:
+		return fmt.Sprintf("storage trie node %x of account %x at path %x", e.Hash, e.Account, e.Path)
+	}
+}
+
+// CheckState walks the whole state of the given root: the account trie, the
+// storage tries and the contract codes. Unlike the iterators it doesn't stop
+// at the first missing entry, every one of them is passed to onMissing. It
+// returns the number of the visited trie nodes and of the missing entries.
+func CheckState(db ethdb.Database, root common.Hash, onMissing func(*MissingStateEntry)) (uint64, uint64, error) {
+	var (
+		triedb   = trie.NewDatabase(db)
+		storages = make(map[common.Hash]bool)
+		codes    = make(map[common.Hash]bool)
+		missing  uint64
+		nodes    uint64
+	)
+	report := func(entry *MissingStateEntry) {
+		missing++
+		if onMissing != nil {
+			onMissing(entry)
+		}
+	}
+	onAccount := func(key, value []byte) error {
+		var acc state.Account
+		if err := rlp.DecodeBytes(value, &acc); err != nil {
+			return fmt.Errorf("invalid account %x: %v", key, err)
+		}
+		account := common.BytesToHash(key)
+
+		if codeHash := common.BytesToHash(acc.CodeHash); codeHash != emptyCode && !codes[codeHash] {
+			codes[codeHash] = true
+			if has, _ := db.Has(codeHash[:]); !has {
+				report(&MissingStateEntry{Account: account, Hash: codeHash, Code: true})
+			}
+		}
+		if acc.Root == emptyRoot || storages[acc.Root] {
+			return nil
+		}
+		storages[acc.Root] = true
+
+		count, err := trie.CheckNodes(acc.Root, triedb, nil, func(err *trie.MissingNodeError) {
+			report(&MissingStateEntry{Account: account, Path: err.Path, Hash: err.NodeHash})
+		})
+		nodes += count
+		return err
+	}
+	count, err := trie.CheckNodes(root, triedb, onAccount, func(err *trie.MissingNodeError) {
+		report(&MissingStateEntry{Path: err.Path, Hash: err.NodeHash})
+	})
+	return nodes + count, missing, err
+}
+
+// hasCompleteState iterates over the state of the given root and returns the
+// first missing or invalid entry, if any.
+func hasCompleteState(db ethdb.Database, root common.Hash) error {
+	if has, _ := db.Has(root.Bytes()); !has {
+		return &trie.MissingNodeError{NodeHash: root}
+	}
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error
+}
+
+// RepairReport summarizes the changes of RepairDatabase.
+type RepairReport struct {
+	Head            uint64 // Number of the head header
+	StateHead       uint64 // Number of the new head block, with a complete state
+	CanonicalFixed  uint64 // Number of the rewritten canonical hash mappings
+	CanonicalPruned uint64 // Number of the deleted canonical hash mappings above the head
+	LookupsFixed    uint64 // Number of the rewritten transaction lookup entries
+	LookupsPruned   uint64 // Number of the deleted lookup entries of the rolled back blocks
+	Elapsed         time.Duration
+}
+
+// RepairDatabase restores the chain indexes of a stopped node from the stored
+// headers and bodies:
+//
+//   - the canonical hash mappings are rebuilt along the parent hashes of the
+//     head header, down to the ancient store;
+//   - the heads are rolled back to the highest canonical block whose state is
+//     complete, the canonical mappings above it are dropped;
+//   - the transaction lookup entries of the canonical blocks are rewritten if
+//     missing or pointing elsewhere.
+func RepairDatabase(db ethdb.Database) (*RepairReport, error) {
+	var (
+		report = new(RepairReport)
+		start  = time.Now()
+		frozen uint64
+	)
+	if ancients, ok := db.(rawdb.AncientReader); ok {
+		frozen, _ = ancients.Ancients()
+	}
+	headHash := rawdb.ReadHeadHeaderHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, errors.New("head header is not found")
+	}
+	report.Head = *headNumber
+
+	// Rebuild the canonical mappings from the head header down, the frozen
+	// blocks are canonical by construction.
+	batch := db.NewBatch()
+	for number := *headNumber + 1; rawdb.ReadCanonicalHash(db, number) != (common.Hash{}); number++ {
+		rawdb.DeleteCanonicalHash(batch, number)
+		report.CanonicalPruned++
+	}
+	hash, number := headHash, *headNumber
+	for {
+		if frozen > 0 && number < frozen {
+			break
+		}
+		header := rawdb.ReadHeader(db, hash, number)
+		if header == nil {
+			return nil, fmt.Errorf("missing header #%d [%x…] of the canonical chain", number, hash[:4])
+		}
+		if rawdb.ReadCanonicalHash(db, number) != hash {
+			rawdb.WriteCanonicalHash(batch, hash, number)
+			report.CanonicalFixed++
+		}
+		if number == 0 {
+			break
+		}
+		hash, number = header.ParentHash, number-1
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	batch.Reset()
+
+	// Find the highest block with a complete state. The walk of a candidate
+	// stops at its first missing entry, only the new head is walked in full.
+	var head *types.Block
+	for number := *headNumber; ; number-- {
+		block := rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, number), number)
+		if block != nil {
+			err := hasCompleteState(db, block.Root())
+			if err == nil {
+				head = block
+				break
+			}
+			log.Warn("Incomplete state", "number", number, "root", block.Root(), "err", err)
+		}
+		if number == 0 {
+			return nil, errors.New("no block with a complete state")
+		}
+	}
+	report.StateHead = head.NumberU64()
+
+	// Roll the heads back and drop the chain above
+	for number := head.NumberU64() + 1; number <= *headNumber; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if block := rawdb.ReadBlock(db, hash, number); block != nil {
+			for _, tx := range block.Transactions() {
+				if blockHash, _, _ := rawdb.ReadTxLookupEntry(db, tx.Hash()); blockHash == hash {
+					rawdb.DeleteTxLookupEntry(batch, tx.Hash())
+					report.LookupsPruned++
+				}
+			}
+		}
+		rawdb.DeleteCanonicalHash(batch, number)
+		report.CanonicalPruned++
+	}
+	rawdb.WriteHeadHeaderHash(batch, head.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, head.Hash())
+	rawdb.WriteHeadBlockHash(batch, head.Hash())
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	batch.Reset()
+
+	// Rewrite the transaction lookups of the canonical blocks with a body
+	logged := time.Now()
+	for number := rawdb.ReadHistoryTail(db); number <= head.NumberU64(); number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		body := rawdb.ReadBody(db, hash, number)
+		if body == nil {
+			continue
+		}
+		var stale uint64
+		for i, tx := range body.Transactions {
+			blockHash, blockNumber, index := rawdb.ReadTxLookupEntry(db, tx.Hash())
+			if blockHash != hash || blockNumber != number || index != uint64(i) {
+				stale++
+			}
+		}
+		if stale > 0 {
+			header := rawdb.ReadHeader(db, hash, number)
+			rawdb.WriteTxLookupEntries(batch, types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles))
+			report.LookupsFixed += stale
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Repairing transaction lookups", "number", number, "head", head.NumberU64())
+			logged = time.Now()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
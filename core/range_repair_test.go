@@ -0,0 +1,145 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"range/core/gen3/trie"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairDatabase(t *testing.T) {
+	t.Parallel()
+
+	var (
+		engine  = ethash.NewFaker()
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 10, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, &CacheConfig{Disabled: true}, gspec.Config, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	_, err = chain.InsertChain(blocks)
+	assert.Empty(t, err)
+	chain.Stop()
+
+	// A complete state
+	nodes, missing, err := CheckState(db, blocks[9].Root(), nil)
+	assert.Empty(t, err)
+	assert.Equal(t, uint64(0), missing)
+	assert.NotEqual(t, uint64(0), nodes)
+
+	// Corrupt the indexes and the states of the two last blocks
+	rawdb.DeleteCanonicalHash(db, 3)
+	rawdb.WriteCanonicalHash(db, common.Hash{0x01}, 5)
+	rawdb.WriteCanonicalHash(db, common.Hash{0x02}, 11)
+	rawdb.DeleteTxLookupEntry(db, blocks[3].Transactions()[0].Hash())
+	assert.Empty(t, db.Delete(blocks[8].Root().Bytes()))
+	assert.Empty(t, db.Delete(blocks[9].Root().Bytes()))
+
+	var entries []*MissingStateEntry
+	_, missing, err = CheckState(db, blocks[9].Root(), func(entry *MissingStateEntry) {
+		entries = append(entries, entry)
+	})
+	assert.Empty(t, err)
+	assert.Equal(t, uint64(1), missing)
+	assert.Equal(t, blocks[9].Root(), entries[0].Hash)
+
+	report, err := RepairDatabase(db)
+	assert.Empty(t, err)
+	assert.Equal(t, uint64(10), report.Head)
+	assert.Equal(t, uint64(8), report.StateHead)
+	assert.Equal(t, uint64(2), report.CanonicalFixed)
+	assert.Equal(t, uint64(3), report.CanonicalPruned)
+	assert.Equal(t, uint64(1), report.LookupsFixed)
+	assert.Equal(t, uint64(2), report.LookupsPruned)
+
+	assert.Equal(t, blocks[7].Hash(), rawdb.ReadHeadBlockHash(db))
+	assert.Equal(t, blocks[7].Hash(), rawdb.ReadHeadHeaderHash(db))
+	for i, block := range blocks[:8] {
+		assert.Equal(t, block.Hash(), rawdb.ReadCanonicalHash(db, uint64(i+1)))
+	}
+	assert.Equal(t, common.Hash{}, rawdb.ReadCanonicalHash(db, 9))
+	assert.Equal(t, common.Hash{}, rawdb.ReadCanonicalHash(db, 11))
+
+	blockHash, number, _ := rawdb.ReadTxLookupEntry(db, blocks[3].Transactions()[0].Hash())
+	assert.Equal(t, blocks[3].Hash(), blockHash)
+	assert.Equal(t, uint64(4), number)
+
+	// The repaired chain loads on the complete state
+	chain, err = NewBlockChain(db, &CacheConfig{Disabled: true}, gspec.Config, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+	assert.Equal(t, blocks[7].Hash(), chain.CurrentBlock().Hash())
+}
+
+func TestHasCompleteState(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db    = ethdb.NewMemDatabase()
+		alloc = make(GenesisAlloc)
+	)
+	for i := 0; i < 32; i++ {
+		alloc[common.BigToAddress(big.NewInt(int64(i+1)))] = GenesisAccount{Balance: big.NewInt(1)}
+	}
+	root := (&Genesis{Config: params.TestChainConfig, Alloc: alloc}).MustCommit(db).Root()
+	assert.Empty(t, hasCompleteState(db, root))
+
+	// A missing node below the root is found by the walk
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	assert.Empty(t, err)
+	var child common.Hash
+	for nodes := tr.NodeIterator(nil); nodes.Next(true); {
+		if hash := nodes.Hash(); hash != (common.Hash{}) && hash != root {
+			child = hash
+			break
+		}
+	}
+	assert.NotEqual(t, common.Hash{}, child)
+	assert.Empty(t, db.Delete(child.Bytes()))
+
+	err = hasCompleteState(db, root)
+	assert.IsType(t, &trie.MissingNodeError{}, err)
+	_, missing, _ := CheckState(db, root, nil)
+	assert.Equal(t, uint64(1), missing)
+
+	// As well as a missing root
+	assert.Empty(t, db.Delete(root.Bytes()))
+	assert.IsType(t, &trie.MissingNodeError{}, hasCompleteState(db, root))
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+	"range/core/gen3/rlp"
+)
+
+// txImportBatch is the number of transactions added to the pool at once while
+// loading a journal or an import.
+const txImportBatch = 1024
+
+// remoteJournalEntry is a journaled remote transaction along with the time its
+// sender was last active, to expire it.
+type remoteJournalEntry struct {
+	Time uint64 // Unix time
+	Tx   *types.Transaction
+}
+
+// remoteJournal is a snapshot of the remote transactions of the pool, so that
+// the pool is not empty after a restart until the peers rebroadcast. Unlike the
+// local journal, it's not appended to on every transaction: it's regenerated
+// periodically and on shutdown, up to a number of transactions.
+type remoteJournal struct {
+	path     string        // Filesystem path to store the transactions at
+	limit    int           // Maximum number of transactions to store
+	lifetime time.Duration // Maximum age of the stored transactions on load
+}
+
+func newRemoteJournal(path string, limit uint64, lifetime time.Duration) *remoteJournal {
+	return &remoteJournal{
+		path:     path,
+		limit:    int(limit),
+		lifetime: lifetime,
+	}
+}
+
+// load parses the remote journal, passing the unexpired transactions to the
+// pool for revalidation against the current state.
+func (journal *remoteJournal) load(add func([]*types.Transaction) []error) error {
+	input, err := os.Open(journal.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var (
+		stream  = rlp.NewStream(bufio.NewReader(input), 0)
+		expiry  = time.Now().Add(-journal.lifetime)
+		batch   types.Transactions
+		total   int
+		expired int
+		dropped int
+	)
+	for {
+		entry := new(remoteJournalEntry)
+		if err = stream.Decode(entry); err != nil {
+			break
+		}
+		total++
+		if time.Unix(int64(entry.Time), 0).Before(expiry) {
+			expired++
+			continue
+		}
+		batch = append(batch, entry.Tx)
+		if len(batch) >= txImportBatch {
+			dropped += countErrors(add(batch))
+			batch = batch[:0]
+		}
+	}
+	dropped += countErrors(add(batch))
+
+	log.Info("Loaded remote transaction journal", "transactions", total, "expired", expired, "dropped", dropped)
+
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// rotate regenerates the remote journal out of the remote transactions of the
+// pool. Senders are taken in the order of their last activity, most recent
+// first, and their transactions by nonce, so that the bound never leaves a
+// nonce gap behind.
+func (journal *remoteJournal) rotate(all map[common.Address]types.Transactions, beats map[common.Address]time.Time) error {
+	addrs := make([]common.Address, 0, len(all))
+	for addr := range all {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return beats[addrs[i]].After(beats[addrs[j]])
+	})
+
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		output    = bufio.NewWriter(replacement)
+		now       = time.Now()
+		journaled int
+	)
+	for _, addr := range addrs {
+		txs := all[addr]
+		if journaled+len(txs) > journal.limit {
+			txs = txs[:journal.limit-journaled]
+		}
+		active, ok := beats[addr]
+		if !ok {
+			active = now
+		}
+		for _, tx := range txs {
+			if err = rlp.Encode(output, &remoteJournalEntry{Time: uint64(active.Unix()), Tx: tx}); err != nil {
+				replacement.Close()
+				return err
+			}
+		}
+		if journaled += len(txs); journaled >= journal.limit {
+			break
+		}
+	}
+	if err = output.Flush(); err != nil {
+		replacement.Close()
+		return err
+	}
+	replacement.Close()
+
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated remote transaction journal", "transactions", journaled, "accounts", len(all))
+	return nil
+}
+
+// remote retrieves all currently known remote transactions, sorted by nonce per
+// account. The calling method must hold the pool lock.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			if !pool.locals.contains(addr) {
+				txs[addr] = append(txs[addr], list.Flatten()...)
+			}
+		}
+	}
+	return txs
+}
+
+// rotateRemotes regenerates the remote journal, if enabled.
+func (pool *TxPool) rotateRemotes() {
+	if pool.remoteJournal == nil {
+		return
+	}
+	pool.mu.RLock()
+	all, beats := pool.remote(), make(map[common.Address]time.Time, len(pool.beats))
+	for addr, beat := range pool.beats {
+		beats[addr] = beat
+	}
+	pool.mu.RUnlock()
+
+	if err := pool.remoteJournal.rotate(all, beats); err != nil {
+		log.Warn("Failed to rotate remote tx journal", "err", err)
+	}
+}
+
+// Export writes all the transactions of the pool into the writer as an RLP
+// stream, sorted by nonce per account, and returns their number.
+func (pool *TxPool) Export(w io.Writer) (int, error) {
+	pending, queued := pool.Content()
+
+	output := bufio.NewWriter(w)
+	exported := 0
+	for _, content := range []map[common.Address]types.Transactions{pending, queued} {
+		for _, txs := range content {
+			for _, tx := range txs {
+				if err := rlp.Encode(output, tx); err != nil {
+					return exported, err
+				}
+				exported++
+			}
+		}
+	}
+	return exported, output.Flush()
+}
+
+// Import adds the transactions of an RLP stream, as produced by Export, to the
+// pool as remote ones. They are validated as any other remote transaction. The
+// number of the added and the dropped transactions is returned.
+func (pool *TxPool) Import(r io.Reader) (int, int, error) {
+	var (
+		stream = rlp.NewStream(bufio.NewReader(r), 0)
+		batch  types.Transactions
+		total  int
+		failed int
+		err    error
+	)
+	for {
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			break
+		}
+		total++
+		if batch = append(batch, tx); len(batch) >= txImportBatch {
+			failed += countErrors(pool.AddRemotes(batch))
+			batch = batch[:0]
+		}
+	}
+	failed += countErrors(pool.AddRemotes(batch))
+
+	log.Info("Imported pool transactions", "transactions", total, "dropped", failed)
+
+	if err != io.EOF {
+		return total - failed, failed, err
+	}
+	return total - failed, failed, nil
+}
+
+func countErrors(errs []error) (failed int) {
+	for _, err := range errs {
+		if err != nil {
+			log.Debug("Failed to add transaction", "err", err)
+			failed++
+		}
+	}
+	return
+}
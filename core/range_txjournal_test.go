@@ -0,0 +1,95 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/state"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/event"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteJournal(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "remotejournal")
+	assert.Empty(t, err)
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = filepath.Join(dir, "remotes.rlp")
+	config.RemoteJournalSize = 5
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	first, _ := crypto.GenerateKey()
+	second, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(first.PublicKey), big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(second.PublicKey), big.NewInt(1000000))
+
+	for i := uint64(0); i < 3; i++ {
+		assert.Empty(t, pool.AddRemote(transaction(i, 100000, first)))
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := uint64(0); i < 3; i++ {
+		assert.Empty(t, pool.AddRemote(transaction(i, 100000, second)))
+	}
+	pool.Stop()
+
+	// The most recently active sender goes first, the bound leaves no nonce gap
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	pending, queued := pool.Stats()
+	assert.Equal(t, 5, pending)
+	assert.Equal(t, 0, queued)
+	assert.Equal(t, 3, pool.pending[crypto.PubkeyToAddress(second.PublicKey)].Len())
+	assert.Equal(t, 2, pool.pending[crypto.PubkeyToAddress(first.PublicKey)].Len())
+
+	// Exported transactions are revalidated on import
+	var export bytes.Buffer
+	exported, err := pool.Export(&export)
+	assert.Empty(t, err)
+	assert.Equal(t, 5, exported)
+	pool.Stop()
+
+	// Expired transactions are not loaded
+	config.RemoteJournalLifetime = time.Nanosecond
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	pending, queued = pool.Stats()
+	assert.Equal(t, 0, pending+queued)
+
+	statedb.SetNonce(crypto.PubkeyToAddress(second.PublicKey), 1)
+	imported, dropped, err := pool.Import(&export)
+	assert.Empty(t, err)
+	assert.Equal(t, 4, imported)
+	assert.Equal(t, 1, dropped)
+	pool.Stop()
+}
@@ -0,0 +1,291 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+	"range/core/gen3/metrics"
+)
+
+// TxLane is a partition of the transaction pool with its own capacity and
+// eviction policy.
+type TxLane uint8
+
+const (
+	// TxLaneNormal holds the fee paying transactions. Its capacity is given by
+	// the global slots and queue, it's evicted by price after the senders
+	// holding more than their fair share.
+	TxLaneNormal TxLane = iota
+
+	// TxLaneConsensus holds the zero-fee consensus calls: masternode heartbeats
+	// and invalidations, checkpoint signatures and Gen2 claims. Its capacity is
+	// reserved, so that they never compete with the normal traffic, and it's
+	// evicted oldest first.
+	TxLaneConsensus
+)
+
+func (l TxLane) String() string {
+	switch l {
+	case TxLaneNormal:
+		return "normal"
+	case TxLaneConsensus:
+		return "consensus"
+	default:
+		return "unknown"
+	}
+}
+
+// TxLaneOf returns the pool lane of the transaction.
+func TxLaneOf(tx *types.Transaction) TxLane {
+	if IsValidZeroFee(tx) {
+		return TxLaneConsensus
+	}
+	return TxLaneNormal
+}
+
+// Reasons of the transaction evictions.
+const (
+	EvictUnderpriced  = "underpriced"        // Cheapest of a full normal lane
+	EvictSenderShare  = "sender-share"       // Sender over its fair share of a full normal lane
+	EvictLaneFull     = "lane-full"          // Oldest of a full consensus lane
+	EvictReplaced     = "replaced"           // Replaced by a transaction with the same nonce
+	EvictAccountQueue = "account-queue"      // Over the queue limit of the sender
+	EvictFairness     = "pending-fairness"   // Over the pending allowance while equalizing senders
+	EvictQueueFull    = "queue-full"         // Over the global queue limit
+	EvictNoFunds      = "insufficient-funds" // Not payable anymore, or over the block gas limit
+	EvictStale        = "stale"              // Not included in time
+)
+
+// txEvictionLimit is the number of the most recent evictions remembered.
+const txEvictionLimit = 1024
+
+var (
+	// ErrTxLaneFull is returned if a transaction is refused as its lane is full
+	// and it's not preferred over any of the transactions in it.
+	ErrTxLaneFull = errors.New("transaction pool lane full")
+
+	consensusLaneGauge    = metrics.NewRegisteredGauge("txpool/lane/consensus", nil)
+	normalLaneGauge       = metrics.NewRegisteredGauge("txpool/lane/normal", nil)
+	consensusEvictCounter = metrics.NewRegisteredCounter("txpool/lane/consensus/evict", nil)
+	senderShareCounter    = metrics.NewRegisteredCounter("txpool/lane/normal/sendershare", nil)
+)
+
+// TxEviction describes a transaction dropped by the pool before its inclusion.
+type TxEviction struct {
+	Hash   common.Hash
+	From   common.Address
+	Nonce  uint64
+	Lane   TxLane
+	Reason string
+	Time   time.Time
+}
+
+// txEvictions is a bounded log of the most recent evictions.
+type txEvictions struct {
+	items []TxEviction
+	next  int
+}
+
+func (e *txEvictions) add(ev TxEviction) {
+	if len(e.items) < txEvictionLimit {
+		e.items = append(e.items, ev)
+		return
+	}
+	e.items[e.next] = ev
+	e.next = (e.next + 1) % txEvictionLimit
+}
+
+// list returns the evictions, oldest first.
+func (e *txEvictions) list() []TxEviction {
+	res := make([]TxEviction, 0, len(e.items))
+	res = append(res, e.items[e.next:]...)
+	return append(res, e.items[:e.next]...)
+}
+
+// evicted records the eviction of the transaction.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evicted(tx *types.Transaction, reason string) {
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	pool.evictions.add(TxEviction{
+		Hash:   tx.Hash(),
+		From:   from,
+		Nonce:  tx.Nonce(),
+		Lane:   TxLaneOf(tx),
+		Reason: reason,
+		Time:   time.Now(),
+	})
+	log.Trace("Evicted transaction", "hash", tx.Hash(), "from", from, "reason", reason)
+}
+
+// Evictions returns the most recent evictions, oldest first.
+func (pool *TxPool) Evictions() []TxEviction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.evictions.list()
+}
+
+// evictSender records the eviction of all the transactions of the sender, and
+// removes them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) evictSender(addr common.Address) {
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		if list := lists[addr]; list != nil {
+			for _, tx := range list.Flatten() {
+				pool.evicted(tx, EvictStale)
+			}
+		}
+	}
+	pool.removeBySenderLocked(addr)
+}
+
+// validateLane checks the transaction against the admission rules specific to
+// its lane.
+func (pool *TxPool) validateLane(tx *types.Transaction, lane TxLane) error {
+	// Range: protect against zero-fee DoS
+	if lane == TxLaneConsensus {
+		if err := pool.zfProtector.checkDoS(pool, tx); err != nil {
+			return err
+		}
+	}
+	// Range: preliminary blacklist handling
+	return pool.preBlacklist.processTx(pool, tx)
+}
+
+// makeRoom evicts transactions of a full lane in favour of the new one, or
+// returns an error if the new one is not preferred over any of them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) makeRoom(tx *types.Transaction, from common.Address, lane TxLane, local bool) error {
+	if lane == TxLaneConsensus {
+		return pool.makeConsensusRoom()
+	}
+	slots := int(pool.config.GlobalSlots + pool.config.GlobalQueue)
+
+	// Fairness goes first: the sender holding most transactions over its share
+	// loses its highest nonce ones, unless the new transaction is its own.
+	for !local && pool.all.LaneCount(TxLaneNormal) >= slots {
+		heavy, count := pool.heaviestSender()
+		if count <= int(pool.config.AccountSlots) {
+			break
+		}
+		senderShareCounter.Inc(1)
+		if heavy == from {
+			return ErrTxLaneFull
+		}
+		drop := pool.lastTx(heavy)
+		if drop == nil {
+			break
+		}
+		pool.evicted(drop, EvictSenderShare)
+		pool.removeTx(drop.Hash(), true)
+	}
+	if pool.all.LaneCount(TxLaneNormal) < slots {
+		return nil
+	}
+	// Otherwise the cheapest transactions are evicted
+	if !local && pool.priced.Underpriced(tx, pool.locals) {
+		log.Trace("Discarding underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+		underpricedTxCounter.Inc(1)
+		return ErrUnderpriced
+	}
+	drop := pool.priced.Discard(pool.all.LaneCount(TxLaneNormal)-slots+1, pool.locals)
+	for _, tx := range drop {
+		log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+		underpricedTxCounter.Inc(1)
+		pool.evicted(tx, EvictUnderpriced)
+		pool.removeTx(tx.Hash(), false)
+	}
+	return nil
+}
+
+// makeConsensusRoom evicts the oldest transaction of a full consensus lane.
+// Consensus calls are periodic, so the most recent ones supersede it.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) makeConsensusRoom() error {
+	if uint64(pool.all.LaneCount(TxLaneConsensus)) < pool.config.ConsensusSlots {
+		return nil
+	}
+	oldest := pool.all.OldestInLane(TxLaneConsensus)
+	if oldest == nil {
+		return ErrTxLaneFull
+	}
+	pool.evicted(oldest, EvictLaneFull)
+	consensusEvictCounter.Inc(1)
+	pool.removeTx(oldest.Hash(), true)
+	return nil
+}
+
+// heaviestSender returns the non-local sender holding the most transactions in
+// the normal lane, if any holds more than its share.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) heaviestSender() (common.Address, int) {
+	return pool.all.Heaviest(pool.locals.contains)
+}
+
+// pooledTx returns the pending or queued transaction of the sender with the
+// given nonce, if any.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) pooledTx(addr common.Address, nonce uint64) *types.Transaction {
+	if list := pool.pending[addr]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
+// lastTx returns the highest nonce normal lane transaction of the sender, so
+// that evicting it lowers the lane count and leaves the fewest nonce gaps. The
+// consensus lane transactions of the sender are left alone.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) lastTx(addr common.Address) *types.Transaction {
+	for _, list := range []*txList{pool.queue[addr], pool.pending[addr]} {
+		if list == nil {
+			continue
+		}
+		txs := list.Flatten()
+		for i := len(txs) - 1; i >= 0; i-- {
+			if TxLaneOf(txs[i]) == TxLaneNormal {
+				return txs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// updateLaneMetrics reports the lane occupancy.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) updateLaneMetrics() {
+	consensusLaneGauge.Update(int64(pool.all.LaneCount(TxLaneConsensus)))
+	normalLaneGauge.Update(int64(pool.all.LaneCount(TxLaneNormal)))
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"range/core/gen3/accounts/abi"
+	"range/core/gen3/common"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/event"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi_params "range/core/gen3/energi/params"
+)
+
+func heartbeatTransaction(t *testing.T, nonce uint64, key *ecdsa.PrivateKey) *types.Transaction {
+	mnreg_abi, err := abi.JSON(strings.NewReader(energi_abi.IMasternodeRegistryV2ABI))
+	assert.Empty(t, err)
+	call, err := mnreg_abi.Pack("heartbeat", common.Big1, common.Hash{}, common.Big0)
+	assert.Empty(t, err)
+
+	tx, err := types.SignTx(types.NewTransaction(
+		nonce, energi_params.Range_MasternodeRegistry, common.Big0,
+		ZeroFeeGasLimit, common.Big0, call), types.HomesteadSigner{}, key)
+	assert.Empty(t, err)
+	return tx
+}
+
+func TestTxLaneSenderShare(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountSlots = 2
+	config.GlobalSlots = 4
+	config.AccountQueue = 4
+	config.GlobalQueue = 4
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	heavy, _ := crypto.GenerateKey()
+	light, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(heavy.PublicKey), big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(light.PublicKey), big.NewInt(1000000))
+
+	// A single sender fills up the normal lane, both pending and queued
+	for _, nonce := range []uint64{0, 1, 2, 3, 5, 6, 7, 8} {
+		assert.Empty(t, pool.AddRemote(transaction(nonce, 100000, heavy)))
+	}
+	assert.Equal(t, 8, pool.all.LaneCount(TxLaneNormal))
+
+	// Another sender is admitted at its expense, regardless of the price
+	assert.Empty(t, pool.AddRemote(transaction(1, 100000, light)))
+	assert.Equal(t, 8, pool.all.LaneCount(TxLaneNormal))
+	assert.Equal(t, 3, pool.queue[crypto.PubkeyToAddress(heavy.PublicKey)].Len())
+
+	evictions := pool.Evictions()
+	if assert.Len(t, evictions, 1) {
+		assert.Equal(t, EvictSenderShare, evictions[0].Reason)
+		assert.Equal(t, crypto.PubkeyToAddress(heavy.PublicKey), evictions[0].From)
+		assert.Equal(t, uint64(8), evictions[0].Nonce)
+		assert.Equal(t, TxLaneNormal, evictions[0].Lane)
+	}
+
+	// The heaviest sender can't push out anybody
+	assert.Equal(t, ErrTxLaneFull, pool.AddRemote(transaction(8, 100000, heavy)))
+
+	// But it can replace its own transactions, pending or queued
+	assert.Empty(t, pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(2), heavy)))
+	assert.Empty(t, pool.AddRemote(pricedTransaction(6, 100000, big.NewInt(2), heavy)))
+	assert.Equal(t, 8, pool.all.LaneCount(TxLaneNormal))
+	assert.Len(t, pool.Evictions(), 3)
+
+	// Its consensus transactions don't count against its share
+	beat := heartbeatTransaction(t, 9, heavy)
+	pool.mu.Lock()
+	assert.Empty(t, pool.makeRoom(beat, crypto.PubkeyToAddress(heavy.PublicKey), TxLaneConsensus, false))
+	_, err := pool.enqueueTx(beat.Hash(), beat)
+	pool.mu.Unlock()
+	assert.Empty(t, err)
+	_, count := pool.heaviestSender()
+	assert.Equal(t, 7, count)
+
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the sender share eviction only picks the normal lane transactions
+// of the heaviest sender, sparing its consensus calls.
+func TestTxLaneSenderShareConsensus(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountSlots = 2
+	config.GlobalSlots = 4
+	config.AccountQueue = 8
+	config.GlobalQueue = 4
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	heavy, _ := crypto.GenerateKey()
+	light, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(heavy.PublicKey), big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(light.PublicKey), big.NewInt(1000000))
+
+	for _, nonce := range []uint64{0, 1, 2, 3, 5, 6, 7, 8} {
+		assert.Empty(t, pool.AddRemote(transaction(nonce, 100000, heavy)))
+	}
+	// The highest nonce of the sender is a consensus call
+	beat := heartbeatTransaction(t, 9, heavy)
+	pool.mu.Lock()
+	_, err := pool.enqueueTx(beat.Hash(), beat)
+	pool.mu.Unlock()
+	assert.Empty(t, err)
+	assert.Equal(t, 8, pool.all.LaneCount(TxLaneNormal))
+	assert.Equal(t, 1, pool.all.LaneCount(TxLaneConsensus))
+
+	// Room is made for another sender by dropping a normal lane transaction
+	pool.mu.Lock()
+	assert.Empty(t, pool.makeRoom(transaction(0, 100000, light), crypto.PubkeyToAddress(light.PublicKey), TxLaneNormal, false))
+	pool.mu.Unlock()
+
+	assert.Equal(t, 7, pool.all.LaneCount(TxLaneNormal))
+	assert.Equal(t, 1, pool.all.LaneCount(TxLaneConsensus))
+	assert.NotNil(t, pool.all.Get(beat.Hash()))
+
+	evictions := pool.Evictions()
+	if assert.Len(t, evictions, 1) {
+		assert.Equal(t, EvictSenderShare, evictions[0].Reason)
+		assert.Equal(t, uint64(8), evictions[0].Nonce)
+		assert.Equal(t, TxLaneNormal, evictions[0].Lane)
+	}
+
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+func TestTxLaneConsensus(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPool()
+	defer pool.Stop()
+	pool.config.ConsensusSlots = 2
+
+	var keys [3]*ecdsa.PrivateKey
+	var txs [3]*types.Transaction
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		txs[i] = heartbeatTransaction(t, 0, keys[i])
+		assert.Equal(t, TxLaneConsensus, TxLaneOf(txs[i]))
+	}
+	assert.Equal(t, TxLaneNormal, TxLaneOf(transaction(0, 100000, keys[0])))
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	// Consensus transactions are kept off the price heap
+	for _, tx := range txs[:2] {
+		assert.Empty(t, pool.makeRoom(tx, common.Address{}, TxLaneConsensus, false))
+		_, err := pool.enqueueTx(tx.Hash(), tx)
+		assert.Empty(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2, pool.all.LaneCount(TxLaneConsensus))
+	assert.Equal(t, 0, pool.all.LaneCount(TxLaneNormal))
+	assert.Equal(t, 0, pool.priced.items.Len())
+	assert.Equal(t, txs[0].Hash(), pool.all.OldestInLane(TxLaneConsensus).Hash())
+
+	// A full lane evicts its oldest transaction
+	assert.Empty(t, pool.makeRoom(txs[2], common.Address{}, TxLaneConsensus, false))
+	_, err := pool.enqueueTx(txs[2].Hash(), txs[2])
+	assert.Empty(t, err)
+
+	assert.Equal(t, 2, pool.all.LaneCount(TxLaneConsensus))
+	assert.Nil(t, pool.all.Get(txs[0].Hash()))
+	assert.Equal(t, txs[1].Hash(), pool.all.OldestInLane(TxLaneConsensus).Hash())
+
+	evictions := pool.evictions.list()
+	if assert.Len(t, evictions, 1) {
+		assert.Equal(t, EvictLaneFull, evictions[0].Reason)
+		assert.Equal(t, TxLaneConsensus, evictions[0].Lane)
+		assert.Equal(t, txs[0].Hash(), evictions[0].Hash)
+	}
+}
+
+func TestTxEvictionsLimit(t *testing.T) {
+	t.Parallel()
+
+	var evictions txEvictions
+	for i := 0; i < txEvictionLimit+10; i++ {
+		evictions.add(TxEviction{Nonce: uint64(i)})
+	}
+	list := evictions.list()
+	assert.Len(t, list, txEvictionLimit)
+	assert.Equal(t, uint64(10), list[0].Nonce)
+	assert.Equal(t, uint64(txEvictionLimit+9), list[len(list)-1].Nonce)
+}
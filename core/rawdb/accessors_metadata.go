@@ -95,3 +95,16 @@ func WritePreimages(db DatabaseWriter, preimages map[common.Hash][]byte) {
 	preimageCounter.Inc(int64(len(preimages)))
 	preimageHitCounter.Inc(int64(len(preimages)))
 }
+
+// ReadReorgJournal retrieves the encoded journal of the recent chain reorgs.
+func ReadReorgJournal(db DatabaseReader) []byte {
+	data, _ := db.Get(reorgJournalKey)
+	return data
+}
+
+// WriteReorgJournal stores the encoded journal of the recent chain reorgs.
+func WriteReorgJournal(db DatabaseWriter, journal []byte) {
+	if err := db.Put(reorgJournalKey, journal); err != nil {
+		log.Crit("Failed to store reorg journal", "err", err)
+	}
+}
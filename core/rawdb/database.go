@@ -0,0 +1,321 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// freezerdb is a database wrapper that enabled freezer data retrievals.
+type freezerdb struct {
+	ethdb.Database
+	*freezer
+}
+
+// Close implements ethdb.Database, closing both the fast key-value store as
+// well as the slow ancient tables.
+func (frdb *freezerdb) Close() {
+	if err := frdb.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	frdb.Database.Close()
+}
+
+// NewIteratorWithPrefix returns an iterator over the key-value store content
+// with a particular prefix. Ancient data is not iterated.
+func (frdb *freezerdb) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	it, ok := frdb.Database.(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		panic(errNotSupported)
+	}
+	return it.NewIteratorWithPrefix(prefix)
+}
+
+// LDB returns the LevelDB instance backing the key-value store, or nil for
+// the memory databases.
+func (frdb *freezerdb) LDB() *leveldb.DB {
+	if ldb, ok := frdb.Database.(interface {
+		LDB() *leveldb.DB
+	}); ok {
+		return ldb.LDB()
+	}
+	return nil
+}
+
+// KeyValueStore returns the fast key-value store behind the database, without
+// the ancient tables.
+func KeyValueStore(db ethdb.Database) ethdb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments into cold
+// storage.
+func NewDatabaseWithFreezer(db ethdb.Database, freezerDir string, namespace string) (ethdb.Database, error) {
+	// Create the idle freezer instance
+	frdb, err := newFreezer(freezerDir, namespace)
+	if err != nil {
+		return nil, err
+	}
+	// Since the freezer can be stored separately from the user's key-value database,
+	// there's a fairly high probability that the user requests invalid combinations
+	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
+	// by serving up conflicting data, leading to both datastores getting corrupted.
+	//
+	//   - If both the freezer and key-value store is empty (no genesis), we just
+	//     initialized a new empty freezer, so everything's fine.
+	//   - If the key-value store is empty, but the freezer is not, we need to make
+	//     sure the user's genesis matches the freezer. That will be checked in the
+	//     blockchain, since we don't have the genesis block here (nor should we at
+	//     this point care, the key-value/freezer combo is valid).
+	//   - If neither the key-value store nor the freezer is empty, cross validate
+	//     the genesis hashes to make sure they are compatible. If they are, also
+	//     ensure that there's no gap between the freezer and subsequently leveldb.
+	//   - If the key-value store is not empty, but the freezer is we might just be
+	//     upgrading to the freezer release, or we might have had a small chain and
+	//     not frozen anything yet. Ensure that no blocks are missing yet from the
+	//     key-value store, since that would mean we already had an old freezer.
+	if kvgenesis, _ := db.Get(headerHashKey(0)); len(kvgenesis) > 0 {
+		if frozen, _ := frdb.Ancients(); frozen > 0 {
+			// If the freezer already contains something, ensure that the genesis blocks
+			// match, otherwise we might mix up freezers across chains and destroy both
+			// the freezer and the key-value store.
+			if frgenesis, _ := frdb.Ancient(freezerHashTable, 0); !bytes.Equal(kvgenesis, frgenesis) {
+				frdb.Close()
+				return nil, fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
+			}
+			// Key-value store and freezer belong to the same network. Ensure that they
+			// are contiguous, otherwise we might end up with a non-functional freezer.
+			if kvhash, _ := db.Get(headerHashKey(frozen)); len(kvhash) == 0 {
+				// Subsequent header after the freezer limit is missing from the database.
+				// Reject startup if the database has a more recent head.
+				if head := ReadHeaderNumber(db, ReadHeadHeaderHash(db)); head != nil && *head > frozen-1 {
+					frdb.Close()
+					return nil, fmt.Errorf("gap (#%d) in the chain between ancients and leveldb", frozen)
+				}
+				// Database contains only older data than the freezer, this happens if the
+				// state was wiped and reinited from an existing freezer.
+			}
+			// Otherwise, key-value store continues where the freezer left off, all is fine.
+			// We might have duplicate blocks (crash after freezer write but before key-value
+			// store deletion, but that's fine).
+		} else {
+			// If the freezer is empty, ensure nothing was moved yet from the key-value
+			// store, otherwise we'll end up missing data. We check block #1 to decide
+			// if we froze anything previously or not, but do take care of databases with
+			// only the genesis block.
+			if ReadHeadHeaderHash(db) != common.BytesToHash(kvgenesis) {
+				// Key-value store contains more data than the genesis block, make sure we
+				// didn't freeze anything yet.
+				if kvblob, _ := db.Get(headerHashKey(1)); len(kvblob) == 0 {
+					frdb.Close()
+					return nil, fmt.Errorf("ancient chain segments already extracted, please set --datadir.ancient to the correct path")
+				}
+				// Block #1 is still in the database, we're allowed to init a new freezer
+			}
+			// Otherwise, the head header is still the genesis, we're allowed to init a new
+			// freezer.
+		}
+	}
+	return &freezerdb{
+		Database: db,
+		freezer:  frdb,
+	}, nil
+}
+
+// errAncientsExtracted is returned if a database is opened without its freezer
+// after chain segments were already moved into it.
+var errAncientsExtracted = errors.New("ancient chain segments already extracted, please enable --freezer and set --datadir.ancient to the correct path")
+
+// HasAncients reports whether the freezer in the given directory holds frozen
+// chain segments, without opening or creating it.
+func HasAncients(freezerDir string) bool {
+	stat, err := os.Stat(filepath.Join(freezerDir, freezerHashTable+".ridx"))
+	return err == nil && stat.Size() > indexEntrySize
+}
+
+// CheckNoAncients ensures that no chain segment was moved out of a key-value
+// store that is about to be used without its freezer, which would otherwise
+// silently lose the frozen blocks.
+func CheckNoAncients(db ethdb.Database) error {
+	kvgenesis, _ := db.Get(headerHashKey(0))
+	if len(kvgenesis) == 0 || ReadHeadHeaderHash(db) == common.BytesToHash(kvgenesis) {
+		return nil
+	}
+	if kvblob, _ := db.Get(headerHashKey(1)); len(kvblob) == 0 {
+		return errAncientsExtracted
+	}
+	return nil
+}
+
+// DatabaseStat is the number and the size of the entries of a category of
+// the database content.
+type DatabaseStat struct {
+	Category string
+	Count    uint64
+	Size     common.StorageSize
+}
+
+func (s *DatabaseStat) add(size int) {
+	s.Count++
+	s.Size += common.StorageSize(size)
+}
+
+// InspectDatabase iterates over the whole key-value store and sums up the
+// entries per key prefix, followed by the sizes of the ancient tables. The
+// entries not matching any known schema are reported as unaccounted.
+func InspectDatabase(db ethdb.Database) ([]DatabaseStat, error) {
+	it, ok := KeyValueStore(db).(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		return nil, errNotSupported
+	}
+	var (
+		headers      = DatabaseStat{Category: "Headers"}
+		tds          = DatabaseStat{Category: "Total difficulties"}
+		hashes       = DatabaseStat{Category: "Canonical hashes"}
+		numbers      = DatabaseStat{Category: "Header numbers"}
+		bodies       = DatabaseStat{Category: "Bodies"}
+		receipts     = DatabaseStat{Category: "Receipts"}
+		lookups      = DatabaseStat{Category: "Tx lookups"}
+		bloomBits    = DatabaseStat{Category: "Bloombits"}
+		snapAccounts = DatabaseStat{Category: "Snapshot accounts"}
+		snapStorages = DatabaseStat{Category: "Snapshot storages"}
+		tries        = DatabaseStat{Category: "Trie nodes and codes"}
+		preimages    = DatabaseStat{Category: "Preimages"}
+		checkpoints  = DatabaseStat{Category: "CHT and bloom tries"}
+		rangeMeta    = DatabaseStat{Category: "Range metadata"}
+		metadata     = DatabaseStat{Category: "Metadata"}
+		unaccounted  = DatabaseStat{Category: "Unaccounted"}
+
+		start  = time.Now()
+		logged = time.Now()
+		count  uint64
+	)
+	iter := it.NewIteratorWithPrefix(nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		var (
+			key  = iter.Key()
+			size = len(key) + len(iter.Value())
+		)
+		switch {
+		case bytes.HasPrefix(key, headerPrefix) && len(key) == len(headerPrefix)+8+common.HashLength:
+			headers.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix) && len(key) == len(headerPrefix)+8+common.HashLength+len(headerTDSuffix):
+			tds.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix) && len(key) == len(headerPrefix)+8+len(headerHashSuffix):
+			hashes.add(size)
+		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == len(headerNumberPrefix)+common.HashLength:
+			numbers.add(size)
+		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == len(blockBodyPrefix)+8+common.HashLength:
+			bodies.add(size)
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+			receipts.add(size)
+		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == len(txLookupPrefix)+common.HashLength:
+			lookups.add(size)
+		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+2+8+common.HashLength,
+			bytes.HasPrefix(key, BloomBitsIndexPrefix):
+			bloomBits.add(size)
+		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == len(SnapshotAccountPrefix)+common.HashLength:
+			snapAccounts.add(size)
+		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == len(SnapshotStoragePrefix)+2*common.HashLength:
+			snapStorages.add(size)
+		case len(key) == common.HashLength:
+			tries.add(size)
+		case bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+common.HashLength:
+			preimages.add(size)
+		case hasAnyPrefix(key, checkpointPrefixes):
+			checkpoints.add(size)
+		case bytes.Equal(key, historyTailKey), bytes.Equal(key, reorgJournalKey), bytes.HasPrefix(key, rangeMetadataPrefix):
+			rangeMeta.add(size)
+		case bytes.HasPrefix(key, configPrefix), isMetadataKey(key):
+			metadata.add(size)
+		default:
+			unaccounted.add(size)
+		}
+		count++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	stats := []DatabaseStat{
+		headers, tds, hashes, numbers, bodies, receipts, lookups, bloomBits, snapAccounts,
+		snapStorages, tries, preimages, checkpoints, rangeMeta, metadata, unaccounted,
+	}
+	// Append the ancient tables, if any
+	if ancients, ok := db.(AncientReader); ok {
+		for _, table := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable, freezerHashTable} {
+			size, err := ancients.AncientSize(table)
+			if err != nil {
+				return nil, err
+			}
+			frozen, _ := ancients.Ancients()
+			stats = append(stats, DatabaseStat{Category: "Ancient " + table, Count: frozen, Size: common.StorageSize(size)})
+		}
+	}
+	return stats, nil
+}
+
+var (
+	// checkpointPrefixes are the light client checkpoint tries, along with
+	// their roots (see light.ChtTablePrefix and light.BloomTrieTablePrefix).
+	checkpointPrefixes = [][]byte{[]byte("chtRoot-"), []byte("cht-"), []byte("bltRoot-"), []byte("blt-")}
+
+	// rangeMetadataPrefix is the common prefix of the Range specific data.
+	rangeMetadataPrefix = []byte("range-")
+)
+
+func hasAnyPrefix(key []byte, prefixes [][]byte) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMetadataKey(key []byte) bool {
+	for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey, snapshotRootKey, snapshotGeneratorKey} {
+		if bytes.Equal(key, meta) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/metrics"
+)
+
+// getChunk returns a chunk of data of the given size, filled with the byte.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+func newTestTable(t *testing.T, dir string, maxFileSize uint32) *freezerTable {
+	t.Helper()
+
+	table, err := newCustomTable(dir, "test", metrics.NilMeter{}, metrics.NilMeter{}, maxFileSize, true)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	return table
+}
+
+// Tests that items are retrievable across data file boundaries and reopens.
+func TestFreezerTableBasics(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "freezer")
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, 50)
+	for i := 0; i < 255; i++ {
+		if err := table.Append(uint64(i), getChunk(15, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.Append(300, getChunk(15, 0)); err == nil {
+		t.Fatalf("out of order item accepted")
+	}
+	table.Close()
+
+	table = newTestTable(t, dir, 50)
+	defer table.Close()
+
+	for i := 0; i < 255; i++ {
+		got, err := table.Retrieve(uint64(i))
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(got, getChunk(15, i)) {
+			t.Fatalf("item %d mismatch: have %x", i, got)
+		}
+	}
+	if _, err := table.Retrieve(255); err != errOutOfBounds {
+		t.Fatalf("missing item: have %v, want %v", err, errOutOfBounds)
+	}
+}
+
+// Tests that a data file cut short by a crash is repaired on open, dropping the
+// items it does not contain anymore.
+func TestFreezerTableRepairDanglingHead(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "freezer")
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, 50)
+	for i := 0; i < 255; i++ {
+		table.Append(uint64(i), getChunk(15, i))
+	}
+	table.Close()
+
+	// Chop off the last few bytes of the head data file
+	head := filepath.Join(dir, "test.0084.rdat")
+	stat, err := os.Stat(head)
+	if err != nil {
+		t.Fatalf("head data file missing: %v", err)
+	}
+	if err := os.Truncate(head, stat.Size()-4); err != nil {
+		t.Fatalf("failed to truncate head: %v", err)
+	}
+
+	table = newTestTable(t, dir, 50)
+	defer table.Close()
+
+	if table.items != 254 {
+		t.Fatalf("dangling item not dropped: have %d items", table.items)
+	}
+	if _, err := table.Retrieve(253); err != nil {
+		t.Fatalf("failed to retrieve last item: %v", err)
+	}
+	if err := table.Append(254, getChunk(15, 254)); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+}
+
+// Tests that truncation drops the items across data files.
+func TestFreezerTableTruncate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "freezer")
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, 50)
+	defer table.Close()
+
+	for i := 0; i < 30; i++ {
+		table.Append(uint64(i), getChunk(15, i))
+	}
+	if err := table.truncate(10); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if _, err := table.Retrieve(10); err != errOutOfBounds {
+		t.Fatalf("truncated item: have %v, want %v", err, errOutOfBounds)
+	}
+	if err := table.Append(10, getChunk(15, 0xff)); err != nil {
+		t.Fatalf("failed to append after truncation: %v", err)
+	}
+	if got, err := table.Retrieve(10); !bytes.Equal(got, getChunk(15, 0xff)) {
+		t.Fatalf("item mismatch after truncation: have %x, %v", got, err)
+	}
+}
+
+// Tests that the chain accessors transparently read the frozen blocks, and
+// that the freezer is refused if it does not belong to the key-value store.
+func TestAncientStorage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "freezer")
+	defer os.RemoveAll(dir)
+
+	kvdb := ethdb.NewMemDatabase()
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+
+	var blocks []*types.Block
+	for i := int64(0); i < 4; i++ {
+		header := &types.Header{Number: big.NewInt(i), Extra: []byte("test block")}
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		blocks = append(blocks, types.NewBlockWithHeader(header))
+	}
+	receipts := types.Receipts{&types.Receipt{CumulativeGasUsed: 1, Logs: []*types.Log{}}}
+
+	for _, block := range blocks[:3] {
+		if err := WriteAncientBlock(db.(AncientWriter), block, receipts, big.NewInt(int64(block.NumberU64()+1))); err != nil {
+			t.Fatalf("failed to freeze block %d: %v", block.NumberU64(), err)
+		}
+	}
+	WriteCanonicalHash(kvdb, blocks[0].Hash(), 0)
+	WriteCanonicalHash(kvdb, blocks[3].Hash(), 3)
+	WriteHeader(kvdb, blocks[3].Header())
+	WriteHeadHeaderHash(kvdb, blocks[3].Hash())
+
+	for _, block := range blocks[:3] {
+		hash, number := block.Hash(), block.NumberU64()
+
+		if have := ReadCanonicalHash(db, number); have != hash {
+			t.Fatalf("block %d: canonical hash mismatch: have %x, want %x", number, have, hash)
+		}
+		if header := ReadHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Fatalf("block %d: frozen header not found", number)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) || !HasReceipts(db, hash, number) {
+			t.Fatalf("block %d: frozen data not reported", number)
+		}
+		if td := ReadTd(db, hash, number); td == nil || td.Uint64() != number+1 {
+			t.Fatalf("block %d: total difficulty mismatch: have %v", number, td)
+		}
+		if have := ReadReceipts(db, hash, number); len(have) != 1 || have[0].CumulativeGasUsed != 1 {
+			t.Fatalf("block %d: receipts mismatch: have %v", number, have)
+		}
+		// Unknown blocks at frozen heights must not be served from the freezer
+		if header := ReadHeader(db, common.Hash{0x01}, number); header != nil {
+			t.Fatalf("block %d: side chain header served from the freezer", number)
+		}
+		if HasBody(db, common.Hash{0x01}, number) {
+			t.Fatalf("block %d: side chain body reported by the freezer", number)
+		}
+	}
+	// Plain key-value stores keep working without the freezer
+	if header := ReadHeader(kvdb, blocks[1].Hash(), 1); header != nil {
+		t.Fatalf("frozen header returned from the key-value store")
+	}
+
+	// Truncate the freezer and ensure the accessors observe it
+	if err := db.(AncientWriter).TruncateAncients(2); err != nil {
+		t.Fatalf("failed to truncate ancients: %v", err)
+	}
+	if HasHeader(db, blocks[2].Hash(), 2) {
+		t.Fatalf("truncated header reported")
+	}
+	db.Close()
+
+	// A gap between the ancients and the key-value store is refused
+	if db, err = NewDatabaseWithFreezer(kvdb, dir, ""); err == nil {
+		t.Fatalf("gap between ancients and key-value store accepted")
+	}
+	// A freezer of another chain is refused
+	WriteCanonicalHash(kvdb, common.Hash{0x01}, 0)
+	if db, err = NewDatabaseWithFreezer(kvdb, dir, ""); err == nil {
+		t.Fatalf("genesis mismatch accepted")
+	}
+	WriteCanonicalHash(kvdb, blocks[0].Hash(), 0)
+	WriteHeadHeaderHash(kvdb, blocks[1].Hash())
+
+	if db, err = NewDatabaseWithFreezer(kvdb, dir, ""); err != nil {
+		t.Fatalf("failed to reopen database with freezer: %v", err)
+	}
+	db.Close()
+}
+
+// Tests that frozen chain segments are detected both in the ancient directory
+// and through the blocks missing from the key-value store.
+func TestAncientDetection(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "freezer")
+	defer os.RemoveAll(dir)
+
+	if HasAncients(filepath.Join(dir, "missing")) {
+		t.Fatalf("ancients reported in a missing directory")
+	}
+	kvdb := ethdb.NewMemDatabase()
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	if HasAncients(dir) {
+		t.Fatalf("ancients reported in an empty freezer")
+	}
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()})
+	receipts := types.Receipts{}
+
+	if err := WriteAncientBlock(db.(AncientWriter), genesis, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to freeze genesis: %v", err)
+	}
+	db.Close()
+
+	if !HasAncients(dir) {
+		t.Fatalf("frozen genesis not reported")
+	}
+	// Without the freezer, the key-value store must reveal the moved blocks
+	WriteCanonicalHash(kvdb, genesis.Hash(), 0)
+	WriteHeadHeaderHash(kvdb, genesis.Hash())
+	if err := CheckNoAncients(kvdb); err != nil {
+		t.Fatalf("genesis-only store refused: %v", err)
+	}
+	WriteCanonicalHash(kvdb, block.Hash(), 1)
+	WriteHeadHeaderHash(kvdb, block.Hash())
+	if err := CheckNoAncients(kvdb); err != nil {
+		t.Fatalf("complete store refused: %v", err)
+	}
+	DeleteCanonicalHash(kvdb, 1)
+	if err := CheckNoAncients(kvdb); err != errAncientsExtracted {
+		t.Fatalf("extracted ancients not detected: %v", err)
+	}
+}
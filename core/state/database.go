@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	"range/core/gen3/common"
+	"range/core/gen3/core/state/snapshot"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/trie"
 	lru "github.com/hashicorp/golang-lru"
@@ -165,6 +166,30 @@ func (db *cachingDB) TrieDB() *trie.Database {
 	return db.db
 }
 
+// snapshotProvider is implemented by the state databases backed by a flat
+// state snapshot.
+type snapshotProvider interface {
+	Snapshots() *snapshot.Tree
+}
+
+// snapshotDB attaches a snapshot tree to a state database.
+type snapshotDB struct {
+	Database
+	snaps *snapshot.Tree
+}
+
+// WithSnapshots returns a state database, which serves the state reads from
+// the snapshot tree, if available for the requested root, and updates the
+// tree on commit.
+func WithSnapshots(db Database, snaps *snapshot.Tree) Database {
+	return &snapshotDB{Database: db, snaps: snaps}
+}
+
+// Snapshots returns the snapshot tree of the database.
+func (db *snapshotDB) Snapshots() *snapshot.Tree {
+	return db.snaps
+}
+
 // cachedTrie inserts its trie into a cachingDB on commit.
 type cachedTrie struct {
 	*trie.SecureTrie
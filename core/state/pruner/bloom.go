@@ -0,0 +1,76 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"math"
+
+	"range/core/gen3/common"
+)
+
+// stateBloomHashes is the number of bit positions set per entry. The keys are
+// keccak hashes already, so the positions are taken from their 8 byte words.
+const stateBloomHashes = 4
+
+// stateBloom is a bloom filter over the hashes of the trie nodes and contract
+// codes which are reachable from the retained states. Its size is fixed upfront
+// so that marking runs in bounded memory. A false positive only leaves a stale
+// entry on disk, it can never delete a live one.
+type stateBloom struct {
+	bits  []uint64
+	size  uint64 // Number of bits in the filter
+	items uint64 // Number of entries added
+}
+
+// newStateBloom creates a bloom filter of the given size in megabytes.
+func newStateBloom(megabytes uint64) *stateBloom {
+	if megabytes == 0 {
+		megabytes = 1
+	}
+	words := megabytes * 1024 * 1024 / 8
+	return &stateBloom{
+		bits: make([]uint64, words),
+		size: words * 64,
+	}
+}
+
+// add inserts a hash into the filter.
+func (b *stateBloom) add(hash common.Hash) {
+	for i := 0; i < stateBloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(hash[i*8:]) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+	b.items++
+}
+
+// contains checks whether a hash might have been inserted into the filter.
+func (b *stateBloom) contains(hash []byte) bool {
+	for i := 0; i < stateBloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(hash[i*8:]) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// falsePositiveRate estimates the probability of a stale entry being kept.
+func (b *stateBloom) falsePositiveRate() float64 {
+	k := float64(stateBloomHashes)
+	return math.Pow(1-math.Exp(-k*float64(b.items)/float64(b.size)), k)
+}
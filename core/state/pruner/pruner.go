@@ -0,0 +1,319 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline removal of the state trie nodes and
+// contract codes which are not referenced by any of the retained states.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"range/core/gen3/rlp"
+	"range/core/gen3/trie"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// errNoRetainedState is returned if none of the states to retain is
+	// available on disk, pruning would wipe the whole state.
+	errNoRetainedState = errors.New("no retained state available")
+)
+
+// logInterval is the frequency of the progress reports.
+const logInterval = 8 * time.Second
+
+// iteratee is the database functionality required to sweep the stale entries.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Report summarises the outcome of a pruning, or the expected one in case of
+// a dry run.
+type Report struct {
+	Retained          []common.Hash      // State roots kept on disk
+	Missing           []common.Hash      // Requested state roots not available on disk
+	Marked            uint64             // Trie nodes and codes reachable from the retained states
+	Scanned           uint64             // Trie nodes and codes found on disk
+	Deleted           uint64             // Trie nodes and codes deleted (or to delete)
+	Freed             common.StorageSize // Disk space released (or to release), before compaction
+	FalsePositiveRate float64            // Estimated share of stale entries kept by the bloom filter
+	Elapsed           time.Duration      // Total time of the pruning
+}
+
+// Pruner deletes the trie nodes and contract codes not reachable from a set of
+// retained state roots. It works on the key-value store of a stopped node: the
+// reachable entries are marked in a bloom filter of bounded size, then every
+// other entry keyed by a bare hash is swept.
+type Pruner struct {
+	db        ethdb.Database
+	bloomSize uint64
+}
+
+// NewPruner creates a pruner over the key-value store of the chain, using a
+// bloom filter of the given size in megabytes for the mark phase.
+func NewPruner(db ethdb.Database, bloomSize uint64) (*Pruner, error) {
+	db = rawdb.KeyValueStore(db)
+	if _, ok := db.(iteratee); !ok {
+		return nil, errors.New("state database is not iterable")
+	}
+	return &Pruner{
+		db:        db,
+		bloomSize: bloomSize,
+	}, nil
+}
+
+// Prune deletes all the state entries which are not referenced by the given
+// state roots, which are expected in ascending block order. Roots missing from
+// the disk are reported and fail the pruning, the states built on top of them
+// could not be regenerated anymore. In a dry run nothing is deleted, only the
+// report is assembled.
+func (p *Pruner) Prune(roots []common.Hash, dryRun bool) (*Report, error) {
+	var (
+		start  = time.Now()
+		report = new(Report)
+		bloom  = newStateBloom(p.bloomSize)
+		triedb = trie.NewDatabase(p.db)
+	)
+	for _, root := range roots {
+		if _, err := trie.New(root, triedb); err != nil {
+			report.Missing = append(report.Missing, root)
+			continue
+		}
+		report.Retained = append(report.Retained, root)
+	}
+	if len(report.Retained) == 0 {
+		return report, errNoRetainedState
+	}
+	if len(report.Missing) > 0 {
+		return report, fmt.Errorf("%d retained states missing, first %x", len(report.Missing), report.Missing[0])
+	}
+
+	// Mark all the entries reachable from the retained states. Any missing node
+	// aborts the pruning, as the marked set would be incomplete.
+	log.Info("Marking retained state", "roots", len(report.Retained))
+
+	mark := func(hash common.Hash) error {
+		bloom.add(hash)
+		return nil
+	}
+	if err := walkStates(triedb, report.Retained, mark, mark); err != nil {
+		return report, err
+	}
+	report.Marked = bloom.items
+	report.FalsePositiveRate = bloom.falsePositiveRate()
+	log.Info("Marked retained state", "entries", report.Marked, "fpr", report.FalsePositiveRate,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep everything keyed by a bare hash but not marked. The snapshot and
+	// the other flat data have prefixed keys and are never considered.
+	var (
+		it     = p.db.(iteratee).NewIteratorWithPrefix(nil)
+		batch  = p.db.NewBatch()
+		logged = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		report.Scanned++
+		if bloom.contains(key) {
+			continue
+		}
+		report.Deleted++
+		report.Freed += common.StorageSize(len(key) + len(it.Value()))
+
+		if !dryRun {
+			batch.Delete(common.CopyBytes(key))
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return report, err
+				}
+				batch.Reset()
+			}
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Sweeping stale state", "scanned", report.Scanned, "deleted", report.Deleted, "freed", report.Freed,
+				"at", common.BytesToHash(key), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return report, err
+	}
+	if !dryRun {
+		if err := batch.Write(); err != nil {
+			return report, err
+		}
+		// A persisted snapshot of a pruned state can't be resumed anymore
+		if root := rawdb.ReadSnapshotRoot(p.db); root != (common.Hash{}) && !contains(report.Retained, root) {
+			log.Warn("Invalidating state snapshot of a pruned state", "root", root)
+			rawdb.DeleteSnapshotRoot(p.db)
+		}
+	}
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// Verify checks that the given states are complete on disk: all the trie nodes
+// and contract codes they reference must be present.
+func (p *Pruner) Verify(roots []common.Hash) error {
+	check := func(hash common.Hash) error {
+		if has, err := p.db.Has(hash[:]); !has || err != nil {
+			return fmt.Errorf("missing contract code %x", hash)
+		}
+		return nil
+	}
+	// Trie nodes are checked by resolving them during the walk
+	return walkStates(trie.NewDatabase(p.db), roots, func(common.Hash) error { return nil }, check)
+}
+
+// walkStates iterates over all the trie nodes and contract codes referenced by
+// the state roots, which are expected in ascending block order. Only the first
+// state is iterated completely, every subsequent one is diffed against its
+// predecessor, skipping the subtries they share.
+func walkStates(triedb *trie.Database, roots []common.Hash, onNode, onCode func(common.Hash) error) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		nodes  uint64
+	)
+	var prev *trie.Trie
+	for i, root := range roots {
+		curr, err := trie.New(root, triedb)
+		if err != nil {
+			return err
+		}
+		var it trie.NodeIterator
+		if prev == nil {
+			it = curr.NodeIterator(nil)
+		} else {
+			it, _ = trie.NewDifferenceIterator(prev.NodeIterator(nil), curr.NodeIterator(nil))
+		}
+		for it.Next(true) {
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				if err := onNode(hash); err != nil {
+					return err
+				}
+				nodes++
+			}
+			if !it.Leaf() {
+				continue
+			}
+			var acc state.Account
+			if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+				return fmt.Errorf("invalid account %x in state %x: %v", it.LeafKey(), root, err)
+			}
+			if codeHash := common.BytesToHash(acc.CodeHash); codeHash != emptyCode {
+				if err := onCode(codeHash); err != nil {
+					return err
+				}
+			}
+			n, err := walkStorage(triedb, prev, it.LeafKey(), acc.Root, onNode)
+			if err != nil {
+				return err
+			}
+			nodes += n
+
+			if time.Since(logged) > logInterval {
+				log.Info("Iterating state", "root", root, "state", i+1, "of", len(roots), "nodes", nodes,
+					"elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if err := it.Error(); err != nil {
+			return fmt.Errorf("failed to iterate state %x: %v", root, err)
+		}
+		prev = curr
+	}
+	return nil
+}
+
+// walkStorage iterates over the storage trie nodes of an account, skipping the
+// ones shared with the same account in the preceding state.
+func walkStorage(triedb *trie.Database, prev *trie.Trie, key []byte, root common.Hash, onNode func(common.Hash) error) (uint64, error) {
+	if root == emptyRoot {
+		return 0, nil
+	}
+	prevRoot := emptyRoot
+	if prev != nil {
+		if blob, err := prev.TryGet(key); err != nil {
+			return 0, err
+		} else if len(blob) > 0 {
+			var acc state.Account
+			if err := rlp.DecodeBytes(blob, &acc); err != nil {
+				return 0, err
+			}
+			prevRoot = acc.Root
+		}
+	}
+	if prevRoot == root {
+		return 0, nil
+	}
+	curr, err := trie.New(root, triedb)
+	if err != nil {
+		return 0, err
+	}
+	var it trie.NodeIterator
+	if prevRoot == emptyRoot {
+		it = curr.NodeIterator(nil)
+	} else {
+		old, err := trie.New(prevRoot, triedb)
+		if err != nil {
+			return 0, err
+		}
+		it, _ = trie.NewDifferenceIterator(old.NodeIterator(nil), curr.NodeIterator(nil))
+	}
+	var nodes uint64
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if err := onNode(hash); err != nil {
+				return nodes, err
+			}
+			nodes++
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nodes, fmt.Errorf("failed to iterate storage %x: %v", root, err)
+	}
+	return nodes, nil
+}
+
+// contains checks whether the hash is in the list.
+func contains(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state"
+	"range/core/gen3/ethdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeStates commits a sequence of states, each one modifying the balances,
+// storage and code of a few accounts of its predecessor.
+func makeStates(t *testing.T, db ethdb.Database, count int) []common.Hash {
+	var (
+		sdb   = state.NewDatabase(db)
+		roots []common.Hash
+		root  common.Hash
+	)
+	for i := 0; i < count; i++ {
+		statedb, err := state.New(root, sdb)
+		if err != nil {
+			t.Fatalf("failed to open state %d: %v", i, err)
+		}
+		for j := 0; j < 32; j++ {
+			addr := common.BytesToAddress([]byte{byte(j)})
+			if j%4 == i%4 {
+				statedb.AddBalance(addr, big.NewInt(int64(i+1)))
+				statedb.SetState(addr, common.BytesToHash([]byte{byte(i)}), common.BytesToHash([]byte{byte(j + 1)}))
+			}
+			if i == 0 && j%8 == 0 {
+				statedb.SetCode(addr, []byte{byte(j), 0x60, 0x00})
+			}
+		}
+		if root, err = statedb.Commit(false); err != nil {
+			t.Fatalf("failed to commit state %d: %v", i, err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("failed to flush state %d: %v", i, err)
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+func TestPruneState(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	roots := makeStates(t, db, 8)
+	retained := roots[5:]
+
+	pruner, err := NewPruner(db, 1)
+	assert.Empty(t, err)
+
+	// Stale states must be complete before the pruning
+	assert.Empty(t, pruner.Verify(roots[:1]))
+
+	// Missing retained states fail the pruning
+	entries := db.Len()
+	report, err := pruner.Prune(append([]common.Hash{{0x01}}, retained...), false)
+	assert.NotEmpty(t, err)
+	assert.Equal(t, []common.Hash{{0x01}}, report.Missing)
+	assert.Equal(t, retained, report.Retained)
+	assert.Zero(t, report.Deleted)
+	assert.Equal(t, entries, db.Len())
+
+	// A dry run only reports
+	report, err = pruner.Prune(retained, true)
+	assert.Empty(t, err)
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, retained, report.Retained)
+	assert.NotZero(t, report.Deleted)
+	assert.Equal(t, entries, db.Len())
+
+	// A snapshot of a stale state is invalidated by the pruning
+	rawdb.WriteSnapshotRoot(db, roots[0])
+
+	deleted := report.Deleted
+	report, err = pruner.Prune(retained, false)
+	assert.Empty(t, err)
+	assert.Equal(t, deleted, report.Deleted)
+	assert.Equal(t, entries-int(deleted), db.Len())
+	assert.Equal(t, common.Hash{}, rawdb.ReadSnapshotRoot(db))
+
+	// The retained states are intact, the stale ones gone
+	assert.Empty(t, pruner.Verify(retained))
+	assert.NotEmpty(t, pruner.Verify(roots[:1]))
+
+	for _, root := range retained {
+		statedb, err := state.New(root, state.NewDatabase(db))
+		assert.Empty(t, err)
+		assert.NotEmpty(t, statedb.GetCode(common.BytesToAddress([]byte{8})))
+	}
+
+	// Nothing is pruned without any retained state
+	_, err = pruner.Prune([]common.Hash{{0x01}}, false)
+	assert.Equal(t, errNoRetainedState, err)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/ethdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetStorage(t *testing.T) {
+	db := NewDatabase(ethdb.NewMemDatabase())
+	state, _ := New(common.Hash{}, db)
+
+	addr := common.Address{0x01}
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x01})
+	state.SetState(addr, common.Hash{0x02}, common.Hash{0x02})
+	root, err := state.Commit(false)
+	assert.Empty(t, err)
+	assert.Empty(t, db.TrieDB().Commit(root, false))
+
+	// The fake storage hides the committed one entirely
+	state, _ = New(root, db)
+	state.SetStorage(addr, map[common.Hash]common.Hash{{0x02}: {0x22}})
+	assert.Equal(t, common.Hash{}, state.GetState(addr, common.Hash{0x01}))
+	assert.Equal(t, common.Hash{0x22}, state.GetState(addr, common.Hash{0x02}))
+	assert.Equal(t, common.Hash{0x22}, state.GetCommittedState(addr, common.Hash{0x02}))
+
+	state.SetState(addr, common.Hash{0x03}, common.Hash{0x33})
+	assert.Equal(t, common.Hash{0x33}, state.GetState(addr, common.Hash{0x03}))
+
+	// Other states are unaffected
+	state, _ = New(root, db)
+	assert.Equal(t, common.Hash{0x01}, state.GetState(addr, common.Hash{0x01}))
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"range/core/gen3/common"
+)
+
+// WriteWatcher is notified of the account and storage writes of a state before
+// they are applied, the ones reverted later included. The previous value must
+// not be modified.
+type WriteWatcher interface {
+	WatchBalance(addr common.Address, prev *big.Int)
+	WatchNonce(addr common.Address, prev uint64)
+	WatchCode(addr common.Address, prev []byte)
+	WatchStorage(addr common.Address, key, prev common.Hash)
+}
+
+// Watch sets the observer of the writes, nil stops watching. The copies of the
+// state are not watched. This function should only be used for debugging.
+func (self *StateDB) Watch(watcher WriteWatcher) {
+	self.watcher = watcher
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/ethdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testWatcher struct {
+	balances []string
+	nonces   []uint64
+	codes    []string
+	storage  []common.Hash
+}
+
+func (w *testWatcher) WatchBalance(addr common.Address, prev *big.Int) {
+	w.balances = append(w.balances, prev.String())
+}
+
+func (w *testWatcher) WatchNonce(addr common.Address, prev uint64) {
+	w.nonces = append(w.nonces, prev)
+}
+
+func (w *testWatcher) WatchCode(addr common.Address, prev []byte) {
+	w.codes = append(w.codes, string(prev))
+}
+
+func (w *testWatcher) WatchStorage(addr common.Address, key, prev common.Hash) {
+	w.storage = append(w.storage, prev)
+}
+
+func TestWatch(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+	addr := common.Address{0x01}
+
+	w := new(testWatcher)
+	state.Watch(w)
+
+	state.SetBalance(addr, big.NewInt(10))
+	state.AddBalance(addr, big.NewInt(5))
+	state.SetNonce(addr, 1)
+	state.SetCode(addr, []byte("code"))
+	state.SetCode(addr, []byte("new code"))
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x01})
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x01}) // Unchanged, not a write
+
+	// Reverted writes are observed as well
+	snapshot := state.Snapshot()
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x02})
+	state.RevertToSnapshot(snapshot)
+
+	state.Suicide(addr)
+
+	// Copies are not watched
+	state.Copy().SetBalance(addr, big.NewInt(1))
+
+	state.Watch(nil)
+	state.SetBalance(addr, big.NewInt(2))
+
+	assert.Equal(t, []string{"0", "10", "15"}, w.balances)
+	assert.Equal(t, []uint64{0}, w.nonces)
+	assert.Equal(t, []string{"", "code"}, w.codes)
+	assert.Equal(t, []common.Hash{{}, {0x01}}, w.storage)
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"range/core/gen3/trie"
+)
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb ethdb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database // Trie node cache for reconstruction purposes
+	root   common.Hash    // Root hash of the base snapshot
+	stale  bool           // Signals that the layer became stale (state progressed)
+
+	genMarker []byte             // Marker for the state that's indexed during generation, nil if complete
+	genAbort  chan chan struct{} // Notification channel to abort generating the snapshot in this layer
+	genDone   chan struct{}      // Closed once the generation is not running anymore
+
+	lock sync.RWMutex
+}
+
+func newDiskLayer(diskdb ethdb.Database, triedb *trie.Database, root common.Hash, marker []byte) *diskLayer {
+	dl := &diskLayer{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		root:      root,
+		genMarker: marker,
+		genAbort:  make(chan chan struct{}),
+		genDone:   make(chan struct{}),
+	}
+	if marker == nil {
+		close(dl.genDone)
+	}
+	return dl
+}
+
+// Root returns the root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent always returns nil as there's no layer below the disk.
+func (dl *diskLayer) Parent() layer {
+	return nil
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// covered returns whether the generation has already passed the account.
+// The lock must be held.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	return dl.genMarker == nil || bytes.Compare(hash[:], dl.genMarker) <= 0
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot, using the trie encoding.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	return rawdb.ReadAccountSnapshot(dl.diskdb, hash), nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	return rawdb.ReadStorageSnapshot(dl.diskdb, accountHash, storageHash), nil
+}
+
+// stopGeneration aborts the background generation, if running, and returns
+// the generation progress marker.
+func (dl *diskLayer) stopGeneration() []byte {
+	abort := make(chan struct{})
+	select {
+	case dl.genAbort <- abort:
+		<-abort
+	case <-dl.genDone:
+	}
+
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genMarker
+}
+
+// merge writes the diff layer into the persistent database and returns the new
+// disk layer. Only the entries already covered by the generation are written,
+// the rest is picked up by the generator from the new root.
+func (dl *diskLayer) merge(diff *diffLayer, marker []byte) *diskLayer {
+	covered := func(hash common.Hash) bool {
+		return marker == nil || bytes.Compare(hash[:], marker) <= 0
+	}
+
+	// The snapshot is inconsistent until the whole diff is written
+	rawdb.DeleteSnapshotRoot(dl.diskdb)
+
+	batch := dl.diskdb.NewBatch()
+	flush := func() {
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write snapshot", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+
+	for hash := range diff.destructs {
+		if !covered(hash) {
+			continue
+		}
+		rawdb.DeleteAccountSnapshot(batch, hash)
+		deletePrefix(dl.diskdb, rawdb.StorageSnapshotsPrefix(hash), storageSnapshotKeyLength)
+		flush()
+	}
+	for hash, data := range diff.accounts {
+		if !covered(hash) {
+			continue
+		}
+		if len(data) == 0 {
+			rawdb.DeleteAccountSnapshot(batch, hash)
+		} else {
+			rawdb.WriteAccountSnapshot(batch, hash, data)
+		}
+		flush()
+	}
+	for accountHash, storage := range diff.storage {
+		if !covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range storage {
+			if len(data) == 0 {
+				rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+			} else {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+			}
+		}
+		flush()
+	}
+
+	if marker != nil {
+		rawdb.WriteSnapshotGenerator(batch, marker)
+	}
+	rawdb.WriteSnapshotRoot(batch, diff.root)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot", "err", err)
+	}
+
+	dl.markStale()
+	return newDiskLayer(dl.diskdb, dl.triedb, diff.root, marker)
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"range/core/gen3/rlp"
+	"range/core/gen3/trie"
+)
+
+// generatorLogInterval is the time between two generation progress reports.
+const generatorLogInterval = 8 * time.Second
+
+// generate is a background thread that iterates over the state and storage tries
+// and constructs a state snapshot. It resumes from the progress marker and gets
+// restarted on top of every new disk layer, as the blocks arrive.
+func (dl *diskLayer) generate() {
+	defer close(dl.genDone)
+
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	// The account at the marker is covered already, the ones after it may have
+	// been written partially by an interrupted run.
+	resumed := common.CopyBytes(marker)
+
+	accTrie, err := trie.NewSecure(dl.root, dl.triedb, 0)
+	if err != nil {
+		log.Error("Snapshot generation failed", "root", dl.root, "err", err)
+		return
+	}
+
+	var (
+		batch   = dl.diskdb.NewBatch()
+		start   = time.Now()
+		logged  = time.Now()
+		account = common.BytesToHash(marker)
+		slots   uint64
+		count   uint64
+	)
+	log.Debug("Generating state snapshot", "root", dl.root, "at", account)
+
+	// checkpoint persists the batch together with the progress marker, which
+	// only then gets visible to the readers.
+	checkpoint := func(marker []byte) {
+		if marker != nil {
+			rawdb.WriteSnapshotGenerator(batch, marker)
+		} else {
+			rawdb.DeleteSnapshotGenerator(batch)
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write snapshot", "err", err)
+		}
+		batch.Reset()
+
+		dl.lock.Lock()
+		dl.genMarker = marker
+		dl.lock.Unlock()
+	}
+
+	it := trie.NewIterator(accTrie.NodeIterator(marker))
+	for it.Next() {
+		select {
+		case abort := <-dl.genAbort:
+			checkpoint(marker)
+			log.Debug("Aborted state snapshot generation", "root", dl.root, "at", account,
+				"accounts", count, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			abort <- struct{}{}
+			return
+		default:
+		}
+
+		account = common.BytesToHash(it.Key)
+		if !bytes.Equal(account[:], resumed) {
+			deletePrefix(dl.diskdb, rawdb.StorageSnapshotsPrefix(account), storageSnapshotKeyLength)
+		}
+		rawdb.WriteAccountSnapshot(batch, account, it.Value)
+		generatedAccountMeter.Mark(1)
+
+		var acc Account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			log.Error("Invalid account encountered during snapshot generation", "account", account, "err", err)
+			return
+		}
+
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.NewSecure(acc.Root, dl.triedb, 0)
+			if err != nil {
+				log.Error("Snapshot generation failed", "account", account, "err", err)
+				return
+			}
+
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, account, common.BytesToHash(storeIt.Key), storeIt.Value)
+				generatedStorageMeter.Mark(1)
+				slots++
+
+				// The account is not covered until completed, so a partial write is fine
+				if batch.ValueSize() > ethdb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						log.Crit("Failed to write snapshot", "err", err)
+					}
+					batch.Reset()
+				}
+			}
+			if storeIt.Err != nil {
+				log.Error("Snapshot generation failed", "account", account, "err", storeIt.Err)
+				return
+			}
+		}
+		count++
+
+		marker = common.CopyBytes(account[:])
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			checkpoint(marker)
+		}
+
+		if time.Since(logged) > generatorLogInterval {
+			log.Info("Generating state snapshot", "at", account, "accounts", count,
+				"slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Err != nil {
+		log.Error("Snapshot generation failed", "root", dl.root, "err", it.Err)
+		return
+	}
+
+	checkpoint(nil)
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", count,
+		"slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+}
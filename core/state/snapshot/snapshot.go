@@ -0,0 +1,391 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat key-value view of the state, which is
+// kept on disk for an older block and in memory diff layers for the most
+// recent blocks on top of it.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/log"
+	"range/core/gen3/metrics"
+	"range/core/gen3/trie"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	diffLayersGauge       = metrics.NewRegisteredGauge("state/snapshot/difflayers", nil)
+	generatedAccountMeter = metrics.NewRegisteredMeter("state/snapshot/generation/account", nil)
+	generatedStorageMeter = metrics.NewRegisteredMeter("state/snapshot/generation/storage", nil)
+)
+
+const (
+	// Key lengths of the flat snapshot entries, to tell them apart from trie
+	// nodes sharing the same leading byte.
+	accountSnapshotKeyLength = 1 + common.HashLength
+	storageSnapshotKeyLength = 1 + 2*common.HashLength
+)
+
+// Account is the consensus representation of an account, as stored in the
+// snapshot. It is identical to the trie encoding to avoid conversions.
+type Account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// AccountRLP directly retrieves the account trie value associated with
+	// a particular hash in the snapshot. A nil value means the account does
+	// not exist.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage trie value associated with a
+	// particular hash within a particular account. A nil value means the
+	// slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// layer is the internal interface of the snapshot layers.
+type layer interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() layer
+
+	// Stale returns whether this layer has become stale (was flattened across)
+	// or if it's still live.
+	Stale() bool
+
+	// markStale invalidates the layer.
+	markStale()
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than
+// the disk layer, everything needs to be regenerated.
+//
+// The goal of a state snapshot is to allow direct access to account and
+// storage data to avoid expensive multi-level trie lookups.
+type Tree struct {
+	diskdb ethdb.Database        // Persistent database to store the snapshot
+	triedb *trie.Database        // In-memory cache to access the trie through
+	layers map[common.Hash]layer // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// iteratee is the database functionality required to wipe stale entries.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the head of the snapshot matches the expected one.
+//
+// If the snapshot is missing or inconsistent, the entirety is deleted and will
+// be reconstructed from scratch based on the tries in the key-value store, on a
+// background thread.
+func New(diskdb ethdb.Database, triedb *trie.Database, root common.Hash) (*Tree, error) {
+	if _, ok := diskdb.(iteratee); !ok {
+		return nil, errors.New("snapshot database is not iterable")
+	}
+
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		layers: make(map[common.Hash]layer),
+	}
+
+	if rawdb.ReadSnapshotRoot(diskdb) != root {
+		log.Warn("Snapshot is missing or outdated, rebuilding", "root", root)
+		snap.Rebuild(root)
+		return snap, nil
+	}
+
+	marker := rawdb.ReadSnapshotGenerator(diskdb)
+	disk := newDiskLayer(diskdb, triedb, root, marker)
+	snap.layers[root] = disk
+
+	if marker != nil {
+		log.Info("Resuming snapshot generation", "root", root, "at", common.BytesToHash(marker))
+		go disk.generate()
+	}
+	return snap, nil
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if l, ok := t.layers[root]; ok {
+		return l
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(
+	root common.Hash,
+	parentRoot common.Hash,
+	destructs map[common.Hash]struct{},
+	accounts map[common.Hash][]byte,
+	storage map[common.Hash]map[common.Hash][]byte,
+) error {
+	if root == parentRoot {
+		return errors.New("snapshot cycle")
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+
+	t.layers[root] = newDiffLayer(parent, root, destructs, accounts, storage)
+	diffLayersGauge.Update(int64(len(t.layers) - 1))
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the disk layer. All layers not descending from
+// the new disk layer are dropped.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	head, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+
+	// Collect the diff layers from the head down to the disk one
+	var chain []*diffLayer
+	for l := head; l != nil; l = l.Parent() {
+		if diff, ok := l.(*diffLayer); ok {
+			chain = append(chain, diff)
+		}
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+
+	// Flatten the excess layers into the disk one, oldest first
+	disk := t.diskLayer()
+	if disk == nil {
+		return errors.New("snapshot disk layer missing")
+	}
+	marker := disk.stopGeneration()
+
+	for i := len(chain) - 1; i >= layers; i-- {
+		disk = disk.merge(chain[i], marker)
+	}
+	if layers > 0 {
+		chain[layers-1].setParent(disk)
+	}
+
+	if marker != nil {
+		go disk.generate()
+	}
+
+	// Drop all the layers not descending from the new disk layer
+	for hash, l := range t.layers {
+		if !descendsFrom(l, disk) {
+			l.markStale()
+			delete(t.layers, hash)
+		}
+	}
+	t.layers[disk.root] = disk
+	diffLayersGauge.Update(int64(len(t.layers) - 1))
+	return nil
+}
+
+// Discard drops the layer of the given root together with all of its
+// descendants, e.g. the layers of blocks removed by a chain reorganisation.
+// The disk layer is never discarded.
+func (t *Tree) Discard(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	discarded, ok := t.layers[root].(*diffLayer)
+	if !ok {
+		return
+	}
+
+	for hash, l := range t.layers {
+		if descendsFrom(l, discarded) {
+			l.markStale()
+			delete(t.layers, hash)
+		}
+	}
+	diffLayersGauge.Update(int64(len(t.layers) - 1))
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if disk := t.diskLayer(); disk != nil {
+		disk.stopGeneration()
+	}
+	for _, l := range t.layers {
+		l.markStale()
+	}
+
+	wipeSnapshot(t.diskdb)
+
+	disk := newDiskLayer(t.diskdb, t.triedb, root, []byte{})
+	rawdb.WriteSnapshotGenerator(t.diskdb, disk.genMarker)
+	rawdb.WriteSnapshotRoot(t.diskdb, root)
+
+	t.layers = map[common.Hash]layer{root: disk}
+	diffLayersGauge.Update(0)
+
+	log.Info("Rebuilding state snapshot", "root", root)
+	go disk.generate()
+}
+
+// Stop flattens all the diff layers up to the given root into the disk layer,
+// so that the snapshot can be reused on the next startup, and terminates the
+// background generation.
+func (t *Tree) Stop(root common.Hash) {
+	if err := t.Cap(root, 0); err != nil {
+		log.Warn("Failed to persist snapshot", "root", root, "err", err)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if disk := t.diskLayer(); disk != nil {
+		disk.stopGeneration()
+	}
+}
+
+// WaitGeneration blocks until the background generation of the snapshot is
+// finished, or stopped due to an error.
+func (t *Tree) WaitGeneration() {
+	for {
+		t.lock.RLock()
+		disk := t.diskLayer()
+		t.lock.RUnlock()
+
+		if disk == nil {
+			return
+		}
+		<-disk.genDone
+
+		// The generation continues on top of a new disk layer after capping
+		if !disk.Stale() {
+			return
+		}
+	}
+}
+
+// diskLayer returns the disk layer of the tree. The lock must be held.
+func (t *Tree) diskLayer() *diskLayer {
+	for _, l := range t.layers {
+		for ; l != nil; l = l.Parent() {
+			if disk, ok := l.(*diskLayer); ok {
+				return disk
+			}
+		}
+	}
+	return nil
+}
+
+// descendsFrom checks whether the layer is the given base or one of its
+// descendants.
+func descendsFrom(l layer, base layer) bool {
+	for ; l != nil; l = l.Parent() {
+		if l == base {
+			return true
+		}
+	}
+	return false
+}
+
+// wipeSnapshot removes all the persisted snapshot data.
+func wipeSnapshot(db ethdb.Database) {
+	rawdb.DeleteSnapshotRoot(db)
+
+	deletePrefix(db, rawdb.SnapshotAccountPrefix, accountSnapshotKeyLength)
+	deletePrefix(db, rawdb.SnapshotStoragePrefix, storageSnapshotKeyLength)
+}
+
+// deletePrefix removes all the entries of the given key length under the
+// prefix.
+func deletePrefix(db ethdb.Database, prefix []byte, keyLength int) {
+	it := db.(iteratee).NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		if len(it.Key()) != keyLength {
+			continue
+		}
+		batch.Delete(it.Key())
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to wipe snapshot entries", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to wipe snapshot entries", "err", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/rlp"
+	"range/core/gen3/trie"
+)
+
+var (
+	testAccountA = common.HexToHash("0x0a")
+	testAccountB = common.HexToHash("0x0b")
+	testSlot1    = common.HexToHash("0x01")
+	testSlot2    = common.HexToHash("0x02")
+)
+
+func encodeAccount(nonce uint64, root common.Hash) []byte {
+	data, _ := rlp.EncodeToBytes(&Account{
+		Nonce:    nonce,
+		Balance:  big.NewInt(1),
+		Root:     root,
+		CodeHash: crypto.Keccak256(nil),
+	})
+	return data
+}
+
+func encodeSlot(value byte) []byte {
+	data, _ := rlp.EncodeToBytes([]byte{value})
+	return data
+}
+
+// makeTestState persists a state with one contract and one plain account.
+func makeTestState(t *testing.T, db ethdb.Database) (*trie.Database, common.Hash) {
+	triedb := trie.NewDatabase(db)
+
+	storage, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	storage.Update(testSlot1[:], encodeSlot(1))
+	storage.Update(testSlot2[:], encodeSlot(2))
+	storageRoot, err := storage.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit storage: %v", err)
+	}
+
+	accounts, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	accounts.Update(testAccountA[:], encodeAccount(1, storageRoot))
+	accounts.Update(testAccountB[:], encodeAccount(2, emptyRoot))
+	root, err := accounts.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit accounts: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return triedb, root
+}
+
+func hashKey(key common.Hash) common.Hash {
+	return crypto.Keccak256Hash(key[:])
+}
+
+func newTestTree(t *testing.T) (*Tree, ethdb.Database, common.Hash) {
+	db := ethdb.NewMemDatabase()
+	triedb, root := makeTestState(t, db)
+
+	tree, err := New(db, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	<-tree.diskLayer().genDone
+
+	return tree, db, root
+}
+
+func checkAccount(t *testing.T, snap Snapshot, account common.Hash, want []byte) {
+	t.Helper()
+
+	data, err := snap.AccountRLP(hashKey(account))
+	if err != nil {
+		t.Fatalf("account %x: failed to read: %v", account, err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("account %x: mismatch: have %x, want %x", account, data, want)
+	}
+}
+
+func checkStorage(t *testing.T, snap Snapshot, account, slot common.Hash, want []byte) {
+	t.Helper()
+
+	data, err := snap.Storage(hashKey(account), hashKey(slot))
+	if err != nil {
+		t.Fatalf("slot %x/%x: failed to read: %v", account, slot, err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("slot %x/%x: mismatch: have %x, want %x", account, slot, data, want)
+	}
+}
+
+// Tests that the snapshot generated from the tries matches their content.
+func TestSnapshotGeneration(t *testing.T) {
+	tree, db, root := newTestTree(t)
+
+	snap := tree.Snapshot(root)
+	if snap == nil {
+		t.Fatalf("snapshot missing for the root")
+	}
+	data, err := snap.AccountRLP(hashKey(testAccountA))
+	if err != nil {
+		t.Fatalf("failed to read account: %v", err)
+	}
+	var acc Account
+	if err := rlp.DecodeBytes(data, &acc); err != nil || acc.Nonce != 1 || acc.Root == emptyRoot {
+		t.Fatalf("account A not generated: %x", data)
+	}
+	checkAccount(t, snap, testAccountB, encodeAccount(2, emptyRoot))
+	checkStorage(t, snap, testAccountA, testSlot1, encodeSlot(1))
+	checkStorage(t, snap, testAccountA, testSlot2, encodeSlot(2))
+	checkStorage(t, snap, testAccountB, testSlot1, nil)
+
+	if rawdb.ReadSnapshotRoot(db) != root {
+		t.Fatalf("snapshot root not persisted")
+	}
+	if marker := rawdb.ReadSnapshotGenerator(db); marker != nil {
+		t.Fatalf("generator marker left: %x", marker)
+	}
+}
+
+// Tests that diff layers shadow their parents, that destructed accounts do
+// not leak their old storage and that capping flattens into the disk layer.
+func TestSnapshotDiffLayers(t *testing.T) {
+	tree, db, root := newTestTree(t)
+
+	// Recreate account A with a fresh storage and modify account B
+	root1 := common.HexToHash("0x01")
+	err := tree.Update(root1, root,
+		map[common.Hash]struct{}{hashKey(testAccountA): {}},
+		map[common.Hash][]byte{
+			hashKey(testAccountA): encodeAccount(3, emptyRoot),
+			hashKey(testAccountB): encodeAccount(4, emptyRoot),
+		},
+		map[common.Hash]map[common.Hash][]byte{
+			hashKey(testAccountA): {hashKey(testSlot2): encodeSlot(5)},
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	// Delete account B
+	root2 := common.HexToHash("0x02")
+	err = tree.Update(root2, root1,
+		map[common.Hash]struct{}{hashKey(testAccountB): {}},
+		map[common.Hash][]byte{},
+		map[common.Hash]map[common.Hash][]byte{},
+	)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := tree.Update(root2, common.HexToHash("0xff"), nil, nil, nil); err != nil {
+		t.Fatalf("known layer should be ignored: %v", err)
+	}
+	if err := tree.Update(common.HexToHash("0x03"), common.HexToHash("0xff"), nil, nil, nil); err == nil {
+		t.Fatalf("unknown parent accepted")
+	}
+
+	checks := func(base, snap1, snap2 Snapshot) {
+		t.Helper()
+
+		if base != nil {
+			checkAccount(t, base, testAccountB, encodeAccount(2, emptyRoot))
+			checkStorage(t, base, testAccountA, testSlot1, encodeSlot(1))
+		}
+		checkAccount(t, snap1, testAccountA, encodeAccount(3, emptyRoot))
+		checkAccount(t, snap1, testAccountB, encodeAccount(4, emptyRoot))
+		checkStorage(t, snap1, testAccountA, testSlot1, nil)
+		checkStorage(t, snap1, testAccountA, testSlot2, encodeSlot(5))
+
+		checkAccount(t, snap2, testAccountA, encodeAccount(3, emptyRoot))
+		checkAccount(t, snap2, testAccountB, nil)
+		checkStorage(t, snap2, testAccountA, testSlot2, encodeSlot(5))
+	}
+	base, snap1, snap2 := tree.Snapshot(root), tree.Snapshot(root1), tree.Snapshot(root2)
+	checks(base, snap1, snap2)
+
+	// Flatten the first diff into the disk layer
+	if err := tree.Cap(root2, 1); err != nil {
+		t.Fatalf("failed to cap: %v", err)
+	}
+	if tree.Snapshot(root) != nil {
+		t.Fatalf("flattened base layer still available")
+	}
+	if _, err := base.AccountRLP(hashKey(testAccountB)); err != ErrSnapshotStale {
+		t.Fatalf("stale base layer: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if rawdb.ReadSnapshotRoot(db) != root1 {
+		t.Fatalf("snapshot root not updated")
+	}
+	if data := rawdb.ReadStorageSnapshot(db, hashKey(testAccountA), hashKey(testSlot1)); data != nil {
+		t.Fatalf("destructed storage not wiped: %x", data)
+	}
+	checks(nil, tree.Snapshot(root1), tree.Snapshot(root2))
+
+	// Discard the remaining diff, like a reorg would do
+	tree.Discard(root2)
+	if tree.Snapshot(root2) != nil {
+		t.Fatalf("discarded layer still available")
+	}
+	tree.Discard(root1)
+	if tree.Snapshot(root1) == nil {
+		t.Fatalf("disk layer discarded")
+	}
+}
+
+// Tests that the persisted snapshot is reused on restart, and is rebuilt
+// if it does not match the head.
+func TestSnapshotRestart(t *testing.T) {
+	tree, db, root := newTestTree(t)
+
+	root1 := common.HexToHash("0x01")
+	err := tree.Update(root1, root,
+		map[common.Hash]struct{}{},
+		map[common.Hash][]byte{hashKey(testAccountB): encodeAccount(4, emptyRoot)},
+		map[common.Hash]map[common.Hash][]byte{},
+	)
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	tree.Stop(root1)
+
+	restored, err := New(db, tree.triedb, root1)
+	if err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+	checkAccount(t, restored.Snapshot(root1), testAccountB, encodeAccount(4, emptyRoot))
+
+	// A mismatching root triggers the regeneration from the tries
+	rebuilt, err := New(db, tree.triedb, root)
+	if err != nil {
+		t.Fatalf("failed to rebuild snapshot: %v", err)
+	}
+	<-rebuilt.diskLayer().genDone
+	checkAccount(t, rebuilt.Snapshot(root), testAccountB, encodeAccount(2, emptyRoot))
+}
+
+// Tests that the resumed generation drops the stale storage of the accounts
+// left half-generated by an interrupted run.
+func TestSnapshotResumeWipesStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	triedb, root := makeTestState(t, db)
+
+	stale := common.HexToHash("0x03")
+	rawdb.WriteSnapshotRoot(db, root)
+	rawdb.WriteSnapshotGenerator(db, common.Hash{}.Bytes())
+	rawdb.WriteStorageSnapshot(db, hashKey(testAccountA), hashKey(stale), encodeSlot(3))
+	rawdb.WriteStorageSnapshot(db, hashKey(testAccountB), hashKey(stale), encodeSlot(3))
+
+	tree, err := New(db, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to resume snapshot: %v", err)
+	}
+	<-tree.diskLayer().genDone
+
+	snap := tree.Snapshot(root)
+	checkStorage(t, snap, testAccountA, testSlot1, encodeSlot(1))
+	checkStorage(t, snap, testAccountA, stale, nil)
+	checkStorage(t, snap, testAccountB, stale, nil)
+}
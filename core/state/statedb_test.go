@@ -31,6 +31,7 @@ import (
 	check "gopkg.in/check.v1"
 
 	"range/core/gen3/common"
+	"range/core/gen3/core/state/snapshot"
 	"range/core/gen3/core/types"
 	"range/core/gen3/ethdb"
 )
@@ -435,3 +436,67 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that the state read through the flat snapshot matches the tries,
+// including accounts destructed and recreated within a block.
+func TestFlatSnapshotReads(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	var (
+		addrA = common.BytesToAddress([]byte{0x0a})
+		addrB = common.BytesToAddress([]byte{0x0b})
+		addrC = common.BytesToAddress([]byte{0x0c})
+		slot1 = common.BytesToHash([]byte{0x01})
+		slot2 = common.BytesToHash([]byte{0x02})
+	)
+
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(addrA, big.NewInt(1))
+	state.SetState(addrA, slot1, common.BytesToHash([]byte{0x11}))
+	state.SetBalance(addrB, big.NewInt(2))
+	state.SetState(addrB, slot1, common.BytesToHash([]byte{0x21}))
+	root, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+
+	snaps, err := snapshot.New(db, sdb.TrieDB(), root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	snaps.WaitGeneration()
+
+	snapdb := WithSnapshots(sdb, snaps)
+
+	// Recreate A with a fresh storage, modify B and create C
+	state, _ = New(root, snapdb)
+	state.Suicide(addrA)
+	state.Finalise(true)
+	state.CreateAccount(addrA)
+	state.SetBalance(addrA, big.NewInt(3))
+	state.SetState(addrA, slot2, common.BytesToHash([]byte{0x12}))
+	state.SetState(addrB, slot1, common.Hash{})
+	state.SetState(addrB, slot2, common.BytesToHash([]byte{0x22}))
+	state.SetBalance(addrC, big.NewInt(4))
+	root, _ = state.Commit(false)
+
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("snapshot layer not added on commit")
+	}
+	want, _ := New(root, sdb)
+	have, _ := New(root, snapdb)
+	if have.snap == nil {
+		t.Fatalf("snapshot not used")
+	}
+	for _, addr := range []common.Address{addrA, addrB, addrC} {
+		if h, w := have.GetBalance(addr), want.GetBalance(addr); h.Cmp(w) != 0 {
+			t.Errorf("%x: balance mismatch: have %v, want %v", addr, h, w)
+		}
+		for _, slot := range []common.Hash{slot1, slot2} {
+			if h, w := have.GetState(addr, slot), want.GetState(addr, slot); h != w {
+				t.Errorf("%x/%x: state mismatch: have %x, want %x", addr, slot, h, w)
+			}
+		}
+	}
+}
@@ -410,26 +410,35 @@ func newTxPricedList(all *txLookup) *txPricedList {
 	}
 }
 
-// Put inserts a new transaction into the heap.
+// Put inserts a new transaction into the heap. Only the normal lane is priced,
+// the transactions of the other lanes are ignored.
 func (l *txPricedList) Put(tx *types.Transaction) {
+	if TxLaneOf(tx) != TxLaneNormal {
+		return
+	}
 	heap.Push(l.items, tx)
 }
 
 // Removed notifies the prices transaction list that an old transaction dropped
 // from the pool. The list will just keep a counter of stale objects and update
 // the heap if a large enough ratio of transactions go stale.
-func (l *txPricedList) Removed() {
+func (l *txPricedList) Removed(tx *types.Transaction) {
+	if TxLaneOf(tx) != TxLaneNormal {
+		return
+	}
 	// Bump the stale counter, but exit if still too low (< 25%)
 	l.stales++
 	if l.stales <= len(*l.items)/4 {
 		return
 	}
 	// Seems we've reached a critical number of stale transactions, reheap
-	reheap := make(priceHeap, 0, l.all.Count())
+	reheap := make(priceHeap, 0, l.all.LaneCount(TxLaneNormal))
 
 	l.stales, l.items = 0, &reheap
 	l.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
-		*l.items = append(*l.items, tx)
+		if TxLaneOf(tx) == TxLaneNormal {
+			*l.items = append(*l.items, tx)
+		}
 		return true
 	})
 	heap.Init(l.items)
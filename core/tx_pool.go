@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math"
@@ -134,6 +135,10 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal         string        // Journal of remote transactions to survive node restarts (disabled if empty)
+	RemoteJournalSize     uint64        // Maximum number of remote transactions to journal
+	RemoteJournalLifetime time.Duration // Maximum age of the journaled remote transactions on load
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -142,6 +147,8 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
+	ConsensusSlots uint64 // Number of slots reserved for the zero-fee consensus transactions
+
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 
 	// Range
@@ -154,6 +161,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalSize:     4096,
+	RemoteJournalLifetime: time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -162,6 +172,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
+	ConsensusSlots: 1024,
+
 	Lifetime:   3 * time.Hour,
 	Protection: "protection.rlp",
 }
@@ -174,6 +186,14 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournalSize < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal size", "provided", conf.RemoteJournalSize, "updated", DefaultTxPoolConfig.RemoteJournalSize)
+		conf.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+	}
+	if conf.RemoteJournalLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal lifetime", "provided", conf.RemoteJournalLifetime, "updated", DefaultTxPoolConfig.RemoteJournalLifetime)
+		conf.RemoteJournalLifetime = DefaultTxPoolConfig.RemoteJournalLifetime
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -198,6 +218,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool global queue", "provided", conf.GlobalQueue, "updated", DefaultTxPoolConfig.GlobalQueue)
 		conf.GlobalQueue = DefaultTxPoolConfig.GlobalQueue
 	}
+	if conf.ConsensusSlots < 1 {
+		log.Warn("Sanitizing invalid txpool consensus slots", "provided", conf.ConsensusSlots, "updated", DefaultTxPoolConfig.ConsensusSlots)
+		conf.ConsensusSlots = DefaultTxPoolConfig.ConsensusSlots
+	}
 	if conf.Lifetime < 1 {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
@@ -228,14 +252,17 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals        *accountSet    // Set of local transaction to exempt from eviction rules
+	journal       *txJournal     // Journal of local transaction to back up to disk
+	remoteJournal *remoteJournal // Snapshot of the remote transactions to back up to disk
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
-	priced  *txPricedList                // All transactions sorted by price
+	priced  *txPricedList                // Normal lane transactions sorted by price
+
+	evictions txEvictions // Most recent evictions, for inspection
 
 	wg sync.WaitGroup // for shutdown sync
 
@@ -243,6 +270,7 @@ type TxPool struct {
 	preBlacklist *preBlacklist
 
 	homestead bool
+	istanbul  bool // Fork indicator whether the next block is in the istanbul stage
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -260,7 +288,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
-		all:         newTxLookup(),
+		all:         newTxLookup(int(config.AccountSlots)),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 
@@ -292,6 +320,14 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote journaling is enabled, load from disk after the locals
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newRemoteJournal(config.RemoteJournal, config.RemoteJournalSize, config.RemoteJournalLifetime)
+
+		if err := pool.remoteJournal.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -347,6 +383,7 @@ func (pool *TxPool) loop() {
 			pool.mu.RLock()
 			pending, queued := pool.stats()
 			stales := pool.priced.stales
+			pool.updateLaneMetrics()
 			pool.mu.RUnlock()
 
 			if pending != prevPending || queued != prevQueued || stales != prevStales {
@@ -370,14 +407,14 @@ func (pool *TxPool) loop() {
 				// Stalled MN zero-fees
 				if age > zeroFeesMNTimeoutInterval && IsMasternodeCall(txs[0]) {
 					log.Debug("Cleaning up stalled MN xfers", "addr", addr)
-					pool.removeBySenderLocked(addr)
+					pool.evictSender(addr)
 					continue
 				}
 
 				// Stalled zero-fees
 				if age > zeroFeesTimeoutInterval && IsValidZeroFee(txs[0]) {
 					log.Debug("Cleaning up stalled Zero-Fee xfers", "addr", addr)
-					pool.removeBySenderLocked(addr)
+					pool.evictSender(addr)
 					continue
 				}
 
@@ -385,6 +422,7 @@ func (pool *TxPool) loop() {
 				if age > lifetime {
 					log.Warn("Cleaning up stalled general xfers", "addr", addr)
 					for _, tx := range txs {
+						pool.evicted(tx, EvictStale)
 						pool.removeTx(tx.Hash(), true)
 					}
 				}
@@ -399,6 +437,7 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > lifetime {
 					log.Debug("Cleaning up stalled general queue", "addr", addr)
 					for _, tx := range pool.queue[addr].Flatten() {
+						pool.evicted(tx, EvictStale)
 						pool.removeTx(tx.Hash(), true)
 					}
 				}
@@ -418,6 +457,8 @@ func (pool *TxPool) loop() {
 				log.Debug("pool persistenceWriter failed", "err", err)
 			}
 			pool.mu.Unlock()
+
+			pool.rotateRemotes()
 		}
 	}
 }
@@ -494,6 +535,9 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Update the fork indicators of the next pending block
+	pool.istanbul = pool.chainconfig.IsIstanbul(new(big.Int).Add(newHead.Number, big.NewInt(1)))
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -533,6 +577,7 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	pool.rotateRemotes()
 	log.Info("Transaction pool stopped")
 }
 
@@ -558,11 +603,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
-		// Do not drop eligible zero-fee
-		if IsValidZeroFee(tx) {
-			continue
-		}
-
+		pool.evicted(tx, EvictUnderpriced)
 		pool.removeTx(tx.Hash(), false)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -675,11 +716,11 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Range: Treat properly created zero-fee local in this context
-	is_zerofee := IsValidZeroFee(tx)
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price,
+	// only the normal lane is priced
+	lane := TxLaneOf(tx)
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 && !is_zerofee {
+	if !local && lane == TxLaneNormal && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -691,26 +732,14 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead, pool.istanbul)
 	if err != nil {
 		return err
 	}
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
-	// Range: protect against zero-fee DoS
-	if is_zerofee {
-		err = pool.zfProtector.checkDoS(pool, tx)
-		if err != nil {
-			return err
-		}
-	}
-	// Range: preliminary blacklist handling
-	err = pool.preBlacklist.processTx(pool, tx)
-	if err != nil {
-		return err
-	}
-	return nil
+	return pool.validateLane(tx, lane)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for
@@ -722,7 +751,8 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // whitelisted, preventing any associated transaction from being dropped out of
 // the pool due to pricing constraints.
 func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
-	local = local && !IsValidZeroFee(tx)
+	lane := TxLaneOf(tx)
+	local = local && lane == TxLaneNormal
 
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
@@ -736,24 +766,15 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
-	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if !local && pool.priced.Underpriced(tx, pool.locals) && !IsValidZeroFee(tx) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			return false, ErrUnderpriced
-		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
-		for _, tx := range drop {
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash(), false)
+	// If the lane of the transaction is full, make room or discard it. A
+	// replacement takes the place of the replaced one in its lane.
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	if old := pool.pooledTx(from, tx.Nonce()); old == nil || TxLaneOf(old) != lane {
+		if err := pool.makeRoom(tx, from, lane, local); err != nil {
+			return false, err
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -763,11 +784,12 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 		// New transaction is better, replace old one
 		if old != nil {
+			pool.evicted(old, EvictReplaced)
 			pool.all.Remove(old.Hash())
-			pool.priced.Removed()
+			pool.priced.Removed(old)
 			pendingReplaceCounter.Inc(1)
 		}
-		pool.all.Add(tx)
+		pool.all.Add(tx, from)
 		pool.priced.Put(tx)
 		pool.journalTx(from, tx)
 
@@ -813,12 +835,13 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
+		pool.evicted(old, EvictReplaced)
 		pool.all.Remove(old.Hash())
-		pool.priced.Removed()
+		pool.priced.Removed(old)
 		queuedReplaceCounter.Inc(1)
 	}
 	if pool.all.Get(hash) == nil {
-		pool.all.Add(tx)
+		pool.all.Add(tx, from)
 		pool.priced.Put(tx)
 	}
 	return old != nil, nil
@@ -855,21 +878,22 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	if !inserted {
 		// An older transaction was better, discard this
 		pool.all.Remove(hash)
-		pool.priced.Removed()
+		pool.priced.Removed(tx)
 
 		pendingDiscardCounter.Inc(1)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
+		pool.evicted(old, EvictReplaced)
 		pool.all.Remove(old.Hash())
-		pool.priced.Removed()
+		pool.priced.Removed(old)
 
 		pendingReplaceCounter.Inc(1)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
-		pool.all.Add(tx)
+		pool.all.Add(tx, addr)
 		pool.priced.Put(tx)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
@@ -998,7 +1022,7 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 	// Remove it from the list of known transactions
 	pool.all.Remove(hash)
 	if outofbound {
-		pool.priced.Removed()
+		pool.priced.Removed(tx)
 	}
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
@@ -1053,15 +1077,16 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
 			pool.all.Remove(hash)
-			pool.priced.Removed()
+			pool.priced.Removed(tx)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
+			pool.evicted(tx, EvictNoFunds)
 			pool.all.Remove(hash)
-			pool.priced.Removed()
+			pool.priced.Removed(tx)
 			queuedNofundsCounter.Inc(1)
 		}
 		// Gather all executable transactions and promote them
@@ -1076,8 +1101,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		if !pool.locals.contains(addr) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
+				pool.evicted(tx, EvictAccountQueue)
 				pool.all.Remove(hash)
-				pool.priced.Removed()
+				pool.priced.Removed(tx)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -1125,8 +1151,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						for _, tx := range list.Cap(list.Len() - 1) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
+							pool.evicted(tx, EvictFairness)
 							pool.all.Remove(hash)
-							pool.priced.Removed()
+							pool.priced.Removed(tx)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1147,8 +1174,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 					for _, tx := range list.Cap(list.Len() - 1) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
+						pool.evicted(tx, EvictFairness)
 						pool.all.Remove(hash)
-						pool.priced.Removed()
+						pool.priced.Removed(tx)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1187,6 +1215,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
+					pool.evicted(tx, EvictQueueFull)
 					pool.removeTx(tx.Hash(), true)
 				}
 				drop -= size
@@ -1196,6 +1225,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				pool.evicted(txs[i], EvictQueueFull)
 				pool.removeTx(txs[i].Hash(), true)
 				drop--
 				queuedRateLimitCounter.Inc(1)
@@ -1217,15 +1247,16 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
 			pool.all.Remove(hash)
-			pool.priced.Removed()
+			pool.priced.Removed(tx)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
+			pool.evicted(tx, EvictNoFunds)
 			pool.all.Remove(hash)
-			pool.priced.Removed()
+			pool.priced.Removed(tx)
 			pendingNofundsCounter.Inc(1)
 		}
 		for _, tx := range invalids {
@@ -1322,14 +1353,24 @@ func (as *accountSet) flatten() []common.Address {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all       map[common.Hash]*types.Transaction
+	consensus map[common.Hash]time.Time      // Arrival of the consensus lane transactions
+	senders   map[common.Hash]common.Address // Senders of the normal lane transactions
+	counts    map[common.Address]int         // Normal lane transactions of each sender
+	heavy     map[common.Address]struct{}    // Senders holding more than their share
+	share     int                            // Fair share of a sender in the normal lane
+	lock      sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
-func newTxLookup() *txLookup {
+func newTxLookup(share int) *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:       make(map[common.Hash]*types.Transaction),
+		consensus: make(map[common.Hash]time.Time),
+		senders:   make(map[common.Hash]common.Address),
+		counts:    make(map[common.Address]int),
+		heavy:     make(map[common.Address]struct{}),
+		share:     share,
 	}
 }
 
@@ -1361,12 +1402,78 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
-// Add adds a transaction to the lookup.
-func (t *txLookup) Add(tx *types.Transaction) {
+// LaneCount returns the current number of items of a pool lane in the lookup.
+func (t *txLookup) LaneCount(lane TxLane) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if lane == TxLaneConsensus {
+		return len(t.consensus)
+	}
+	return len(t.all) - len(t.consensus)
+}
+
+// OldestInLane returns the earliest added transaction of a pool lane. Only the
+// consensus lane is tracked by arrival.
+func (t *txLookup) OldestInLane(lane TxLane) *types.Transaction {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if lane != TxLaneConsensus {
+		return nil
+	}
+	var (
+		oldest  *types.Transaction
+		arrival time.Time
+	)
+	for hash, added := range t.consensus {
+		if oldest == nil || added.Before(arrival) {
+			oldest, arrival = t.all[hash], added
+		}
+	}
+	return oldest
+}
+
+// Heaviest returns the sender holding the most normal lane transactions over
+// its share, along with their number. The excluded senders are skipped.
+func (t *txLookup) Heaviest(exclude func(common.Address) bool) (common.Address, int) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var (
+		heavy common.Address
+		max   int
+	)
+	for addr := range t.heavy {
+		if exclude(addr) {
+			continue
+		}
+		count := t.counts[addr]
+		if count > max || (count == max && bytes.Compare(addr[:], heavy[:]) < 0) {
+			heavy, max = addr, count
+		}
+	}
+	return heavy, max
+}
+
+// Add adds a transaction of the given sender to the lookup.
+func (t *txLookup) Add(tx *types.Transaction, from common.Address) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	if _, ok := t.all[hash]; ok {
+		return
+	}
+	t.all[hash] = tx
+	if TxLaneOf(tx) == TxLaneConsensus {
+		t.consensus[hash] = time.Now()
+		return
+	}
+	t.senders[hash] = from
+	if t.counts[from]++; t.counts[from] > t.share {
+		t.heavy[from] = struct{}{}
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1375,4 +1482,17 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 
 	delete(t.all, hash)
+	delete(t.consensus, hash)
+
+	from, ok := t.senders[hash]
+	if !ok {
+		return
+	}
+	delete(t.senders, hash)
+	if t.counts[from]--; t.counts[from] <= t.share {
+		delete(t.heavy, from)
+	}
+	if t.counts[from] == 0 {
+		delete(t.counts, from)
+	}
 }
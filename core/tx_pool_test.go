@@ -117,8 +117,9 @@ func validateTxPoolInternals(pool *TxPool) error {
 	if total := pool.all.Count(); total != pending+queued {
 		return fmt.Errorf("total transaction count %d != %d pending + %d queued", total, pending, queued)
 	}
-	if priced := pool.priced.items.Len() - pool.priced.stales; priced != pending+queued {
-		return fmt.Errorf("total priced transaction count %d != %d pending + %d queued", priced, pending, queued)
+	consensus := pool.all.LaneCount(TxLaneConsensus)
+	if priced := pool.priced.items.Len() - pool.priced.stales; priced != pending+queued-consensus {
+		return fmt.Errorf("total priced transaction count %d != %d pending + %d queued - %d consensus", priced, pending, queued, consensus)
 	}
 	// Ensure the next nonce to assign is the correct one
 	for addr, txs := range pool.pending {
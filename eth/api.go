@@ -276,6 +276,105 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ExportTxPool exports the transactions of the pool into a local file as an RLP
+// stream, and returns their number.
+func (api *PrivateAdminAPI) ExportTxPool(file string) (hexutil.Uint, error) {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	var (
+		writer io.Writer = out
+		zipper *gzip.Writer
+	)
+	if strings.HasSuffix(file, ".gz") {
+		zipper = gzip.NewWriter(writer)
+		writer = zipper
+	}
+	exported, err := api.eth.TxPool().Export(writer)
+	if err != nil {
+		return 0, err
+	}
+	// Closing the gzip stream writes its last block and checksum
+	if zipper != nil {
+		if err := zipper.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return hexutil.Uint(exported), out.Close()
+}
+
+// ImportTxPool imports the transactions of a local file, as exported by
+// ExportTxPool, into the pool. They are revalidated as remote transactions, the
+// number of the imported and dropped ones is returned.
+func (api *PrivateAdminAPI) ImportTxPool(file string) (map[string]hexutil.Uint, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	imported, dropped, err := api.eth.TxPool().Import(reader)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]hexutil.Uint{
+		"imported": hexutil.Uint(imported),
+		"dropped":  hexutil.Uint(dropped),
+	}, nil
+}
+
+// Reorgs returns the journal of the recent chain reorgs, oldest first,
+// including the ones refused for going beyond the finality depth.
+func (api *PrivateAdminAPI) Reorgs() []core.ReorgEntry {
+	return api.eth.BlockChain().Reorgs()
+}
+
+// ApproveReorg lets the chain of the given block replace the canonical one,
+// even if the reorg goes beyond the finality depth.
+func (api *PrivateAdminAPI) ApproveReorg(hash common.Hash) (bool, error) {
+	if err := api.eth.BlockChain().ApproveReorg(hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ChainReorgs creates a subscription that fires for every journaled reorg,
+// allowing to halt the processing of deposits on deep reorgs.
+func (api *PrivateAdminAPI) ChainReorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent)
+		reorgsSub := api.eth.BlockChain().SubscribeChainReorgEvent(reorgs)
+		defer reorgsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, ev.ReorgEntry)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -379,7 +478,7 @@ type storageEntry struct {
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex, 0)
+	_, _, statedb, err := api.computeTxEnv(ctx, blockHash, txIndex, 0)
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
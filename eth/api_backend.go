@@ -19,6 +19,7 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"range/core/gen3/common/math"
 	"range/core/gen3/core"
 	"range/core/gen3/core/bloombits"
+	"range/core/gen3/core/rawdb"
 	"range/core/gen3/core/state"
 	"range/core/gen3/core/types"
 	"range/core/gen3/core/vm"
@@ -108,6 +110,26 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return stateDb, header, err
 }
 
+// StateAndHeaderByNumberOrHash returns the state and the header of the block
+// selected either by number or by hash (EIP-1898).
+func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.eth.blockchain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, nil, errors.New("header for hash not found")
+		}
+		if blockNrOrHash.RequireCanonical && rawdb.ReadCanonicalHash(b.ChainDb(), header.Number.Uint64()) != hash {
+			return nil, nil, errors.New("hash is not currently canonical")
+		}
+		stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+		return stateDb, header, err
+	}
+	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
 func (b *EthAPIBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
 	return b.eth.blockchain.GetBlockByHash(hash), nil
 }
@@ -192,6 +214,14 @@ func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.TxPool().Content()
 }
 
+func (b *EthAPIBackend) TxPoolEvictions() []core.TxEviction {
+	return b.eth.TxPool().Evictions()
+}
+
+func (b *EthAPIBackend) IsPreBlacklisted(addr common.Address) bool {
+	return b.eth.TxPool().IsPreBlacklisted(addr)
+}
+
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -96,6 +96,11 @@ type Ethereum struct {
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
+	traceJobs *traceJobs // Batch tracing jobs, nil without a data directory
+
+	rangeAPIs     []rpc.API // Range APIs, shared by all their consumers
+	rangeAPIsOnce sync.Once
+
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
 
@@ -119,11 +124,25 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		config.MinerGasPrice = new(big.Int).Set(DefaultConfig.MinerGasPrice)
 	}
 	// Assemble the Ethereum object
-	chainDb, err := CreateDB(ctx, config, "chaindata")
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if !config.DatabaseFreezerEnabled && ctx.HasAncients("chaindata", config.DatabaseFreezer) {
+		log.Warn("Enabling the freezer of the existing ancient store")
+		config.DatabaseFreezerEnabled = true
+	}
+	if config.DatabaseFreezerEnabled {
+		chainDb, err = ctx.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/")
+	} else if chainDb, err = CreateDB(ctx, config, "chaindata"); err == nil {
+		if err = rawdb.CheckNoAncients(chainDb); err != nil {
+			chainDb.Close()
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.ConstantinopleOverride)
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.ConstantinopleOverride, config.IstanbulOverride)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
@@ -162,7 +181,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
 		}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieCleanLimit: config.TrieCleanCache, TrieDirtyLimit: config.TrieDirtyCache, TrieTimeLimit: config.TrieTimeout, TrieRapidLimit: config.TrieRapidTime}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieCleanLimit: config.TrieCleanCache, TrieDirtyLimit: config.TrieDirtyCache, TrieTimeLimit: config.TrieTimeout, TrieRapidLimit: config.TrieRapidTime, Snapshot: config.Snapshot, FreezerDepth: config.DatabaseFreezerDepth, FinalityDepth: config.FinalityDepth}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig, eth.shouldPreserve)
 	if err != nil {
@@ -179,6 +198,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = ctx.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	config.TxPool.Protection = ctx.ResolvePath(core.DefaultTxPoolConfig.Protection)
 
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
@@ -207,6 +229,14 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	eth.miner.SetEthAPIBackend(eth.APIBackend)
 	eth.miner.SetMinerAutocollateral(config.MinerAutocollateral)
 
+	traceJobsDir := config.TraceJobsDir
+	if traceJobsDir == "" {
+		traceJobsDir = "tracejobs"
+	}
+	if traceJobsDir = ctx.ResolvePath(traceJobsDir); traceJobsDir != "" {
+		eth.traceJobs = newTraceJobs(traceJobsDir, NewPrivateDebugAPI(eth.chainConfig, eth))
+	}
+
 	if energi, ok := eth.engine.(*energi.Range); ok {
 		energi.SetMinerCB(
 			func() []common.Address {
@@ -368,6 +398,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s.chainConfig, s),
+		}, {
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewPrivateTraceAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -377,48 +411,7 @@ func (s *Ethereum) APIs() []rpc.API {
 	}...)
 
 	// Append Range-specific APIs
-	apis = append(apis, []rpc.API{
-		{
-			Namespace: "energi",
-			Version:   "1.0",
-			Service:   energi_api.NewBlacklistAPI(s.APIBackend),
-			Public:    true,
-		},
-		{
-			Namespace: "energi",
-			Version:   "1.0",
-			Service:   energi_api.NewCheckpointAPI(s.APIBackend),
-			Public:    true,
-		},
-		{
-			Namespace: "admin",
-			Version:   "1.0",
-			Service:   energi_api.NewCheckpointAdminAPI(s.APIBackend),
-		},
-		{
-			Namespace: "energi",
-			Version:   "1.0",
-			Service:   energi_api.NewGovernanceAPI(s.APIBackend),
-			Public:    true,
-		},
-		{
-			Namespace: "energi",
-			Version:   "1.0",
-			Service:   energi_api.NewMigrationAPI(s.APIBackend),
-			Public:    true,
-		},
-		{
-			Namespace: "admin",
-			Version:   "1.0",
-			Service:   energi_api.NewMigrationAdminAPI(s.APIBackend),
-		},
-		{
-			Namespace: "masternode",
-			Version:   "1.0",
-			Service:   energi_api.NewMasternodeAPI(s.APIBackend),
-			Public:    true,
-		},
-	}...)
+	apis = append(apis, s.RangeAPIs()...)
 
 	// Rename a copy of eth to nrg
 	nrgAPIs := make([]rpc.API, 0, 4)
@@ -433,6 +426,57 @@ func (s *Ethereum) APIs() []rpc.API {
 	return append(apis, nrgAPIs...)
 }
 
+// RangeAPIs returns the Range-specific APIs. They are created once, so that
+// the other services reuse the instances registered with the RPC along with
+// their caches.
+func (s *Ethereum) RangeAPIs() []rpc.API {
+	s.rangeAPIsOnce.Do(func() {
+		s.rangeAPIs = []rpc.API{
+			{
+				Namespace: "energi",
+				Version:   "1.0",
+				Service:   energi_api.NewBlacklistAPI(s.APIBackend),
+				Public:    true,
+			},
+			{
+				Namespace: "energi",
+				Version:   "1.0",
+				Service:   energi_api.NewCheckpointAPI(s.APIBackend),
+				Public:    true,
+			},
+			{
+				Namespace: "admin",
+				Version:   "1.0",
+				Service:   energi_api.NewCheckpointAdminAPI(s.APIBackend),
+			},
+			{
+				Namespace: "energi",
+				Version:   "1.0",
+				Service:   energi_api.NewGovernanceAPI(s.APIBackend),
+				Public:    true,
+			},
+			{
+				Namespace: "energi",
+				Version:   "1.0",
+				Service:   energi_api.NewMigrationAPI(s.APIBackend),
+				Public:    true,
+			},
+			{
+				Namespace: "admin",
+				Version:   "1.0",
+				Service:   energi_api.NewMigrationAdminAPI(s.APIBackend),
+			},
+			{
+				Namespace: "masternode",
+				Version:   "1.0",
+				Service:   energi_api.NewMasternodeAPI(s.APIBackend),
+				Public:    true,
+			},
+		}
+	})
+	return s.rangeAPIs
+}
+
 func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
 	s.blockchain.ResetWithGenesisBlock(gb)
 }
@@ -650,12 +694,18 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.traceJobs != nil {
+		s.traceJobs.start()
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
+	if s.traceJobs != nil {
+		s.traceJobs.stop()
+	}
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
@@ -60,6 +60,8 @@ var DefaultConfig = Config{
 
 	MinerAutocollateral: 1,
 
+	DatabaseFreezerDepth: 10000,
+
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
@@ -103,13 +105,21 @@ type Config struct {
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
 	// Database options
-	SkipBcVersionCheck bool `toml:"-"`
-	DatabaseHandles    int  `toml:"-"`
-	DatabaseCache      int
-	TrieCleanCache     int
-	TrieDirtyCache     int
-	TrieTimeout        time.Duration
-	TrieRapidTime      time.Duration
+	SkipBcVersionCheck     bool `toml:"-"`
+	DatabaseHandles        int  `toml:"-"`
+	DatabaseCache          int
+	DatabaseFreezerEnabled bool
+	DatabaseFreezer        string
+	DatabaseFreezerDepth   uint64
+	TrieCleanCache         int
+	TrieDirtyCache         int
+	TrieTimeout            time.Duration
+	TrieRapidTime          time.Duration
+	Snapshot               bool
+
+	// Maximum number of canonical blocks a reorg may drop without the approval
+	// of the operator (0 = unlimited)
+	FinalityDepth uint64 `toml:",omitempty"`
 
 	// Mining-related options
 	Etherbase      common.Address `toml:",omitempty"`
@@ -153,8 +163,14 @@ type Config struct {
 	// Constantinople block override (TODO: remove after the fork)
 	ConstantinopleOverride *big.Int
 
+	// Istanbul block override, until the fork is scheduled in the bundled configs
+	IstanbulOverride *big.Int
+
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
+
+	// Directory of the batch tracing jobs ("tracejobs" in the data directory if empty)
+	TraceJobsDir string `toml:",omitempty"`
 }
 
 type configMarshaling struct {
@@ -106,6 +106,7 @@ type Downloader struct {
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
 
 	checkpoint uint64   // Checkpoint block number to enforce head against (e.g. fast sync)
+	cpHeader   *types.Header // Trusted checkpoint header to start from (checkpoint sync)
 	genesis    uint64   // Genesis block number to limit sync to (e.g. light client CHT)
 	queue      *queue   // Scheduler for selecting the hashes to download
 	peers      *peerSet // Set of active peers from which download can proceed
@@ -269,7 +270,7 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 	switch d.mode {
 	case FullSync:
 		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, CheckpointSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
 	case LightSync:
 		current = d.lightchain.CurrentHeader().Number.Uint64()
@@ -447,6 +448,16 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
+	// Start a checkpoint sync from the trusted checkpoint, unless there is
+	// nothing to gain from it anymore
+	if d.mode == CheckpointSync {
+		if d.cpHeader, err = d.fetchCheckpoint(p, height); err != nil {
+			return err
+		}
+		if d.cpHeader == nil {
+			d.mode = FullSync
+		}
+	}
 	origin, err := d.findAncestor(p, latest)
 	if err != nil {
 		return err
@@ -470,12 +481,24 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 			}
 		}
 	}
+	// Checkpoint sync pivots below the trusted checkpoint
+	if d.mode == CheckpointSync {
+		pivot = d.cpHeader.Number.Uint64() - checkpointStakeWindow
+		if pivot <= origin {
+			origin = pivot - 1
+		}
+	}
 	d.committed = 1
-	if d.mode == FastSync && pivot != 0 {
+	if (d.mode == FastSync || d.mode == CheckpointSync) && pivot != 0 {
 		d.committed = 0
 	}
-	// Initiate the sync using a concurrent header and content retrieval algorithm
-	d.queue.Prepare(origin+1, d.mode)
+	// Initiate the sync using a concurrent header and content retrieval algorithm,
+	// no content is retrieved below the pivot of a checkpoint sync
+	if d.mode == CheckpointSync {
+		d.queue.Prepare(pivot, d.mode)
+	} else {
+		d.queue.Prepare(origin+1, d.mode)
+	}
 	if d.syncInitHook != nil {
 		d.syncInitHook(origin, height)
 	}
@@ -488,6 +511,8 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	if d.mode == FastSync {
 		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
+	} else if d.mode == CheckpointSync {
+		fetchers = append(fetchers, func() error { return d.processCheckpointSyncContent(pivot) })
 	} else if d.mode == FullSync {
 		fetchers = append(fetchers, d.processFullSyncContent)
 	}
@@ -675,7 +700,7 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 	switch d.mode {
 	case FullSync:
 		localHeight = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, CheckpointSync:
 		localHeight = d.blockchain.CurrentFastBlock().NumberU64()
 	default:
 		localHeight = d.lightchain.CurrentHeader().Number.Uint64()
@@ -756,7 +781,7 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 				switch d.mode {
 				case FullSync:
 					known = d.blockchain.HasBlock(h, n)
-				case FastSync:
+				case FastSync, CheckpointSync:
 					known = d.blockchain.HasFastBlock(h, n)
 				default:
 					known = d.lightchain.HasHeader(h, n)
@@ -829,7 +854,7 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 				switch d.mode {
 				case FullSync:
 					known = d.blockchain.HasBlock(h, n)
-				case FastSync:
+				case FastSync, CheckpointSync:
 					known = d.blockchain.HasFastBlock(h, n)
 				default:
 					known = d.lightchain.HasHeader(h, n)
@@ -1401,6 +1426,42 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				}
 				chunk := headers[:limit]
 
+				// In case of checkpoint syncing, link the headers up to the checkpoint
+				// without their seals, which are verified against the state otherwise.
+				// The headers after it are verified on block import.
+				if d.mode == CheckpointSync {
+					trusted := chunk
+					for i, header := range chunk {
+						if header.Number.Cmp(d.cpHeader.Number) > 0 {
+							trusted = chunk[:i]
+							break
+						}
+					}
+					if len(trusted) > 0 {
+						unknown := make([]*types.Header, 0, len(trusted))
+						for _, header := range trusted {
+							if !d.lightchain.HasHeader(header.Hash(), header.Number.Uint64()) {
+								unknown = append(unknown, header)
+							}
+						}
+						if n, err := d.lightchain.InsertHeaderChain(trusted, 0); err != nil {
+							if n > 0 {
+								rollback = append(rollback, trusted[:n]...)
+							}
+							log.Debug("Invalid header encountered", "number", trusted[n].Number, "hash", trusted[n].Hash(), "err", err)
+							return errInvalidChain
+						}
+						// Keep all of them uncertain until the checkpoint is linked
+						rollback = append(rollback, unknown...)
+						if last := trusted[len(trusted)-1]; last.Number.Cmp(d.cpHeader.Number) == 0 {
+							if last.Hash() != d.cpHeader.Hash() {
+								log.Debug("Checkpoint not linked", "number", last.Number, "hash", last.Hash(), "want", d.cpHeader.Hash())
+								return errInvalidChain
+							}
+							rollback = nil
+						}
+					}
+				}
 				// In case of header only syncing, validate the chunk immediately
 				if d.mode == FastSync || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
@@ -1430,7 +1491,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 					}
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
-				if d.mode == FullSync || d.mode == FastSync {
+				if d.mode == FullSync || d.mode == FastSync || d.mode == CheckpointSync {
 					// If we've reached the allowed number of pending headers, stall a bit
 					for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.PendingReceipts() >= maxQueuedHeaders {
 						select {
@@ -1439,9 +1500,14 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 						case <-time.After(time.Second):
 						}
 					}
-					// Otherwise insert the headers for content retrieval
-					inserts := d.queue.Schedule(chunk, origin)
-					if len(inserts) != len(chunk) {
+					// Otherwise insert the headers for content retrieval, skipping
+					// the history below the pivot of a checkpoint sync
+					scheduled, from := chunk, origin
+					for d.mode == CheckpointSync && len(scheduled) > 0 && scheduled[0].Number.Uint64() < pivot {
+						scheduled, from = scheduled[1:], from+1
+					}
+					inserts := d.queue.Schedule(scheduled, from)
+					if len(inserts) != len(scheduled) {
 						log.Debug("Stale headers")
 						return errBadPeer
 					}
@@ -0,0 +1,176 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"sync/atomic"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+
+	energi_params "range/core/gen3/energi/params"
+)
+
+// checkpointStakeWindow is the number of blocks below the checkpoint whose
+// states are needed to verify the blocks after it, as the stake weights are
+// looked up over the maturity period. The state is synced at the pivot block
+// at this distance below the checkpoint.
+const checkpointStakeWindow = energi_params.StakeWindow
+
+// checkpointChain is implemented by the chains supporting the checkpoint sync.
+type checkpointChain interface {
+	// LatestCheckpoint returns the highest trusted checkpoint.
+	LatestCheckpoint() (core.Checkpoint, bool)
+
+	// CheckpointCommitHead sets the checkpoint block as head, on top of the
+	// state of the pivot block.
+	CheckpointCommitHead(pivot common.Hash, hash common.Hash) error
+}
+
+// fetchCheckpoint retrieves the header of the latest trusted checkpoint from
+// the remote peer, verifying its hash. It returns nil if there is nothing to
+// gain from a checkpoint sync: no usable checkpoint, or the local chain is at
+// it already.
+func (d *Downloader) fetchCheckpoint(p *peerConnection, height uint64) (*types.Header, error) {
+	chain, ok := d.blockchain.(checkpointChain)
+	if !ok {
+		return nil, nil
+	}
+	cp, ok := chain.LatestCheckpoint()
+	if !ok || cp.Number <= checkpointStakeWindow || d.blockchain.CurrentBlock().NumberU64() >= cp.Number {
+		return nil, nil
+	}
+	if height < cp.Number {
+		p.log.Warn("Remote head below checkpoint", "number", height, "checkpoint", cp.Number)
+		return nil, errUnsyncedPeer
+	}
+	p.log.Debug("Retrieving checkpoint header", "number", cp.Number, "hash", cp.Hash)
+	go p.peer.RequestHeadersByNumber(cp.Number, 1, 0, false)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			// Make sure the peer is on the trusted chain
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				p.log.Debug("Multiple headers for single request", "headers", len(headers))
+				return nil, errBadPeer
+			}
+			header := headers[0]
+			if header.Number.Uint64() != cp.Number || header.Hash() != cp.Hash {
+				p.log.Warn("Checkpoint mismatch", "number", header.Number, "hash", header.Hash(), "want", cp.Hash)
+				return nil, errInvalidChain
+			}
+			p.log.Debug("Checkpoint header identified", "number", header.Number, "hash", header.Hash(), "root", header.Root)
+			return header, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint header timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
+// processCheckpointSyncContent takes fetch results from the queue, which start
+// at the pivot block. The state of the pivot is synced, the blocks up to the
+// checkpoint are written along with their receipts, and the checkpoint becomes
+// the head once the state is there. The blocks after it are fully imported.
+func (d *Downloader) processCheckpointSyncContent(pivot uint64) error {
+	var (
+		checkpoint = d.cpHeader
+		sync       *stateSync
+		pivotHash  common.Hash
+	)
+	defer func() {
+		if sync != nil {
+			sync.Cancel()
+		}
+	}()
+	for {
+		results := d.queue.Results(true)
+		if len(results) == 0 {
+			if sync != nil {
+				return sync.Cancel()
+			}
+			return nil
+		}
+		if d.chainInsertHook != nil {
+			d.chainInsertHook(results)
+		}
+		if atomic.LoadInt32(&d.committed) == 0 {
+			// Start syncing the state once the pivot arrives, it's always first
+			if sync == nil {
+				if number := results[0].Header.Number.Uint64(); number != pivot {
+					log.Debug("Unexpected checkpoint pivot", "number", number, "pivot", pivot)
+					return errInvalidChain
+				}
+				pivotHash = results[0].Header.Hash()
+				sync = d.syncState(results[0].Header.Root)
+				go func(s *stateSync) {
+					if err := s.Wait(); err != nil && err != errCancelStateFetch {
+						d.queue.Close() // wake up Results
+					}
+				}(sync)
+			}
+			// Write the blocks up to the checkpoint without execution
+			trusted := results
+			for i, result := range results {
+				if result.Header.Number.Uint64() > checkpoint.Number.Uint64() {
+					trusted = results[:i]
+					break
+				}
+			}
+			if err := d.commitFastSyncData(trusted, sync); err != nil {
+				return err
+			}
+			results = results[len(trusted):]
+
+			// Commit the checkpoint as head once the pivot state is complete
+			if len(trusted) > 0 && trusted[len(trusted)-1].Hash == checkpoint.Hash() {
+				if err := sync.Wait(); err != nil {
+					return err
+				}
+				log.Debug("Committing checkpoint sync head", "number", checkpoint.Number, "hash", checkpoint.Hash(), "pivot", pivot)
+				if err := d.blockchain.(checkpointChain).CheckpointCommitHead(pivotHash, checkpoint.Hash()); err != nil {
+					return err
+				}
+				atomic.StoreInt32(&d.committed, 1)
+			}
+		}
+		// Checkpoint sync done, full import
+		if err := d.importBlockResults(results); err != nil {
+			return err
+		}
+	}
+}
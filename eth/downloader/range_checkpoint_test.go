@@ -0,0 +1,147 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/event"
+	"range/core/gen3/trie"
+)
+
+// checkpointTester is a download tester trusting a checkpoint of its chain.
+type checkpointTester struct {
+	*downloadTester
+
+	checkpoint core.Checkpoint
+	pivot      common.Hash // Pivot block committed along with the checkpoint
+}
+
+// newCheckpointTester creates a download tester trusting the given block of
+// the chain as its latest checkpoint.
+func newCheckpointTester(chain *testChain, number uint64) *checkpointTester {
+	tester := &checkpointTester{
+		downloadTester: newTester(),
+		checkpoint:     core.Checkpoint{Number: number, Hash: chain.chain[number]},
+	}
+	tester.downloader = New(FullSync, 0, tester.stateDb, new(event.TypeMux), tester, tester, nil, tester.dropPeer)
+	return tester
+}
+
+// LatestCheckpoint returns the trusted checkpoint of the tester.
+func (dl *checkpointTester) LatestCheckpoint() (core.Checkpoint, bool) {
+	return dl.checkpoint, true
+}
+
+// CheckpointCommitHead checks the synced state of the pivot block and makes the
+// checkpoint the head block.
+func (dl *checkpointTester) CheckpointCommitHead(pivot common.Hash, hash common.Hash) error {
+	block, head := dl.GetBlockByHash(pivot), dl.GetBlockByHash(hash)
+	if block == nil || head == nil {
+		return fmt.Errorf("non existent blocks: %x, %x", pivot[:4], hash[:4])
+	}
+	if _, err := trie.NewSecure(block.Root(), trie.NewDatabase(dl.stateDb), 0); err != nil {
+		return err
+	}
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.pivot = pivot
+	dl.stateDb.Put(head.Root().Bytes(), []byte{0x00})
+	return nil
+}
+
+// InsertReceiptChain injects a new batch of receipts into the simulated chain,
+// accepting the pivot block without the history below it.
+func (dl *checkpointTester) InsertReceiptChain(blocks types.Blocks, receipts []types.Receipts) (int, error) {
+	dl.lock.Lock()
+	skipped := 0
+	if len(blocks) > 0 && dl.ownBlocks[blocks[0].ParentHash()] == nil {
+		if _, ok := dl.ownHeaders[blocks[0].ParentHash()]; !ok {
+			dl.lock.Unlock()
+			return 0, errors.New("unknown parent")
+		}
+		dl.ownBlocks[blocks[0].Hash()] = blocks[0]
+		dl.ownReceipts[blocks[0].Hash()] = receipts[0]
+		blocks, receipts, skipped = blocks[1:], receipts[1:], 1
+	}
+	dl.lock.Unlock()
+
+	n, err := dl.downloadTester.InsertReceiptChain(blocks, receipts)
+	return skipped + n, err
+}
+
+// Tests that a checkpoint sync links the headers up to the trusted checkpoint,
+// syncs the state of the pivot block a stake window below it, writes the
+// blocks from the pivot on and imports the blocks after the checkpoint.
+func TestCheckpointSync70(t *testing.T) {
+	t.Parallel()
+
+	var (
+		chain  = testChainBase.shorten(int(checkpointStakeWindow*2 + 250))
+		number = checkpointStakeWindow + 200
+		pivot  = number - checkpointStakeWindow
+	)
+	tester := newCheckpointTester(chain, number)
+	defer tester.terminate()
+
+	tester.newPeer("peer", 70, chain)
+	if err := tester.sync("peer", nil, CheckpointSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	// The state of the pivot was synced and committed with the checkpoint
+	if want := chain.chain[pivot]; tester.pivot != want {
+		t.Fatalf("pivot mismatch: have %x, want %x", tester.pivot, want)
+	}
+	root := chain.blockm[chain.chain[pivot]].Root()
+	if _, err := trie.NewSecure(root, trie.NewDatabase(tester.stateDb), 0); err != nil {
+		t.Fatalf("pivot state missing: %v", err)
+	}
+	// The blocks after the checkpoint were imported on top of it
+	if head := tester.CurrentBlock(); head.Hash() != chain.headBlock().Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), chain.headBlock().NumberU64())
+	}
+	// All headers are linked, the bodies only retrieved from the pivot on
+	if hs := len(tester.ownHeaders); hs != chain.len() {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, chain.len())
+	}
+	for i, hash := range chain.chain[1:] {
+		number := uint64(i + 1)
+
+		block := tester.GetBlockByHash(hash)
+		if number < pivot {
+			if block != nil {
+				t.Fatalf("block #%d below the pivot retrieved", number)
+			}
+			continue
+		}
+		if block == nil {
+			t.Fatalf("block #%d missing", number)
+		}
+		if want := chain.blockm[hash]; block.Transactions().Len() != want.Transactions().Len() || len(block.Uncles()) != len(want.Uncles()) {
+			t.Fatalf("block #%d body mismatch", number)
+		}
+		if !tester.HasFastBlock(hash, number) {
+			t.Fatalf("block #%d receipts missing", number)
+		}
+	}
+}
@@ -142,6 +142,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	if f.begin >= 0 {
+		if err := rpc.CheckBlockRange(ctx, uint64(f.begin), end); err != nil {
+			return nil, err
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
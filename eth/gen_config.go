@@ -29,10 +29,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SkipBcVersionCheck      bool                   `toml:"-"`
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
+		DatabaseFreezerEnabled  bool
+		DatabaseFreezer         string
+		DatabaseFreezerDepth    uint64
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
 		TrieRapidTime           time.Duration
+		Snapshot                bool
+		FinalityDepth           uint64         `toml:",omitempty"`
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerNotify             []string       `toml:",omitempty"`
 		MinerExtraData          hexutil.Bytes  `toml:",omitempty"`
@@ -54,7 +59,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EWASMInterpreter        string
 		EVMInterpreter          string
 		ConstantinopleOverride  *big.Int
+		IstanbulOverride        *big.Int
 		RPCGasCap               *big.Int `toml:",omitempty"`
+		TraceJobsDir            string   `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -67,10 +74,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseFreezerEnabled = c.DatabaseFreezerEnabled
+	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseFreezerDepth = c.DatabaseFreezerDepth
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
 	enc.TrieRapidTime = c.TrieRapidTime
+	enc.Snapshot = c.Snapshot
+	enc.FinalityDepth = c.FinalityDepth
 	enc.Etherbase = c.Etherbase
 	enc.MinerNotify = c.MinerNotify
 	enc.MinerExtraData = c.MinerExtraData
@@ -92,7 +104,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EWASMInterpreter = c.EWASMInterpreter
 	enc.EVMInterpreter = c.EVMInterpreter
 	enc.ConstantinopleOverride = c.ConstantinopleOverride
+	enc.IstanbulOverride = c.IstanbulOverride
 	enc.RPCGasCap = c.RPCGasCap
+	enc.TraceJobsDir = c.TraceJobsDir
 	return &enc, nil
 }
 
@@ -109,10 +123,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SkipBcVersionCheck      *bool                  `toml:"-"`
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
+		DatabaseFreezerEnabled  *bool
+		DatabaseFreezer         *string
+		DatabaseFreezerDepth    *uint64
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
 		TrieRapidTime           *time.Duration
+		Snapshot                *bool
+		FinalityDepth           *uint64         `toml:",omitempty"`
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerNotify             []string        `toml:",omitempty"`
 		MinerExtraData          *hexutil.Bytes  `toml:",omitempty"`
@@ -134,7 +153,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
 		ConstantinopleOverride  *big.Int
+		IstanbulOverride        *big.Int
 		RPCGasCap               *big.Int `toml:",omitempty"`
+		TraceJobsDir            *string  `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -170,6 +191,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseFreezerEnabled != nil {
+		c.DatabaseFreezerEnabled = *dec.DatabaseFreezerEnabled
+	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
+	if dec.DatabaseFreezerDepth != nil {
+		c.DatabaseFreezerDepth = *dec.DatabaseFreezerDepth
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -182,6 +212,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieRapidTime != nil {
 		c.TrieRapidTime = *dec.TrieRapidTime
 	}
+	if dec.Snapshot != nil {
+		c.Snapshot = *dec.Snapshot
+	}
+	if dec.FinalityDepth != nil {
+		c.FinalityDepth = *dec.FinalityDepth
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -245,8 +281,14 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.ConstantinopleOverride != nil {
 		c.ConstantinopleOverride = dec.ConstantinopleOverride
 	}
+	if dec.IstanbulOverride != nil {
+		c.IstanbulOverride = dec.IstanbulOverride
+	}
 	if dec.RPCGasCap != nil {
 		c.RPCGasCap = dec.RPCGasCap
 	}
+	if dec.TraceJobsDir != nil {
+		c.TraceJobsDir = *dec.TraceJobsDir
+	}
 	return nil
 }
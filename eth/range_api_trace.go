@@ -0,0 +1,606 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/eth/tracers"
+	"range/core/gen3/rpc"
+)
+
+// Trace types of the replay requests.
+const (
+	traceTypeTrace     = "trace"
+	traceTypeStateDiff = "stateDiff"
+	traceTypeVmTrace   = "vmTrace"
+)
+
+// PrivateTraceAPI is the collection of the trace_* style APIs replaying the
+// transactions: flat call traces, state diffs and filtering of the calls.
+type PrivateTraceAPI struct {
+	eth   *Ethereum
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the trace methods of the
+// Ethereum service.
+func NewPrivateTraceAPI(eth *Ethereum) *PrivateTraceAPI {
+	return &PrivateTraceAPI{
+		eth:   eth,
+		debug: NewPrivateDebugAPI(eth.chainConfig, eth),
+	}
+}
+
+// AccountDiff is the change of an account: every field is either "=" when
+// unchanged, {"+": value} when the account is created, {"-": value} when it is
+// removed or {"*": {"from": value, "to": value}} when modified.
+type AccountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Nonce   interface{}                 `json:"nonce"`
+	Code    interface{}                 `json:"code"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// StateDiff is the set of the accounts changed.
+type StateDiff map[common.Address]*AccountDiff
+
+// TraceReplayResult is the replay of a transaction, the parts not requested
+// are left null. The state diff only covers the changes of the transaction:
+// the writes of the consensus finalization of the block are not attributed to
+// any transaction, BlockStateDiff returns them along with the rest.
+type TraceReplayResult struct {
+	Output          hexutil.Bytes            `json:"output"`
+	StateDiff       StateDiff                `json:"stateDiff"`
+	Trace           []*tracers.FlatCallTrace `json:"trace"`
+	VmTrace         interface{}              `json:"vmTrace"`
+	TransactionHash *common.Hash             `json:"transactionHash,omitempty"`
+}
+
+// TraceFilterArgs are the criteria of the calls to return, the calls must
+// match both address sets if not empty.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// txReplay is the outcome of a replayed transaction.
+type txReplay struct {
+	tx     *types.Transaction
+	index  int
+	output []byte
+	traces []*tracers.FlatCallTrace
+	diff   StateDiff
+}
+
+// ReplayTransaction re-executes the transaction, returning the requested
+// trace types: "trace" for the flat call traces and "stateDiff" for the
+// changes of the state.
+func (api *PrivateTraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*TraceReplayResult, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	trace, stateDiff, err := parseTraceTypes(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	replays, err := api.replayBlock(ctx, block, int(index))
+	if err != nil {
+		return nil, err
+	}
+	return replays[0].result(trace, stateDiff), nil
+}
+
+// ReplayBlockTransactions re-executes all the transactions of the block,
+// returning the requested trace types of each.
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*TraceReplayResult, error) {
+	trace, stateDiff, err := parseTraceTypes(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.debug.blockByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	replays, err := api.replayBlock(ctx, block, -1)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*TraceReplayResult, len(replays))
+	for i, replay := range replays {
+		results[i] = replay.result(trace, stateDiff)
+		hash := replay.tx.Hash()
+		results[i].TransactionHash = &hash
+	}
+	return results, nil
+}
+
+// Transaction returns the flat call traces of the transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*tracers.FlatCallTrace, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	replays, err := api.replayBlock(ctx, block, int(index))
+	if err != nil {
+		return nil, err
+	}
+	return replays[0].located(block), nil
+}
+
+// Block returns the flat call traces of all the transactions of the block.
+func (api *PrivateTraceAPI) Block(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*tracers.FlatCallTrace, error) {
+	block, err := api.debug.blockByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	replays, err := api.replayBlock(ctx, block, -1)
+	if err != nil {
+		return nil, err
+	}
+	traces := []*tracers.FlatCallTrace{}
+	for _, replay := range replays {
+		traces = append(traces, replay.located(block)...)
+	}
+	return traces, nil
+}
+
+// Filter returns the flat call traces of the block range matching the from and
+// to addresses. The sender of a self-destruct is the destructed contract and
+// the receiver the beneficiary, the receiver of a create the new contract.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*tracers.FlatCallTrace, error) {
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+
+	resolve := func(number *rpc.BlockNumber) (uint64, error) {
+		switch {
+		case number == nil || *number == rpc.LatestBlockNumber:
+			return head, nil
+		case *number == rpc.PendingBlockNumber:
+			return 0, errors.New("tracing of the pending block is not supported")
+		}
+		return uint64(*number), nil
+	}
+	begin, err := resolve(args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	end, err := resolve(args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if begin > end {
+		return nil, fmt.Errorf("fromBlock #%d is after toBlock #%d", begin, end)
+	}
+	if end > head {
+		end = head
+	}
+	if err := rpc.CheckBlockRange(ctx, begin, end); err != nil {
+		return nil, err
+	}
+	if begin == 0 {
+		begin = 1 // Genesis has no transactions
+	}
+	if begin > end {
+		return []*tracers.FlatCallTrace{}, nil
+	}
+	// Regenerate the state once, the blocks of the range are then applied on
+	// the post-state of their predecessor.
+	bc := api.eth.blockchain
+	block := bc.GetBlockByNumber(begin)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", begin)
+	}
+	statedb, err := api.parentState(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		triedb  = statedb.Database().TrieDB()
+		proot   = bc.GetHeaderByHash(block.ParentHash()).Root
+		traces  = []*tracers.FlatCallTrace{}
+		matched uint64
+	)
+	triedb.Reference(proot, common.Hash{})
+	defer func() { triedb.Dereference(proot) }()
+
+	for number := begin; number <= end; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if number > begin {
+			if block = bc.GetBlockByNumber(number); block == nil {
+				return nil, fmt.Errorf("block #%d not found", number)
+			}
+		}
+		var replays []*txReplay
+		if len(block.Transactions()) > 0 {
+			if replays, err = api.replayState(ctx, block, statedb.Copy(), -1); err != nil {
+				return nil, err
+			}
+		}
+		// Advance the state with the finalization of the block included
+		if number < end {
+			if _, _, _, err := bc.Processor().Process(block, statedb, vm.Config{}); err != nil {
+				return nil, fmt.Errorf("processing of block #%d failed: %v", number, err)
+			}
+			root, err := statedb.Commit(api.eth.chainConfig.IsEIP158(block.Number()))
+			if err != nil {
+				return nil, err
+			}
+			if err := statedb.Reset(root); err != nil {
+				return nil, err
+			}
+			triedb.Reference(root, common.Hash{})
+			triedb.Dereference(proot)
+			proot = root
+		}
+		for _, replay := range replays {
+			for _, trace := range replay.located(block) {
+				if !args.matches(trace) {
+					continue
+				}
+				matched++
+				if args.After != nil && matched <= *args.After {
+					continue
+				}
+				traces = append(traces, trace)
+				if args.Count != nil && uint64(len(traces)) >= *args.Count {
+					return traces, nil
+				}
+			}
+		}
+	}
+	return traces, nil
+}
+
+// BlockStateDiff returns the aggregate state changes of the block, the ones of
+// the consensus finalization included.
+func (api *PrivateTraceAPI) BlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (StateDiff, error) {
+	block, err := api.debug.blockByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := api.parentState(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	recorder := newStateRecorder(statedb)
+	statedb.Watch(recorder)
+	_, _, _, err = api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+	statedb.Watch(nil)
+	if err != nil {
+		return nil, fmt.Errorf("processing failed: %v", err)
+	}
+	statedb.Finalise(api.eth.chainConfig.IsEIP158(block.Number()))
+	return recorder.diff(), nil
+}
+
+// parentState regenerates the state the block is applied on.
+func (api *PrivateTraceAPI) parentState(ctx context.Context, block *types.Block) (*state.StateDB, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	return api.debug.computeStateDB(ctx, parent, defaultTraceReexec)
+}
+
+// replayBlock re-executes the transactions of the block in order, returning the
+// replay of the one at the given index only, or of all of them if negative.
+func (api *PrivateTraceAPI) replayBlock(ctx context.Context, block *types.Block, only int) ([]*txReplay, error) {
+	statedb, err := api.parentState(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return api.replayState(ctx, block, statedb, only)
+}
+
+// replayState is replayBlock on the given parent state, which is modified.
+func (api *PrivateTraceAPI) replayState(ctx context.Context, block *types.Block, statedb *state.StateDB, only int) ([]*txReplay, error) {
+	var (
+		signer  = types.MakeSigner(api.eth.chainConfig, block.Number())
+		replays []*txReplay
+	)
+	for i, tx := range block.Transactions() {
+		if only >= 0 && i > only {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var (
+			traced   = only < 0 || i == only
+			recorder = newStateRecorder(statedb)
+			tracer   = tracers.NewFlatCallTracer()
+			config   vm.Config
+		)
+		if traced {
+			statedb.Watch(recorder)
+			config = vm.Config{Debug: true, Tracer: tracer}
+		}
+		// Consensus - always the last
+		signer = api.debug.handleConsensusTx(statedb, tx, signer)
+
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		vmenv := vm.NewEVM(vmctx, statedb, api.eth.chainConfig, config)
+		output, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+		statedb.Watch(nil)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+		if !traced {
+			continue
+		}
+		traces, err := tracer.Traces()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x tracing failed: %v", tx.Hash(), err)
+		}
+		replays = append(replays, &txReplay{
+			tx:     tx,
+			index:  i,
+			output: output,
+			traces: traces,
+			diff:   recorder.diff(),
+		})
+	}
+	if only >= len(block.Transactions()) {
+		return nil, fmt.Errorf("transaction index %d out of range for block %#x", only, block.Hash())
+	}
+	return replays, nil
+}
+
+// parseTraceTypes checks the requested trace types.
+func parseTraceTypes(traceTypes []string) (trace, stateDiff bool, err error) {
+	for _, traceType := range traceTypes {
+		switch traceType {
+		case traceTypeTrace:
+			trace = true
+		case traceTypeStateDiff:
+			stateDiff = true
+		case traceTypeVmTrace:
+			return false, false, errors.New("vmTrace is not supported")
+		default:
+			return false, false, fmt.Errorf("unknown trace type %q", traceType)
+		}
+	}
+	return trace, stateDiff, nil
+}
+
+// result returns the requested parts of the replay.
+func (r *txReplay) result(trace, stateDiff bool) *TraceReplayResult {
+	result := &TraceReplayResult{Output: r.output}
+	if trace {
+		result.Trace = r.traces
+	}
+	if stateDiff {
+		result.StateDiff = r.diff
+	}
+	return result
+}
+
+// located returns the traces of the replay with the block and transaction
+// they belong to.
+func (r *txReplay) located(block *types.Block) []*tracers.FlatCallTrace {
+	var (
+		blockHash   = block.Hash()
+		blockNumber = block.NumberU64()
+		txHash      = r.tx.Hash()
+		txIndex     = uint64(r.index)
+	)
+	for _, trace := range r.traces {
+		trace.BlockHash = &blockHash
+		trace.BlockNumber = &blockNumber
+		trace.TransactionHash = &txHash
+		trace.TransactionPosition = &txIndex
+	}
+	return r.traces
+}
+
+// matches checks the sender and the receiver of the call against the filter.
+func (args *TraceFilterArgs) matches(trace *tracers.FlatCallTrace) bool {
+	var from, to *common.Address
+	switch trace.Type {
+	case "create":
+		from = trace.Action.From
+		if trace.Result != nil {
+			to = trace.Result.Address
+		}
+	case "suicide":
+		from, to = trace.Action.Address, trace.Action.RefundAddress
+	default:
+		from, to = trace.Action.From, trace.Action.To
+	}
+	return matchAddress(args.FromAddress, from) && matchAddress(args.ToAddress, to)
+}
+
+// matchAddress checks if the address is in the set, any address matching an
+// empty set.
+func matchAddress(set []common.Address, addr *common.Address) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	for _, a := range set {
+		if a == *addr {
+			return true
+		}
+	}
+	return false
+}
+
+// accountSnapshot is an account before its first write.
+type accountSnapshot struct {
+	exist   bool
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+}
+
+// stateRecorder watches the writes of a state, recording the accounts and the
+// storage slots before their first write to diff them against the state
+// afterwards.
+type stateRecorder struct {
+	statedb  *state.StateDB
+	accounts map[common.Address]*accountSnapshot
+	storage  map[common.Address]map[common.Hash]common.Hash
+}
+
+func newStateRecorder(statedb *state.StateDB) *stateRecorder {
+	return &stateRecorder{
+		statedb:  statedb,
+		accounts: make(map[common.Address]*accountSnapshot),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// touch snapshots the account at its first write.
+func (r *stateRecorder) touch(addr common.Address) {
+	if _, ok := r.accounts[addr]; ok {
+		return
+	}
+	r.accounts[addr] = &accountSnapshot{
+		exist:   r.exists(addr),
+		balance: new(big.Int).Set(r.statedb.GetBalance(addr)),
+		nonce:   r.statedb.GetNonce(addr),
+		code:    common.CopyBytes(r.statedb.GetCode(addr)),
+	}
+}
+
+// exists checks if the account exists and is not empty.
+func (r *stateRecorder) exists(addr common.Address) bool {
+	return r.statedb.Exist(addr) && !r.statedb.Empty(addr)
+}
+
+// WatchBalance implements state.WriteWatcher.
+func (r *stateRecorder) WatchBalance(addr common.Address, prev *big.Int) {
+	r.touch(addr)
+}
+
+// WatchNonce implements state.WriteWatcher.
+func (r *stateRecorder) WatchNonce(addr common.Address, prev uint64) {
+	r.touch(addr)
+}
+
+// WatchCode implements state.WriteWatcher.
+func (r *stateRecorder) WatchCode(addr common.Address, prev []byte) {
+	r.touch(addr)
+}
+
+// WatchStorage implements state.WriteWatcher.
+func (r *stateRecorder) WatchStorage(addr common.Address, key, prev common.Hash) {
+	r.touch(addr)
+
+	slots := r.storage[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]common.Hash)
+		r.storage[addr] = slots
+	}
+	if _, ok := slots[key]; !ok {
+		slots[key] = prev
+	}
+}
+
+// diff compares the written accounts against their snapshots, the accounts
+// written back to their original values are left out.
+func (r *stateRecorder) diff() StateDiff {
+	diff := make(StateDiff)
+	for addr, pre := range r.accounts {
+		post := r.exists(addr)
+		if !pre.exist && !post {
+			continue
+		}
+		var (
+			born = !pre.exist && post
+			died = pre.exist && !post
+
+			balance = r.statedb.GetBalance(addr)
+			nonce   = r.statedb.GetNonce(addr)
+			code    = r.statedb.GetCode(addr)
+		)
+		account := &AccountDiff{
+			Balance: diffValue(born, died, balance.Cmp(pre.balance) != 0,
+				(*hexutil.Big)(pre.balance), (*hexutil.Big)(new(big.Int).Set(balance))),
+			Nonce: diffValue(born, died, nonce != pre.nonce,
+				hexutil.Uint64(pre.nonce), hexutil.Uint64(nonce)),
+			Code: diffValue(born, died, !bytes.Equal(code, pre.code),
+				hexutil.Bytes(pre.code), hexutil.Bytes(common.CopyBytes(code))),
+			Storage: make(map[common.Hash]interface{}),
+		}
+		for key, prev := range r.storage[addr] {
+			var value common.Hash
+			if post {
+				value = r.statedb.GetState(addr, key)
+			}
+			if value == prev {
+				continue
+			}
+			account.Storage[key] = diffValue(born, died, true, prev, value)
+		}
+		if !born && !died && len(account.Storage) == 0 &&
+			account.Balance == "=" && account.Nonce == "=" && account.Code == "=" {
+			continue
+		}
+		diff[addr] = account
+	}
+	return diff
+}
+
+// diffValue formats the change of a value.
+func diffValue(born, died, changed bool, from, to interface{}) interface{} {
+	switch {
+	case born:
+		return map[string]interface{}{"+": to}
+	case died:
+		return map[string]interface{}{"-": from}
+	case changed:
+		return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}
+	}
+	return "="
+}
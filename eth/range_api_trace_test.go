@@ -0,0 +1,84 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/eth/tracers"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"range/core/gen3/rpc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that filtering a range, which carries the state from block to block,
+// returns the same traces as tracing every block on its own.
+func TestTraceFilterCarriesState(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.HexToAddress("0x0102")
+		db      = ethdb.NewMemDatabase()
+		config  = params.TestChainConfig
+		gspec   = &core.Genesis{Config: config, Alloc: core.GenesisAlloc{sender: {Balance: big.NewInt(1e18)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 4, func(i int, gen *core.BlockGen) {
+		if i == 1 {
+			return // Empty blocks still advance the state
+		}
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(sender), to, big.NewInt(int64(i+1)), 21000, big.NewInt(1), nil), signer, key)
+		assert.Empty(t, err)
+		gen.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+	_, err = chain.InsertChain(blocks)
+	assert.Empty(t, err)
+
+	api := NewPrivateTraceAPI(&Ethereum{blockchain: chain, chainDb: db, chainConfig: config})
+
+	from, until := rpc.BlockNumber(1), rpc.BlockNumber(4)
+	filtered, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &until})
+	assert.Empty(t, err)
+
+	var expected []*tracers.FlatCallTrace
+	for number := rpc.BlockNumber(1); number <= 4; number++ {
+		traces, err := api.Block(context.Background(), rpc.BlockNumberOrHashWithNumber(number))
+		assert.Empty(t, err)
+		expected = append(expected, traces...)
+	}
+	assert.Equal(t, 3, len(filtered))
+	assert.Equal(t, expected, filtered)
+
+	// Only the matching calls of the range
+	filtered, err = api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &until, ToAddress: []common.Address{sender}})
+	assert.Empty(t, err)
+	assert.Empty(t, filtered)
+}
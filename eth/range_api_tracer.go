@@ -0,0 +1,160 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/eth/tracers"
+	"range/core/gen3/rpc"
+
+	energi_consensus "range/core/gen3/energi/consensus"
+)
+
+// defaultFinalizationTracer traces the EVM calls of the finalization steps
+// unless another tracer is configured.
+const defaultFinalizationTracer = "callTracer"
+
+// blockByNumberOrHash retrieves the block of the selector, pending excluded.
+func (api *PrivateDebugAPI) blockByNumberOrHash(blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block := api.eth.blockchain.GetBlockByHash(hash)
+		if block == nil {
+			return nil, fmt.Errorf("block %#x not found", hash)
+		}
+		return block, nil
+	}
+	number, _ := blockNrOrHash.Number()
+	switch number {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("tracing of the pending block is not supported")
+	case rpc.LatestBlockNumber:
+		return api.eth.blockchain.CurrentBlock(), nil
+	}
+	block := api.eth.blockchain.GetBlockByNumber(uint64(number))
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// TraceBlockFinalization re-executes the regular transactions of the block and
+// traces its consensus finalization step by step: the EVM calls of every step,
+// traced by the configured tracer, the gas used and the balance and storage
+// changes. The state root after the traced finalization is reported along
+// with the one of the block. The tracers of all the calls share the timeout of
+// a single transaction trace.
+func (api *PrivateDebugAPI) TraceBlockFinalization(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (*energi_consensus.FinalizationTrace, error) {
+	engine, ok := api.eth.engine.(*energi_consensus.Range)
+	if !ok {
+		return nil, errors.New("finalization tracing requires the Range consensus engine")
+	}
+	block, err := api.blockByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not finalized")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	name := defaultFinalizationTracer
+	if config != nil && config.Tracer != nil {
+		name = *config.Tracer
+	}
+	tracer, err := tracers.NewTracer(name)
+	if err != nil {
+		return nil, err
+	}
+	tracer.GetResult() // Release the resources of the JavaScript tracers
+
+	timeout := defaultTraceTimeout
+	if config != nil && config.Timeout != nil {
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	statedb, err := api.computeStateDB(ctx, parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	// The trailing consensus transactions are recreated by the finalization
+	txs := block.Transactions()
+	for len(txs) > 0 && txs[len(txs)-1].IsConsensus() {
+		txs = txs[:len(txs)-1]
+	}
+	var (
+		header   = block.Header()
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas  = new(uint64)
+		receipts = make(types.Receipts, 0, len(txs))
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, err := core.ApplyTransaction(api.config, api.eth.blockchain, nil, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	// Handle timeouts and RPC cancellations, stopping the tracers created so far
+	// and the ones of the later calls
+	var (
+		lock    sync.Mutex
+		created []tracers.Interface
+		expired error
+	)
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	go func() {
+		<-deadlineCtx.Done()
+
+		lock.Lock()
+		defer lock.Unlock()
+		expired = errors.New("execution timeout")
+		for _, tracer := range created {
+			tracer.Stop(expired)
+		}
+	}()
+	newTracer := func() vm.Tracer {
+		tracer, err := tracers.NewTracer(name)
+		if err != nil {
+			return nil
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if expired != nil {
+			tracer.Stop(expired)
+		}
+		created = append(created, tracer)
+		return tracer
+	}
+	return engine.TraceFinalization(api.eth.blockchain, header, statedb, txs, receipts, newTracer), nil
+}
@@ -0,0 +1,689 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/eth/tracers"
+	"range/core/gen3/log"
+	"range/core/gen3/rpc"
+)
+
+const (
+	// defaultTraceJobTracer is the tracer of the jobs not configuring any, the
+	// struct logs are rarely what a batch analysis wants.
+	defaultTraceJobTracer = "callTracer"
+
+	// defaultTraceJobFileSize is the size of an output file of a job before
+	// rotating to the next one.
+	defaultTraceJobFileSize = 256 * 1024 * 1024
+
+	// traceJobCheckpointInterval is how often the progress of a job is persisted.
+	traceJobCheckpointInterval = 8 * time.Second
+
+	// traceJobFile is the name of the persisted job in its directory.
+	traceJobFile = "job.json"
+)
+
+// States of a tracing job.
+const (
+	TraceJobRunning   = "running"
+	TraceJobDone      = "done"
+	TraceJobFailed    = "failed"
+	TraceJobCancelled = "cancelled"
+)
+
+var (
+	errTraceJobStopped   = errors.New("node stopped")
+	errTraceJobCancelled = errors.New("job cancelled")
+)
+
+// TraceJobConfig holds the parameters of a batch tracing job.
+type TraceJobConfig struct {
+	TraceConfig
+	FileSize *uint64 // Size of an output file before rotating to the next one
+}
+
+// TraceJob is a batch tracing job and its progress. The traces of every block
+// with transactions are written as a JSON line to the output files of the job
+// in its directory, the last file being the one written.
+type TraceJob struct {
+	ID      string         `json:"id"`
+	Dir     string         `json:"dir"`
+	Start   hexutil.Uint64 `json:"start"`
+	End     hexutil.Uint64 `json:"end"`
+	Config  TraceJobConfig `json:"config"`
+	Status  string         `json:"status"`
+	Next    hexutil.Uint64 `json:"next"`         // First block not written yet
+	Traced  hexutil.Uint64 `json:"transactions"` // Number of transactions traced
+	Files   []string       `json:"files"`
+	Offset  int64          `json:"offset"` // Size of the last file at the last checkpoint
+	Error   string         `json:"error,omitempty"`
+	Created time.Time      `json:"created"`
+	Updated time.Time      `json:"updated"`
+}
+
+// traceJobRunner is a job along with the means to abort it.
+type traceJobRunner struct {
+	lock   sync.Mutex // Protects the job and the reason
+	job    *TraceJob
+	reason error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// snapshot returns a copy of the job.
+func (r *traceJobRunner) snapshot() *TraceJob {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	job := *r.job
+	job.Files = append([]string{}, r.job.Files...)
+	return &job
+}
+
+// abort stops the job for the given reason, unless it's stopping already.
+func (r *traceJobRunner) abort(reason error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.reason == nil {
+		r.reason = reason
+		r.cancel()
+	}
+}
+
+// traceJobs runs the batch tracing jobs, persisting their progress in their
+// directories so that the interrupted ones resume after a restart.
+type traceJobs struct {
+	dir string
+	api *PrivateDebugAPI
+
+	lock sync.Mutex // Protects the jobs
+	jobs map[string]*traceJobRunner
+	quit bool
+	wg   sync.WaitGroup
+}
+
+// newTraceJobs loads the jobs of the directory.
+func newTraceJobs(dir string, api *PrivateDebugAPI) *traceJobs {
+	m := &traceJobs{
+		dir:  dir,
+		api:  api,
+		jobs: make(map[string]*traceJobRunner),
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		blob, err := ioutil.ReadFile(filepath.Join(dir, entry.Name(), traceJobFile))
+		if err != nil {
+			continue
+		}
+		job := new(TraceJob)
+		if err := json.Unmarshal(blob, job); err != nil {
+			log.Warn("Skipping corrupt tracing job", "dir", entry.Name(), "err", err)
+			continue
+		}
+		job.Dir = filepath.Join(dir, entry.Name())
+		m.jobs[job.ID] = m.runner(job)
+	}
+	return m
+}
+
+func (m *traceJobs) runner(job *TraceJob) *traceJobRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &traceJobRunner{job: job, ctx: ctx, cancel: cancel}
+}
+
+// start resumes the jobs interrupted by the last shutdown.
+func (m *traceJobs) start() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, r := range m.jobs {
+		if r.job.Status == TraceJobRunning {
+			log.Info("Resuming tracing job", "id", r.job.ID, "next", uint64(r.job.Next), "end", uint64(r.job.End))
+			m.wg.Add(1)
+			go m.run(r)
+		}
+	}
+}
+
+// stop interrupts the running jobs, leaving them to resume on the next start.
+func (m *traceJobs) stop() {
+	m.lock.Lock()
+	m.quit = true
+	for _, r := range m.jobs {
+		r.abort(errTraceJobStopped)
+	}
+	m.lock.Unlock()
+
+	m.wg.Wait()
+}
+
+// submit creates and starts a job over the given blocks.
+func (m *traceJobs) submit(start, end uint64, config TraceJobConfig) (*TraceJob, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.quit {
+		return nil, errTraceJobStopped
+	}
+	id := string(rpc.NewID())
+	job := &TraceJob{
+		ID:      id,
+		Dir:     filepath.Join(m.dir, id),
+		Start:   hexutil.Uint64(start),
+		End:     hexutil.Uint64(end),
+		Config:  config,
+		Status:  TraceJobRunning,
+		Next:    hexutil.Uint64(start),
+		Files:   []string{},
+		Created: time.Now(),
+	}
+	if err := os.MkdirAll(job.Dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := saveTraceJob(job); err != nil {
+		return nil, err
+	}
+	r := m.runner(job)
+	m.jobs[id] = r
+
+	log.Info("Starting tracing job", "id", id, "start", start, "end", end, "dir", job.Dir)
+	m.wg.Add(1)
+	go m.run(r)
+
+	return r.snapshot(), nil
+}
+
+// get returns the runner of the job.
+func (m *traceJobs) get(id string) (*traceJobRunner, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("tracing job %s not found", id)
+	}
+	return r, nil
+}
+
+// list returns all the jobs, the oldest first.
+func (m *traceJobs) list() []*TraceJob {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	jobs := make([]*TraceJob, 0, len(m.jobs))
+	for _, r := range m.jobs {
+		jobs = append(jobs, r.snapshot())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Created.Before(jobs[j].Created)
+	})
+	return jobs
+}
+
+// run traces the job to completion or until aborted, and records the outcome.
+func (m *traceJobs) run(r *traceJobRunner) {
+	defer m.wg.Done()
+
+	err := m.trace(r)
+
+	r.lock.Lock()
+	switch err {
+	case nil:
+		r.job.Status = TraceJobDone
+		log.Info("Tracing job finished", "id", r.job.ID, "transactions", uint64(r.job.Traced))
+	case errTraceJobStopped:
+		// Still running, resumed on the next start
+	case errTraceJobCancelled:
+		r.job.Status = TraceJobCancelled
+		log.Info("Tracing job cancelled", "id", r.job.ID, "next", uint64(r.job.Next))
+	default:
+		r.job.Status = TraceJobFailed
+		r.job.Error = err.Error()
+		log.Warn("Tracing job failed", "id", r.job.ID, "next", uint64(r.job.Next), "err", err)
+	}
+	r.job.Updated = time.Now()
+	if err := saveTraceJob(r.job); err != nil {
+		log.Error("Failed to save tracing job", "id", r.job.ID, "err", err)
+	}
+	r.lock.Unlock()
+}
+
+// trace executes the remaining blocks of the job. The state of the blocks is
+// carried over from one block to the next, only the first state is looked up
+// or regenerated. The blocks are traced concurrently over copies of their
+// parent state and written in order.
+func (m *traceJobs) trace(r *traceJobRunner) error {
+	var (
+		api  = m.api
+		bc   = api.eth.blockchain
+		job  = r.snapshot()
+		ctx  = r.ctx
+		fail = func(err error) error {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			if r.reason != nil {
+				return r.reason
+			}
+			return err
+		}
+	)
+	sink, err := openTraceSink(job)
+	if err != nil {
+		return err
+	}
+	defer sink.close()
+
+	r.lock.Lock()
+	r.job.Files = append([]string{}, job.Files...)
+	r.lock.Unlock()
+
+	if job.Next > job.End {
+		return nil
+	}
+	parent := bc.GetBlockByNumber(uint64(job.Next) - 1)
+	if parent == nil {
+		return fmt.Errorf("block #%d not found", job.Next-1)
+	}
+	reexec := defaultTraceReexec
+	if job.Config.Reexec != nil {
+		reexec = *job.Config.Reexec
+	}
+	statedb, err := api.computeStateDB(ctx, parent, reexec)
+	if err != nil {
+		return fail(err)
+	}
+	var (
+		triedb  = statedb.Database().TrieDB()
+		threads = runtime.NumCPU()
+		pend    = new(sync.WaitGroup)
+		tasks   = make(chan *blockTraceTask, threads)
+		results = make(chan *blockTraceTask, threads)
+		failed  error
+	)
+	for th := 0; th < threads; th++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			for task := range tasks {
+				api.traceBlockTask(ctx, task, &job.Config.TraceConfig)
+				results <- task
+			}
+		}()
+	}
+	// Feed the blocks into the tracers, advancing the state without tracing
+	go func() {
+		defer func() {
+			close(tasks)
+			pend.Wait()
+			close(results)
+		}()
+		proot := parent.Root()
+		triedb.Reference(proot, common.Hash{})
+		defer func() { triedb.Dereference(proot) }()
+
+		for number := uint64(job.Next); number <= uint64(job.End); number++ {
+			block := bc.GetBlockByNumber(number)
+			if block == nil {
+				failed = fmt.Errorf("block #%d not found", number)
+				return
+			}
+			// The tracer holds its own reference to the parent state
+			triedb.Reference(proot, common.Hash{})
+			task := &blockTraceTask{
+				statedb: statedb.Copy(),
+				block:   block,
+				rootref: proot,
+				results: make([]*txTraceResult, len(block.Transactions())),
+			}
+			select {
+			case tasks <- task:
+			case <-ctx.Done():
+				triedb.Dereference(proot)
+				return
+			}
+			if _, _, _, err := bc.Processor().Process(block, statedb, vm.Config{}); err != nil {
+				failed = fmt.Errorf("processing of block #%d failed: %v", number, err)
+				return
+			}
+			root, err := statedb.Commit(api.config.IsEIP158(block.Number()))
+			if err != nil {
+				failed = err
+				return
+			}
+			if err := statedb.Reset(root); err != nil {
+				failed = err
+				return
+			}
+			triedb.Reference(root, common.Hash{})
+			triedb.Dereference(proot)
+			proot = root
+		}
+	}()
+	// Write the traces in order, checkpointing the progress along the way
+	var (
+		done      = make(map[uint64]*blockTraceTask)
+		next      = uint64(job.Next)
+		fileSize  = uint64(defaultTraceJobFileSize)
+		persisted = time.Now()
+		werr      error
+	)
+	if job.Config.FileSize != nil && *job.Config.FileSize > 0 {
+		fileSize = *job.Config.FileSize
+	}
+	for task := range results {
+		triedb.Dereference(task.rootref)
+		if werr != nil {
+			continue
+		}
+		done[task.block.NumberU64()] = task
+
+		for task, ok := done[next]; ok && werr == nil; task, ok = done[next] {
+			delete(done, next)
+			next++
+
+			if len(task.results) > 0 {
+				if uint64(sink.size) >= fileSize {
+					werr = sink.rotate()
+				}
+				if werr == nil {
+					werr = sink.write(&blockTraceResult{
+						Block:  hexutil.Uint64(task.block.NumberU64()),
+						Hash:   task.block.Hash(),
+						Traces: task.results,
+					})
+				}
+			}
+			r.lock.Lock()
+			r.job.Next = hexutil.Uint64(next)
+			r.job.Traced += hexutil.Uint64(len(task.results))
+			r.job.Files = append([]string{}, sink.files...)
+			r.lock.Unlock()
+
+			if werr == nil && (time.Since(persisted) > traceJobCheckpointInterval || next > uint64(job.End)) {
+				werr = r.checkpoint(sink)
+				persisted = time.Now()
+			}
+		}
+		if werr != nil {
+			r.abort(werr)
+		}
+	}
+	if werr != nil {
+		return werr
+	}
+	if failed != nil {
+		return failed
+	}
+	if next <= uint64(job.End) {
+		// Aborted, keep the blocks written so far
+		if err := r.checkpoint(sink); err != nil {
+			log.Error("Failed to checkpoint tracing job", "id", job.ID, "err", err)
+		}
+		return fail(ctx.Err())
+	}
+	return nil
+}
+
+// checkpoint syncs the output and persists the progress of the job.
+func (r *traceJobRunner) checkpoint(sink *traceSink) error {
+	if err := sink.sync(); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.job.Offset = sink.size
+	r.job.Updated = time.Now()
+	return saveTraceJob(r.job)
+}
+
+// traceBlockTask traces the transactions of the block of the task over the
+// state of its parent. A failed transaction ends the tracing of the block,
+// its error recorded in place of its trace.
+func (api *PrivateDebugAPI) traceBlockTask(ctx context.Context, task *blockTraceTask, config *TraceConfig) {
+	signer := types.MakeSigner(api.config, task.block.Number())
+
+	for i, tx := range task.block.Transactions() {
+		// Consensus - always the last
+		signer = api.handleConsensusTx(task.statedb, tx, signer)
+
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
+
+		res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+		if err != nil {
+			task.results[i] = &txTraceResult{Error: err.Error()}
+			task.results = task.results[:i+1]
+			return
+		}
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		task.statedb.Finalise(api.config.IsEIP158(task.block.Number()))
+		task.results[i] = &txTraceResult{Result: res}
+	}
+}
+
+// saveTraceJob persists the job, replacing the previous version atomically.
+func saveTraceJob(job *TraceJob) error {
+	blob, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(job.Dir, traceJobFile+".tmp")
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(job.Dir, traceJobFile))
+}
+
+// traceSink writes the JSON lines of a job to its rotating output files.
+type traceSink struct {
+	dir   string
+	files []string
+	file  *os.File
+	buf   *bufio.Writer
+	size  int64 // Size of the current file, buffered writes included
+}
+
+// openTraceSink opens the last output file of the job, dropping anything
+// written after the last checkpoint, or creates the first one.
+func openTraceSink(job *TraceJob) (*traceSink, error) {
+	sink := &traceSink{
+		dir:   job.Dir,
+		files: append([]string{}, job.Files...),
+	}
+	if len(sink.files) == 0 {
+		return sink, sink.rotate()
+	}
+	file, err := os.OpenFile(filepath.Join(sink.dir, sink.files[len(sink.files)-1]), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(job.Offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(job.Offset, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	sink.file, sink.buf, sink.size = file, bufio.NewWriter(file), job.Offset
+	return sink, nil
+}
+
+// rotate closes the current output file and starts the next one.
+func (s *traceSink) rotate() error {
+	if s.file != nil {
+		if err := s.sync(); err != nil {
+			return err
+		}
+		s.file.Close()
+	}
+	name := fmt.Sprintf("traces-%06d.jsonl", len(s.files))
+	file, err := os.OpenFile(filepath.Join(s.dir, name), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	s.files = append(s.files, name)
+	s.file, s.buf, s.size = file, bufio.NewWriter(file), 0
+	return nil
+}
+
+// write appends the value as a JSON line.
+func (s *traceSink) write(v interface{}) error {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	n, err := s.buf.Write(append(blob, '\n'))
+	s.size += int64(n)
+	return err
+}
+
+// sync flushes the buffered lines to disk.
+func (s *traceSink) sync() error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *traceSink) close() {
+	if s.file != nil {
+		s.buf.Flush()
+		s.file.Close()
+	}
+}
+
+// SubmitTraceJob starts a server side job tracing the blocks from start to end,
+// both included, writing the traces of the blocks with transactions to
+// rotating JSON line files in the job directory. The progress is persisted, an
+// interrupted job resumes when the node restarts.
+func (api *PrivateDebugAPI) SubmitTraceJob(start, end rpc.BlockNumber, config *TraceJobConfig) (*TraceJob, error) {
+	if api.eth.traceJobs == nil {
+		return nil, errors.New("tracing jobs are not available")
+	}
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+
+	resolve := func(number rpc.BlockNumber) (uint64, error) {
+		switch number {
+		case rpc.PendingBlockNumber:
+			return 0, errors.New("tracing of the pending block is not supported")
+		case rpc.LatestBlockNumber:
+			return head, nil
+		}
+		if uint64(number) > head {
+			return 0, fmt.Errorf("block #%d not found", number)
+		}
+		return uint64(number), nil
+	}
+	from, err := resolve(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolve(end)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = 1 // Genesis has no transactions
+	}
+	if from > to {
+		return nil, fmt.Errorf("end block #%d needs to come after start block #%d", to, from)
+	}
+	var cfg TraceJobConfig
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Tracer == nil {
+		tracer := defaultTraceJobTracer
+		cfg.Tracer = &tracer
+	}
+	if cfg.Timeout != nil {
+		if _, err := time.ParseDuration(*cfg.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	tracer, err := tracers.NewTracer(*cfg.Tracer)
+	if err != nil {
+		return nil, err
+	}
+	tracer.GetResult() // Release the resources of the JavaScript tracers
+
+	return api.eth.traceJobs.submit(from, to, cfg)
+}
+
+// TraceJobs returns all the tracing jobs, the oldest first.
+func (api *PrivateDebugAPI) TraceJobs() ([]*TraceJob, error) {
+	if api.eth.traceJobs == nil {
+		return nil, errors.New("tracing jobs are not available")
+	}
+	return api.eth.traceJobs.list(), nil
+}
+
+// TraceJobStatus returns the tracing job and its progress.
+func (api *PrivateDebugAPI) TraceJobStatus(id string) (*TraceJob, error) {
+	if api.eth.traceJobs == nil {
+		return nil, errors.New("tracing jobs are not available")
+	}
+	r, err := api.eth.traceJobs.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.snapshot(), nil
+}
+
+// CancelTraceJob stops the running tracing job for good, the traces written
+// so far are kept.
+func (api *PrivateDebugAPI) CancelTraceJob(id string) (*TraceJob, error) {
+	if api.eth.traceJobs == nil {
+		return nil, errors.New("tracing jobs are not available")
+	}
+	r, err := api.eth.traceJobs.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job := r.snapshot(); job.Status != TraceJobRunning {
+		return nil, fmt.Errorf("tracing job %s is %s", id, job.Status)
+	}
+	r.abort(errTraceJobCancelled)
+	return r.snapshot(), nil
+}
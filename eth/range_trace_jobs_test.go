@@ -0,0 +1,69 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that the output of a job resumes from its last checkpoint, dropping
+// the lines written afterwards, and rotates the files.
+func TestTraceSinkResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracejobs")
+	assert.Empty(t, err)
+	defer os.RemoveAll(dir)
+
+	job := &TraceJob{ID: "0x1", Dir: dir, Status: TraceJobRunning}
+	assert.Empty(t, saveTraceJob(job))
+
+	sink, err := openTraceSink(job)
+	assert.Empty(t, err)
+	assert.Empty(t, sink.write(1))
+	assert.Empty(t, sink.sync())
+	job.Files, job.Offset = sink.files, sink.size
+	assert.Empty(t, saveTraceJob(job))
+
+	// Written after the checkpoint, lost in a crash
+	assert.Empty(t, sink.write(2))
+	sink.close()
+
+	jobs := newTraceJobs(filepath.Dir(dir), nil)
+	loaded := jobs.jobs[job.ID].job
+	assert.Equal(t, []string{"traces-000000.jsonl"}, loaded.Files)
+	assert.Equal(t, int64(2), loaded.Offset)
+
+	sink, err = openTraceSink(loaded)
+	assert.Empty(t, err)
+	assert.Empty(t, sink.write(3))
+	assert.Empty(t, sink.rotate())
+	assert.Empty(t, sink.write(4))
+	sink.close()
+
+	blob, err := ioutil.ReadFile(filepath.Join(dir, "traces-000000.jsonl"))
+	assert.Empty(t, err)
+	assert.Equal(t, "1\n3\n", string(blob))
+
+	blob, err = ioutil.ReadFile(filepath.Join(dir, "traces-000001.jsonl"))
+	assert.Empty(t, err)
+	assert.Equal(t, "4\n", string(blob))
+	assert.Equal(t, []string{"traces-000000.jsonl", "traces-000001.jsonl"}, sink.files)
+}
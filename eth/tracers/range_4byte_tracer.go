@@ -0,0 +1,76 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/vm"
+)
+
+// fourByteTracer is the native version of 4byte_tracer.js, counting the 4
+// byte method identifiers of the calls along with their input sizes.
+type fourByteTracer struct {
+	nativeTracer
+	ids map[string]int // Calls per "<id>-<size>" key
+}
+
+func newFourByteTracer() *fourByteTracer {
+	return &fourByteTracer{ids: make(map[string]int)}
+}
+
+// store counts a call of the method id with the given argument size.
+func (t *fourByteTracer) store(id []byte, size int64) {
+	t.ids[fmt.Sprintf("%s-%d", hexutil.Encode(id), size)]++
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if !t.tracing() {
+		return nil
+	}
+	// Stack position of the input memory offset
+	var pos int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		pos = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		pos = 2
+	default:
+		return nil
+	}
+	stackw := &stackWrapper{stack: stack}
+	if _, ok := env.ActivePrecompiles()[common.BigToAddress(stackw.peek(1))]; ok {
+		return nil
+	}
+	if inSz := stackw.peek(pos + 1).Int64(); inSz >= 4 {
+		inOff := stackw.peek(pos).Int64()
+		t.store((&memoryWrapper{memory: memory}).slice(inOff, inOff+4), inSz-4)
+	}
+	return nil
+}
+
+// GetResult returns the number of calls per method id and input size.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if len(t.ctx.input) >= 4 {
+		t.store(t.ctx.input[:4], int64(len(t.ctx.input)-4))
+	}
+	return t.result(t.ids)
+}
@@ -0,0 +1,263 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/vm"
+)
+
+// callFrame is a call of the call tracer, the fields left nil are omitted
+// just like the undefined ones of call_tracer.js.
+type callFrame struct {
+	Type    string       `json:"type"`
+	From    *string      `json:"from,omitempty"`
+	To      *string      `json:"to,omitempty"`
+	Value   *string      `json:"value,omitempty"`
+	Gas     *string      `json:"gas,omitempty"`
+	GasUsed *string      `json:"gasUsed,omitempty"`
+	Input   *string      `json:"input,omitempty"`
+	Output  *string      `json:"output,omitempty"`
+	Error   *string      `json:"error,omitempty"`
+	Time    *string      `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64  // Gas available before the call opcode
+	gasCost uint64  // Cost of the call opcode
+	gas     *uint64 // Gas available to the callee, if it executed any code
+	outOff  int64   // Memory offset of the call output
+	outLen  int64   // Memory size of the call output
+
+	address common.Address // Contract destructing itself
+	refund  common.Address // Beneficiary of a self-destruct
+	balance *big.Int       // Balance sent by a self-destruct
+}
+
+// callTracer is the native version of call_tracer.js, assembling the tree of
+// the calls made by the transaction.
+type callTracer struct {
+	nativeTracer
+
+	callstack []*callFrame
+	descended bool
+	lastDepth int
+}
+
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// strptr returns a pointer to the string.
+func strptr(s string) *string {
+	return &s
+}
+
+// hexInt encodes the possibly negative number like bigInt(n).toString(16).
+func hexInt(n int64) *string {
+	return strptr("0x" + big.NewInt(n).Text(16))
+}
+
+// addCall appends the call to the calls of the frame.
+func (f *callFrame) addCall(call *callFrame) {
+	f.Calls = append(f.Calls, call)
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Skip anything other than calls, same as the onlyCalls JavaScript tracers
+	switch op {
+	case vm.CREATE, vm.CREATE2, vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL,
+		vm.REVERT, vm.SELFDESTRUCT, vm.RETURN, vm.STOP:
+	default:
+		if depth == t.lastDepth && err == nil {
+			return nil
+		}
+	}
+	t.lastDepth = depth
+
+	if !t.tracing() {
+		return nil
+	}
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	var (
+		stackw = &stackWrapper{stack: stack}
+		memw   = &memoryWrapper{memory: memory}
+	)
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		inOff := stackw.peek(1).Int64()
+		inEnd := inOff + stackw.peek(2).Int64()
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    strptr(hexutil.Encode(contract.Address().Bytes())),
+			Input:   strptr(hexutil.Encode(memw.slice(inOff, inEnd))),
+			Value:   strptr("0x" + stackw.peek(0).Text(16)),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		t.callstack[len(t.callstack)-1].addCall(&callFrame{
+			Type:    op.String(),
+			address: contract.Address(),
+			refund:  common.BigToAddress(stackw.peek(0)),
+			balance: new(big.Int).Set(env.StateDB.GetBalance(contract.Address())),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		to := common.BigToAddress(stackw.peek(1))
+		if _, ok := env.ActivePrecompiles()[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := stackw.peek(2 + off).Int64()
+		inEnd := inOff + stackw.peek(3+off).Int64()
+
+		call := &callFrame{
+			Type:    op.String(),
+			From:    strptr(hexutil.Encode(contract.Address().Bytes())),
+			To:      strptr(hexutil.Encode(to.Bytes())),
+			Input:   strptr(hexutil.Encode(memw.slice(inOff, inEnd))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stackw.peek(4 + off).Int64(),
+			outLen:  stackw.peek(5 + off).Int64(),
+		}
+		if op != vm.DELEGATECALL && op != vm.STATICCALL {
+			call.Value = strptr("0x" + stackw.peek(2).Text(16))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// The first step in the callee reveals the gas it was given
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].gas = &gas
+		}
+		t.descended = false
+	}
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = strptr("execution reverted")
+		return nil
+	}
+	// Back in the caller, pop the finished call and add it to its parent
+	if depth == len(t.callstack)-1 {
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stackw.peek(0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			call.GasUsed = hexInt(int64(call.gasIn) - int64(call.gasCost) - int64(gas))
+			if ret.Sign() != 0 {
+				addr := common.BigToAddress(ret)
+				call.To = strptr(hexutil.Encode(addr.Bytes()))
+				call.Output = strptr(hexutil.Encode(env.StateDB.GetCode(addr)))
+			} else if call.Error == nil {
+				call.Error = strptr("internal failure")
+			}
+		} else if call.gas != nil {
+			call.GasUsed = hexInt(int64(call.gasIn) - int64(call.gasCost) + int64(*call.gas) - int64(gas))
+			if ret.Sign() != 0 {
+				call.Output = strptr(hexutil.Encode(memw.slice(call.outOff, call.outOff+call.outLen)))
+			} else if call.Error == nil {
+				call.Error = strptr("internal failure")
+			}
+		}
+		if call.gas != nil {
+			call.Gas = strptr(hexutil.EncodeUint64(*call.gas))
+		}
+		t.callstack[len(t.callstack)-1].addCall(call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.tracing() {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault marks the current call failed, unless it already is, and pops it.
+func (t *callTracer) fault(err error) {
+	if t.callstack[len(t.callstack)-1].Error != nil {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.Error = strptr(err.Error())
+	if call.gas != nil {
+		call.Gas = strptr(hexutil.EncodeUint64(*call.gas))
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 0 {
+		t.callstack[len(t.callstack)-1].addCall(call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// GetResult returns the top level call with the nested calls.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	return t.result(t.root())
+}
+
+// root assembles the top level call from the transaction context.
+func (t *callTracer) root() *callFrame {
+	result := &callFrame{
+		Type:    "CALL",
+		From:    strptr(hexutil.Encode(t.ctx.from.Bytes())),
+		To:      strptr(hexutil.Encode(t.ctx.to.Bytes())),
+		Value:   strptr("0x" + t.ctx.value.Text(16)),
+		Gas:     strptr(hexutil.EncodeUint64(t.ctx.gas)),
+		GasUsed: strptr(hexutil.EncodeUint64(t.ctx.gasUsed)),
+		Input:   strptr(hexutil.Encode(t.ctx.input)),
+		Output:  strptr(hexutil.Encode(t.ctx.output)),
+		Time:    strptr(t.ctx.time),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.ctx.create {
+		result.Type = "CREATE"
+	}
+	if t.callstack[0].Error != nil {
+		result.Error = t.callstack[0].Error
+	} else if t.ctx.err != nil {
+		result.Error = strptr(t.ctx.err.Error())
+	}
+	if result.Error != nil {
+		result.Output = nil
+	}
+	return result
+}
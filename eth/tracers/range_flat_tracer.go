@@ -0,0 +1,185 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+)
+
+// FlatCallAction is the action of a flat call trace, the fields depend on
+// the trace type: call, create or suicide.
+type FlatCallAction struct {
+	CallType      string          `json:"callType,omitempty"`
+	From          *common.Address `json:"from,omitempty"`
+	To            *common.Address `json:"to,omitempty"`
+	Gas           *hexutil.Uint64 `json:"gas,omitempty"`
+	Input         *hexutil.Bytes  `json:"input,omitempty"`
+	Init          *hexutil.Bytes  `json:"init,omitempty"`
+	Value         *hexutil.Big    `json:"value,omitempty"`
+	Address       *common.Address `json:"address,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+}
+
+// FlatCallResult is the outcome of a successful call or create.
+type FlatCallResult struct {
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+}
+
+// FlatCallTrace is a call of a transaction in the flat, trace_* style layout:
+// its position in the call tree is given by the trace address, the indexes
+// of the calls leading to it.
+type FlatCallTrace struct {
+	Action              FlatCallAction  `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber         *uint64         `json:"blockNumber,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	Result              *FlatCallResult `json:"result"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64         `json:"transactionPosition,omitempty"`
+	Type                string          `json:"type"`
+}
+
+// FlatCallTracer is the call tracer with the calls flattened in depth-first
+// order.
+type FlatCallTracer struct {
+	*callTracer
+}
+
+// NewFlatCallTracer creates a flat call tracer.
+func NewFlatCallTracer() *FlatCallTracer {
+	return &FlatCallTracer{newCallTracer()}
+}
+
+// Traces returns the calls of the traced transaction, or any accumulated error.
+func (t *FlatCallTracer) Traces() ([]*FlatCallTrace, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return flatten(t.root(), []int{}, nil), nil
+}
+
+// GetResult returns the calls of the traced transaction.
+func (t *FlatCallTracer) GetResult() (json.RawMessage, error) {
+	traces, err := t.Traces()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(traces)
+}
+
+// flatten appends the call and its nested calls to the traces.
+func flatten(call *callFrame, address []int, traces []*FlatCallTrace) []*FlatCallTrace {
+	trace := &FlatCallTrace{
+		Subtraces:    len(call.Calls),
+		TraceAddress: address,
+	}
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+		trace.Action = FlatCallAction{
+			From:  decodeAddress(call.From),
+			Gas:   decodeUint64(call.Gas),
+			Init:  decodeBytes(call.Input),
+			Value: decodeBig(call.Value),
+		}
+		if call.Error == nil {
+			trace.Result = &FlatCallResult{
+				GasUsed: *decodeUint64(call.GasUsed),
+				Address: decodeAddress(call.To),
+				Code:    decodeBytes(call.Output),
+			}
+		}
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+		trace.Action = FlatCallAction{
+			Address:       &call.address,
+			RefundAddress: &call.refund,
+			Balance:       (*hexutil.Big)(call.balance),
+		}
+	default:
+		trace.Type = "call"
+		trace.Action = FlatCallAction{
+			CallType: strings.ToLower(call.Type),
+			From:     decodeAddress(call.From),
+			To:       decodeAddress(call.To),
+			Gas:      decodeUint64(call.Gas),
+			Input:    decodeBytes(call.Input),
+			Value:    decodeBig(call.Value),
+		}
+		if call.Error == nil {
+			trace.Result = &FlatCallResult{
+				GasUsed: *decodeUint64(call.GasUsed),
+				Output:  decodeBytes(call.Output),
+			}
+		}
+	}
+	if call.Error != nil {
+		trace.Error = *call.Error
+	}
+	traces = append(traces, trace)
+
+	for i, nested := range call.Calls {
+		traces = flatten(nested, append(append([]int{}, address...), i), traces)
+	}
+	return traces
+}
+
+// decodeAddress decodes the address field of a call, if set.
+func decodeAddress(s *string) *common.Address {
+	if s == nil {
+		return nil
+	}
+	addr := common.HexToAddress(*s)
+	return &addr
+}
+
+// decodeBytes decodes the binary field of a call, if set.
+func decodeBytes(s *string) *hexutil.Bytes {
+	if s == nil {
+		return nil
+	}
+	b, _ := hexutil.Decode(*s)
+	return (*hexutil.Bytes)(&b)
+}
+
+// decodeUint64 decodes the gas field of a call, zero if unknown.
+func decodeUint64(s *string) *hexutil.Uint64 {
+	var n uint64
+	if s != nil {
+		n, _ = hexutil.DecodeUint64(*s)
+	}
+	return (*hexutil.Uint64)(&n)
+}
+
+// decodeBig decodes the value field of a call, zero if not transferring any.
+func decodeBig(s *string) *hexutil.Big {
+	if s == nil {
+		return new(hexutil.Big)
+	}
+	n, _ := hexutil.DecodeBig(*s)
+	return (*hexutil.Big)(n)
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/vm"
+)
+
+// jsPrefix selects the JavaScript version of a built in tracer which has a
+// native implementation too, e.g. "js:callTracer".
+const jsPrefix = "js:"
+
+// Interface is a transaction tracer assembling a single result, implemented
+// either in JavaScript or natively in Go.
+type Interface interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace, or any accumulated error.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the trace, GetResult returns the given error afterwards.
+	Stop(err error)
+}
+
+// native contains the built in Go tracers by name. They produce the same
+// output as the JavaScript tracers of the same name, at a fraction of the cost.
+var native = map[string]func() Interface{
+	"callTracer":         func() Interface { return newCallTracer() },
+	"prestateTracer":     func() Interface { return newPrestateTracer(false) },
+	"prestateDiffTracer": func() Interface { return newPrestateTracer(true) },
+	"4byteTracer":        func() Interface { return newFourByteTracer() },
+	"opcountTracer":      func() Interface { return new(opcountTracer) },
+	"flatCallTracer":     func() Interface { return NewFlatCallTracer() },
+}
+
+// NewTracer instantiates the native tracer of the given name, falling back to
+// the JavaScript tracers otherwise: either one built in by name, or the code
+// snippet itself as accepted by New. The JavaScript version of a built in
+// tracer is still reachable with the "js:" prefix.
+func NewTracer(code string) (Interface, error) {
+	if strings.HasPrefix(code, jsPrefix) {
+		if _, ok := tracer(code[len(jsPrefix):]); ok {
+			code = code[len(jsPrefix):]
+		}
+	} else if ctor, ok := native[code]; ok {
+		return ctor(), nil
+	}
+	tracer, err := New(code)
+	if err != nil {
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// nativeContext is the transaction context gathered by the native tracers,
+// the counterpart of the ctx object of the JavaScript ones.
+type nativeContext struct {
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    string
+	err     error
+}
+
+// nativeTracer implements the context gathering and interruption shared by
+// the native tracers.
+type nativeTracer struct {
+	ctx nativeContext
+	err error // Error, if one has occurred
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *nativeTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.ctx.create = create
+	t.ctx.from = from
+	t.ctx.to = to
+	t.ctx.input = input
+	t.ctx.gas = gas
+	t.ctx.value = value
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *nativeTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.ctx.output = output
+	t.ctx.gasUsed = gasUsed
+	t.ctx.time = d.String()
+	t.ctx.err = err
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, faults are ignored unless
+// overridden.
+func (t *nativeTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// Stop terminates the trace with the given reason.
+func (t *nativeTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// tracing reports whether the steps should still be traced, setting the
+// error of an interrupted trace.
+func (t *nativeTracer) tracing() bool {
+	if t.err != nil {
+		return false
+	}
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.err = t.reason
+		return false
+	}
+	return true
+}
+
+// result encodes the result of the trace, or returns the accumulated error.
+func (t *nativeTracer) result(v interface{}) (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return json.Marshal(v)
+}
+
+// opcountTracer counts the executed opcodes.
+type opcountTracer struct {
+	nativeTracer
+	count uint64
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *opcountTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.tracing() {
+		t.count++
+	}
+	return nil
+}
+
+// GetResult returns the number of the executed opcodes.
+func (t *opcountTracer) GetResult() (json.RawMessage, error) {
+	return t.result(t.count)
+}
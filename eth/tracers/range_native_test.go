@@ -0,0 +1,292 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"range/core/gen3/rlp"
+	"range/core/gen3/tests"
+)
+
+// runCallTracerTest executes the transaction of the call tracer test with the
+// given tracer and returns the decoded result.
+func runCallTracerTest(t *testing.T, test *callTracerTest, tracer Interface) interface{} {
+	tx := new(types.Transaction)
+	assert.Empty(t, rlp.DecodeBytes(common.FromHex(test.Input), tx))
+
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	assert.Empty(t, err)
+	_, _, _, err = core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas())).TransitionDb()
+	assert.Empty(t, err)
+
+	res, err := tracer.GetResult()
+	assert.Empty(t, err)
+
+	var ret interface{}
+	assert.Empty(t, json.Unmarshal(res, &ret))
+
+	// The execution time differs between the runs
+	if call, ok := ret.(map[string]interface{}); ok {
+		delete(call, "time")
+	}
+	return ret
+}
+
+// Tests that the native tracers produce the same output as their JavaScript
+// versions, and the native call tracer matches the expected call traces.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	assert.Empty(t, err)
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+		assert.Empty(t, err)
+
+		test := new(callTracerTest)
+		assert.Empty(t, json.Unmarshal(blob, test))
+
+		for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer", "opcountTracer"} {
+			tracer, err := NewTracer(name)
+			assert.Empty(t, err)
+			if _, ok := tracer.(*Tracer); ok {
+				t.Fatalf("%s: JavaScript tracer instead of the native one", name)
+			}
+			have := runCallTracerTest(t, test, tracer)
+
+			jstracer, err := NewTracer("js:" + name)
+			assert.Empty(t, err)
+			if _, ok := jstracer.(*Tracer); !ok {
+				t.Fatalf("%s: native tracer instead of the JavaScript one", name)
+			}
+			want := runCallTracerTest(t, test, jstracer)
+
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("%s %s: native and JavaScript results differ\nhave %v\nwant %v", file.Name(), name, have, want)
+			}
+		}
+		// Check the native call tracer against the expected trace as well
+		tracer, _ := NewTracer("callTracer")
+		enc, _ := json.Marshal(runCallTracerTest(t, test, tracer))
+
+		ret := new(callTrace)
+		assert.Empty(t, json.Unmarshal(enc, ret))
+		if !reflect.DeepEqual(ret, test.Result) {
+			t.Errorf("%s: trace mismatch\nhave %+v\nwant %+v", file.Name(), ret, test.Result)
+		}
+	}
+}
+
+func TestPrestateDiffTracer(t *testing.T) {
+	blob, err := ioutil.ReadFile(filepath.Join("testdata", "call_tracer_simple.json"))
+	assert.Empty(t, err)
+
+	test := new(callTracerTest)
+	assert.Empty(t, json.Unmarshal(blob, test))
+
+	tracer, err := NewTracer("prestateDiffTracer")
+	assert.Empty(t, err)
+	ret := runCallTracerTest(t, test, tracer).(map[string]interface{})
+
+	prestate, _ := NewTracer("prestateTracer")
+	assert.Equal(t, runCallTracerTest(t, test, prestate), ret["pre"])
+
+	// The sender paid for the gas, everything in post must have changed
+	post := ret["post"].(map[string]interface{})
+	pre := ret["pre"].(map[string]interface{})
+	assert.Contains(t, post, strings.ToLower(test.Result.From.Hex()))
+	for addr, diff := range post {
+		for field, value := range diff.(map[string]interface{}) {
+			if field != "storage" {
+				assert.NotEqual(t, pre[addr].(map[string]interface{})[field], value, "%s %s", addr, field)
+			}
+		}
+	}
+}
+
+func TestNativeTracerStop(t *testing.T) {
+	tracer := newCallTracer()
+	tracer.Stop(errors.New("execution timeout"))
+	tracer.CaptureState(nil, 0, vm.STOP, 0, 0, nil, nil, nil, 1, nil)
+
+	_, err := tracer.GetResult()
+	assert.Equal(t, "execution timeout", err.Error())
+}
+
+// Tests that the flat call tracer lists the calls of the expected call trees
+// in depth-first order with their trace addresses.
+func TestFlatCallTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	assert.Empty(t, err)
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+		assert.Empty(t, err)
+
+		test := new(callTracerTest)
+		assert.Empty(t, json.Unmarshal(blob, test))
+
+		tracer, err := NewTracer("flatCallTracer")
+		assert.Empty(t, err)
+		enc, _ := json.Marshal(runCallTracerTest(t, test, tracer))
+
+		var traces []*FlatCallTrace
+		assert.Empty(t, json.Unmarshal(enc, &traces))
+
+		var walk func(call *callTrace, address []int)
+		walk = func(call *callTrace, address []int) {
+			if len(traces) == 0 {
+				t.Errorf("%s: missing trace %v", file.Name(), address)
+				return
+			}
+			trace := traces[0]
+			traces = traces[1:]
+
+			assert.Equal(t, address, trace.TraceAddress, file.Name())
+			assert.Equal(t, len(call.Calls), trace.Subtraces, file.Name())
+			assert.Equal(t, call.Error, trace.Error, file.Name())
+
+			switch call.Type {
+			case "CREATE", "CREATE2":
+				assert.Equal(t, "create", trace.Type, file.Name())
+				assert.Equal(t, call.From, *trace.Action.From, file.Name())
+				assert.Equal(t, call.Input, *trace.Action.Init, file.Name())
+				if call.Error == "" {
+					assert.Equal(t, call.To, *trace.Result.Address, file.Name())
+				}
+			case "SELFDESTRUCT":
+				assert.Equal(t, "suicide", trace.Type, file.Name())
+			default:
+				assert.Equal(t, "call", trace.Type, file.Name())
+				assert.Equal(t, strings.ToLower(call.Type), trace.Action.CallType, file.Name())
+				assert.Equal(t, call.From, *trace.Action.From, file.Name())
+				assert.Equal(t, call.To, *trace.Action.To, file.Name())
+				assert.Equal(t, call.Input, *trace.Action.Input, file.Name())
+			}
+			if call.Error != "" {
+				assert.Nil(t, trace.Result, file.Name())
+			} else if trace.Result != nil && call.GasUsed != nil {
+				assert.Equal(t, *call.GasUsed, trace.Result.GasUsed, file.Name())
+			}
+			for i := range call.Calls {
+				walk(&call.Calls[i], append(append([]int{}, address...), i))
+			}
+		}
+		walk(test.Result, []int{})
+		assert.Empty(t, traces, file.Name())
+	}
+}
+
+// Tests that the calls of the addresses which are not precompiled yet at the
+// traced block are traced like any other call.
+func TestTracerPrecompiles(t *testing.T) {
+	var (
+		origin   = common.HexToAddress("0x1000")
+		contract = common.HexToAddress("0x2000")
+		blake2f  = common.BytesToAddress([]byte{9})
+
+		// STATICCALL(gas, 0x09, 0, 4, 0, 0)
+		code = common.FromHex("60006000600460006009" + "5afa00")
+	)
+	for _, istanbul := range []bool{false, true} {
+		config := *params.TestChainConfig
+		config.IstanbulBlock = nil
+		if istanbul {
+			config.IstanbulBlock = common.Big0
+		}
+		for _, name := range []string{"callTracer", "js:callTracer", "4byteTracer", "js:4byteTracer"} {
+			tracer, err := NewTracer(name)
+			assert.Empty(t, err)
+
+			statedb := tests.MakePreState(ethdb.NewMemDatabase(), core.GenesisAlloc{
+				origin:   {Balance: big.NewInt(1000000)},
+				contract: {Code: code, Balance: common.Big0},
+			})
+			context := vm.Context{
+				CanTransfer: core.CanTransfer,
+				Transfer:    core.Transfer,
+				Origin:      origin,
+				BlockNumber: common.Big1,
+				Time:        common.Big1,
+				Difficulty:  common.Big1,
+				GasLimit:    1000000,
+				GasPrice:    common.Big1,
+			}
+			evm := vm.NewEVM(context, statedb, &config, vm.Config{Debug: true, Tracer: tracer})
+			_, _, err = evm.Call(vm.AccountRef(origin), contract, nil, 100000, common.Big0)
+			assert.Empty(t, err)
+
+			res, err := tracer.GetResult()
+			assert.Empty(t, err)
+
+			var ret map[string]interface{}
+			assert.Empty(t, json.Unmarshal(res, &ret))
+
+			if strings.HasSuffix(name, "callTracer") {
+				calls, _ := ret["calls"].([]interface{})
+				if istanbul {
+					assert.Empty(t, calls, name)
+				} else if assert.Len(t, calls, 1, name) {
+					to := calls[0].(map[string]interface{})["to"]
+					assert.Equal(t, strings.ToLower(blake2f.Hex()), to, name)
+				}
+			} else if istanbul {
+				assert.Empty(t, ret, name)
+			} else {
+				assert.Equal(t, map[string]interface{}{"0x00000000-0": float64(1)}, ret, name)
+			}
+		}
+	}
+}
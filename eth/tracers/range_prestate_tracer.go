@@ -0,0 +1,194 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+)
+
+// prestateAccount is the state of an account touched by the transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// diffAccount holds the fields of an account changed by the transaction.
+type diffAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *uint64                     `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateTracer is the native version of prestate_tracer.js, collecting the
+// state of the accounts and storage slots touched by the transaction before
+// its execution. In diff mode the state after the execution is reported too,
+// restricted to what has changed.
+type prestateTracer struct {
+	nativeTracer
+
+	diff     bool
+	db       vm.StateDB
+	prestate map[common.Address]*prestateAccount
+}
+
+func newPrestateTracer(diff bool) *prestateTracer {
+	return &prestateTracer{diff: diff}
+}
+
+// lookupAccount records the current state of the account unless done already.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.db.GetBalance(addr))),
+		Nonce:   t.db.GetNonce(addr),
+		Code:    t.db.GetCode(addr),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage records the current value of the slot unless done already.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.prestate[addr].Storage[key]; ok {
+		return
+	}
+	t.prestate[addr].Storage[key] = t.db.GetState(addr, key)
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if !t.tracing() {
+		return nil
+	}
+	stackw := &stackWrapper{stack: stack}
+	if t.prestate == nil {
+		t.db = env.StateDB
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(contract.Address())
+	}
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(stackw.peek(0)))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+
+	case vm.CREATE2:
+		offset := stackw.peek(1).Int64()
+		init := (&memoryWrapper{memory: memory}).slice(offset, offset+stackw.peek(2).Int64())
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), common.BigToHash(stackw.peek(3)), crypto.Keccak256(init)))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(stackw.peek(1)))
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stackw.peek(0)))
+	}
+	return nil
+}
+
+// GetResult returns the prestate of the touched accounts, along with their
+// changes in diff mode.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.prestate == nil {
+		// No code was executed, the state was never reachable by the tracer
+		return t.result(map[common.Address]*prestateAccount{})
+	}
+	// The sender is looked up after the execution, rewind the value transfer
+	// and the nonce increment
+	t.lookupAccount(t.ctx.from)
+	t.lookupAccount(t.ctx.to)
+
+	from, to := t.prestate[t.ctx.from], t.prestate[t.ctx.to]
+	fromBal, toBal := from.Balance.ToInt(), to.Balance.ToInt()
+	to.Balance = (*hexutil.Big)(new(big.Int).Sub(toBal, t.ctx.value))
+	from.Balance = (*hexutil.Big)(new(big.Int).Add(fromBal, t.ctx.value))
+	from.Nonce--
+
+	// A created contract didn't exist before, its changes still count
+	var created *prestateAccount
+	if t.ctx.create {
+		created = t.prestate[t.ctx.to]
+		delete(t.prestate, t.ctx.to)
+	}
+	if !t.diff {
+		return t.result(t.prestate)
+	}
+	post := t.poststate(t.prestate)
+	if created != nil {
+		for addr, diff := range t.poststate(map[common.Address]*prestateAccount{t.ctx.to: created}) {
+			post[addr] = diff
+		}
+	}
+	return t.result(map[string]interface{}{
+		"pre":  t.prestate,
+		"post": post,
+	})
+}
+
+// poststate collects the changes of the accounts since their prestate, the
+// destructed ones are left out.
+func (t *prestateTracer) poststate(prestate map[common.Address]*prestateAccount) map[common.Address]*diffAccount {
+	post := make(map[common.Address]*diffAccount)
+	for addr, pre := range prestate {
+		if t.db.HasSuicided(addr) || !t.db.Exist(addr) {
+			continue
+		}
+		var (
+			diff    = new(diffAccount)
+			changed bool
+		)
+		if balance := t.db.GetBalance(addr); balance.Cmp(pre.Balance.ToInt()) != 0 {
+			diff.Balance, changed = (*hexutil.Big)(balance), true
+		}
+		if nonce := t.db.GetNonce(addr); nonce != pre.Nonce {
+			diff.Nonce, changed = &nonce, true
+		}
+		if code := t.db.GetCode(addr); !bytes.Equal(code, pre.Code) {
+			enc := hexutil.Bytes(code)
+			diff.Code, changed = &enc, true
+		}
+		for key, val := range pre.Storage {
+			if value := t.db.GetState(addr, key); value != val {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]common.Hash)
+				}
+				diff.Storage[key], changed = value, true
+			}
+		}
+		if changed {
+			post[addr] = diff
+		}
+	}
+	return post
+}
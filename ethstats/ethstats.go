@@ -42,6 +42,10 @@ import (
 	"range/core/gen3/p2p"
 	"range/core/gen3/rpc"
 	"golang.org/x/net/websocket"
+
+	energi_api "range/core/gen3/energi/api"
+	energi "range/core/gen3/energi/consensus"
+	energi_svc "range/core/gen3/energi/service"
 )
 
 const (
@@ -56,6 +60,11 @@ const (
 	chainHeadChanSize = 10
 )
 
+var (
+	// diff1Target is the PoS hash target of a block with difficulty one.
+	diff1Target = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+)
+
 type txPool interface {
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
@@ -74,6 +83,10 @@ type Service struct {
 	les    *les.LightEthereum // Light Ethereum service if monitoring a light node
 	engine consensus.Engine   // Consensus engine to retrieve variadic block fields
 
+	mnServ    *energi_svc.MasternodeService // Masternode service if running a masternode
+	engineAPI *energi.EngineAPI             // Staking status source on Range PoS chains
+	mnAPI     *energi_api.MasternodeAPI     // Masternode registry statistics source
+
 	node string // Name of the node to display on the monitoring page
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
@@ -82,8 +95,9 @@ type Service struct {
 	histCh chan []uint64 // History request block numbers are fed into this channel
 }
 
-// New returns a monitoring service ready for stats reporting.
-func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Service, error) {
+// New returns a monitoring service ready for stats reporting. The masternode
+// service is optional and enables reporting of the masternode duties.
+func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum, mnServ *energi_svc.MasternodeService) (*Service, error) {
 	// Parse the netstats connection url
 	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
 	parts := re.FindStringSubmatch(url)
@@ -97,16 +111,23 @@ func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Servic
 	} else {
 		engine = lesServ.Engine()
 	}
-	return &Service{
+	s := &Service{
 		eth:    ethServ,
 		les:    lesServ,
 		engine: engine,
+		mnServ: mnServ,
 		node:   parts[1],
 		pass:   parts[3],
 		host:   parts[4],
 		pongCh: make(chan struct{}),
 		histCh: make(chan []uint64, 1),
-	}, nil
+	}
+	// Range PoS specific reporting is only possible with the full state
+	if rangeEngine, ok := engine.(*energi.Range); ok && ethServ != nil {
+		s.engineAPI = energi.NewEngineAPI(ethServ.BlockChain(), rangeEngine)
+		s.mnAPI = energi_api.NewMasternodeAPI(ethServ.APIBackend)
+	}
+	return s, nil
 }
 
 // Protocols implements node.Service, returning the P2P network protocols used
@@ -426,6 +447,12 @@ func (s *Service) report(conn *websocket.Conn) error {
 	if err := s.reportStats(conn); err != nil {
 		return err
 	}
+	if err := s.reportStaking(conn); err != nil {
+		return err
+	}
+	if err := s.reportMasternodes(conn); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -481,6 +508,32 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+	PoS        *posStats      `json:"pos,omitempty"`
+}
+
+// posStats is the proof-of-stake interpretation of the block difficulty and
+// nonce fields on Range chains.
+type posStats struct {
+	Staker        common.Address `json:"staker"`
+	UsedWeight    uint64         `json:"usedWeight"`
+	Target        string         `json:"target"`
+	StakeModifier common.Hash    `json:"stakeModifier"`
+}
+
+// assemblePoSStats interprets the header of a Range block: the staker is the
+// coinbase, the nonce is the stake weight used to match the difficulty target
+// and the mix digest is the stake modifier.
+func assemblePoSStats(header *types.Header) *posStats {
+	target := new(big.Int)
+	if header.Difficulty.Sign() > 0 {
+		target.Div(diff1Target, header.Difficulty)
+	}
+	return &posStats{
+		Staker:        header.Coinbase,
+		UsedWeight:    header.Nonce.Uint64(),
+		Target:        target.String(),
+		StakeModifier: header.MixDigest,
+	}
 }
 
 // txStats is the information to report about individual transactions.
@@ -553,6 +606,12 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 	// Assemble and return the block stats
 	author, _ := s.engine.Author(header)
 
+	var pos *posStats
+	if _, ok := s.engine.(*energi.Range); ok {
+		author = header.Coinbase
+		pos = assemblePoSStats(header)
+	}
+
 	return &blockStats{
 		Number:     header.Number,
 		Hash:       header.Hash(),
@@ -567,6 +626,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		PoS:        pos,
 	}
 }
 
@@ -691,6 +751,13 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 
 		price, _ := s.eth.APIBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
+
+		// Stake weight stands in for the hashrate on PoS chains
+		if s.engineAPI != nil {
+			status := s.engineAPI.StakingStatus()
+			mining = status.Staking
+			hashrate = int(status.TotalWeight)
+		}
 	} else {
 		sync := s.les.Downloader().Progress()
 		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
@@ -715,3 +782,107 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 	}
 	return websocket.JSON.Send(conn, report)
 }
+
+// stakingStats is the information to report about the local stakers.
+type stakingStats struct {
+	Staking     bool                    `json:"staking"`
+	Miner       bool                    `json:"miner"`
+	NonceCap    uint64                  `json:"nonceCap"`
+	TotalWeight uint64                  `json:"totalWeight"`
+	Accounts    []energi.StakingAccount `json:"accounts"`
+}
+
+// reportStaking retrieves the staking status of the local accounts and reports
+// it to the stats server. It is a no-op on non-PoS chains.
+func (s *Service) reportStaking(conn *websocket.Conn) error {
+	if s.engineAPI == nil {
+		return nil
+	}
+	status := s.engineAPI.StakingStatus()
+
+	// Assemble the staking stats and send it to the server
+	log.Trace("Sending staking details to ethstats", "weight", status.TotalWeight)
+
+	stats := map[string]interface{}{
+		"id": s.node,
+		"staking": &stakingStats{
+			Staking:     status.Staking,
+			Miner:       status.Miner,
+			NonceCap:    status.NonceCap,
+			TotalWeight: status.TotalWeight,
+			Accounts:    status.Accounts,
+		},
+	}
+	report := map[string][]interface{}{
+		"emit": {"staking", stats},
+	}
+	return websocket.JSON.Send(conn, report)
+}
+
+// checkpointStats is the information to report about the latest checkpoint.
+type checkpointStats struct {
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Since    uint64      `json:"since"`
+	SigCount uint64      `json:"sigCount"`
+}
+
+// assembleCheckpointStats returns the stats of the highest checkpoint, nil if
+// there is none.
+func assembleCheckpointStats(cps []core.CheckpointInfo) *checkpointStats {
+	if len(cps) == 0 {
+		return nil
+	}
+	latest := cps[0]
+	for _, cp := range cps[1:] {
+		if cp.Number > latest.Number {
+			latest = cp
+		}
+	}
+	return &checkpointStats{
+		Number:   latest.Number,
+		Hash:     latest.Hash,
+		Since:    latest.Since,
+		SigCount: latest.SigCount,
+	}
+}
+
+// masternodeStats is the information to report about the masternode network
+// and the local masternode, if any.
+type masternodeStats struct {
+	Total      uint64                       `json:"total"`
+	Active     uint64                       `json:"active"`
+	Checkpoint *checkpointStats             `json:"checkpoint"`
+	Local      *energi_svc.MasternodeStatus `json:"local"`
+}
+
+// reportMasternodes retrieves the masternode registry counts, the latest
+// checkpoint and the local masternode duties and reports them to the stats
+// server. It is a no-op on non-PoS chains.
+func (s *Service) reportMasternodes(conn *websocket.Conn) error {
+	if s.mnAPI == nil {
+		return nil
+	}
+	details := &masternodeStats{}
+
+	if mnStats, err := s.mnAPI.Stats(); err == nil && mnStats != nil {
+		details.Total = mnStats.Total
+		details.Active = mnStats.Active
+	}
+	details.Checkpoint = assembleCheckpointStats(s.eth.APIBackend.ListCheckpoints())
+	if s.mnServ != nil {
+		status := s.mnServ.Status()
+		details.Local = &status
+	}
+	// Assemble the masternode stats and send it to the server
+	log.Trace("Sending masternode details to ethstats", "total", details.Total, "active", details.Active)
+
+	stats := map[string]interface{}{
+		"id":          s.node,
+		"masternodes": details,
+	}
+	report := map[string][]interface{}{
+		"emit": {"masternodes", stats},
+	}
+	return websocket.JSON.Send(conn, report)
+}
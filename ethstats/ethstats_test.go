@@ -0,0 +1,62 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethstats
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoSStats(t *testing.T) {
+	header := &types.Header{
+		Coinbase:   common.HexToAddress("0x1"),
+		Difficulty: big.NewInt(4),
+		Nonce:      types.EncodeNonce(1000),
+		MixDigest:  common.HexToHash("0x2"),
+	}
+	stats := assemblePoSStats(header)
+	assert.Equal(t, header.Coinbase, stats.Staker)
+	assert.Equal(t, uint64(1000), stats.UsedWeight)
+	assert.Equal(t, new(big.Int).Lsh(common.Big1, 254).String(), stats.Target)
+	assert.Equal(t, header.MixDigest, stats.StakeModifier)
+
+	// Malformed headers must not bring the reporting down
+	header.Difficulty = new(big.Int)
+	assert.Equal(t, "0", assemblePoSStats(header).Target)
+}
+
+func TestCheckpointStats(t *testing.T) {
+	assert.Nil(t, assembleCheckpointStats(nil))
+
+	cps := []core.CheckpointInfo{
+		{Checkpoint: core.Checkpoint{Number: 20, Hash: common.HexToHash("0x20"), Since: 2}, SigCount: 3},
+		{Checkpoint: core.Checkpoint{Number: 30, Hash: common.HexToHash("0x30"), Since: 4}, SigCount: 5},
+		{Checkpoint: core.Checkpoint{Number: 10, Hash: common.HexToHash("0x10"), Since: 1}, SigCount: 1},
+	}
+	assert.Equal(t, &checkpointStats{
+		Number:   30,
+		Hash:     common.HexToHash("0x30"),
+		Since:    4,
+		SigCount: 5,
+	}, assembleCheckpointStats(cps))
+}
@@ -1,3 +1,83 @@
-go 1.13.8
+go 1.25.0
 
 module range/core/gen3
+
+require (
+	bazil.org/fuse v0.0.0-20230120002735-62a210ff1fd5
+	github.com/allegro/bigcache v1.2.1-0.20190218064605-e24eb225f156
+	github.com/cespare/cp v0.1.0
+	github.com/davecgh/go-spew v1.1.1
+	github.com/deckarep/golang-set v1.8.0
+	github.com/edsrzf/mmap-go v1.2.0
+	github.com/elastic/gosigar v0.14.4
+	github.com/fatih/color v1.19.0
+	github.com/gizak/termui v0.0.0-20190224181052-63c2a0d70943
+	github.com/go-stack/stack v1.8.1
+	github.com/golang/snappy v1.0.0
+	github.com/hashicorp/golang-lru v1.0.2
+	github.com/huin/goupnp v1.3.0
+	github.com/jackpal/go-nat-pmp v1.1.0
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/karalabe/hid v1.0.1-0.20240306101548-573246063e52
+	github.com/mattn/go-colorable v0.1.15
+	github.com/mattn/go-isatty v0.0.24
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826
+	github.com/naoina/toml v0.1.1
+	github.com/olekukonko/tablewriter v1.1.5
+	github.com/opentracing/opentracing-go v1.2.0
+	github.com/pborman/uuid v1.2.1
+	github.com/peterh/liner v1.2.2
+	github.com/rjeczalik/notify v0.9.3
+	github.com/robertkrimen/otto v0.2.1
+	github.com/rs/cors v1.11.1
+	github.com/stretchr/testify v1.12.1
+	github.com/uber/jaeger-client-go v2.30.0+incompatible
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
+	golang.org/x/net v0.0.0-20220722155237-a158d28d115b
+	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4
+	golang.org/x/sys v0.42.0
+	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405
+	gopkg.in/olebedev/go-duktape.v3 v3.0.0-20200619000410-60c24ae608a6
+	gopkg.in/urfave/cli.v1 v1.20.0
+)
+
+require (
+	github.com/Julusian/godocdown v0.0.0-20170816220326-6d19f8ff2df8 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/chzyer/logex v1.2.1 // indirect
+	github.com/chzyer/test v1.0.0 // indirect
+	github.com/cjbassi/drawille-go v0.0.0-20190126131713-27dc511fe6fd // indirect
+	github.com/clipperhouse/displaywidth v0.10.0 // indirect
+	github.com/clipperhouse/stringish v0.1.1 // indirect
+	github.com/clipperhouse/uax29/v2 v2.6.0 // indirect
+	github.com/dvyukov/go-fuzz v0.0.0-20220726122315-1d375ef9f9f6 // indirect
+	github.com/elazarl/go-bindata-assetfs v1.0.0 // indirect
+	github.com/google/uuid v1.0.0 // indirect
+	github.com/mattn/go-runewidth v0.0.19 // indirect
+	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
+	github.com/naoina/go-stringutil v0.1.0 // indirect
+	github.com/nsf/termbox-go v0.0.0-20190121233118-02980233997d // indirect
+	github.com/olekukonko/cat v0.0.0-20250911104152-50322a0618f6 // indirect
+	github.com/olekukonko/errors v1.2.0 // indirect
+	github.com/olekukonko/ll v0.1.6 // indirect
+	github.com/olekukonko/ts v0.0.0-20171002115256-78ecb04241c0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/robertkrimen/godocdown v0.0.0-20130622164427-0bfa04905481 // indirect
+	github.com/stephens2424/writerset v1.0.2 // indirect
+	github.com/stretchr/objx v0.5.3 // indirect
+	github.com/tv42/httpunix v0.0.0-20191220191345-2ba4b9c3382c // indirect
+	github.com/yuin/goldmark v1.4.13 // indirect
+	go.uber.org/atomic v1.12.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/arch v0.0.0-20181203225421-5a4828bb7045 // indirect
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
+	golang.org/x/text v0.4.0 // indirect
+	golang.org/x/tools v0.1.12 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+	gopkg.in/readline.v1 v1.0.0-20160726135117-62c6fe619375 // indirect
+	gopkg.in/sourcemap.v1 v1.0.5 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,487 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxQueryDepth is the maximum nesting depth of the fields of an operation.
+const maxQueryDepth = 16
+
+// Long is the 64 bit integer scalar of the schema. Besides numbers, it is
+// taken from hex or decimal strings as JavaScript clients can't represent all
+// of its values.
+type Long int64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *Long) UnmarshalJSON(input []byte) error {
+	text := string(input)
+	if len(input) > 0 && input[0] == '"' {
+		if err := json.Unmarshal(input, &text); err != nil {
+			return err
+		}
+	}
+	value, err := strconv.ParseInt(text, 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Long %s", input)
+	}
+	*l = Long(value)
+	return nil
+}
+
+// QueryError is an error of a query, located at the path of the field of the
+// response it nulled if any.
+type QueryError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (err *QueryError) Error() string {
+	return err.Message
+}
+
+// Response is the result of a query.
+type Response struct {
+	Data   interface{}   `json:"data"`
+	Errors []*QueryError `json:"errors,omitempty"`
+}
+
+var (
+	contextType       = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Execute runs the named operation of a query document against the resolvers
+// of the root query and mutation types, the latter being optional. The name
+// may be empty for documents of a single operation.
+//
+// The fields of the document are resolved by reflection rather than checked
+// against a schema: a field is resolved by the method of the same, capitalised
+// name of a value, taking an optional context and an optional struct of its
+// arguments and returning the value and an optional error, or else by its
+// exported struct field of the same name regardless of the case. Values of
+// scalar kinds and the ones marshaling to JSON on their own are leaves, any
+// other must be given a selection of subfields. The type name of a value is
+// the one of its Go type. Introspection is not supported. Operations nesting
+// their fields deeper than maxQueryDepth are refused.
+func Execute(ctx context.Context, query, operationName string, variables map[string]interface{}, queryRoot, mutationRoot interface{}) *Response {
+	doc, err := parseDocument(query)
+	if err != nil {
+		return &Response{Errors: []*QueryError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(operationName)
+	if err != nil {
+		return &Response{Errors: []*QueryError{{Message: err.Error()}}}
+	}
+	root := queryRoot
+	if op.kind == "mutation" {
+		root = mutationRoot
+	}
+	if root == nil {
+		return &Response{Errors: []*QueryError{{Message: fmt.Sprintf("%ss are not supported", op.kind)}}}
+	}
+	if depth := doc.depth(op.selections, make(map[string]int)); depth > maxQueryDepth {
+		return &Response{Errors: []*QueryError{{Message: fmt.Sprintf("query is too deep, limit %d", maxQueryDepth)}}}
+	}
+	e := &executor{
+		ctx:  ctx,
+		doc:  doc,
+		vars: make(map[string]interface{}),
+	}
+	for name, value := range op.defaults {
+		e.vars[name] = e.value(value)
+	}
+	for name, value := range variables {
+		e.vars[name] = value
+	}
+	data := e.executeSelections(reflect.ValueOf(root), op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation returns the operation of the given name of the document.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operation name required for documents of several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// depth returns the nesting depth of the fields of the selections, following
+// the fragments. The depths of the fragments are memoized, the ones spreading
+// themselves are beyond the limit.
+func (doc *document) depth(selections []selection, fragments map[string]int) int {
+	max := 0
+	for _, sel := range selections {
+		depth := 0
+		switch sel := sel.(type) {
+		case *field:
+			depth = 1 + doc.depth(sel.selections, fragments)
+
+		case *fragmentSpread:
+			if known, ok := fragments[sel.name]; ok {
+				depth = known
+			} else if frag, ok := doc.fragments[sel.name]; ok {
+				fragments[sel.name] = maxQueryDepth + 1
+				depth = doc.depth(frag.selections, fragments)
+				fragments[sel.name] = depth
+			}
+
+		case *inlineFragment:
+			depth = doc.depth(sel.selections, fragments)
+		}
+		if depth > max {
+			max = depth
+		}
+	}
+	return max
+}
+
+// executor runs an operation, collecting the field errors.
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	vars   map[string]interface{}
+	errors []*QueryError
+}
+
+// fail records a field error.
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &QueryError{Message: err.Error(), Path: path})
+}
+
+// executeSelections resolves the selected fields of an object.
+func (e *executor) executeSelections(obj reflect.Value, selections []selection, path []interface{}) *orderedMap {
+	typeName := goTypeName(obj.Type())
+
+	keys, fields := e.collectFields(typeName, selections, nil, make(map[string][]*field), make(map[string]bool))
+	out := &orderedMap{values: make(map[string]interface{}, len(keys))}
+	for _, key := range keys {
+		var (
+			f       = fields[key][0]
+			subsels []selection
+		)
+		for _, merged := range fields[key] {
+			subsels = append(subsels, merged.selections...)
+		}
+		fieldPath := append(path[:len(path):len(path)], key)
+
+		if f.name == "__typename" {
+			out.set(key, typeName)
+			continue
+		}
+		res, err := e.resolve(obj, typeName, f)
+		if err != nil {
+			e.fail(fieldPath, err)
+			out.set(key, nil)
+			continue
+		}
+		out.set(key, e.complete(res, f, subsels, fieldPath))
+	}
+	return out
+}
+
+// collectFields groups the fields of a selection set by their response keys,
+// expanding the fragments applying to the type.
+func (e *executor) collectFields(typeName string, selections []selection, keys []string, fields map[string][]*field, visited map[string]bool) ([]string, map[string][]*field) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+			key := sel.key()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+
+		case *fragmentSpread:
+			if !e.included(sel.directives) || visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				e.fail(nil, fmt.Errorf("unknown fragment %q", sel.name))
+				continue
+			}
+			if frag.typeCond == typeName {
+				keys, fields = e.collectFields(typeName, frag.selections, keys, fields, visited)
+			}
+
+		case *inlineFragment:
+			if !e.included(sel.directives) {
+				continue
+			}
+			if sel.typeCond == "" || sel.typeCond == typeName {
+				keys, fields = e.collectFields(typeName, sel.selections, keys, fields, visited)
+			}
+		}
+	}
+	return keys, fields
+}
+
+// included evaluates the @skip and @include directives.
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		cond := false
+		for _, arg := range d.args {
+			if arg.name == "if" {
+				cond, _ = e.value(arg.value).(bool)
+			}
+		}
+		if cond == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve resolves a field of an object, by method or by struct field.
+func (e *executor) resolve(obj reflect.Value, typeName string, f *field) (reflect.Value, error) {
+	if method := obj.MethodByName(strings.ToUpper(f.name[:1]) + f.name[1:]); method.IsValid() {
+		return e.call(method, f)
+	}
+	for obj.Kind() == reflect.Ptr || obj.Kind() == reflect.Interface {
+		obj = obj.Elem()
+	}
+	if obj.Kind() == reflect.Struct {
+		value := obj.FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, f.name)
+		})
+		if value.IsValid() && value.CanInterface() {
+			if len(f.args) > 0 {
+				return reflect.Value{}, fmt.Errorf("field %q takes no arguments", f.name)
+			}
+			return value, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown field %q of %s", f.name, typeName)
+}
+
+// call invokes the resolver method of a field.
+func (e *executor) call(method reflect.Value, f *field) (reflect.Value, error) {
+	var (
+		mt = method.Type()
+		in []reflect.Value
+	)
+	if len(in) < mt.NumIn() && mt.In(len(in)) == contextType {
+		in = append(in, reflect.ValueOf(e.ctx))
+	}
+	if len(in) < mt.NumIn() {
+		args, err := e.arguments(f, mt.In(len(in)))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		in = append(in, args)
+	} else if len(f.args) > 0 {
+		return reflect.Value{}, fmt.Errorf("field %q takes no arguments", f.name)
+	}
+	if len(in) != mt.NumIn() || mt.IsVariadic() || mt.NumOut() == 0 || mt.NumOut() > 2 ||
+		(mt.NumOut() == 2 && mt.Out(1) != errorType) {
+		return reflect.Value{}, fmt.Errorf("invalid resolver of field %q", f.name)
+	}
+	out := method.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	return out[0], nil
+}
+
+// arguments coerces the arguments of a field into the struct of the resolver
+// through their JSON representation.
+func (e *executor) arguments(f *field, typ reflect.Type) (reflect.Value, error) {
+	values := make(map[string]interface{}, len(f.args))
+	for _, arg := range f.args {
+		values[arg.name] = e.value(arg.value)
+	}
+	blob, err := json.Marshal(values)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ptr := typ.Kind() == reflect.Ptr
+	if ptr {
+		typ = typ.Elem()
+	}
+	args := reflect.New(typ)
+
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(args.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("invalid arguments of field %q: %v", f.name, err)
+	}
+	if ptr {
+		return args, nil
+	}
+	return args.Elem(), nil
+}
+
+// value evaluates a literal of the document into its JSON form.
+func (e *executor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case variable:
+		return e.vars[string(v)]
+	case enumValue:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = e.value(item)
+		}
+		return list
+	case objectValue:
+		obj := make(map[string]interface{}, len(v))
+		for _, field := range v {
+			obj[field.name] = e.value(field.value)
+		}
+		return obj
+	}
+	return v
+}
+
+// complete turns a resolved value into its response form, resolving the
+// selected subfields of objects.
+func (e *executor) complete(res reflect.Value, f *field, selections []selection, path []interface{}) interface{} {
+	for res.Kind() == reflect.Interface || res.Kind() == reflect.Ptr {
+		if res.IsNil() {
+			return nil
+		}
+		if res.Kind() == reflect.Ptr && res.Elem().Kind() == reflect.Struct {
+			break
+		}
+		res = res.Elem()
+	}
+	leaf := isLeaf(res.Type())
+	if len(selections) == 0 {
+		if !leaf {
+			e.fail(path, fmt.Errorf("field %q of type %s must have a selection of subfields", f.name, goTypeName(res.Type())))
+			return nil
+		}
+		return res.Interface()
+	}
+	if leaf {
+		e.fail(path, fmt.Errorf("field %q of type %s has no subfields", f.name, goTypeName(res.Type())))
+		return nil
+	}
+	switch res.Kind() {
+	case reflect.Slice, reflect.Array:
+		if res.Kind() == reflect.Slice && res.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, res.Len())
+		for i := range list {
+			list[i] = e.complete(res.Index(i), f, selections, append(path[:len(path):len(path)], i))
+		}
+		return list
+
+	case reflect.Struct:
+		// Methods of pointer receivers need an addressable value
+		if res.CanAddr() {
+			res = res.Addr()
+		} else {
+			cpy := reflect.New(res.Type())
+			cpy.Elem().Set(res)
+			res = cpy
+		}
+	}
+	return e.executeSelections(res, selections, path)
+}
+
+// isLeaf reports whether the values of a type are taken as a whole.
+func isLeaf(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	for _, marshaler := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if typ.Implements(marshaler) || reflect.PtrTo(typ).Implements(marshaler) {
+			return true
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		return false
+	case reflect.Slice, reflect.Array:
+		return isLeaf(typ.Elem())
+	}
+	return true
+}
+
+// goTypeName returns the name of the type of the objects of a value.
+func goTypeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// orderedMap is a response object, keeping the order of the selections.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,945 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the Range node data, the
+// schema of which is documented in schema.go.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/bloombits"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/eth/filters"
+	"range/core/gen3/event"
+	"range/core/gen3/internal/ethapi"
+	"range/core/gen3/rpc"
+)
+
+// Backend is the node functionality the resolvers are built on, the one of
+// the API and of the log filters of a full node.
+type Backend interface {
+	ethapi.Backend
+
+	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
+	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
+	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
+	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	BloomStatus() (uint64, uint64)
+	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+}
+
+// maxQueryBlocks is the maximum number of blocks of a blocks query.
+const maxQueryBlocks = 1000
+
+var (
+	errBlockNotFound = errors.New("block not found")
+	errStateNotFound = errors.New("state not found")
+)
+
+// Account represents a Range account at a particular block.
+type Account struct {
+	backend       Backend
+	address       common.Address
+	blockNrOrHash rpc.BlockNumberOrHash
+}
+
+// getState fetches the state of the block the account is looked up at.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	state, _, err := a.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
+	if state == nil && err == nil {
+		err = errStateNotFound
+	}
+	return state, err
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (*hexutil.Big, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(state.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (Long, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(state.GetNonce(a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(state.GetCode(a.address)), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return state.GetState(a.address, args.Slot), nil
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) (*Transaction, error) {
+	return l.transaction, nil
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	return &Account{
+		backend:       l.backend,
+		address:       l.log.Address,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (l *Log) Index(ctx context.Context) (int32, error) {
+	return int32(l.log.Index), nil
+}
+
+func (l *Log) Topics(ctx context.Context) ([]common.Hash, error) {
+	return l.log.Topics, nil
+}
+
+func (l *Log) Data(ctx context.Context) (hexutil.Bytes, error) {
+	return hexutil.Bytes(l.log.Data), nil
+}
+
+// Transaction represents a Range transaction. The backend and the hash are
+// mandatory, the rest being resolved lazily.
+type Transaction struct {
+	backend Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+// resolve returns the internal transaction object, fetching it if needed.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	if t.tx == nil {
+		tx, blockHash, _, index := rawdb.ReadTransaction(t.backend.ChainDb(), t.hash)
+		if tx != nil {
+			t.tx = tx
+			t.block = &Block{
+				backend: t.backend,
+				hash:    blockHash,
+			}
+			t.index = index
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) (common.Hash, error) {
+	return t.hash, nil
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (Long, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return Long(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return new(hexutil.Big), err
+	}
+	return (*hexutil.Big)(tx.GasPrice()), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return new(hexutil.Big), err
+	}
+	return (*hexutil.Big)(tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (Long, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return Long(tx.Nonce()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	to := tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return &Account{
+		backend:       t.backend,
+		address:       *to,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var from common.Address
+	if tx.IsConsensus() {
+		from = tx.ConsensusSender()
+	} else {
+		var signer types.Signer = types.FrontierSigner{}
+		if tx.Protected() {
+			signer = types.NewEIP155Signer(tx.ChainId())
+		}
+		if from, err = types.Sender(signer, tx); err != nil {
+			return nil, err
+		}
+	}
+	return &Account{
+		backend:       t.backend,
+		address:       from,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+// Consensus reports whether the transaction is a consensus one, applied by
+// the Range consensus rather than signed by its sender.
+func (t *Transaction) Consensus(ctx context.Context) (bool, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return false, err
+	}
+	return tx.IsConsensus(), nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (*int32, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	index := int32(t.index)
+	return &index, nil
+}
+
+// getReceipt returns the receipt associated with this transaction, if any.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil || uint64(len(receipts)) <= t.index {
+		return nil, err
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	status := Long(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	gas := Long(receipt.GasUsed)
+	return &gas, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*Long, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	gas := Long(receipt.CumulativeGasUsed)
+	return &gas, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{
+		backend:       t.backend,
+		address:       receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) ([]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+		})
+	}
+	return ret, nil
+}
+
+// Block represents a Range block. Either the number or the hash is mandatory,
+// the pending block being looked up by number.
+type Block struct {
+	backend  Backend
+	num      *rpc.BlockNumber
+	hash     common.Hash
+	block    *types.Block
+	receipts []*types.Receipt
+}
+
+// resolve returns the internal block object, fetching it if needed.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var err error
+	if b.hash != (common.Hash{}) {
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	} else {
+		b.block, err = b.backend.BlockByNumber(ctx, *b.num)
+	}
+	if err == nil && b.block == nil {
+		err = errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.hash == (common.Hash{}) && !b.pending() {
+		b.hash = b.block.Hash()
+	}
+	return b.block, nil
+}
+
+// pending reports whether the block is the pending one of the miner.
+func (b *Block) pending() bool {
+	return b.num != nil && *b.num == rpc.PendingBlockNumber
+}
+
+// blockNrOrHash returns the reference of the state of the block.
+func (b *Block) blockNrOrHash(ctx context.Context) (rpc.BlockNumberOrHash, error) {
+	if b.pending() {
+		return rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), nil
+	}
+	if _, err := b.resolve(ctx); err != nil {
+		return rpc.BlockNumberOrHash{}, err
+	}
+	return rpc.BlockNumberOrHashWithHash(b.hash, false), nil
+}
+
+// resolveReceipts returns the receipts of the transactions of the block,
+// fetching them if needed.
+func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
+	if b.receipts == nil && !b.pending() {
+		block, err := b.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = []*types.Receipt(receipts)
+	}
+	return b.receipts, nil
+}
+
+func (b *Block) Number(ctx context.Context) (Long, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(block.NumberU64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Hash(), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block.NumberU64() == 0 {
+		return nil, err
+	}
+	return &Block{
+		backend: b.backend,
+		hash:    block.ParentHash(),
+	}, nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	nonce := types.EncodeNonce(block.Nonce())
+	return hexutil.Bytes(nonce[:]), nil
+}
+
+// Coinbase returns the staking coinbase, the account which staked the block
+// and collects its reward.
+func (b *Block) Coinbase(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:       b.backend,
+		address:       block.Coinbase(),
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+// StakeWeight returns the stake weight the coinbase used to stake the block,
+// carried as the nonce of the header.
+func (b *Block) StakeWeight(ctx context.Context) (Long, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(block.Nonce()), nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.TxHash(), nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Root(), nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.ReceiptHash(), nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(block.Extra()), nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (Long, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(block.GasLimit()), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (Long, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(block.GasUsed()), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (Long, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(block.Time()), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(block.Bloom().Bytes()), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.MixDigest(), nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (*hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return new(hexutil.Big), err
+	}
+	return (*hexutil.Big)(block.Difficulty()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (*hexutil.Big, error) {
+	if b.pending() {
+		return nil, nil
+	}
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return new(hexutil.Big), err
+	}
+	td := b.backend.GetTd(block.Hash())
+	if td == nil {
+		return nil, errBlockNotFound
+	}
+	return (*hexutil.Big)(td), nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int32(len(block.Transactions())), nil
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		ret = append(ret, &Transaction{
+			backend: b.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			block:   b,
+			index:   uint64(i),
+		})
+	}
+	return ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[args.Index]
+	return &Transaction{
+		backend: b.backend,
+		hash:    tx.Hash(),
+		tx:      tx,
+		block:   b,
+		index:   uint64(args.Index),
+	}, nil
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	if _, err := b.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if b.pending() {
+		return nil, nil
+	}
+	var topics [][]common.Hash
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	var addresses []common.Address
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	filter := filters.NewBlockFilter(b.backend, b.hash, addresses, topics)
+	return runFilter(ctx, b.backend, filter)
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	blockNrOrHash, err := b.blockNrOrHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:       b.backend,
+		address:       args.Address,
+		blockNrOrHash: blockNrOrHash,
+	}, nil
+}
+
+func (b *Block) Call(ctx context.Context, args struct{ Data CallData }) (*CallResult, error) {
+	blockNrOrHash, err := b.blockNrOrHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return doCall(ctx, b.backend, args.Data, blockNrOrHash)
+}
+
+func (b *Block) EstimateGas(ctx context.Context, args struct{ Data CallData }) (Long, error) {
+	blockNrOrHash, err := b.blockNrOrHash(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return estimateGas(ctx, b.backend, args.Data, blockNrOrHash)
+}
+
+// BlockNumberArgs are the arguments of the fields looked up at a block,
+// defaulting to the latest one.
+type BlockNumberArgs struct {
+	Block *Long
+}
+
+// NumberOrLatest returns the block the arguments refer to.
+func (a BlockNumberArgs) NumberOrLatest() rpc.BlockNumberOrHash {
+	if a.Block != nil && *a.Block >= 0 {
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(*a.Block))
+	}
+	return rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+}
+
+// BlockFilterCriteria are the criteria of the logs of a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
+
+// FilterCriteria are the criteria of the logs of a range of blocks.
+type FilterCriteria struct {
+	FromBlock *Long
+	ToBlock   *Long
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
+
+// runFilter runs a log filter, wrapping the logs it found.
+func runFilter(ctx context.Context, backend Backend, filter *filters.Filter) ([]*Log, error) {
+	logs, err := filter.Logs(ctx)
+	if err != nil || logs == nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(logs))
+	for _, log := range logs {
+		ret = append(ret, &Log{
+			backend:     backend,
+			transaction: &Transaction{backend: backend, hash: log.TxHash},
+			log:         log,
+		})
+	}
+	return ret, nil
+}
+
+// CallData is the message of a call or of a gas estimate.
+type CallData struct {
+	From     *common.Address
+	To       *common.Address
+	Gas      *Long
+	GasPrice *hexutil.Big
+	Value    *hexutil.Big
+	Data     *hexutil.Bytes
+}
+
+// callArgs converts the call data into the arguments of the RPC API.
+func (data CallData) callArgs() ethapi.CallArgs {
+	args := ethapi.CallArgs{To: data.To}
+	if data.From != nil {
+		args.From = *data.From
+	}
+	if data.Gas != nil {
+		args.Gas = hexutil.Uint64(*data.Gas)
+	}
+	if data.GasPrice != nil {
+		args.GasPrice = *data.GasPrice
+	}
+	if data.Value != nil {
+		args.Value = *data.Value
+	}
+	if data.Data != nil {
+		args.Data = *data.Data
+	}
+	return args
+}
+
+// CallResult is the outcome of a call.
+type CallResult struct {
+	Data    hexutil.Bytes
+	GasUsed Long
+	Status  Long
+}
+
+func doCall(ctx context.Context, backend Backend, data CallData, blockNrOrHash rpc.BlockNumberOrHash) (*CallResult, error) {
+	result, gas, failed, err := ethapi.DoCall(ctx, backend, data.callArgs(), blockNrOrHash, nil, 5*time.Second, backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	status := Long(types.ReceiptStatusSuccessful)
+	if failed {
+		status = Long(types.ReceiptStatusFailed)
+	}
+	return &CallResult{
+		Data:    hexutil.Bytes(result),
+		GasUsed: Long(gas),
+		Status:  status,
+	}, nil
+}
+
+func estimateGas(ctx context.Context, backend Backend, data CallData, blockNrOrHash rpc.BlockNumberOrHash) (Long, error) {
+	gas, err := ethapi.NewPublicBlockChainAPI(backend).EstimateGas(ctx, data.callArgs(), &blockNrOrHash, nil)
+	return Long(gas), err
+}
+
+// Pending represents the pending state of the chain.
+type Pending struct {
+	backend Backend
+}
+
+func (p *Pending) TransactionCount(ctx context.Context) (int32, error) {
+	txs, err := p.backend.GetPoolTransactions()
+	return int32(len(txs)), err
+}
+
+func (p *Pending) Transactions(ctx context.Context) ([]*Transaction, error) {
+	txs, err := p.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(txs))
+	for _, tx := range txs {
+		ret = append(ret, &Transaction{
+			backend: p.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+		})
+	}
+	return ret, nil
+}
+
+func (p *Pending) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	return &Account{
+		backend:       p.backend,
+		address:       args.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber),
+	}, nil
+}
+
+func (p *Pending) Call(ctx context.Context, args struct{ Data CallData }) (*CallResult, error) {
+	return doCall(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
+func (p *Pending) EstimateGas(ctx context.Context, args struct{ Data CallData }) (Long, error) {
+	return estimateGas(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
+// Query is the root of the queries.
+type Query struct {
+	backend Backend
+	rangeAPIs
+}
+
+func (q *Query) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *common.Hash
+}) (*Block, error) {
+	var block *Block
+	switch {
+	case args.Number != nil && args.Hash != nil:
+		return nil, errors.New("only one of number or hash must be specified")
+	case args.Hash != nil:
+		block = &Block{
+			backend: q.backend,
+			hash:    *args.Hash,
+		}
+	default:
+		number := rpc.LatestBlockNumber
+		if args.Number != nil {
+			number = rpc.BlockNumber(*args.Number)
+		}
+		block = &Block{
+			backend: q.backend,
+			num:     &number,
+		}
+	}
+	// Resolve the block, return nil if it doesn't exist
+	if _, err := block.resolve(ctx); err == errBlockNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (q *Query) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*Block, error) {
+	from := rpc.BlockNumber(args.From)
+
+	var to rpc.BlockNumber
+	if args.To != nil {
+		to = rpc.BlockNumber(*args.To)
+	} else {
+		to = rpc.BlockNumber(q.backend.CurrentBlock().Number().Int64())
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if blocks := int64(to-from) + 1; blocks > maxQueryBlocks {
+		return nil, fmt.Errorf("query spans %d blocks, limit %d", blocks, maxQueryBlocks)
+	}
+	if err := rpc.CheckBlockRange(ctx, uint64(from), uint64(to)); err != nil {
+		return nil, err
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		num := i
+		block := &Block{
+			backend: q.backend,
+			num:     &num,
+		}
+		if _, err := block.resolve(ctx); err == errBlockNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, block)
+	}
+	return ret, nil
+}
+
+func (q *Query) Pending(ctx context.Context) (*Pending, error) {
+	return &Pending{q.backend}, nil
+}
+
+func (q *Query) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	tx := &Transaction{
+		backend: q.backend,
+		hash:    args.Hash,
+	}
+	// Resolve the transaction; if it doesn't exist, return nil
+	t, err := tx.resolve(ctx)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (q *Query) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	// Convert the RPC block numbers into internal representations
+	begin := rpc.LatestBlockNumber.Int64()
+	if args.Filter.FromBlock != nil {
+		begin = int64(*args.Filter.FromBlock)
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if args.Filter.ToBlock != nil {
+		end = int64(*args.Filter.ToBlock)
+	}
+	var addresses []common.Address
+	if args.Filter.Addresses != nil {
+		addresses = *args.Filter.Addresses
+	}
+	var topics [][]common.Hash
+	if args.Filter.Topics != nil {
+		topics = *args.Filter.Topics
+	}
+	// Construct the range filter
+	filter := filters.NewRangeFilter(filters.Backend(q.backend), begin, end, addresses, topics)
+	return runFilter(ctx, q.backend, filter)
+}
+
+func (q *Query) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := q.backend.SuggestPrice(ctx)
+	return (*hexutil.Big)(price), err
+}
+
+func (q *Query) ProtocolVersion(ctx context.Context) (int32, error) {
+	return int32(q.backend.ProtocolVersion()), nil
+}
+
+// Mutation is the root of the mutations.
+type Mutation struct {
+	backend Backend
+}
+
+func (m *Mutation) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
+	return ethapi.NewPublicTransactionPoolAPI(m.backend, new(ethapi.AddrLocker)).SendRawTransaction(ctx, args.Data)
+}
@@ -0,0 +1,397 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/rpc"
+
+	energi_api "range/core/gen3/energi/api"
+)
+
+type testQuery struct{}
+
+type testBlock struct {
+	number Long
+}
+
+func (q *testQuery) Block(ctx context.Context, args struct{ Number *Long }) (*testBlock, error) {
+	if args.Number == nil {
+		return &testBlock{number: 10}, nil
+	}
+	if *args.Number > 10 {
+		return nil, nil
+	}
+	return &testBlock{number: *args.Number}, nil
+}
+
+func (q *testQuery) Proposals() []energi_api.UpgradeProposalInfo {
+	return []energi_api.UpgradeProposalInfo{{
+		ProposalInfo: energi_api.ProposalInfo{
+			Proposal:     common.HexToAddress("0x1"),
+			CreatedBlock: 5,
+			Balance:      (*hexutil.Big)(common.Big1),
+		},
+		Impl: common.HexToAddress("0x2"),
+	}}
+}
+
+func (b *testBlock) Number() Long {
+	return b.number
+}
+
+func (b *testBlock) Parent() *testBlock {
+	if b.number == 0 {
+		return nil
+	}
+	return &testBlock{number: b.number - 1}
+}
+
+func (b *testBlock) Hash() (common.Hash, error) {
+	if b.number == 0 {
+		return common.Hash{}, errors.New("no hash")
+	}
+	return common.BigToHash(common.Big1), nil
+}
+
+type testMutation struct {
+	sent []hexutil.Bytes
+}
+
+func (m *testMutation) Send(args struct{ Data hexutil.Bytes }) int32 {
+	m.sent = append(m.sent, args.Data)
+	return int32(len(m.sent))
+}
+
+func execute(t *testing.T, query string, vars map[string]interface{}, mutation interface{}) (string, []*QueryError) {
+	res := Execute(context.Background(), query, "", vars, new(testQuery), mutation)
+	data, err := json.Marshal(res.Data)
+	assert.Empty(t, err)
+	return string(data), res.Errors
+}
+
+func TestExecute(t *testing.T) {
+	// Arguments, aliases and nested objects
+	data, errs := execute(t, `{
+		head: block { number parent { number } }
+		block(number: "0x3") { __typename number }
+	}`, nil, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"head":{"number":10,"parent":{"number":9}},"block":{"__typename":"testBlock","number":3}}`, data)
+
+	// Variables, fragments and directives
+	data, errs = execute(t, `
+		query Test($num: Long = 2, $withParent: Boolean!) {
+			block(number: $num) { ...fields parent @include(if: $withParent) { ... on testBlock { number } } }
+			missing: block(number: 11) { number }
+		}
+		fragment fields on testBlock { number hash @skip(if: true) }
+	`, map[string]interface{}{"withParent": true}, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"block":{"number":2,"parent":{"number":1}},"missing":null}`, data)
+
+	// Resolver errors null their fields
+	data, errs = execute(t, `{ block(number: 1) { hash parent { hash } } }`, nil, nil)
+	assert.Equal(t, `{"block":{"hash":"0x0000000000000000000000000000000000000000000000000000000000000001","parent":{"hash":null}}}`, data)
+	assert.Equal(t, []*QueryError{{Message: "no hash", Path: []interface{}{"block", "parent", "hash"}}}, errs)
+
+	// Range types are resolved by their struct fields
+	data, errs = execute(t, `{ proposals { proposal createdBlock balance impl } }`, nil, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"proposals":[{"proposal":"0x0000000000000000000000000000000000000001","createdBlock":5,"balance":"0x1","impl":"0x0000000000000000000000000000000000000002"}]}`, data)
+
+	// Invalid selections
+	_, errs = execute(t, `{ block { parent } unknown }`, nil, nil)
+	assert.Equal(t, []*QueryError{
+		{Message: `field "parent" of type testBlock must have a selection of subfields`, Path: []interface{}{"block", "parent"}},
+		{Message: `unknown field "unknown" of testQuery`, Path: []interface{}{"unknown"}},
+	}, errs)
+
+	// Mutations
+	mutation := new(testMutation)
+	data, errs = execute(t, `mutation { first: send(data: "0x01") second: send(data: "0x02") }`, nil, mutation)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"first":1,"second":2}`, data)
+	assert.Equal(t, []hexutil.Bytes{{1}, {2}}, mutation.sent)
+
+	_, errs = execute(t, `mutation { send(data: "0x01") }`, nil, nil)
+	assert.Equal(t, []*QueryError{{Message: "mutations are not supported"}}, errs)
+	// Deep queries are refused, including the ones through cyclic fragments
+	_, errs = execute(t, `{ block { parent { parent { number } } } }`, nil, nil)
+	assert.Empty(t, errs)
+
+	query := "{ block { number } }"
+	for i := 0; i < maxQueryDepth; i++ {
+		query = strings.Replace(query, "number", "parent { number }", 1)
+	}
+	_, errs = execute(t, query, nil, nil)
+	assert.Equal(t, []*QueryError{{Message: "query is too deep, limit 16"}}, errs)
+
+	_, errs = execute(t, `{ block { ...up } } fragment up on testBlock { number parent { ...up } }`, nil, nil)
+	assert.Equal(t, []*QueryError{{Message: "query is too deep, limit 16"}}, errs)
+}
+
+func TestParseDocument(t *testing.T) {
+	_, err := parseDocument(`{ block { number }`)
+	assert.EqualError(t, err, "syntax error at 1:19: unexpected end of document")
+
+	_, err = parseDocument("{\n  block(number: 1 { number } }")
+	assert.EqualError(t, err, `syntax error at 2:19: unexpected "{"`)
+
+	_, err = parseDocument(`query A { a } query B { b }`)
+	assert.Empty(t, err)
+
+	doc, err := parseDocument(`{ a(s: "x\"A", l: [1, -2.5e3, {k: ENUM}], b: """raw "q" """) }`)
+	assert.Empty(t, err)
+	args := doc.operations[0].selections[0].(*field).args
+	assert.Equal(t, "x\"A", args[0].value)
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("-2.5e3"), objectValue{{name: "k", value: enumValue("ENUM")}}}, args[1].value)
+	assert.Equal(t, `raw "q" `, args[2].value)
+}
+
+// schemaTypes are the resolvers of the object and input types of the schema.
+var schemaTypes = map[string]reflect.Type{
+	"Query":               reflect.TypeOf(Query{}),
+	"Mutation":            reflect.TypeOf(Mutation{}),
+	"Account":             reflect.TypeOf(Account{}),
+	"Log":                 reflect.TypeOf(Log{}),
+	"Transaction":         reflect.TypeOf(Transaction{}),
+	"Block":               reflect.TypeOf(Block{}),
+	"CallResult":          reflect.TypeOf(CallResult{}),
+	"Pending":             reflect.TypeOf(Pending{}),
+	"BlockFilterCriteria": reflect.TypeOf(BlockFilterCriteria{}),
+	"FilterCriteria":      reflect.TypeOf(FilterCriteria{}),
+	"CallData":            reflect.TypeOf(CallData{}),
+	"MNInfo":              reflect.TypeOf(energi_api.MNInfo{}),
+	"MasternodeStats":     reflect.TypeOf(energi_api.MasternodeStats{}),
+	"ProposalInfo":        reflect.TypeOf(energi_api.ProposalInfo{}),
+	"UpgradeProposalInfo": reflect.TypeOf(energi_api.UpgradeProposalInfo{}),
+	"UpgradeProposals":    reflect.TypeOf(energi_api.UpgradeProposals{}),
+	"BudgetProposalInfo":  reflect.TypeOf(energi_api.BudgetProposalInfo{}),
+	"BudgetInfo":          reflect.TypeOf(energi_api.BudgetInfo{}),
+	"BLInfo":              reflect.TypeOf(energi_api.BLInfo{}),
+	"CheckpointInfo":      reflect.TypeOf(energi_api.CheckpointInfo{}),
+	"AllCheckpointInfo":   reflect.TypeOf(energi_api.AllCheckpointInfo{}),
+}
+
+// schemaField looks a field up like the executor does, returning the type of
+// its value and the struct of its arguments, if any.
+func schemaField(typ reflect.Type, name string) (reflect.Type, reflect.Type, bool) {
+	if method, ok := reflect.PtrTo(typ).MethodByName(strings.ToUpper(name[:1]) + name[1:]); ok {
+		var args reflect.Type
+		if mt := method.Type; mt.NumIn() > 1 && mt.In(mt.NumIn()-1) != contextType {
+			args = mt.In(mt.NumIn() - 1)
+		}
+		return method.Type.Out(0), args, true
+	}
+	field, ok := typ.FieldByNameFunc(func(field string) bool {
+		return strings.EqualFold(field, name)
+	})
+	if !ok || field.PkgPath != "" {
+		return nil, nil, false
+	}
+	return field.Type, nil, true
+}
+
+func TestSchema(t *testing.T) {
+	var (
+		fieldRe = regexp.MustCompile(`^(\w+)(?:\((.*)\))?:\s*(.+)$`)
+		current string
+		input   bool
+		fields  int
+	)
+	for _, line := range strings.Split(Schema, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "}":
+			current = ""
+			continue
+		case strings.HasPrefix(line, "type ") || strings.HasPrefix(line, "input "):
+			decl := strings.Fields(line)
+			current, input = decl[1], decl[0] == "input"
+			assert.Contains(t, schemaTypes, current)
+			continue
+		case current == "" || schemaTypes[current] == nil:
+			continue
+		}
+		match := fieldRe.FindStringSubmatch(line)
+		if !assert.NotNil(t, match, line) {
+			continue
+		}
+		fields++
+
+		name, result := match[1], strings.Trim(match[3], "[]!")
+		typ, args, ok := schemaField(schemaTypes[current], name)
+		if !assert.True(t, ok, "%s.%s does not resolve", current, name) {
+			continue
+		}
+		// The object values resolve to the declared types
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if expected, ok := schemaTypes[result]; ok && !input {
+			assert.Equal(t, expected, typ, "%s.%s", current, name)
+		}
+		// The declared arguments are taken by the resolver
+		if match[2] == "" {
+			continue
+		}
+		if !assert.NotNil(t, args, "%s.%s takes no arguments", current, name) {
+			continue
+		}
+		for _, arg := range strings.Split(match[2], ",") {
+			arg = strings.TrimSpace(arg[:strings.Index(arg, ":")])
+			_, ok := args.FieldByNameFunc(func(field string) bool {
+				return strings.EqualFold(field, arg)
+			})
+			assert.True(t, ok, "%s.%s takes no argument %s", current, name, arg)
+		}
+	}
+	assert.True(t, fields > 100)
+}
+
+// testBackend serves a single staked block and its state.
+type testBackend struct {
+	Backend
+	block *types.Block
+	state *state.StateDB
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.BlockNumber(b.block.NumberU64()) {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if hash == b.block.Hash() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.state, b.block.Header(), nil
+}
+
+type testRangeAPI struct{}
+
+func (a *testRangeAPI) ListMasternodes() ([]energi_api.MNInfo, error) {
+	info, _ := a.MasternodeInfo(common.HexToAddress("0x3"))
+	return []energi_api.MNInfo{info}, nil
+}
+
+func (a *testRangeAPI) MasternodeInfo(owner_or_mn common.Address) (energi_api.MNInfo, error) {
+	if owner_or_mn != common.HexToAddress("0x3") && owner_or_mn != common.HexToAddress("0x4") {
+		return energi_api.MNInfo{}, nil
+	}
+	return energi_api.MNInfo{
+		Masternode: common.HexToAddress("0x3"),
+		Owner:      common.HexToAddress("0x4"),
+		Collateral: (*hexutil.Big)(big.NewInt(10000)),
+		IsActive:   true,
+	}, nil
+}
+
+func (a *testRangeAPI) Stats() (*energi_api.MasternodeStats, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (a *testRangeAPI) CheckpointInfo() (*energi_api.AllCheckpointInfo, error) {
+	cp := energi_api.CheckpointInfo{Number: 100, Hash: common.HexToHash("0x5"), SigCount: 3}
+	return &energi_api.AllCheckpointInfo{
+		Registry: []energi_api.CheckpointInfo{cp},
+		Active:   []energi_api.CheckpointInfo{},
+	}, nil
+}
+
+func TestResolvers(t *testing.T) {
+	staker := common.HexToAddress("0x2")
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	assert.Empty(t, err)
+	statedb.SetBalance(staker, big.NewInt(1000))
+
+	backend := &testBackend{
+		block: types.NewBlockWithHeader(&types.Header{
+			Number:     big.NewInt(7),
+			Coinbase:   staker,
+			Nonce:      types.EncodeNonce(1234),
+			Difficulty: big.NewInt(1),
+		}),
+		state: statedb,
+	}
+	query := &Query{
+		backend: backend,
+		rangeAPIs: rangeAPIs{
+			masternode: new(testRangeAPI),
+			checkpoint: new(testRangeAPI),
+		},
+	}
+	execute := func(q string) (string, []*QueryError) {
+		res := Execute(context.Background(), q, "", nil, query, nil)
+		data, err := json.Marshal(res.Data)
+		assert.Empty(t, err)
+		return string(data), res.Errors
+	}
+
+	// The staking coinbase and its stake weight carried as the nonce
+	data, errs := execute(`{ block { number nonce stakeWeight coinbase { address balance } } }`)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"block":{"number":7,"nonce":"0x00000000000004d2","stakeWeight":1234,`+
+		`"coinbase":{"address":"0x0000000000000000000000000000000000000002","balance":"0x3e8"}}}`, data)
+
+	data, errs = execute(`{ block(number: 8) { number } }`)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"block":null}`, data)
+
+	// Masternodes
+	data, errs = execute(`{
+		masternodes { masternode collateral isActive }
+		byOwner: masternode(address: "0x0000000000000000000000000000000000000004") { masternode }
+		unknown: masternode(address: "0x0000000000000000000000000000000000000005") { masternode }
+	}`)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"masternodes":[{"masternode":"0x0000000000000000000000000000000000000003","collateral":"0x2710","isActive":true}],`+
+		`"byOwner":{"masternode":"0x0000000000000000000000000000000000000003"},"unknown":null}`, data)
+
+	data, errs = execute(`{ masternodeStats { total } }`)
+	assert.Equal(t, `{"masternodeStats":null}`, data)
+	assert.Equal(t, []*QueryError{{Message: "not implemented", Path: []interface{}{"masternodeStats"}}}, errs)
+
+	// Checkpoints
+	data, errs = execute(`{ checkpoints { registry { number hash sigCount } active { number } } }`)
+	assert.Empty(t, errs)
+	assert.Equal(t, `{"checkpoints":{"registry":[{"number":100,"hash":"0x0000000000000000000000000000000000000000000000000000000000000005","sigCount":3}],"active":[]}}`, data)
+}
@@ -0,0 +1,478 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query or a mutation of a document. The types of the variables
+// are not checked, the values given being coerced into the resolver arguments.
+type operation struct {
+	kind       string
+	name       string
+	defaults   map[string]interface{}
+	selections []selection
+}
+
+// selection is one of *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+}
+
+// key returns the name of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCond   string
+	directives []*directive
+	selections []selection
+}
+
+type fragment struct {
+	typeCond   string
+	selections []selection
+}
+
+// Literal values of a document besides the JSON ones: strings, booleans, nulls,
+// numbers as json.Number, lists and objects.
+type (
+	variable    string
+	enumValue   string
+	objectValue []*argument
+)
+
+// token kinds of the lexer
+const (
+	tokenEOF = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  int
+	text  string
+	pos   int
+	value string // unescaped content of strings
+}
+
+// parser is a recursive descent parser of GraphQL query documents.
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// parseDocument parses a query document.
+func parseDocument(src string) (doc *document, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, perr
+		}
+	}()
+	p.next()
+
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.parseSelectionSet()})
+
+		case p.peek(tokenName, "query") || p.peek(tokenName, "mutation"):
+			doc.operations = append(doc.operations, p.parseOperation())
+
+		case p.peek(tokenName, "fragment"):
+			p.next()
+			name := p.expect(tokenName, "").text
+			if _, ok := doc.fragments[name]; ok {
+				p.fail("duplicate fragment %q", name)
+			}
+			p.expect(tokenName, "on")
+			frag := &fragment{typeCond: p.expect(tokenName, "").text}
+			p.parseDirectives()
+			frag.selections = p.parseSelectionSet()
+			doc.fragments[name] = frag
+
+		default:
+			p.fail("unexpected %q", p.tok.text)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &syntaxError{msg: "no operation in document"}
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.next().text, defaults: make(map[string]interface{})}
+	if p.tok.kind == tokenName {
+		op.name = p.next().text
+	}
+	if p.accept(tokenPunct, "(") {
+		for !p.accept(tokenPunct, ")") {
+			p.expect(tokenPunct, "$")
+			name := p.expect(tokenName, "").text
+			p.expect(tokenPunct, ":")
+			p.parseType()
+			if p.accept(tokenPunct, "=") {
+				op.defaults[name] = p.parseValue(true)
+			}
+		}
+	}
+	p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+// parseType skips over a variable type, they are not checked.
+func (p *parser) parseType() {
+	if p.accept(tokenPunct, "[") {
+		p.parseType()
+		p.expect(tokenPunct, "]")
+	} else {
+		p.expect(tokenName, "")
+	}
+	p.accept(tokenPunct, "!")
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect(tokenPunct, "{")
+
+	var selections []selection
+	for !p.accept(tokenPunct, "}") {
+		if p.accept(tokenPunct, "...") {
+			if p.tok.kind == tokenName && p.tok.text != "on" {
+				selections = append(selections, &fragmentSpread{
+					name:       p.next().text,
+					directives: p.parseDirectives(),
+				})
+				continue
+			}
+			inline := new(inlineFragment)
+			if p.accept(tokenName, "on") {
+				inline.typeCond = p.expect(tokenName, "").text
+			}
+			inline.directives = p.parseDirectives()
+			inline.selections = p.parseSelectionSet()
+			selections = append(selections, inline)
+			continue
+		}
+		f := &field{name: p.expect(tokenName, "").text}
+		if p.accept(tokenPunct, ":") {
+			f.alias, f.name = f.name, p.expect(tokenName, "").text
+		}
+		f.args = p.parseArguments(false)
+		f.directives = p.parseDirectives()
+		if p.peek(tokenPunct, "{") {
+			f.selections = p.parseSelectionSet()
+		}
+		selections = append(selections, f)
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	var args []*argument
+	if p.accept(tokenPunct, "(") {
+		for !p.accept(tokenPunct, ")") {
+			arg := &argument{name: p.expect(tokenName, "").text}
+			p.expect(tokenPunct, ":")
+			arg.value = p.parseValue(constant)
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var directives []*directive
+	for p.accept(tokenPunct, "@") {
+		directives = append(directives, &directive{
+			name: p.expect(tokenName, "").text,
+			args: p.parseArguments(false),
+		})
+	}
+	return directives
+}
+
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.next()
+	switch tok.kind {
+	case tokenInt, tokenFloat:
+		return json.Number(tok.text)
+
+	case tokenString:
+		return tok.value
+
+	case tokenName:
+		switch tok.text {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.text)
+
+	case tokenPunct:
+		switch tok.text {
+		case "$":
+			if constant {
+				p.fail("unexpected variable in constant value")
+			}
+			return variable(p.expect(tokenName, "").text)
+
+		case "[":
+			list := []interface{}{}
+			for !p.accept(tokenPunct, "]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+
+		case "{":
+			obj := objectValue{}
+			for !p.accept(tokenPunct, "}") {
+				arg := &argument{name: p.expect(tokenName, "").text}
+				p.expect(tokenPunct, ":")
+				arg.value = p.parseValue(constant)
+				obj = append(obj, arg)
+			}
+			return obj
+		}
+	}
+	p.failAt(tok.pos, "unexpected %q", tok.text)
+	return nil
+}
+
+// peek reports whether the current token is of the given kind and text.
+func (p *parser) peek(kind int, text string) bool {
+	return p.tok.kind == kind && (text == "" || p.tok.text == text)
+}
+
+// accept consumes the current token if it is of the given kind and text.
+func (p *parser) accept(kind int, text string) bool {
+	if p.peek(kind, text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes the current token, failing if it is not of the given kind
+// and text.
+func (p *parser) expect(kind int, text string) token {
+	if !p.peek(kind, text) {
+		if p.tok.kind == tokenEOF {
+			p.fail("unexpected end of document")
+		}
+		p.fail("unexpected %q", p.tok.text)
+	}
+	return p.next()
+}
+
+// next returns the current token and scans the following one.
+func (p *parser) next() token {
+	tok := p.tok
+	p.tok = p.scan()
+	return tok
+}
+
+// scan lexes the next token of the source.
+func (p *parser) scan() token {
+	// Skip over white space, commas and comments
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		return token{kind: tokenEOF, pos: start}
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+		p.pos++
+		return token{kind: tokenPunct, text: p.src[start:p.pos], pos: start}
+
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		return token{kind: tokenPunct, text: "...", pos: start}
+
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		return token{kind: tokenName, text: p.src[start:p.pos], pos: start}
+
+	case c == '-' || isDigit(c):
+		return p.scanNumber()
+
+	case c == '"':
+		return p.scanString()
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.failAt(start, "unexpected character %q", r)
+	return token{}
+}
+
+func (p *parser) scanNumber() token {
+	start, kind := p.pos, tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		begin := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == begin {
+			p.failAt(start, "invalid number")
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		digits()
+		kind = tokenFloat
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+		kind = tokenFloat
+	}
+	return token{kind: kind, text: p.src[start:p.pos], pos: start}
+}
+
+func (p *parser) scanString() token {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			p.failAt(start, "unterminated string")
+		}
+		p.pos += end + 6
+		value := strings.Replace(p.src[start+3:p.pos-3], `\"""`, `"""`, -1)
+		return token{kind: tokenString, text: p.src[start:p.pos], pos: start, value: value}
+	}
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			p.failAt(start, "unterminated string")
+		}
+		if p.src[p.pos] == '"' {
+			break
+		}
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	p.pos++
+
+	// The escapes of GraphQL are the ones of JSON
+	var value string
+	if err := json.Unmarshal([]byte(p.src[start:p.pos]), &value); err != nil {
+		p.failAt(start, "invalid string: %v", err)
+	}
+	return token{kind: tokenString, text: p.src[start:p.pos], pos: start, value: value}
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// syntaxError is a parse error of a document at a line and column.
+type syntaxError struct {
+	msg       string
+	line, col int
+}
+
+func (err *syntaxError) Error() string {
+	if err.line == 0 {
+		return "syntax error: " + err.msg
+	}
+	return "syntax error at " + strconv.Itoa(err.line) + ":" + strconv.Itoa(err.col) + ": " + err.msg
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	p.failAt(p.tok.pos, format, args...)
+}
+
+func (p *parser) failAt(pos int, format string, args ...interface{}) {
+	line := 1 + strings.Count(p.src[:pos], "\n")
+	col := 1 + utf8.RuneCountInString(p.src[strings.LastIndex(p.src[:pos], "\n")+1:pos])
+	panic(&syntaxError{msg: fmt.Sprintf(format, args...), line: line, col: col})
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"range/core/gen3/common"
+	"range/core/gen3/rpc"
+
+	energi_api "range/core/gen3/energi/api"
+)
+
+// The Range APIs the Range fields of the queries are resolved through.
+type (
+	masternodeAPI interface {
+		ListMasternodes() ([]energi_api.MNInfo, error)
+		MasternodeInfo(owner_or_mn common.Address) (energi_api.MNInfo, error)
+		Stats() (*energi_api.MasternodeStats, error)
+	}
+	governanceAPI interface {
+		ProposalInfo(proposal common.Address) (*energi_api.ProposalInfo, error)
+		UpgradeInfo() *energi_api.UpgradeProposals
+		BudgetInfo() (*energi_api.BudgetInfo, error)
+	}
+	blacklistAPI interface {
+		BlacklistInfo() ([]energi_api.BLInfo, error)
+	}
+	checkpointAPI interface {
+		CheckpointInfo() (*energi_api.AllCheckpointInfo, error)
+	}
+)
+
+// rangeAPIs resolves the Range fields of the queries through the Range RPC
+// APIs, sharing their caches refreshed on every synced head.
+type rangeAPIs struct {
+	masternode masternodeAPI
+	governance governanceAPI
+	blacklist  blacklistAPI
+	checkpoint checkpointAPI
+}
+
+// newRangeAPIs picks the instances of the Range APIs registered with the RPC.
+func newRangeAPIs(apis []rpc.API) (rangeAPIs, error) {
+	var r rangeAPIs
+	for _, api := range apis {
+		switch service := api.Service.(type) {
+		case *energi_api.MasternodeAPI:
+			r.masternode = service
+		case *energi_api.GovernanceAPI:
+			r.governance = service
+		case *energi_api.BlacklistAPI:
+			r.blacklist = service
+		case *energi_api.CheckpointAPI:
+			r.checkpoint = service
+		}
+	}
+	if r.masternode == nil || r.governance == nil || r.blacklist == nil || r.checkpoint == nil {
+		return r, errors.New("missing Range APIs")
+	}
+	return r, nil
+}
+
+func (r *rangeAPIs) Masternodes(ctx context.Context) ([]energi_api.MNInfo, error) {
+	return r.masternode.ListMasternodes()
+}
+
+func (r *rangeAPIs) Masternode(ctx context.Context, args struct{ Address common.Address }) (*energi_api.MNInfo, error) {
+	info, err := r.masternode.MasternodeInfo(args.Address)
+	if err != nil || info.Masternode == (common.Address{}) {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (r *rangeAPIs) MasternodeStats(ctx context.Context) (*energi_api.MasternodeStats, error) {
+	return r.masternode.Stats()
+}
+
+func (r *rangeAPIs) Proposal(ctx context.Context, args struct{ Address common.Address }) (*energi_api.ProposalInfo, error) {
+	return r.governance.ProposalInfo(args.Address)
+}
+
+func (r *rangeAPIs) UpgradeInfo(ctx context.Context) (*energi_api.UpgradeProposals, error) {
+	return r.governance.UpgradeInfo(), nil
+}
+
+func (r *rangeAPIs) BudgetInfo(ctx context.Context) (*energi_api.BudgetInfo, error) {
+	return r.governance.BudgetInfo()
+}
+
+func (r *rangeAPIs) Blacklist(ctx context.Context) ([]energi_api.BLInfo, error) {
+	return r.blacklist.BlacklistInfo()
+}
+
+func (r *rangeAPIs) Checkpoints(ctx context.Context) (*energi_api.AllCheckpointInfo, error) {
+	return r.checkpoint.CheckpointInfo()
+}
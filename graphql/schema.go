@@ -0,0 +1,412 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// Schema is the schema of the GraphQL service. The queries are not validated
+// against it, it documents the resolvers and is served at the /schema path.
+// The tests check every declared field against its resolver.
+const Schema string = `
+    # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes32
+    # Address is a 20 byte Range address, represented as 0x-prefixed hexadecimal.
+    scalar Address
+    # Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+    # An empty byte string is represented as '0x'. Byte strings must have an even number of hexadecimal nybbles.
+    scalar Bytes
+    # BigInt is a large integer. Input is accepted as a 0x-prefixed hexadecimal string.
+    # Output values are all 0x-prefixed hexadecimal.
+    scalar BigInt
+    # Long is a 64 bit signed integer. Input is accepted as a number, or as a
+    # decimal or 0x-prefixed hexadecimal string. Output values are numbers.
+    scalar Long
+
+    schema {
+        query: Query
+        mutation: Mutation
+    }
+
+    # Account is a Range account at a particular block.
+    type Account {
+        # Address is the address owning the account.
+        address: Address!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # TransactionCount is the number of transactions sent from this account,
+        # or in the case of a contract, the number of contracts created. Otherwise
+        # known as the nonce.
+        transactionCount: Long!
+        # Code contains the smart contract code for this account, if the account
+        # is a (non-self-destructed) contract.
+        code: Bytes!
+        # Storage provides access to the storage of a contract account, indexed
+        # by its 32 byte slot identifier.
+        storage(slot: Bytes32!): Bytes32!
+    }
+
+    # Log is a Range event log.
+    type Log {
+        # Index is the index of this log in the block.
+        index: Int!
+        # Account is the account which generated this log - this will always
+        # be a contract account.
+        account(block: Long): Account!
+        # Topics is a list of 0-4 indexed topics for the log.
+        topics: [Bytes32!]!
+        # Data is unindexed data for this log.
+        data: Bytes!
+        # Transaction is the transaction that generated this log entry.
+        transaction: Transaction!
+    }
+
+    # Transaction is a Range transaction.
+    type Transaction {
+        # Hash is the hash of this transaction.
+        hash: Bytes32!
+        # Nonce is the nonce of the account this transaction was generated with.
+        nonce: Long!
+        # Index is the index of this transaction in the parent block. This will
+        # be null if the transaction has not yet been mined.
+        index: Int
+        # From is the account that sent this transaction - this will always be
+        # an externally owned account, or the sender of a consensus transaction.
+        from(block: Long): Account!
+        # To is the account the transaction was sent to. This is null for
+        # contract-creating transactions.
+        to(block: Long): Account
+        # Value is the value, in wei, sent along with this transaction.
+        value: BigInt!
+        # GasPrice is the price offered to miners for gas, in wei per unit.
+        gasPrice: BigInt!
+        # Gas is the maximum amount of gas this transaction can consume.
+        gas: Long!
+        # InputData is the data supplied to the target of the transaction.
+        inputData: Bytes!
+        # Consensus is true for the consensus transactions, applied by the
+        # Range consensus rather than signed by their sender.
+        consensus: Boolean!
+        # Block is the block this transaction was mined in. This will be null if
+        # the transaction has not yet been mined.
+        block: Block
+
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed (due to a revert, or due to
+        # running out of gas). If the transaction has not yet been mined, this
+        # field will be null.
+        status: Long
+        # GasUsed is the amount of gas that was used processing this transaction.
+        # If the transaction has not yet been mined, this field will be null.
+        gasUsed: Long
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # this transaction. If the transaction has not yet been mined, this field
+        # will be null.
+        cumulativeGasUsed: Long
+        # CreatedContract is the account that was created by a contract creation
+        # transaction. If the transaction was not a contract creation transaction,
+        # or it has not yet been mined, this field will be null.
+        createdContract(block: Long): Account
+        # Logs is a list of log entries emitted by this transaction. If the
+        # transaction has not yet been mined, this field will be null.
+        logs: [Log!]
+    }
+
+    # BlockFilterCriteria encapsulates log filter criteria for a filter applied
+    # to a single block.
+    input BlockFilterCriteria {
+        # Addresses is a list of addresses that are of interest. If this list is
+        # empty, results will not be filtered by address.
+        addresses: [Address!]
+        # Topics list restricts matches to particular event topics. Each event has a list
+        # of topics. Topics matches a prefix of that list. An empty element array matches any
+        # topic. Non-empty elements represent an alternative that matches any of the
+        # contained topics.
+        topics: [[Bytes32!]!]
+    }
+
+    # Block is a Range block.
+    type Block {
+        # Number is the number of this block, starting at 0 for the genesis block.
+        number: Long!
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Parent is the parent block of this block.
+        parent: Block
+        # Nonce is the block nonce, an 8 byte sequence.
+        nonce: Bytes!
+        # Coinbase is the staking coinbase, the account which staked this block
+        # and collected its reward.
+        coinbase(block: Long): Account!
+        # StakeWeight is the stake weight the coinbase used to stake this block,
+        # carried as the block nonce.
+        stakeWeight: Long!
+        # TransactionsRoot is the keccak256 hash of the root of the trie of transactions in this block.
+        transactionsRoot: Bytes32!
+        # TransactionCount is the number of transactions in this block.
+        transactionCount: Int!
+        # StateRoot is the keccak256 hash of the state trie after this block was processed.
+        stateRoot: Bytes32!
+        # ReceiptsRoot is the keccak256 hash of the trie of transaction receipts in this block.
+        receiptsRoot: Bytes32!
+        # ExtraData is an arbitrary data field supplied by the staker.
+        extraData: Bytes!
+        # GasLimit is the maximum amount of gas that was available to transactions in this block.
+        gasLimit: Long!
+        # GasUsed is the amount of gas that was used executing transactions in this block.
+        gasUsed: Long!
+        # Timestamp is the unix timestamp at which this block was staked.
+        timestamp: Long!
+        # LogsBloom is a bloom filter that can be used to check if a block may
+        # contain log entries matching a filter.
+        logsBloom: Bytes!
+        # MixHash is the hash that was used as an input to the PoS process.
+        mixHash: Bytes32!
+        # Difficulty is a measure of the difficulty of staking this block.
+        difficulty: BigInt!
+        # TotalDifficulty is the sum of all difficulty values up to and including
+        # this block. This will be null for the pending block.
+        totalDifficulty: BigInt
+        # Transactions is a list of transactions associated with this block.
+        transactions: [Transaction!]
+        # TransactionAt returns the transaction at the specified index.
+        transactionAt(index: Int!): Transaction
+        # Logs returns a filtered set of logs from this block.
+        logs(filter: BlockFilterCriteria!): [Log!]
+        # Account fetches a Range account at the current block's state.
+        account(address: Address!): Account!
+        # Call executes a local call operation at the current block's state.
+        call(data: CallData!): CallResult
+        # EstimateGas estimates the amount of gas that will be required for
+        # successful execution of a transaction at the current block's state.
+        estimateGas(data: CallData!): Long!
+    }
+
+    # CallData represents the data associated with a local contract call.
+    # All fields are optional.
+    input CallData {
+        # From is the address making the call.
+        from: Address
+        # To is the address the call is sent to.
+        to: Address
+        # Gas is the amount of gas sent with the call.
+        gas: Long
+        # GasPrice is the price, in wei, offered for each unit of gas.
+        gasPrice: BigInt
+        # Value is the value, in wei, sent along with the call.
+        value: BigInt
+        # Data is the data sent to the callee.
+        data: Bytes
+    }
+
+    # CallResult is the result of a local call operation.
+    type CallResult {
+        # Data is the return data of the called contract.
+        data: Bytes!
+        # GasUsed is the amount of gas used by the call, after any refunds.
+        gasUsed: Long!
+        # Status is the result of the call - 1 for success or 0 for failure.
+        status: Long!
+    }
+
+    # FilterCriteria encapsulates log filter criteria for searching log entries.
+    input FilterCriteria {
+        # FromBlock is the block at which to start searching, inclusive. Defaults
+        # to the latest block if not supplied.
+        fromBlock: Long
+        # ToBlock is the block at which to stop searching, inclusive. Defaults
+        # to the latest block if not supplied.
+        toBlock: Long
+        # Addresses is a list of addresses that are of interest. If this list is
+        # empty, results will not be filtered by address.
+        addresses: [Address!]
+        # Topics list restricts matches to particular event topics. Each event has a list
+        # of topics. Topics matches a prefix of that list. An empty element array matches any
+        # topic. Non-empty elements represent an alternative that matches any of the
+        # contained topics.
+        topics: [[Bytes32!]!]
+    }
+
+    # Pending represents the current pending state.
+    type Pending {
+        # TransactionCount is the number of transactions in the pending state.
+        transactionCount: Int!
+        # Transactions is a list of transactions in the current pending state.
+        transactions: [Transaction!]
+        # Account fetches a Range account for the pending state.
+        account(address: Address!): Account!
+        # Call executes a local call operation for the pending state.
+        call(data: CallData!): CallResult
+        # EstimateGas estimates the amount of gas that will be required for
+        # successful execution of a transaction for the pending state.
+        estimateGas(data: CallData!): Long!
+    }
+
+    # MNInfo is a masternode of the masternode registry.
+    type MNInfo {
+        masternode: Address!
+        owner: Address!
+        enode: String!
+        collateral: BigInt!
+        announcedBlock: Long!
+        isActive: Boolean!
+        isAlive: Boolean!
+        swFeatures: BigInt!
+        swVersion: String!
+    }
+
+    # MasternodeStats sums up the masternodes and their collaterals.
+    type MasternodeStats {
+        active: Long!
+        total: Long!
+        activeCollateral: BigInt!
+        totalCollateral: BigInt!
+        maxOfAllTimes: BigInt!
+    }
+
+    # ProposalInfo is the state of a governance proposal voted by the masternodes.
+    type ProposalInfo {
+        proposal: Address!
+        proposer: Address!
+        createdBlock: Long!
+        deadline: Long!
+        quorumWeight: BigInt!
+        totalWeight: BigInt!
+        rejectWeight: BigInt!
+        acceptWeight: BigInt!
+        finished: Boolean!
+        accepted: Boolean!
+        balance: BigInt!
+    }
+
+    # UpgradeProposalInfo is a proposal to upgrade the implementation behind a
+    # governed proxy.
+    type UpgradeProposalInfo {
+        proposal: Address!
+        proposer: Address!
+        createdBlock: Long!
+        deadline: Long!
+        quorumWeight: BigInt!
+        totalWeight: BigInt!
+        rejectWeight: BigInt!
+        acceptWeight: BigInt!
+        finished: Boolean!
+        accepted: Boolean!
+        balance: BigInt!
+        impl: Address!
+        proxy: Address!
+    }
+
+    # UpgradeProposals lists the open upgrade proposals of the governed proxies.
+    type UpgradeProposals {
+        treasury: [UpgradeProposalInfo!]
+        masternodeRegistry: [UpgradeProposalInfo!]
+        stakerReward: [UpgradeProposalInfo!]
+        backboneReward: [UpgradeProposalInfo!]
+        sporkRegistry: [UpgradeProposalInfo!]
+        checkpointRegistry: [UpgradeProposalInfo!]
+        blacklistRegistry: [UpgradeProposalInfo!]
+        masternodeToken: [UpgradeProposalInfo!]
+    }
+
+    # BudgetProposalInfo is a proposal to be paid from the treasury.
+    type BudgetProposalInfo {
+        proposal: Address!
+        proposer: Address!
+        createdBlock: Long!
+        deadline: Long!
+        quorumWeight: BigInt!
+        totalWeight: BigInt!
+        rejectWeight: BigInt!
+        acceptWeight: BigInt!
+        finished: Boolean!
+        accepted: Boolean!
+        balance: BigInt!
+        proposedAmount: BigInt!
+        paidAmount: BigInt!
+        refUUID: String!
+    }
+
+    # BudgetInfo is the treasury balance and its budget proposals.
+    type BudgetInfo {
+        balance: BigInt!
+        proposals: [BudgetProposalInfo!]
+    }
+
+    # BLInfo is an address of the blacklist registry along with the proposals
+    # to enforce, revoke and drain its blacklisting.
+    type BLInfo {
+        target: Address!
+        enforce: ProposalInfo
+        revoke: ProposalInfo
+        drain: ProposalInfo
+        blocked: Boolean!
+    }
+
+    # CheckpointInfo is a checkpoint along with the number of its signatures.
+    type CheckpointInfo {
+        number: Long!
+        hash: Bytes32!
+        since: Long!
+        sigCount: Long!
+    }
+
+    # AllCheckpointInfo lists the checkpoints of the registry and the active ones.
+    type AllCheckpointInfo {
+        registry: [CheckpointInfo!]
+        active: [CheckpointInfo!]
+    }
+
+    type Query {
+        # Block fetches a Range block by number or by hash. If neither is
+        # supplied, the most recent known block is returned.
+        block(number: Long, hash: Bytes32): Block
+        # Blocks returns all the blocks between two numbers, inclusive. If
+        # to is not supplied, it defaults to the most recent known block.
+        blocks(from: Long!, to: Long): [Block!]!
+        # Pending returns the current pending state.
+        pending: Pending!
+        # Transaction returns a transaction specified by its hash.
+        transaction(hash: Bytes32!): Transaction
+        # Logs returns log entries matching the provided filter.
+        logs(filter: FilterCriteria!): [Log!]!
+        # GasPrice returns the node's estimate of a gas price sufficient to
+        # ensure a transaction is staked in a timely fashion.
+        gasPrice: BigInt!
+        # ProtocolVersion returns the current wire protocol version number.
+        protocolVersion: Int!
+
+        # Masternodes lists the masternodes of the registry.
+        masternodes: [MNInfo!]
+        # Masternode returns the masternode of the given address or owner.
+        masternode(address: Address!): MNInfo
+        # MasternodeStats sums up the masternodes of the registry.
+        masternodeStats: MasternodeStats
+        # Proposal returns the state of the governance proposal at the address.
+        proposal(address: Address!): ProposalInfo
+        # UpgradeInfo lists the open upgrade proposals.
+        upgradeInfo: UpgradeProposals
+        # BudgetInfo returns the treasury budget.
+        budgetInfo: BudgetInfo
+        # Blacklist lists the blacklist registry.
+        blacklist: [BLInfo!]
+        # Checkpoints lists the checkpoints.
+        checkpoints: AllCheckpointInfo
+    }
+
+    type Mutation {
+        # SendRawTransaction sends an RLP-encoded transaction to the network.
+        sendRawTransaction(data: Bytes!): Bytes32!
+    }
+`
@@ -0,0 +1,47 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"math/big"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/math"
+	"range/core/gen3/crypto"
+)
+
+// ComputeAccumulator returns the root of the binary merkle tree over the
+// (hash, td) pairs of the blocks. The leaves are padded with empty hashes to
+// the next power of two.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) common.Hash {
+	size := 1
+	for size < len(hashes) {
+		size <<= 1
+	}
+	level := make([]common.Hash, size)
+	for i, hash := range hashes {
+		level[i] = crypto.Keccak256Hash(hash[:], math.PaddedBigBytes(tds[i], 32))
+	}
+	for len(level) > 1 {
+		next := make([]common.Hash, len(level)/2)
+		for i := range next {
+			next[i] = crypto.Keccak256Hash(level[2*i][:], level[2*i+1][:])
+		}
+		level = next
+	}
+	return level[0]
+}
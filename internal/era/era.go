@@ -0,0 +1,357 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements the segmented archives of the chain history.
+//
+// An archive holds a single epoch of consecutive blocks along with their
+// receipts and total difficulties, so that it can be verified on its own:
+//
+//	archive = magic || entry* || index || start || count || accumulator || magic
+//	entry   = RLP([block, [receipt-for-storage*], td])
+//	index   = offset(entry)*
+//
+// The numbers are 8 byte big endian. The accumulator is the root of the binary
+// merkle tree over the (hash, td) pairs of the blocks, it commits to the whole
+// epoch and names the archive file.
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/types"
+	"range/core/gen3/rlp"
+)
+
+const (
+	// DefaultEpochSize is the number of blocks in an archive.
+	DefaultEpochSize = 8192
+
+	// Extension is the file extension of the archives.
+	Extension = ".era"
+
+	footerSize = 8 + 8 + common.HashLength + 8
+)
+
+var (
+	magic = [8]byte{'r', 'a', 'n', 'g', 'e', 'e', 'r', 'a'}
+
+	errBadMagic = errors.New("not an era archive")
+	errEmpty    = errors.New("empty era archive")
+)
+
+// Entry is a single block of an archive.
+type Entry struct {
+	Block    *types.Block
+	Receipts []*types.ReceiptForStorage
+	TD       *big.Int
+}
+
+// ConsensusReceipts returns the receipts of the entry in the form used for
+// the receipt root of the block.
+func (e *Entry) ConsensusReceipts() types.Receipts {
+	receipts := make(types.Receipts, len(e.Receipts))
+	for i, receipt := range e.Receipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	return receipts
+}
+
+// Filename returns the name of the archive of the given epoch.
+func Filename(network string, epoch uint64, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%x%s", network, epoch, root[:4], Extension)
+}
+
+// ReadDir returns the archives of the network in the directory, in epoch order.
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasPrefix(name, network+"-") && strings.HasSuffix(name, Extension) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// countWriter tracks the offset of the written data.
+type countWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += uint64(n)
+	return n, err
+}
+
+// Builder writes an archive of consecutive blocks.
+type Builder struct {
+	w       *countWriter
+	start   uint64
+	offsets []uint64
+	hashes  []common.Hash
+	tds     []*big.Int
+}
+
+// NewBuilder creates a builder writing the archive into w.
+func NewBuilder(w io.Writer) *Builder {
+	return &Builder{w: &countWriter{w: w}}
+}
+
+// Add appends the next block of the epoch to the archive.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	if len(b.offsets) == 0 {
+		if _, err := b.w.Write(magic[:]); err != nil {
+			return err
+		}
+		b.start = block.NumberU64()
+	} else if want := b.start + uint64(len(b.offsets)); block.NumberU64() != want {
+		return fmt.Errorf("non contiguous block #%d, want #%d", block.NumberU64(), want)
+	}
+	storage := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		storage[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	b.offsets = append(b.offsets, b.w.n)
+	if err := rlp.Encode(b.w, &Entry{Block: block, Receipts: storage, TD: td}); err != nil {
+		return err
+	}
+	b.hashes = append(b.hashes, block.Hash())
+	b.tds = append(b.tds, td)
+	return nil
+}
+
+// Finalize writes the index and the footer, it returns the accumulator root.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if len(b.offsets) == 0 {
+		return common.Hash{}, errEmpty
+	}
+	root := ComputeAccumulator(b.hashes, b.tds)
+
+	buf := make([]byte, 8*len(b.offsets), 8*len(b.offsets)+footerSize)
+	for i, offset := range b.offsets {
+		binary.BigEndian.PutUint64(buf[8*i:], offset)
+	}
+	buf = appendUint64(buf, b.start)
+	buf = appendUint64(buf, uint64(len(b.offsets)))
+	buf = append(buf, root[:]...)
+	buf = append(buf, magic[:]...)
+
+	if _, err := b.w.Write(buf); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+func appendUint64(buf []byte, n uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], n)
+	return append(buf, enc[:]...)
+}
+
+// ReadAtCloser is the storage of an opened archive.
+type ReadAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// Era is an opened archive.
+type Era struct {
+	f       ReadAtCloser
+	start   uint64
+	root    common.Hash
+	offsets []uint64
+	end     uint64 // Offset of the index, closing the last entry
+}
+
+// Open opens the archive file.
+func Open(path string) (*Era, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	e, err := From(f, uint64(info.Size()))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return e, nil
+}
+
+// From opens the archive held by the storage of the given size.
+func From(f ReadAtCloser, size uint64) (*Era, error) {
+	if size < uint64(len(magic))+footerSize {
+		return nil, errBadMagic
+	}
+	footer := make([]byte, footerSize)
+	if _, err := f.ReadAt(footer, int64(size-footerSize)); err != nil {
+		return nil, err
+	}
+	var head [8]byte
+	if _, err := f.ReadAt(head[:], 0); err != nil {
+		return nil, err
+	}
+	if head != magic || !bytes.Equal(footer[footerSize-8:], magic[:]) {
+		return nil, errBadMagic
+	}
+	var (
+		start = binary.BigEndian.Uint64(footer[0:])
+		count = binary.BigEndian.Uint64(footer[8:])
+		root  = common.BytesToHash(footer[16 : 16+common.HashLength])
+	)
+	if count == 0 {
+		return nil, errEmpty
+	}
+	if count > (size-uint64(len(magic))-footerSize)/8 {
+		return nil, fmt.Errorf("invalid block count %d", count)
+	}
+	end := size - footerSize - 8*count
+
+	index := make([]byte, 8*count)
+	if _, err := f.ReadAt(index, int64(end)); err != nil {
+		return nil, err
+	}
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint64(index[8*i:])
+		if offsets[i] < uint64(len(magic)) || offsets[i] >= end || (i > 0 && offsets[i] <= offsets[i-1]) {
+			return nil, fmt.Errorf("invalid index entry %d", i)
+		}
+	}
+	return &Era{f: f, start: start, root: root, offsets: offsets, end: end}, nil
+}
+
+// Close closes the archive.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block.
+func (e *Era) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks.
+func (e *Era) Count() uint64 {
+	return uint64(len(e.offsets))
+}
+
+// Accumulator returns the accumulator root recorded in the archive.
+func (e *Era) Accumulator() common.Hash {
+	return e.root
+}
+
+// GetEntry retrieves the block of the given number with its receipts and
+// total difficulty.
+func (e *Era) GetEntry(number uint64) (*Entry, error) {
+	if number < e.start || number >= e.start+e.Count() {
+		return nil, fmt.Errorf("block #%d out of archive range [%d, %d]", number, e.start, e.start+e.Count()-1)
+	}
+	i := number - e.start
+	end := e.end
+	if i+1 < e.Count() {
+		end = e.offsets[i+1]
+	}
+	entry := new(Entry)
+	section := io.NewSectionReader(e.f, int64(e.offsets[i]), int64(end-e.offsets[i]))
+	if err := rlp.Decode(section, entry); err != nil {
+		return nil, fmt.Errorf("block #%d: %v", number, err)
+	}
+	if entry.Block.NumberU64() != number {
+		return nil, fmt.Errorf("block #%d: archived as #%d", number, entry.Block.NumberU64())
+	}
+	return entry, nil
+}
+
+// Verify checks the archive on its own: the bodies and receipts must match
+// their headers, the blocks must be linked, their total difficulties must add
+// up and the accumulator root must match the recorded one.
+func (e *Era) Verify() error {
+	var (
+		hashes = make([]common.Hash, 0, e.Count())
+		tds    = make([]*big.Int, 0, e.Count())
+		parent *Entry
+	)
+	for number := e.start; number < e.start+e.Count(); number++ {
+		entry, err := e.GetEntry(number)
+		if err != nil {
+			return err
+		}
+		if err := VerifyEntry(entry); err != nil {
+			return err
+		}
+		if parent != nil {
+			if entry.Block.ParentHash() != parent.Block.Hash() {
+				return fmt.Errorf("block #%d: parent hash mismatch", number)
+			}
+			if td := new(big.Int).Add(parent.TD, entry.Block.Difficulty()); td.Cmp(entry.TD) != 0 {
+				return fmt.Errorf("block #%d: total difficulty mismatch: have %v, want %v", number, entry.TD, td)
+			}
+		}
+		hashes = append(hashes, entry.Block.Hash())
+		tds = append(tds, entry.TD)
+		parent = entry
+	}
+	if root := ComputeAccumulator(hashes, tds); root != e.root {
+		return fmt.Errorf("accumulator mismatch: have %x, want %x", root, e.root)
+	}
+	return nil
+}
+
+// VerifyEntry checks the body and the receipts of the block against its header.
+func VerifyEntry(entry *Entry) error {
+	var (
+		block  = entry.Block
+		header = block.Header()
+	)
+	if entry.TD == nil {
+		return fmt.Errorf("block #%d: missing total difficulty", block.NumberU64())
+	}
+	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
+		return fmt.Errorf("block #%d: transaction root mismatch: have %x, want %x", block.NumberU64(), hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
+		return fmt.Errorf("block #%d: uncle root mismatch: have %x, want %x", block.NumberU64(), hash, header.UncleHash)
+	}
+	if len(entry.Receipts) != len(block.Transactions()) {
+		return fmt.Errorf("block #%d: %d receipts for %d transactions", block.NumberU64(), len(entry.Receipts), len(block.Transactions()))
+	}
+	if hash := types.DeriveSha(entry.ConsensusReceipts()); hash != header.ReceiptHash {
+		return fmt.Errorf("block #%d: receipt root mismatch: have %x, want %x", block.NumberU64(), hash, header.ReceiptHash)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/consensus/ethash"
+	"range/core/gen3/core"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "era")
+	assert.Empty(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+
+	// Write the archive of the blocks 3..8
+	var buf bytes.Buffer
+	builder := NewBuilder(&buf)
+	td := new(big.Int).Set(genesis.Difficulty())
+	for i, block := range blocks[:8] {
+		td.Add(td, block.Difficulty())
+		if i >= 2 {
+			assert.Empty(t, builder.Add(block, receipts[i], new(big.Int).Set(td)))
+		}
+	}
+	assert.NotEmpty(t, builder.Add(blocks[9], receipts[9], td))
+	root, err := builder.Finalize()
+	assert.Empty(t, err)
+
+	fn := filepath.Join(dir, Filename("test", 0, root))
+	assert.Empty(t, ioutil.WriteFile(fn, buf.Bytes(), 0644))
+
+	files, err := ReadDir(dir, "test")
+	assert.Empty(t, err)
+	assert.Equal(t, []string{fn}, files)
+
+	archive, err := Open(fn)
+	assert.Empty(t, err)
+	defer archive.Close()
+
+	assert.Equal(t, uint64(3), archive.Start())
+	assert.Equal(t, uint64(6), archive.Count())
+	assert.Equal(t, root, archive.Accumulator())
+	assert.Empty(t, archive.Verify())
+
+	entry, err := archive.GetEntry(5)
+	assert.Empty(t, err)
+	assert.Equal(t, blocks[4].Hash(), entry.Block.Hash())
+	assert.Equal(t, types.DeriveSha(receipts[4]), types.DeriveSha(entry.ConsensusReceipts()))
+	_, err = archive.GetEntry(9)
+	assert.NotEmpty(t, err)
+
+	// Tampered archives fail the verification
+	tampered := append([]byte{}, buf.Bytes()...)
+	copy(tampered[len(tampered)-8-common.HashLength:], make([]byte, common.HashLength))
+	bad, err := From(nopCloser{bytes.NewReader(tampered)}, uint64(len(tampered)))
+	assert.Empty(t, err)
+	assert.NotEmpty(t, bad.Verify())
+
+	tampered = append([]byte{}, buf.Bytes()...)
+	tampered[0] = 'x'
+	_, err = From(nopCloser{bytes.NewReader(tampered)}, uint64(len(tampered)))
+	assert.Equal(t, errBadMagic, err)
+}
+
+func TestStateDump(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address:           {Balance: big.NewInt(1000000000)},
+				common.Address{2}: {Balance: big.NewInt(1), Code: []byte{0x60, 0x00}, Storage: map[common.Hash]common.Hash{{1}: {2}}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	var dump bytes.Buffer
+	count, err := WriteState(&dump, genesis.Header(), state.NewDatabase(db).TrieDB())
+	assert.Empty(t, err)
+
+	imported := ethdb.NewMemDatabase()
+	header, n, err := ReadState(&dump, imported)
+	assert.Empty(t, err)
+	assert.Equal(t, count, n)
+	assert.Equal(t, genesis.Hash(), header.Hash)
+
+	statedb, err := state.New(header.Root, state.NewDatabase(imported))
+	assert.Empty(t, err)
+	assert.Equal(t, big.NewInt(1000000000), statedb.GetBalance(address))
+	assert.Equal(t, []byte{0x60, 0x00}, statedb.GetCode(common.Address{2}))
+	assert.Equal(t, common.Hash{2}, statedb.GetState(common.Address{2}, common.Hash{1}))
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,168 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"fmt"
+	"io"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/rlp"
+	"range/core/gen3/trie"
+)
+
+// StateExtension is the file extension of the state dumps.
+const StateExtension = ".state"
+
+var (
+	stateMagic = [8]byte{'r', 'a', 'n', 'g', 'e', 's', 't', 'a'}
+
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// StateHeader identifies the block of a state dump.
+//
+// A state dump is the stream of all the trie nodes and contract codes of the
+// state, each keyed by its own hash:
+//
+//	dump = magic || RLP(header) || RLP(blob)*
+type StateHeader struct {
+	Number uint64
+	Hash   common.Hash
+	Root   common.Hash
+}
+
+// StateFilename returns the name of the state dump of the given epoch.
+func StateFilename(network string, epoch uint64, hash common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%x%s", network, epoch, hash[:4], StateExtension)
+}
+
+// WriteState dumps the state of the block into w, it returns the number of
+// the dumped blobs.
+func WriteState(w io.Writer, header *types.Header, triedb *trie.Database) (uint64, error) {
+	if _, err := w.Write(stateMagic[:]); err != nil {
+		return 0, err
+	}
+	if err := rlp.Encode(w, &StateHeader{Number: header.Number.Uint64(), Hash: header.Hash(), Root: header.Root}); err != nil {
+		return 0, err
+	}
+	var count uint64
+	dump := func(hash common.Hash) error {
+		blob, err := triedb.Node(hash)
+		if err != nil {
+			return err
+		}
+		count++
+		return rlp.Encode(w, blob)
+	}
+	dumpTrie := func(root common.Hash, onLeaf func([]byte) error) error {
+		tr, err := trie.New(root, triedb)
+		if err != nil {
+			return err
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				if err := dump(hash); err != nil {
+					return err
+				}
+			}
+			if it.Leaf() && onLeaf != nil {
+				if err := onLeaf(it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+		}
+		return it.Error()
+	}
+	var (
+		storages = make(map[common.Hash]bool)
+		codes    = make(map[common.Hash]bool)
+	)
+	err := dumpTrie(header.Root, func(leaf []byte) error {
+		var acc state.Account
+		if err := rlp.DecodeBytes(leaf, &acc); err != nil {
+			return err
+		}
+		if codeHash := common.BytesToHash(acc.CodeHash); codeHash != emptyCode && !codes[codeHash] {
+			codes[codeHash] = true
+			if err := dump(codeHash); err != nil {
+				return err
+			}
+		}
+		if acc.Root != emptyRoot && !storages[acc.Root] {
+			storages[acc.Root] = true
+			return dumpTrie(acc.Root, nil)
+		}
+		return nil
+	})
+	return count, err
+}
+
+// ReadState imports a state dump into the database, every blob is checked
+// against its hash. It returns the header of the dump and the number of the
+// imported blobs.
+func ReadState(r io.Reader, db ethdb.Database) (*StateHeader, uint64, error) {
+	var head [8]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, 0, err
+	}
+	if head != stateMagic {
+		return nil, 0, fmt.Errorf("not a state dump")
+	}
+	stream := rlp.NewStream(r, 0)
+
+	header := new(StateHeader)
+	if err := stream.Decode(header); err != nil {
+		return nil, 0, err
+	}
+	var (
+		batch = db.NewBatch()
+		count uint64
+	)
+	for {
+		blob, err := stream.Bytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, count, err
+		}
+		if err := batch.Put(crypto.Keccak256(blob), blob); err != nil {
+			return nil, count, err
+		}
+		count++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, count, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, count, err
+	}
+	return header, count, nil
+}
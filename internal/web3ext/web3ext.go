@@ -34,6 +34,7 @@ var Modules = map[string]string{
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
+	"trace":      Trace_JS,
 	"txpool":     TxPool_JS,
 }
 
@@ -185,6 +186,21 @@ web3._extend({
 			call: 'energi_claimGen2CoinsImport',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'claimGen2CoinsBatch',
+			call: 'energi_claimGen2CoinsBatch',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'claimGen2BatchStatus',
+			call: 'energi_claimGen2BatchStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'claimGen2BatchCancel',
+			call: 'energi_claimGen2BatchCancel',
+			params: 1
+		}),
 
 		// Blacklist
 		new web3._extend.Method({
@@ -254,6 +270,31 @@ web3._extend({
 			],
 			outputFormatter: console.log,
 		}),
+		new web3._extend.Method({
+			name: 'blacklistDrainPreview',
+			call: 'energi_blacklistDrainPreview',
+			params: 1,
+			inputFormatter: [
+				web3._extend.formatters.inputBlockNumberFormatter,
+			],
+		}),
+		new web3._extend.Method({
+			name: 'blacklistTxPreview',
+			call: 'energi_blacklistTxPreview',
+			params: 2,
+			inputFormatter: [
+				web3._extend.formatters.inputAddressFormatter,
+				null,
+			],
+		}),
+		new web3._extend.Method({
+			name: 'blacklistSimulate',
+			call: 'energi_blacklistSimulate',
+			params: 1,
+			inputFormatter: [
+				web3._extend.formatters.inputAddressFormatter,
+			],
+		}),
 
 		// Governance
 		new web3._extend.Method({
@@ -393,6 +434,12 @@ web3._extend({
 			],
 			outputFormatter: console.log,
 		}),
+		new web3._extend.Method({
+			name: 'treasuryTimeline',
+			call: 'energi_treasuryTimeline',
+			params: 2,
+			inputFormatter: [null, null],
+		}),
 
 
 		// Compensation Fund
@@ -556,6 +603,21 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'exportTxPool',
+			call: 'admin_exportTxPool',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'importTxPool',
+			call: 'admin_importTxPool',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'approveReorg',
+			call: 'admin_approveReorg',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -611,6 +673,10 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'reorgs',
+			getter: 'admin_reorgs'
+		}),
 	]
 });
 `
@@ -807,6 +873,33 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceBlockFinalization',
+			call: 'debug_traceBlockFinalization',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'submitTraceJob',
+			call: 'debug_submitTraceJob',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'traceJobs',
+			call: 'debug_traceJobs',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'traceJobStatus',
+			call: 'debug_traceJobStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancelTraceJob',
+			call: 'debug_cancelTraceJob',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'traceTransaction',
 			call: 'debug_traceTransaction',
@@ -1235,6 +1328,48 @@ web3._extend({
 });
 `
 
+const Trace_JS = `
+web3._extend({
+	property: 'trace',
+	methods: [
+		new web3._extend.Method({
+			name: 'replayTransaction',
+			call: 'trace_replayTransaction',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'replayBlockTransactions',
+			call: 'trace_replayBlockTransactions',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'transaction',
+			call: 'trace_transaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'block',
+			call: 'trace_block',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'filter',
+			call: 'trace_filter',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'blockStateDiff',
+			call: 'trace_blockStateDiff',
+			params: 1,
+			inputFormatter: [null]
+		}),
+	],
+	properties: []
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
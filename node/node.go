@@ -28,6 +28,7 @@ import (
 	"sync"
 
 	"range/core/gen3/accounts"
+	"range/core/gen3/core/rawdb"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/event"
 	"range/core/gen3/internal/debug"
@@ -341,6 +342,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if err != nil {
 		return err
 	}
+	handler.SetPolicy(n.config.RPCPolicy)
 	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
@@ -374,6 +376,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if err != nil {
 		return err
 	}
+	handler.SetPolicy(n.config.RPCPolicy)
 	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
@@ -586,6 +589,58 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	return ethdb.NewLDBDatabase(n.config.ResolvePath(name), cache, handles)
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer, namespace string) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return openDatabaseWithFreezer(n.config, name, cache, handles, freezer, namespace)
+}
+
+// HasAncients reports whether the chain freezer of the named database already
+// holds frozen blocks, in which case it must be attached when opening it.
+func (n *Node) HasAncients(name, freezer string) bool {
+	if n.config.DataDir == "" {
+		return false
+	}
+	return rawdb.HasAncients(ancientDir(n.config, name, freezer))
+}
+
+// ancientDir returns the directory of the chain freezer of the named database.
+// An empty freezer path places the ancients inside the database directory, a
+// relative one is resolved against the instance directory.
+func ancientDir(config *Config, name, freezer string) string {
+	switch {
+	case freezer == "":
+		return filepath.Join(config.ResolvePath(name), "ancient")
+	case !filepath.IsAbs(freezer):
+		return config.ResolvePath(freezer)
+	}
+	return freezer
+}
+
+// openDatabaseWithFreezer opens the key-value store and the chain freezer of
+// a persistent node.
+func openDatabaseWithFreezer(config *Config, name string, cache, handles int, freezer, namespace string) (ethdb.Database, error) {
+	kvdb, err := ethdb.NewLDBDatabase(config.ResolvePath(name), cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	if namespace != "" {
+		kvdb.Meter(namespace)
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, ancientDir(config, name, freezer), namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
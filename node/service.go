@@ -20,6 +20,7 @@ import (
 	"reflect"
 
 	"range/core/gen3/accounts"
+	"range/core/gen3/core/rawdb"
 	"range/core/gen3/ethdb"
 	"range/core/gen3/event"
 	"range/core/gen3/p2p"
@@ -50,6 +51,27 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string, namespace string) (ethdb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return openDatabaseWithFreezer(ctx.config, name, cache, handles, freezer, namespace)
+}
+
+// HasAncients reports whether the chain freezer of the named database already
+// holds frozen blocks, in which case it must be attached when opening it.
+func (ctx *ServiceContext) HasAncients(name, freezer string) bool {
+	if ctx.config.DataDir == "" {
+		return false
+	}
+	return rawdb.HasAncients(ancientDir(ctx.config, name, freezer))
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
@@ -79,3 +79,59 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckConfigForkOrder(t *testing.T) {
+	tests := []struct {
+		config  *ChainConfig
+		wantErr bool
+	}{
+		{config: AllEthashProtocolChanges, wantErr: false},
+		{config: RangeMainnetChainConfig, wantErr: false},
+		{config: RangeTestnetChainConfig, wantErr: false},
+		{
+			// Petersburg follows Constantinople when unset
+			config: &ChainConfig{
+				HomesteadBlock:      big.NewInt(0),
+				EIP150Block:         big.NewInt(0),
+				EIP155Block:         big.NewInt(0),
+				EIP158Block:         big.NewInt(0),
+				ByzantiumBlock:      big.NewInt(0),
+				ConstantinopleBlock: big.NewInt(10),
+				IstanbulBlock:       big.NewInt(20),
+			},
+			wantErr: false,
+		},
+		{
+			config: &ChainConfig{
+				HomesteadBlock:      big.NewInt(0),
+				EIP150Block:         big.NewInt(0),
+				EIP155Block:         big.NewInt(0),
+				EIP158Block:         big.NewInt(0),
+				ByzantiumBlock:      big.NewInt(0),
+				ConstantinopleBlock: big.NewInt(0),
+				PetersburgBlock:     big.NewInt(0),
+				BerlinBlock:         big.NewInt(10),
+			},
+			wantErr: true,
+		},
+		{
+			config: &ChainConfig{
+				HomesteadBlock:      big.NewInt(0),
+				EIP150Block:         big.NewInt(0),
+				EIP155Block:         big.NewInt(0),
+				EIP158Block:         big.NewInt(0),
+				ByzantiumBlock:      big.NewInt(0),
+				ConstantinopleBlock: big.NewInt(0),
+				PetersburgBlock:     big.NewInt(20),
+				IstanbulBlock:       big.NewInt(10),
+			},
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		err := test.config.CheckConfigForkOrder()
+		if (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, test.wantErr)
+		}
+	}
+}
@@ -191,6 +191,46 @@ func (_BackboneRewardV1 *BackboneRewardV1TransactorRaw) Transact(opts *bind.Tran
 	return _BackboneRewardV1.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of BackboneRewardV1.
+func (_BackboneRewardV1 *BackboneRewardV1Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _BackboneRewardV1.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of BackboneRewardV1.
+func (_BackboneRewardV1 *BackboneRewardV1Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _BackboneRewardV1.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of BackboneRewardV1 switches to.
+func (_BackboneRewardV1 *BackboneRewardV1Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _BackboneRewardV1.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of BackboneRewardV1 to a new implementation, the proposal fee being the value.
+func (_BackboneRewardV1 *BackboneRewardV1Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _BackboneRewardV1.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// BackboneRewardV1 to the implementation of an accepted proposal.
+func (_BackboneRewardV1 *BackboneRewardV1Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BackboneRewardV1.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of BackboneRewardV1.
+func (_BackboneRewardV1 *BackboneRewardV1Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BackboneRewardV1.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // BackboneAddress is a free data retrieval call binding the contract method 0x7079cf33.
 //
 // Solidity: function backbone_address() constant returns(address)
@@ -191,6 +191,46 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1TransactorRaw) Transact(opts *bin
 	return _BlacklistRegistryV1.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of BlacklistRegistryV1.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _BlacklistRegistryV1.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of BlacklistRegistryV1.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _BlacklistRegistryV1.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of BlacklistRegistryV1 switches to.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _BlacklistRegistryV1.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of BlacklistRegistryV1 to a new implementation, the proposal fee being the value.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _BlacklistRegistryV1.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// BlacklistRegistryV1 to the implementation of an accepted proposal.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BlacklistRegistryV1.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of BlacklistRegistryV1.
+func (_BlacklistRegistryV1 *BlacklistRegistryV1Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BlacklistRegistryV1.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // EBISigner is a free data retrieval call binding the contract method 0x94c210fc.
 //
 // Solidity: function EBI_signer() constant returns(address)
@@ -795,7 +835,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) FilterBlacklistProposal
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.FilterLogs(opts, "BlacklistProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.FilterProxiedLogs(opts, "BlacklistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -812,7 +852,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) WatchBlacklistProposal(
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.WatchLogs(opts, "BlacklistProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.WatchProxiedLogs(opts, "BlacklistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -928,7 +968,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) FilterDrainProposal(opt
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.FilterLogs(opts, "DrainProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.FilterProxiedLogs(opts, "DrainProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -945,7 +985,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) WatchDrainProposal(opts
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.WatchLogs(opts, "DrainProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.WatchProxiedLogs(opts, "DrainProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1061,7 +1101,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) FilterWhitelistProposal
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.FilterLogs(opts, "WhitelistProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.FilterProxiedLogs(opts, "WhitelistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1078,7 +1118,7 @@ func (_BlacklistRegistryV1 *BlacklistRegistryV1Filterer) WatchWhitelistProposal(
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _BlacklistRegistryV1.contract.WatchLogs(opts, "WhitelistProposal", targetRule)
+	logs, sub, err := _BlacklistRegistryV1.contract.WatchProxiedLogs(opts, "WhitelistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
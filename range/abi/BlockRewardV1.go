@@ -191,6 +191,46 @@ func (_BlockRewardV1 *BlockRewardV1TransactorRaw) Transact(opts *bind.TransactOp
 	return _BlockRewardV1.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of BlockRewardV1.
+func (_BlockRewardV1 *BlockRewardV1Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _BlockRewardV1.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of BlockRewardV1.
+func (_BlockRewardV1 *BlockRewardV1Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _BlockRewardV1.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of BlockRewardV1 switches to.
+func (_BlockRewardV1 *BlockRewardV1Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _BlockRewardV1.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of BlockRewardV1 to a new implementation, the proposal fee being the value.
+func (_BlockRewardV1 *BlockRewardV1Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _BlockRewardV1.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// BlockRewardV1 to the implementation of an accepted proposal.
+func (_BlockRewardV1 *BlockRewardV1Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BlockRewardV1.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of BlockRewardV1.
+func (_BlockRewardV1 *BlockRewardV1Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _BlockRewardV1.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // GetReward is a free data retrieval call binding the contract method 0x1c4b774b.
 //
 // Solidity: function getReward(uint256 _blockNumber) constant returns(uint256 amount)
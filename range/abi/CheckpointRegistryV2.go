@@ -191,6 +191,46 @@ func (_CheckpointRegistryV2 *CheckpointRegistryV2TransactorRaw) Transact(opts *b
 	return _CheckpointRegistryV2.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of CheckpointRegistryV2.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _CheckpointRegistryV2.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of CheckpointRegistryV2.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _CheckpointRegistryV2.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of CheckpointRegistryV2 switches to.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _CheckpointRegistryV2.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of CheckpointRegistryV2 to a new implementation, the proposal fee being the value.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _CheckpointRegistryV2.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// CheckpointRegistryV2 to the implementation of an accepted proposal.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _CheckpointRegistryV2.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of CheckpointRegistryV2.
+func (_CheckpointRegistryV2 *CheckpointRegistryV2Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _CheckpointRegistryV2.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // CPPSigner is a free data retrieval call binding the contract method 0xd59f1758.
 //
 // Solidity: function CPP_signer() constant returns(address)
@@ -516,7 +556,7 @@ func (_CheckpointRegistryV2 *CheckpointRegistryV2Filterer) FilterCheckpoint(opts
 		numberRule = append(numberRule, numberItem)
 	}
 
-	logs, sub, err := _CheckpointRegistryV2.contract.FilterLogs(opts, "Checkpoint", numberRule)
+	logs, sub, err := _CheckpointRegistryV2.contract.FilterProxiedLogs(opts, "Checkpoint", numberRule)
 	if err != nil {
 		return nil, err
 	}
@@ -533,7 +573,7 @@ func (_CheckpointRegistryV2 *CheckpointRegistryV2Filterer) WatchCheckpoint(opts
 		numberRule = append(numberRule, numberItem)
 	}
 
-	logs, sub, err := _CheckpointRegistryV2.contract.WatchLogs(opts, "Checkpoint", numberRule)
+	logs, sub, err := _CheckpointRegistryV2.contract.WatchProxiedLogs(opts, "Checkpoint", numberRule)
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +172,46 @@ func (_IBlacklistRegistry *IBlacklistRegistryTransactorRaw) Transact(opts *bind.
 	return _IBlacklistRegistry.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of IBlacklistRegistry.
+func (_IBlacklistRegistry *IBlacklistRegistryCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _IBlacklistRegistry.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of IBlacklistRegistry.
+func (_IBlacklistRegistry *IBlacklistRegistryCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _IBlacklistRegistry.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of IBlacklistRegistry switches to.
+func (_IBlacklistRegistry *IBlacklistRegistryCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _IBlacklistRegistry.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of IBlacklistRegistry to a new implementation, the proposal fee being the value.
+func (_IBlacklistRegistry *IBlacklistRegistryTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _IBlacklistRegistry.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// IBlacklistRegistry to the implementation of an accepted proposal.
+func (_IBlacklistRegistry *IBlacklistRegistryTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IBlacklistRegistry.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of IBlacklistRegistry.
+func (_IBlacklistRegistry *IBlacklistRegistryTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IBlacklistRegistry.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // EBISigner is a free data retrieval call binding the contract method 0x94c210fc.
 //
 // Solidity: function EBI_signer() constant returns(address)
@@ -604,7 +644,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) FilterBlacklistProposal(o
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.FilterLogs(opts, "BlacklistProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.FilterProxiedLogs(opts, "BlacklistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -621,7 +661,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) WatchBlacklistProposal(op
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.WatchLogs(opts, "BlacklistProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.WatchProxiedLogs(opts, "BlacklistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -737,7 +777,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) FilterDrainProposal(opts
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.FilterLogs(opts, "DrainProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.FilterProxiedLogs(opts, "DrainProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -754,7 +794,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) WatchDrainProposal(opts *
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.WatchLogs(opts, "DrainProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.WatchProxiedLogs(opts, "DrainProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -870,7 +910,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) FilterWhitelistProposal(o
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.FilterLogs(opts, "WhitelistProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.FilterProxiedLogs(opts, "WhitelistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
@@ -887,7 +927,7 @@ func (_IBlacklistRegistry *IBlacklistRegistryFilterer) WatchWhitelistProposal(op
 		targetRule = append(targetRule, targetItem)
 	}
 
-	logs, sub, err := _IBlacklistRegistry.contract.WatchLogs(opts, "WhitelistProposal", targetRule)
+	logs, sub, err := _IBlacklistRegistry.contract.WatchProxiedLogs(opts, "WhitelistProposal", targetRule)
 	if err != nil {
 		return nil, err
 	}
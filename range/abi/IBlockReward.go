@@ -172,6 +172,46 @@ func (_IBlockReward *IBlockRewardTransactorRaw) Transact(opts *bind.TransactOpts
 	return _IBlockReward.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of IBlockReward.
+func (_IBlockReward *IBlockRewardCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _IBlockReward.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of IBlockReward.
+func (_IBlockReward *IBlockRewardCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _IBlockReward.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of IBlockReward switches to.
+func (_IBlockReward *IBlockRewardCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _IBlockReward.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of IBlockReward to a new implementation, the proposal fee being the value.
+func (_IBlockReward *IBlockRewardTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _IBlockReward.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// IBlockReward to the implementation of an accepted proposal.
+func (_IBlockReward *IBlockRewardTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IBlockReward.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of IBlockReward.
+func (_IBlockReward *IBlockRewardTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IBlockReward.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // GetReward is a free data retrieval call binding the contract method 0x1c4b774b.
 //
 // Solidity: function getReward(uint256 _blockNumber) constant returns(uint256 amount)
@@ -172,6 +172,46 @@ func (_ICheckpointRegistry *ICheckpointRegistryTransactorRaw) Transact(opts *bin
 	return _ICheckpointRegistry.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of ICheckpointRegistry.
+func (_ICheckpointRegistry *ICheckpointRegistryCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _ICheckpointRegistry.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of ICheckpointRegistry.
+func (_ICheckpointRegistry *ICheckpointRegistryCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _ICheckpointRegistry.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of ICheckpointRegistry switches to.
+func (_ICheckpointRegistry *ICheckpointRegistryCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _ICheckpointRegistry.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of ICheckpointRegistry to a new implementation, the proposal fee being the value.
+func (_ICheckpointRegistry *ICheckpointRegistryTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _ICheckpointRegistry.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// ICheckpointRegistry to the implementation of an accepted proposal.
+func (_ICheckpointRegistry *ICheckpointRegistryTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ICheckpointRegistry.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of ICheckpointRegistry.
+func (_ICheckpointRegistry *ICheckpointRegistryTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ICheckpointRegistry.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // CPPSigner is a free data retrieval call binding the contract method 0xd59f1758.
 //
 // Solidity: function CPP_signer() constant returns(address)
@@ -377,7 +417,7 @@ func (_ICheckpointRegistry *ICheckpointRegistryFilterer) FilterCheckpoint(opts *
 		numberRule = append(numberRule, numberItem)
 	}
 
-	logs, sub, err := _ICheckpointRegistry.contract.FilterLogs(opts, "Checkpoint", numberRule)
+	logs, sub, err := _ICheckpointRegistry.contract.FilterProxiedLogs(opts, "Checkpoint", numberRule)
 	if err != nil {
 		return nil, err
 	}
@@ -394,7 +434,7 @@ func (_ICheckpointRegistry *ICheckpointRegistryFilterer) WatchCheckpoint(opts *b
 		numberRule = append(numberRule, numberItem)
 	}
 
-	logs, sub, err := _ICheckpointRegistry.contract.WatchLogs(opts, "Checkpoint", numberRule)
+	logs, sub, err := _ICheckpointRegistry.contract.WatchProxiedLogs(opts, "Checkpoint", numberRule)
 	if err != nil {
 		return nil, err
 	}
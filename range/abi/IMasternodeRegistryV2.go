@@ -172,6 +172,46 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2TransactorRaw) Transact(opts
 	return _IMasternodeRegistryV2.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of IMasternodeRegistryV2.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _IMasternodeRegistryV2.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of IMasternodeRegistryV2.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _IMasternodeRegistryV2.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of IMasternodeRegistryV2 switches to.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _IMasternodeRegistryV2.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of IMasternodeRegistryV2 to a new implementation, the proposal fee being the value.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _IMasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// IMasternodeRegistryV2 to the implementation of an accepted proposal.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IMasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of IMasternodeRegistryV2.
+func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IMasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // CanHeartbeat is a free data retrieval call binding the contract method 0xd9966aba.
 //
 // Solidity: function canHeartbeat(address masternode) constant returns(bool can_heartbeat)
@@ -764,7 +804,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) FilterAnnounced(opt
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.FilterLogs(opts, "Announced", masternodeRule, ownerRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Announced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -785,7 +825,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) WatchAnnounced(opts
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.WatchLogs(opts, "Announced", masternodeRule, ownerRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Announced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -900,7 +940,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) FilterDeactivated(o
 		masternodeRule = append(masternodeRule, masternodeItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.FilterLogs(opts, "Deactivated", masternodeRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Deactivated", masternodeRule)
 	if err != nil {
 		return nil, err
 	}
@@ -917,7 +957,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) WatchDeactivated(op
 		masternodeRule = append(masternodeRule, masternodeItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.WatchLogs(opts, "Deactivated", masternodeRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Deactivated", masternodeRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1037,7 +1077,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) FilterDenounced(opt
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.FilterLogs(opts, "Denounced", masternodeRule, ownerRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Denounced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1058,7 +1098,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) WatchDenounced(opts
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.WatchLogs(opts, "Denounced", masternodeRule, ownerRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Denounced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1178,7 +1218,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) FilterInvalidation(
 		validatorRule = append(validatorRule, validatorItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.FilterLogs(opts, "Invalidation", masternodeRule, validatorRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Invalidation", masternodeRule, validatorRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1199,7 +1239,7 @@ func (_IMasternodeRegistryV2 *IMasternodeRegistryV2Filterer) WatchInvalidation(o
 		validatorRule = append(validatorRule, validatorItem)
 	}
 
-	logs, sub, err := _IMasternodeRegistryV2.contract.WatchLogs(opts, "Invalidation", masternodeRule, validatorRule)
+	logs, sub, err := _IMasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Invalidation", masternodeRule, validatorRule)
 	if err != nil {
 		return nil, err
 	}
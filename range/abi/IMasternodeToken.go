@@ -172,6 +172,46 @@ func (_IMasternodeToken *IMasternodeTokenTransactorRaw) Transact(opts *bind.Tran
 	return _IMasternodeToken.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of IMasternodeToken.
+func (_IMasternodeToken *IMasternodeTokenCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _IMasternodeToken.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of IMasternodeToken.
+func (_IMasternodeToken *IMasternodeTokenCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _IMasternodeToken.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of IMasternodeToken switches to.
+func (_IMasternodeToken *IMasternodeTokenCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _IMasternodeToken.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of IMasternodeToken to a new implementation, the proposal fee being the value.
+func (_IMasternodeToken *IMasternodeTokenTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _IMasternodeToken.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// IMasternodeToken to the implementation of an accepted proposal.
+func (_IMasternodeToken *IMasternodeTokenTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IMasternodeToken.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of IMasternodeToken.
+func (_IMasternodeToken *IMasternodeTokenTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _IMasternodeToken.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // Allowance is a free data retrieval call binding the contract method 0xdd62ed3e.
 //
 // Solidity: function allowance(address _owner, address _spender) constant returns(uint256 remaining)
@@ -558,7 +598,7 @@ func (_IMasternodeToken *IMasternodeTokenFilterer) FilterApproval(opts *bind.Fil
 		spenderRule = append(spenderRule, spenderItem)
 	}
 
-	logs, sub, err := _IMasternodeToken.contract.FilterLogs(opts, "Approval", ownerRule, spenderRule)
+	logs, sub, err := _IMasternodeToken.contract.FilterProxiedLogs(opts, "Approval", ownerRule, spenderRule)
 	if err != nil {
 		return nil, err
 	}
@@ -579,7 +619,7 @@ func (_IMasternodeToken *IMasternodeTokenFilterer) WatchApproval(opts *bind.Watc
 		spenderRule = append(spenderRule, spenderItem)
 	}
 
-	logs, sub, err := _IMasternodeToken.contract.WatchLogs(opts, "Approval", ownerRule, spenderRule)
+	logs, sub, err := _IMasternodeToken.contract.WatchProxiedLogs(opts, "Approval", ownerRule, spenderRule)
 	if err != nil {
 		return nil, err
 	}
@@ -700,7 +740,7 @@ func (_IMasternodeToken *IMasternodeTokenFilterer) FilterTransfer(opts *bind.Fil
 		toRule = append(toRule, toItem)
 	}
 
-	logs, sub, err := _IMasternodeToken.contract.FilterLogs(opts, "Transfer", fromRule, toRule)
+	logs, sub, err := _IMasternodeToken.contract.FilterProxiedLogs(opts, "Transfer", fromRule, toRule)
 	if err != nil {
 		return nil, err
 	}
@@ -721,7 +761,7 @@ func (_IMasternodeToken *IMasternodeTokenFilterer) WatchTransfer(opts *bind.Watc
 		toRule = append(toRule, toItem)
 	}
 
-	logs, sub, err := _IMasternodeToken.contract.WatchLogs(opts, "Transfer", fromRule, toRule)
+	logs, sub, err := _IMasternodeToken.contract.WatchProxiedLogs(opts, "Transfer", fromRule, toRule)
 	if err != nil {
 		return nil, err
 	}
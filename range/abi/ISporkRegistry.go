@@ -172,6 +172,46 @@ func (_ISporkRegistry *ISporkRegistryTransactorRaw) Transact(opts *bind.Transact
 	return _ISporkRegistry.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of ISporkRegistry.
+func (_ISporkRegistry *ISporkRegistryCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _ISporkRegistry.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of ISporkRegistry.
+func (_ISporkRegistry *ISporkRegistryCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _ISporkRegistry.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of ISporkRegistry switches to.
+func (_ISporkRegistry *ISporkRegistryCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _ISporkRegistry.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of ISporkRegistry to a new implementation, the proposal fee being the value.
+func (_ISporkRegistry *ISporkRegistryTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _ISporkRegistry.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// ISporkRegistry to the implementation of an accepted proposal.
+func (_ISporkRegistry *ISporkRegistryTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ISporkRegistry.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of ISporkRegistry.
+func (_ISporkRegistry *ISporkRegistryTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ISporkRegistry.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // ConsensusGasLimits is a free data retrieval call binding the contract method 0xc00ebced.
 //
 // Solidity: function consensusGasLimits() constant returns(uint256 callGas, uint256 xferGas)
@@ -172,6 +172,46 @@ func (_ITreasury *ITreasuryTransactorRaw) Transact(opts *bind.TransactOpts, meth
 	return _ITreasury.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of ITreasury.
+func (_ITreasury *ITreasuryCaller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _ITreasury.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of ITreasury.
+func (_ITreasury *ITreasuryCaller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _ITreasury.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of ITreasury switches to.
+func (_ITreasury *ITreasuryCaller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _ITreasury.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of ITreasury to a new implementation, the proposal fee being the value.
+func (_ITreasury *ITreasuryTransactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _ITreasury.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// ITreasury to the implementation of an accepted proposal.
+func (_ITreasury *ITreasuryTransactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ITreasury.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of ITreasury.
+func (_ITreasury *ITreasuryTransactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _ITreasury.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // Balance is a free data retrieval call binding the contract method 0xb69ef8a8.
 //
 // Solidity: function balance() constant returns(uint256 amount)
@@ -431,7 +471,7 @@ func (_ITreasury *ITreasuryFilterer) FilterBudgetProposal(opts *bind.FilterOpts,
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _ITreasury.contract.FilterLogs(opts, "BudgetProposal", ref_uuidRule)
+	logs, sub, err := _ITreasury.contract.FilterProxiedLogs(opts, "BudgetProposal", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -448,7 +488,7 @@ func (_ITreasury *ITreasuryFilterer) WatchBudgetProposal(opts *bind.WatchOpts, s
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _ITreasury.contract.WatchLogs(opts, "BudgetProposal", ref_uuidRule)
+	logs, sub, err := _ITreasury.contract.WatchProxiedLogs(opts, "BudgetProposal", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -559,7 +599,7 @@ type ITreasuryContribution struct {
 // Solidity: event Contribution(address from, uint256 amount)
 func (_ITreasury *ITreasuryFilterer) FilterContribution(opts *bind.FilterOpts) (*ITreasuryContributionIterator, error) {
 
-	logs, sub, err := _ITreasury.contract.FilterLogs(opts, "Contribution")
+	logs, sub, err := _ITreasury.contract.FilterProxiedLogs(opts, "Contribution")
 	if err != nil {
 		return nil, err
 	}
@@ -571,7 +611,7 @@ func (_ITreasury *ITreasuryFilterer) FilterContribution(opts *bind.FilterOpts) (
 // Solidity: event Contribution(address from, uint256 amount)
 func (_ITreasury *ITreasuryFilterer) WatchContribution(opts *bind.WatchOpts, sink chan<- *ITreasuryContribution) (event.Subscription, error) {
 
-	logs, sub, err := _ITreasury.contract.WatchLogs(opts, "Contribution")
+	logs, sub, err := _ITreasury.contract.WatchProxiedLogs(opts, "Contribution")
 	if err != nil {
 		return nil, err
 	}
@@ -688,7 +728,7 @@ func (_ITreasury *ITreasuryFilterer) FilterPayout(opts *bind.FilterOpts, ref_uui
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _ITreasury.contract.FilterLogs(opts, "Payout", ref_uuidRule)
+	logs, sub, err := _ITreasury.contract.FilterProxiedLogs(opts, "Payout", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -705,7 +745,7 @@ func (_ITreasury *ITreasuryFilterer) WatchPayout(opts *bind.WatchOpts, sink chan
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _ITreasury.contract.WatchLogs(opts, "Payout", ref_uuidRule)
+	logs, sub, err := _ITreasury.contract.WatchProxiedLogs(opts, "Payout", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
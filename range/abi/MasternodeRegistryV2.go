@@ -191,6 +191,46 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2TransactorRaw) Transact(opts *b
 	return _MasternodeRegistryV2.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of MasternodeRegistryV2.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _MasternodeRegistryV2.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of MasternodeRegistryV2.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _MasternodeRegistryV2.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of MasternodeRegistryV2 switches to.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _MasternodeRegistryV2.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of MasternodeRegistryV2 to a new implementation, the proposal fee being the value.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _MasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// MasternodeRegistryV2 to the implementation of an accepted proposal.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _MasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of MasternodeRegistryV2.
+func (_MasternodeRegistryV2 *MasternodeRegistryV2Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _MasternodeRegistryV2.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // CanHeartbeat is a free data retrieval call binding the contract method 0xd9966aba.
 //
 // Solidity: function canHeartbeat(address masternode) constant returns(bool can_heartbeat)
@@ -1370,7 +1410,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) FilterAnnounced(opts
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.FilterLogs(opts, "Announced", masternodeRule, ownerRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Announced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1391,7 +1431,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) WatchAnnounced(opts *
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.WatchLogs(opts, "Announced", masternodeRule, ownerRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Announced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1506,7 +1546,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) FilterDeactivated(opt
 		masternodeRule = append(masternodeRule, masternodeItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.FilterLogs(opts, "Deactivated", masternodeRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Deactivated", masternodeRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1523,7 +1563,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) WatchDeactivated(opts
 		masternodeRule = append(masternodeRule, masternodeItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.WatchLogs(opts, "Deactivated", masternodeRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Deactivated", masternodeRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1643,7 +1683,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) FilterDenounced(opts
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.FilterLogs(opts, "Denounced", masternodeRule, ownerRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Denounced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1664,7 +1704,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) WatchDenounced(opts *
 		ownerRule = append(ownerRule, ownerItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.WatchLogs(opts, "Denounced", masternodeRule, ownerRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Denounced", masternodeRule, ownerRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1784,7 +1824,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) FilterInvalidation(op
 		validatorRule = append(validatorRule, validatorItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.FilterLogs(opts, "Invalidation", masternodeRule, validatorRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.FilterProxiedLogs(opts, "Invalidation", masternodeRule, validatorRule)
 	if err != nil {
 		return nil, err
 	}
@@ -1805,7 +1845,7 @@ func (_MasternodeRegistryV2 *MasternodeRegistryV2Filterer) WatchInvalidation(opt
 		validatorRule = append(validatorRule, validatorItem)
 	}
 
-	logs, sub, err := _MasternodeRegistryV2.contract.WatchLogs(opts, "Invalidation", masternodeRule, validatorRule)
+	logs, sub, err := _MasternodeRegistryV2.contract.WatchProxiedLogs(opts, "Invalidation", masternodeRule, validatorRule)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +191,46 @@ func (_MasternodeTokenV2 *MasternodeTokenV2TransactorRaw) Transact(opts *bind.Tr
 	return _MasternodeTokenV2.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of MasternodeTokenV2.
+func (_MasternodeTokenV2 *MasternodeTokenV2Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _MasternodeTokenV2.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of MasternodeTokenV2.
+func (_MasternodeTokenV2 *MasternodeTokenV2Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _MasternodeTokenV2.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of MasternodeTokenV2 switches to.
+func (_MasternodeTokenV2 *MasternodeTokenV2Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _MasternodeTokenV2.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of MasternodeTokenV2 to a new implementation, the proposal fee being the value.
+func (_MasternodeTokenV2 *MasternodeTokenV2Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _MasternodeTokenV2.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// MasternodeTokenV2 to the implementation of an accepted proposal.
+func (_MasternodeTokenV2 *MasternodeTokenV2Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _MasternodeTokenV2.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of MasternodeTokenV2.
+func (_MasternodeTokenV2 *MasternodeTokenV2Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _MasternodeTokenV2.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // Allowance is a free data retrieval call binding the contract method 0xdd62ed3e.
 //
 // Solidity: function allowance(address , address ) constant returns(uint256)
@@ -697,7 +737,7 @@ func (_MasternodeTokenV2 *MasternodeTokenV2Filterer) FilterApproval(opts *bind.F
 		spenderRule = append(spenderRule, spenderItem)
 	}
 
-	logs, sub, err := _MasternodeTokenV2.contract.FilterLogs(opts, "Approval", ownerRule, spenderRule)
+	logs, sub, err := _MasternodeTokenV2.contract.FilterProxiedLogs(opts, "Approval", ownerRule, spenderRule)
 	if err != nil {
 		return nil, err
 	}
@@ -718,7 +758,7 @@ func (_MasternodeTokenV2 *MasternodeTokenV2Filterer) WatchApproval(opts *bind.Wa
 		spenderRule = append(spenderRule, spenderItem)
 	}
 
-	logs, sub, err := _MasternodeTokenV2.contract.WatchLogs(opts, "Approval", ownerRule, spenderRule)
+	logs, sub, err := _MasternodeTokenV2.contract.WatchProxiedLogs(opts, "Approval", ownerRule, spenderRule)
 	if err != nil {
 		return nil, err
 	}
@@ -839,7 +879,7 @@ func (_MasternodeTokenV2 *MasternodeTokenV2Filterer) FilterTransfer(opts *bind.F
 		toRule = append(toRule, toItem)
 	}
 
-	logs, sub, err := _MasternodeTokenV2.contract.FilterLogs(opts, "Transfer", fromRule, toRule)
+	logs, sub, err := _MasternodeTokenV2.contract.FilterProxiedLogs(opts, "Transfer", fromRule, toRule)
 	if err != nil {
 		return nil, err
 	}
@@ -860,7 +900,7 @@ func (_MasternodeTokenV2 *MasternodeTokenV2Filterer) WatchTransfer(opts *bind.Wa
 		toRule = append(toRule, toItem)
 	}
 
-	logs, sub, err := _MasternodeTokenV2.contract.WatchLogs(opts, "Transfer", fromRule, toRule)
+	logs, sub, err := _MasternodeTokenV2.contract.WatchProxiedLogs(opts, "Transfer", fromRule, toRule)
 	if err != nil {
 		return nil, err
 	}
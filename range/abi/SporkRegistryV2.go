@@ -191,6 +191,46 @@ func (_SporkRegistryV2 *SporkRegistryV2TransactorRaw) Transact(opts *bind.Transa
 	return _SporkRegistryV2.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of SporkRegistryV2.
+func (_SporkRegistryV2 *SporkRegistryV2Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _SporkRegistryV2.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of SporkRegistryV2.
+func (_SporkRegistryV2 *SporkRegistryV2Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _SporkRegistryV2.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of SporkRegistryV2 switches to.
+func (_SporkRegistryV2 *SporkRegistryV2Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _SporkRegistryV2.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of SporkRegistryV2 to a new implementation, the proposal fee being the value.
+func (_SporkRegistryV2 *SporkRegistryV2Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _SporkRegistryV2.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// SporkRegistryV2 to the implementation of an accepted proposal.
+func (_SporkRegistryV2 *SporkRegistryV2Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _SporkRegistryV2.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of SporkRegistryV2.
+func (_SporkRegistryV2 *SporkRegistryV2Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _SporkRegistryV2.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // EmergencySigner is a free data retrieval call binding the contract method 0xd4f7068a.
 //
 // Solidity: function Emergency_signer() constant returns(address)
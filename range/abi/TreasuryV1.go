@@ -191,6 +191,46 @@ func (_TreasuryV1 *TreasuryV1TransactorRaw) Transact(opts *bind.TransactOpts, me
 	return _TreasuryV1.Contract.contract.Transact(opts, method, params...)
 }
 
+// UpgradeImpl is a free data retrieval call returning the implementation
+// currently behind the governed proxy of TreasuryV1.
+func (_TreasuryV1 *TreasuryV1Caller) UpgradeImpl(opts *bind.CallOpts) (common.Address, error) {
+	return _TreasuryV1.contract.ProxyImpl(opts)
+}
+
+// UpgradeProposals is a free data retrieval call listing the pending upgrade
+// proposals of the governed proxy of TreasuryV1.
+func (_TreasuryV1 *TreasuryV1Caller) UpgradeProposals(opts *bind.CallOpts) ([]common.Address, error) {
+	var ret []common.Address
+	err := _TreasuryV1.contract.GovernedProxy().Call(opts, &ret, "listUpgradeProposals")
+	return ret, err
+}
+
+// UpgradeProposalImpl is a free data retrieval call returning the implementation
+// an upgrade proposal of the governed proxy of TreasuryV1 switches to.
+func (_TreasuryV1 *TreasuryV1Caller) UpgradeProposalImpl(opts *bind.CallOpts, proposal common.Address) (common.Address, error) {
+	var ret common.Address
+	err := _TreasuryV1.contract.GovernedProxy().Call(opts, &ret, "upgradeProposalImpl", proposal)
+	return ret, err
+}
+
+// UpgradePropose is a paid mutator transaction proposing to upgrade the governed
+// proxy of TreasuryV1 to a new implementation, the proposal fee being the value.
+func (_TreasuryV1 *TreasuryV1Transactor) UpgradePropose(opts *bind.TransactOpts, newImpl common.Address, period *big.Int) (*types.Transaction, error) {
+	return _TreasuryV1.contract.GovernedProxy().Transact(opts, "proposeUpgrade", newImpl, period)
+}
+
+// UpgradeApply is a paid mutator transaction upgrading the governed proxy of
+// TreasuryV1 to the implementation of an accepted proposal.
+func (_TreasuryV1 *TreasuryV1Transactor) UpgradeApply(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _TreasuryV1.contract.GovernedProxy().Transact(opts, "upgrade", proposal)
+}
+
+// UpgradeCollect is a paid mutator transaction cleaning up a finished upgrade
+// proposal of the governed proxy of TreasuryV1.
+func (_TreasuryV1 *TreasuryV1Transactor) UpgradeCollect(opts *bind.TransactOpts, proposal common.Address) (*types.Transaction, error) {
+	return _TreasuryV1.contract.GovernedProxy().Transact(opts, "collectUpgradeProposal", proposal)
+}
+
 // ActiveProposals is a free data retrieval call binding the contract method 0xd5f99789.
 //
 // Solidity: function active_proposals(uint256 ) constant returns(address)
@@ -669,7 +709,7 @@ func (_TreasuryV1 *TreasuryV1Filterer) FilterBudgetProposal(opts *bind.FilterOpt
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _TreasuryV1.contract.FilterLogs(opts, "BudgetProposal", ref_uuidRule)
+	logs, sub, err := _TreasuryV1.contract.FilterProxiedLogs(opts, "BudgetProposal", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -686,7 +726,7 @@ func (_TreasuryV1 *TreasuryV1Filterer) WatchBudgetProposal(opts *bind.WatchOpts,
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _TreasuryV1.contract.WatchLogs(opts, "BudgetProposal", ref_uuidRule)
+	logs, sub, err := _TreasuryV1.contract.WatchProxiedLogs(opts, "BudgetProposal", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -797,7 +837,7 @@ type TreasuryV1Contribution struct {
 // Solidity: event Contribution(address from, uint256 amount)
 func (_TreasuryV1 *TreasuryV1Filterer) FilterContribution(opts *bind.FilterOpts) (*TreasuryV1ContributionIterator, error) {
 
-	logs, sub, err := _TreasuryV1.contract.FilterLogs(opts, "Contribution")
+	logs, sub, err := _TreasuryV1.contract.FilterProxiedLogs(opts, "Contribution")
 	if err != nil {
 		return nil, err
 	}
@@ -809,7 +849,7 @@ func (_TreasuryV1 *TreasuryV1Filterer) FilterContribution(opts *bind.FilterOpts)
 // Solidity: event Contribution(address from, uint256 amount)
 func (_TreasuryV1 *TreasuryV1Filterer) WatchContribution(opts *bind.WatchOpts, sink chan<- *TreasuryV1Contribution) (event.Subscription, error) {
 
-	logs, sub, err := _TreasuryV1.contract.WatchLogs(opts, "Contribution")
+	logs, sub, err := _TreasuryV1.contract.WatchProxiedLogs(opts, "Contribution")
 	if err != nil {
 		return nil, err
 	}
@@ -926,7 +966,7 @@ func (_TreasuryV1 *TreasuryV1Filterer) FilterPayout(opts *bind.FilterOpts, ref_u
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _TreasuryV1.contract.FilterLogs(opts, "Payout", ref_uuidRule)
+	logs, sub, err := _TreasuryV1.contract.FilterProxiedLogs(opts, "Payout", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
@@ -943,7 +983,7 @@ func (_TreasuryV1 *TreasuryV1Filterer) WatchPayout(opts *bind.WatchOpts, sink ch
 		ref_uuidRule = append(ref_uuidRule, ref_uuidItem)
 	}
 
-	logs, sub, err := _TreasuryV1.contract.WatchLogs(opts, "Payout", ref_uuidRule)
+	logs, sub, err := _TreasuryV1.contract.WatchProxiedLogs(opts, "Payout", ref_uuidRule)
 	if err != nil {
 		return nil, err
 	}
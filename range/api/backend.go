@@ -69,6 +69,7 @@ type Backend interface {
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	IsPreBlacklisted(addr common.Address) bool
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -0,0 +1,356 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+	"range/core/gen3/rpc"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi "range/core/gen3/energi/consensus"
+	energi_params "range/core/gen3/energi/params"
+)
+
+const (
+	// Default and maximal number of recent blocks to scan for transactions
+	// of a blacklist target.
+	blacklistScanBlocks    uint64 = 100
+	blacklistScanBlocksMax uint64 = 10000
+)
+
+//=============================================================================
+// Blacklist impact analysis
+//=============================================================================
+
+type DrainPreviewItem struct {
+	Address common.Address
+	Balance *hexutil.Big
+}
+
+type DrainPreview struct {
+	Block            uint64
+	CompensationFund common.Address
+	Total            *hexutil.Big
+	Drained          []DrainPreviewItem
+	Skipped          []common.Address
+}
+
+// BlacklistDrainPreview shows the balances which are moved to the compensation
+// fund at the finalization of the block following the given one, the latest
+// by default. Addresses which are drainable, but are not blacklisted in the
+// state (e.g. whitelisted) are listed as skipped.
+func (b *BlacklistAPI) BlacklistDrainPreview(
+	blockNr *rpc.BlockNumber,
+) (*DrainPreview, error) {
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	if number == rpc.PendingBlockNumber {
+		return nil, errors.New("Pending block is not supported")
+	}
+
+	statedb, header, err := b.backend.StateAndHeaderByNumber(
+		context.Background(), number)
+	if err != nil || statedb == nil {
+		log.Error("Failed at state", "err", err)
+		return nil, err
+	}
+
+	registry, err := energi_abi.NewIBlacklistRegistryCaller(
+		energi_params.Range_BlacklistRegistry, b.backend.(bind.ContractCaller))
+	if err != nil {
+		log.Error("Failed", "err", err)
+		return nil, err
+	}
+
+	call_opts := &bind.CallOpts{
+		BlockNumber: header.Number,
+		GasLimit:    energi_params.UnlimitedGas,
+	}
+	addresses, err := registry.EnumerateDrainable(call_opts)
+	if err != nil {
+		log.Error("Failed EnumerateDrainable", "err", err)
+		return nil, err
+	}
+
+	res := &DrainPreview{
+		Block:   header.Number.Uint64() + 1,
+		Drained: make([]DrainPreviewItem, 0, len(addresses)),
+		Skipped: make([]common.Address, 0),
+	}
+	total := new(big.Int)
+
+	if len(addresses) > 0 {
+		res.CompensationFund, err = registry.CompensationFund(call_opts)
+		if err != nil {
+			log.Error("Failed CompensationFund", "err", err)
+			return nil, err
+		}
+	}
+
+	// NOTE: it must match the drain conditions of consensus finalization
+	for _, addr := range addresses {
+		if (addr == common.Address{}) {
+			continue
+		}
+
+		bal := statedb.GetBalance(addr)
+
+		if bal.Cmp(common.Big0) == 0 {
+			continue
+		}
+
+		if core.CanTransfer(statedb, addr, bal) {
+			res.Skipped = append(res.Skipped, addr)
+			continue
+		}
+
+		total.Add(total, bal)
+		res.Drained = append(res.Drained, DrainPreviewItem{
+			Address: addr,
+			Balance: (*hexutil.Big)(bal),
+		})
+	}
+
+	res.Total = (*hexutil.Big)(total)
+	return res, nil
+}
+
+type BlacklistTxInfo struct {
+	Hash        common.Hash
+	BlockNumber *uint64
+	Nonce       uint64
+	To          *common.Address
+	Value       *hexutil.Big
+	Status      string
+	Filtered    bool
+}
+
+type BlacklistTxPreview struct {
+	Target         common.Address
+	Whitelisted    bool
+	PreBlacklisted bool
+	Blacklisted    bool
+	Pool           []BlacklistTxInfo
+	Recent         []BlacklistTxInfo
+	StakedBlocks   []uint64
+}
+
+// BlacklistTxPreview lists transactions of the proposed blacklist target which
+// are in the pool and in the recent blocks, along with the recent blocks staked
+// by it. Pool transactions are marked as filtered, if the target is already
+// pre-blacklisted, or is not whitelisted, so the preliminary blacklist drops
+// them as soon as an enforce proposal of the EBI signer is seen. Mined
+// transactions and staked blocks are not affected, only later blocks staked by
+// a pre-blacklisted target are refused.
+func (b *BlacklistAPI) BlacklistTxPreview(
+	address common.Address,
+	blocks *uint64,
+) (*BlacklistTxPreview, error) {
+	statedb, header, err := b.backend.StateAndHeaderByNumber(
+		context.Background(), rpc.LatestBlockNumber)
+	if err != nil || statedb == nil {
+		log.Error("Failed at state", "err", err)
+		return nil, err
+	}
+
+	scan := blacklistScanBlocks
+	if blocks != nil {
+		scan = *blocks
+	}
+	if scan > blacklistScanBlocksMax {
+		return nil, errors.New("Too many blocks to scan")
+	}
+
+	res := &BlacklistTxPreview{
+		Target:         address,
+		Whitelisted:    core.IsWhitelisted(statedb, address),
+		PreBlacklisted: b.backend.IsPreBlacklisted(address),
+		Blacklisted:    core.IsBlacklisted(statedb, address),
+		Pool:           make([]BlacklistTxInfo, 0),
+		Recent:         make([]BlacklistTxInfo, 0),
+		StakedBlocks:   make([]uint64, 0),
+	}
+	filtered := res.PreBlacklisted || !res.Whitelisted
+
+	// Pool content
+	pending, queued := b.backend.TxPoolContent()
+	for _, tx := range pending[address] {
+		res.Pool = append(res.Pool, blacklistTxInfo(tx, nil, "pending", filtered))
+	}
+	for _, tx := range queued[address] {
+		res.Pool = append(res.Pool, blacklistTxInfo(tx, nil, "queued", filtered))
+	}
+
+	// Recent blocks
+	cfg := b.backend.ChainConfig()
+	head := header.Number.Uint64()
+
+	for i := uint64(0); i < scan && i <= head; i++ {
+		num := head - i
+		block, err := b.backend.BlockByNumber(
+			context.Background(), rpc.BlockNumber(num))
+		if err != nil || block == nil {
+			log.Debug("Failed at block", "number", num, "err", err)
+			break
+		}
+
+		if block.Coinbase() == address {
+			res.StakedBlocks = append(res.StakedBlocks, num)
+		}
+
+		signer := types.MakeSigner(cfg, block.Number())
+
+		for _, tx := range block.Transactions() {
+			var sender common.Address
+
+			if tx.IsConsensus() {
+				sender = tx.ConsensusSender()
+			} else if sender, err = types.Sender(signer, tx); err != nil {
+				continue
+			}
+
+			if sender == address {
+				res.Recent = append(res.Recent, blacklistTxInfo(tx, &num, "mined", false))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func blacklistTxInfo(
+	tx *types.Transaction,
+	block *uint64,
+	status string,
+	filtered bool,
+) BlacklistTxInfo {
+	return BlacklistTxInfo{
+		Hash:        tx.Hash(),
+		BlockNumber: block,
+		Nonce:       tx.Nonce(),
+		To:          tx.To(),
+		Value:       (*hexutil.Big)(tx.Value()),
+		Status:      status,
+		Filtered:    filtered,
+	}
+}
+
+type WhitelistConflict struct {
+	Address common.Address
+	Owner   common.Address
+}
+
+type BlacklistSimulation struct {
+	Target    common.Address
+	Blocked   bool
+	Conflicts []WhitelistConflict
+	Current   []common.Address
+	Resulting []common.Address
+}
+
+// BlacklistSimulate simulates the blacklist processing of the next block with
+// the target added to the list of enforced proposals. Any blocked address which
+// belongs to the consensus contracts is reported as a whitelist conflict and
+// is never actually blocked.
+func (b *BlacklistAPI) BlacklistSimulate(
+	address common.Address,
+) (*BlacklistSimulation, error) {
+	statedb, header, err := b.backend.StateAndHeaderByNumber(
+		context.Background(), rpc.LatestBlockNumber)
+	if err != nil || statedb == nil {
+		log.Error("Failed at state", "err", err)
+		return nil, err
+	}
+
+	registry, err := energi_abi.NewIBlacklistRegistryCaller(
+		energi_params.Range_BlacklistRegistry, b.backend.(bind.ContractCaller))
+	if err != nil {
+		log.Error("Failed", "err", err)
+		return nil, err
+	}
+
+	call_opts := &bind.CallOpts{
+		BlockNumber: header.Number,
+		GasLimit:    energi_params.UnlimitedGas,
+	}
+	blocked, err := registry.EnumerateBlocked(call_opts)
+	if err != nil {
+		log.Error("Failed EnumerateBlocked", "err", err)
+		return nil, err
+	}
+
+	proposed := make([]common.Address, 0, len(blocked)+1)
+	proposed = append(proposed, blocked...)
+	found := false
+	for _, addr := range blocked {
+		if addr == address {
+			found = true
+			break
+		}
+	}
+	if !found {
+		proposed = append(proposed, address)
+	}
+
+	// NOTE: it must match the blacklist processing of consensus finalization
+	whitelist := energi.ConsensusWhitelist(statedb)
+	res := &BlacklistSimulation{
+		Target:    address,
+		Conflicts: make([]WhitelistConflict, 0),
+		Current:   make([]common.Address, 0, len(blocked)),
+		Resulting: make([]common.Address, 0, len(proposed)),
+	}
+
+	for _, addr := range blocked {
+		if _, ok := whitelist[addr]; addr != (common.Address{}) && !ok {
+			res.Current = append(res.Current, addr)
+		}
+	}
+
+	for _, addr := range proposed {
+		if addr == (common.Address{}) {
+			continue
+		}
+
+		if owner, ok := whitelist[addr]; ok {
+			res.Conflicts = append(res.Conflicts, WhitelistConflict{
+				Address: addr,
+				Owner:   owner,
+			})
+			continue
+		}
+
+		if addr == address {
+			res.Blocked = true
+		}
+
+		res.Resulting = append(res.Resulting, addr)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,308 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	ethereum "range/core/gen3"
+	"range/core/gen3/accounts/abi"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"range/core/gen3/rpc"
+
+	"github.com/stretchr/testify/assert"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi_params "range/core/gen3/energi/params"
+)
+
+// testBlacklistBackend serves the states, blocks and pool of the blacklist
+// impact analysis, along with the calls of the blacklist registry.
+type testBlacklistBackend struct {
+	Backend
+
+	head      uint64
+	states    map[uint64]*state.StateDB
+	blocks    map[uint64]*types.Block
+	pending   map[common.Address]types.Transactions
+	queued    map[common.Address]types.Transactions
+	preBlack  map[common.Address]bool
+	drainable []common.Address
+	blocked   []common.Address
+	fund      common.Address
+
+	registry abi.ABI
+	calls    []*big.Int // Block numbers of the registry calls
+}
+
+func newTestBlacklistBackend(t *testing.T, head uint64) *testBlacklistBackend {
+	registry, err := abi.JSON(strings.NewReader(energi_abi.IBlacklistRegistryABI))
+	assert.Empty(t, err)
+
+	return &testBlacklistBackend{
+		head:     head,
+		states:   make(map[uint64]*state.StateDB),
+		blocks:   make(map[uint64]*types.Block),
+		preBlack: make(map[common.Address]bool),
+		registry: registry,
+	}
+}
+
+func (b *testBlacklistBackend) number(blockNr rpc.BlockNumber) uint64 {
+	if blockNr == rpc.LatestBlockNumber {
+		return b.head
+	}
+	return uint64(blockNr)
+}
+
+func (b *testBlacklistBackend) StateAndHeaderByNumber(
+	ctx context.Context, blockNr rpc.BlockNumber,
+) (*state.StateDB, *types.Header, error) {
+	number := b.number(blockNr)
+	statedb, ok := b.states[number]
+	if !ok {
+		return nil, nil, errors.New("state not found")
+	}
+	return statedb, &types.Header{Number: new(big.Int).SetUint64(number)}, nil
+}
+
+func (b *testBlacklistBackend) BlockByNumber(
+	ctx context.Context, blockNr rpc.BlockNumber,
+) (*types.Block, error) {
+	number := b.number(blockNr)
+	if block, ok := b.blocks[number]; ok {
+		return block, nil
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number)}), nil
+}
+
+func (b *testBlacklistBackend) TxPoolContent() (
+	map[common.Address]types.Transactions, map[common.Address]types.Transactions,
+) {
+	return b.pending, b.queued
+}
+
+func (b *testBlacklistBackend) IsPreBlacklisted(addr common.Address) bool {
+	return b.preBlack[addr]
+}
+
+func (b *testBlacklistBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *testBlacklistBackend) CodeAt(
+	ctx context.Context, contract common.Address, blockNumber *big.Int,
+) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (b *testBlacklistBackend) CallContract(
+	ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int,
+) ([]byte, error) {
+	b.calls = append(b.calls, blockNumber)
+
+	method, err := b.registry.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "enumerateDrainable":
+		return method.Outputs.Pack(b.drainable)
+	case "enumerateBlocked":
+		return method.Outputs.Pack(b.blocked)
+	case "compensation_fund":
+		return method.Outputs.Pack(b.fund)
+	}
+	return nil, errors.New("unexpected call " + method.Name)
+}
+
+func newTestBlacklistState(t *testing.T) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	assert.Empty(t, err)
+	return statedb
+}
+
+func TestBlacklistDrainPreview(t *testing.T) {
+	t.Parallel()
+
+	var (
+		drained  = common.HexToAddress("0x1")
+		skipped  = common.HexToAddress("0x2")
+		empty    = common.HexToAddress("0x3")
+		backend  = newTestBlacklistBackend(t, 10)
+		api      = &BlacklistAPI{backend: backend}
+		blocked  = common.BigToHash(common.Big1)
+		previous = newTestBlacklistState(t)
+		latest   = newTestBlacklistState(t)
+	)
+	backend.drainable = []common.Address{drained, skipped, empty, {}}
+	backend.fund = common.HexToAddress("0xf")
+
+	previous.SetBalance(drained, big.NewInt(10))
+	previous.SetBalance(skipped, big.NewInt(5))
+	previous.SetState(energi_params.Range_Blacklist, drained.Hash(), blocked)
+	backend.states[5] = previous
+
+	latest.SetBalance(drained, big.NewInt(1000))
+	latest.SetState(energi_params.Range_Blacklist, drained.Hash(), blocked)
+	backend.states[10] = latest
+
+	// The state and the calls of the requested block
+	number := rpc.BlockNumber(5)
+	res, err := api.BlacklistDrainPreview(&number)
+	assert.Empty(t, err)
+	assert.Equal(t, uint64(6), res.Block)
+	assert.Equal(t, backend.fund, res.CompensationFund)
+	assert.Equal(t, []DrainPreviewItem{{drained, (*hexutil.Big)(big.NewInt(10))}}, res.Drained)
+	assert.Equal(t, []common.Address{skipped}, res.Skipped)
+	assert.Equal(t, big.NewInt(10), res.Total.ToInt())
+	assert.NotEmpty(t, backend.calls)
+	for _, call := range backend.calls {
+		assert.Equal(t, big.NewInt(5), call)
+	}
+
+	// The latest block by default
+	backend.calls = nil
+	res, err = api.BlacklistDrainPreview(nil)
+	assert.Empty(t, err)
+	assert.Equal(t, uint64(11), res.Block)
+	assert.Equal(t, big.NewInt(1000), res.Total.ToInt())
+	assert.Equal(t, []common.Address{}, res.Skipped)
+	assert.NotEmpty(t, backend.calls)
+	for _, call := range backend.calls {
+		assert.Equal(t, big.NewInt(10), call)
+	}
+
+	number = rpc.PendingBlockNumber
+	_, err = api.BlacklistDrainPreview(&number)
+	assert.NotEmpty(t, err)
+}
+
+func TestBlacklistTxPreview(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		target  = crypto.PubkeyToAddress(key.PublicKey)
+		other   = common.HexToAddress("0x2")
+		backend = newTestBlacklistBackend(t, 3)
+		api     = &BlacklistAPI{backend: backend}
+		statedb = newTestBlacklistState(t)
+		signer  = types.MakeSigner(params.TestChainConfig, big.NewInt(1))
+	)
+	backend.states[3] = statedb
+
+	sign := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, other, common.Big1, 21000, common.Big1, nil), signer, key)
+		assert.Empty(t, err)
+		return tx
+	}
+	backend.pending = map[common.Address]types.Transactions{target: {sign(2)}}
+	backend.queued = map[common.Address]types.Transactions{target: {sign(4)}}
+
+	mined := sign(1)
+	backend.blocks[2] = types.NewBlock(&types.Header{Number: big.NewInt(2), Coinbase: target}, nil, nil, nil)
+	backend.blocks[1] = types.NewBlock(&types.Header{Number: big.NewInt(1), Coinbase: other}, types.Transactions{mined}, nil, nil)
+
+	// Not whitelisted, dropped as soon as the proposal is seen
+	res, err := api.BlacklistTxPreview(target, nil)
+	assert.Empty(t, err)
+	assert.False(t, res.Whitelisted)
+	assert.Equal(t, 2, len(res.Pool))
+	assert.Equal(t, "pending", res.Pool[0].Status)
+	assert.Equal(t, "queued", res.Pool[1].Status)
+	for _, info := range res.Pool {
+		assert.True(t, info.Filtered)
+	}
+	assert.Equal(t, 1, len(res.Recent))
+	assert.Equal(t, mined.Hash(), res.Recent[0].Hash)
+	assert.Equal(t, uint64(1), *res.Recent[0].BlockNumber)
+	assert.False(t, res.Recent[0].Filtered)
+	assert.Equal(t, []uint64{2}, res.StakedBlocks)
+
+	// Whitelisted targets are not pre-blacklisted
+	statedb.SetState(energi_params.Range_Whitelist, target.Hash(), common.BigToHash(common.Big1))
+	res, err = api.BlacklistTxPreview(target, nil)
+	assert.Empty(t, err)
+	assert.True(t, res.Whitelisted)
+	for _, info := range res.Pool {
+		assert.False(t, info.Filtered)
+	}
+
+	// Unless they are already
+	backend.preBlack[target] = true
+	res, err = api.BlacklistTxPreview(target, nil)
+	assert.Empty(t, err)
+	assert.True(t, res.PreBlacklisted)
+	for _, info := range res.Pool {
+		assert.True(t, info.Filtered)
+	}
+
+	// The scanned blocks are limited
+	scan := uint64(1)
+	res, err = api.BlacklistTxPreview(target, &scan)
+	assert.Empty(t, err)
+	assert.Empty(t, res.Recent)
+	scan = blacklistScanBlocksMax + 1
+	_, err = api.BlacklistTxPreview(target, &scan)
+	assert.NotEmpty(t, err)
+}
+
+func TestBlacklistSimulate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		target  = common.HexToAddress("0x1")
+		current = common.HexToAddress("0x2")
+		impl    = common.HexToAddress("0x3")
+		backend = newTestBlacklistBackend(t, 1)
+		api     = &BlacklistAPI{backend: backend}
+		statedb = newTestBlacklistState(t)
+	)
+	statedb.SetState(energi_params.Range_Treasury, energi_params.Storage_ProxyImpl, impl.Hash())
+	backend.states[1] = statedb
+	backend.blocked = []common.Address{current, energi_params.Range_Treasury}
+
+	res, err := api.BlacklistSimulate(target)
+	assert.Empty(t, err)
+	assert.True(t, res.Blocked)
+	assert.Equal(t, []common.Address{current}, res.Current)
+	assert.Equal(t, []common.Address{current, target}, res.Resulting)
+	assert.Equal(t, []WhitelistConflict{
+		{energi_params.Range_Treasury, energi_params.Range_Treasury},
+	}, res.Conflicts)
+
+	// Implementations of the consensus proxies are never blocked either
+	res, err = api.BlacklistSimulate(impl)
+	assert.Empty(t, err)
+	assert.False(t, res.Blocked)
+	assert.Equal(t, []common.Address{current}, res.Resulting)
+	assert.Equal(t, []WhitelistConflict{
+		{energi_params.Range_Treasury, energi_params.Range_Treasury},
+		{impl, energi_params.Range_Treasury},
+	}, res.Conflicts)
+}
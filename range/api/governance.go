@@ -306,6 +306,10 @@ func proposalInfo(backend Backend, address common.Address) (*ProposalInfo, error
 	return p, nil
 }
 
+func (g *GovernanceAPI) ProposalInfo(proposal common.Address) (*ProposalInfo, error) {
+	return proposalInfo(g.backend, proposal)
+}
+
 //=============================================================================
 // SC-15: Upgrade API
 //=============================================================================
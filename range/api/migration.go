@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"sync"
 
 	"range/core/gen3/accounts"
 	"range/core/gen3/accounts/abi/bind"
@@ -57,12 +58,16 @@ type MigrationAPI struct {
 
 	lastCoins   interface{}
 	lastBalance *big.Int
+
+	batchMtx sync.Mutex
+	batches  map[common.Address]*migrationBatch
 }
 
 func NewMigrationAPI(b Backend) *MigrationAPI {
 	r := &MigrationAPI{
 		backend:    b,
 		coinsCache: energi_common.NewCacheStorage(),
+		batches:    make(map[common.Address]*migrationBatch),
 	}
 	b.OnSyncedHeadUpdates(func() {
 		r.listGen2Coins()
@@ -533,22 +538,12 @@ func (m *MigrationAPI) claimGen2Coins(
 		return
 	}
 
-	sig, err := crypto.Sign(hts[:], key.Key)
+	v, r, s, err := signGen2Claim(hts, key)
 	if err != nil {
 		return
 	}
 
-	if len(sig) != 65 {
-		err = errors.New("Wrong signature size")
-		return
-	}
-
 	item := new(big.Int).SetUint64(coin.ItemID)
-	r := [32]byte{}
-	copy(r[:], sig[:32])
-	s := [32]byte{}
-	copy(s[:], sig[32:64])
-	v := uint8(sig[64])
 
 	amt, err := mgrt_contract.VerifyClaim(item, dst, v, r, s)
 	if err != nil {
@@ -569,6 +564,26 @@ func (m *MigrationAPI) claimGen2Coins(
 	return
 }
 
+func signGen2Claim(
+	hts [32]byte,
+	key *Gen2Key,
+) (v uint8, r, s [32]byte, err error) {
+	sig, err := crypto.Sign(hts[:], key.Key)
+	if err != nil {
+		return
+	}
+
+	if len(sig) != 65 {
+		err = errors.New("Wrong signature size")
+		return
+	}
+
+	copy(r[:], sig[:32])
+	copy(s[:], sig[32:64])
+	v = uint8(sig[64])
+	return
+}
+
 type MigrationAdminAPI struct {
 	backend Backend
 }
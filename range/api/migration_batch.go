@@ -0,0 +1,563 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+	"range/core/gen3/core/rawdb"
+	"range/core/gen3/core/types"
+	"range/core/gen3/log"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi_params "range/core/gen3/energi/params"
+)
+
+const (
+	// NOTE: it must match zfMinCoinClaimPeriod of the zero-fee protector
+	migrationBatchRetryPeriod = 3 * time.Minute
+
+	migrationBatchInterval    = 15 * time.Second
+	migrationBatchMaxPending  = 8
+	migrationBatchMaxAttempts = 5
+)
+
+const (
+	Gen2CoinUnclaimed = "unclaimed"
+	Gen2CoinPending   = "pending"
+	Gen2CoinClaimed   = "claimed"
+	Gen2CoinFailed    = "failed"
+)
+
+var migrationBatchPrefix = []byte("range-migration-batch-")
+
+//=============================================================================
+// Batch claim of Gen2 coins
+//=============================================================================
+
+type Gen2BatchCoin struct {
+	ItemID      uint64
+	RawOwner    common.Address
+	Owner       string
+	Amount      *hexutil.Big
+	Status      string
+	TxHash      *common.Hash
+	Attempts    uint64
+	NextAttempt uint64
+	Error       string
+	// Output of verifyClaim, it is filled only on status requests.
+	Claimable *hexutil.Big `json:",omitempty"`
+}
+
+type Gen2BatchStatus struct {
+	Destination common.Address
+	File        string
+	Running     bool
+	Unclaimed   uint64
+	Pending     uint64
+	Claimed     uint64
+	Failed      uint64
+	Coins       []Gen2BatchCoin
+}
+
+// migrationBatchState is the persistent part of a batch claim. Gen2 keys are
+// never stored, they are loaded from the dump file on each resume.
+type migrationBatchState struct {
+	Destination common.Address
+	File        string
+	Coins       []Gen2BatchCoin
+}
+
+type migrationBatch struct {
+	mtx      sync.Mutex
+	state    migrationBatchState
+	keys     map[common.Address]*Gen2Key
+	password *string
+	running  bool
+	quit     chan struct{}
+}
+
+// ClaimGen2CoinsBatch starts or resumes a batch claim of all the coins found
+// in the Gen2 dump file. Claims are queued and sent in the background at
+// the pace allowed by the zero-fee protector. The progress is persisted, so
+// the batch may be resumed after a restart by calling it again.
+func (m *MigrationAPI) ClaimGen2CoinsBatch(
+	password *string,
+	dst common.Address,
+	file string,
+) (*Gen2BatchStatus, error) {
+	if m.backend.IsPublicService() {
+		return nil, errors.New("This API is disabled for security reasons")
+	}
+
+	keys, err := m.loadGen2Dump(file)
+	if err != nil {
+		return nil, err
+	}
+
+	raw_owners := make([]common.Address, len(keys))
+	owner2key := make(map[common.Address]*Gen2Key, len(keys))
+	for i, k := range keys {
+		raw_owners[i] = k.RawOwner
+		owner2key[k.RawOwner] = &keys[i]
+	}
+
+	m.batchMtx.Lock()
+	defer m.batchMtx.Unlock()
+
+	if b, ok := m.batches[dst]; ok && b.isRunning() {
+		return nil, errors.New("Batch claim is already running")
+	}
+
+	state, err := m.readBatchState(dst)
+	if err != nil {
+		state = &migrationBatchState{}
+	}
+	state.Destination = dst
+	state.File = file
+
+	coins, err := m.SearchRawGen2Coins(raw_owners, false)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[uint64]bool, len(state.Coins))
+	for i := range state.Coins {
+		c := &state.Coins[i]
+		known[c.ItemID] = true
+
+		// Failed claims are retried on explicit resume
+		if c.Status == Gen2CoinFailed {
+			c.Status = Gen2CoinUnclaimed
+			c.Attempts = 0
+			c.NextAttempt = 0
+		}
+	}
+
+	for _, c := range coins {
+		if known[c.ItemID] {
+			continue
+		}
+
+		state.Coins = append(state.Coins, Gen2BatchCoin{
+			ItemID:   c.ItemID,
+			RawOwner: c.RawOwner,
+			Owner:    c.Owner,
+			Amount:   c.Amount,
+			Status:   Gen2CoinUnclaimed,
+		})
+	}
+
+	if err = m.writeBatchState(state); err != nil {
+		log.Error("Failed to save batch claim", "err", err)
+		return nil, err
+	}
+
+	b := &migrationBatch{
+		state:    *state,
+		keys:     owner2key,
+		password: password,
+		running:  true,
+		quit:     make(chan struct{}),
+	}
+	m.batches[dst] = b
+
+	log.Info("Starting Gen2 batch claim", "dst", dst, "coins", len(state.Coins))
+	go m.runBatch(b)
+
+	return b.status(), nil
+}
+
+// ClaimGen2BatchStatus reports the per-coin progress of a batch claim along
+// with the current verifyClaim output of each coin.
+func (m *MigrationAPI) ClaimGen2BatchStatus(
+	dst common.Address,
+) (*Gen2BatchStatus, error) {
+	if m.backend.IsPublicService() {
+		return nil, errors.New("This API is disabled for security reasons")
+	}
+
+	var (
+		status *Gen2BatchStatus
+		keys   map[common.Address]*Gen2Key
+	)
+
+	m.batchMtx.Lock()
+	b, ok := m.batches[dst]
+	m.batchMtx.Unlock()
+
+	if ok {
+		status = b.status()
+		keys = b.keys
+	} else {
+		state, err := m.readBatchState(dst)
+		if err != nil {
+			return nil, errors.New("No batch claim found")
+		}
+
+		b = &migrationBatch{state: *state}
+		status = b.status()
+		keys = make(map[common.Address]*Gen2Key)
+
+		if dump, err := m.loadGen2Dump(state.File); err == nil {
+			for i, k := range dump {
+				keys[k.RawOwner] = &dump[i]
+			}
+		}
+	}
+
+	mgrt_contract, err := energi_abi.NewGen2MigrationCaller(
+		energi_params.Range_MigrationContract, m.backend.(bind.ContractCaller))
+	if err != nil {
+		log.Error("Failed to create contract face", "err", err)
+		return nil, err
+	}
+
+	call_opts := &bind.CallOpts{
+		Pending:  true,
+		From:     dst,
+		GasLimit: energi_params.UnlimitedGas,
+	}
+
+	hts, err := mgrt_contract.HashToSign(call_opts, dst)
+	if err != nil {
+		log.Error("Failed to get hash to sign", "err", err)
+		return nil, err
+	}
+
+	for i := range status.Coins {
+		c := &status.Coins[i]
+
+		key, ok := keys[c.RawOwner]
+		if !ok {
+			continue
+		}
+
+		v, r, s, err := signGen2Claim(hts, key)
+		if err != nil {
+			continue
+		}
+
+		amt, err := mgrt_contract.VerifyClaim(
+			call_opts, new(big.Int).SetUint64(c.ItemID), dst, v, r, s)
+		if err != nil {
+			log.Debug("Failed to verify claim", "item", c.ItemID, "err", err)
+			continue
+		}
+
+		c.Claimable = (*hexutil.Big)(amt)
+	}
+
+	return status, nil
+}
+
+// ClaimGen2BatchCancel stops a running batch claim. The progress is kept and
+// the batch may be resumed later.
+func (m *MigrationAPI) ClaimGen2BatchCancel(
+	dst common.Address,
+) (bool, error) {
+	if m.backend.IsPublicService() {
+		return false, errors.New("This API is disabled for security reasons")
+	}
+
+	m.batchMtx.Lock()
+	defer m.batchMtx.Unlock()
+
+	b, ok := m.batches[dst]
+	if !ok {
+		return false, nil
+	}
+
+	delete(m.batches, dst)
+	return b.stop(), nil
+}
+
+func (m *MigrationAPI) runBatch(b *migrationBatch) {
+	ticker := time.NewTicker(migrationBatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.quit:
+			log.Info("Gen2 batch claim is cancelled", "dst", b.state.Destination)
+			return
+		default:
+		}
+
+		if m.processBatch(b) {
+			break
+		}
+
+		select {
+		case <-b.quit:
+		case <-ticker.C:
+		}
+	}
+
+	log.Info("Gen2 batch claim is complete", "dst", b.state.Destination)
+
+	b.mtx.Lock()
+	b.running = false
+	b.mtx.Unlock()
+}
+
+// batchClaim is a claim of a batch coin sent without holding the batch lock.
+type batchClaim struct {
+	index  int
+	coin   *Gen2Coin
+	key    *Gen2Key
+	txhash common.Hash
+	err    error
+}
+
+// processBatch checks pending claims and sends new ones up to the pending
+// limit. It returns true, if there is nothing left to do.
+//
+// The claims are sent without holding the batch lock, as they run the EVM
+// and the status requests would wait on them otherwise.
+func (m *MigrationAPI) processBatch(b *migrationBatch) bool {
+	now := time.Now()
+
+	b.mtx.Lock()
+	dst := b.state.Destination
+	claims := m.nextBatchClaims(b, now)
+	b.mtx.Unlock()
+
+	for _, claim := range claims {
+		claim.txhash, claim.err = m.claimGen2Coins(b.password, dst, claim.coin, claim.key)
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	coins := b.state.Coins
+
+	for _, claim := range claims {
+		m.finishBatchClaim(&coins[claim.index], claim, now)
+	}
+
+	if err := m.writeBatchState(&b.state); err != nil {
+		log.Error("Failed to save batch claim", "err", err)
+	}
+
+	for _, c := range coins {
+		if c.Status == Gen2CoinUnclaimed || c.Status == Gen2CoinPending {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nextBatchClaims updates the pending claims and returns the coins to claim
+// next, up to the pending limit. The batch lock must be held.
+func (m *MigrationAPI) nextBatchClaims(b *migrationBatch, now time.Time) []*batchClaim {
+	coins := b.state.Coins
+	pending := 0
+
+	for i := range coins {
+		c := &coins[i]
+
+		if c.Status == Gen2CoinPending {
+			m.checkBatchClaim(c, now)
+		}
+
+		if c.Status == Gen2CoinPending {
+			pending++
+		}
+	}
+
+	var claims []*batchClaim
+
+	for i := range coins {
+		if pending+len(claims) >= migrationBatchMaxPending {
+			break
+		}
+
+		c := &coins[i]
+
+		if c.Status != Gen2CoinUnclaimed || c.NextAttempt > uint64(now.Unix()) {
+			continue
+		}
+
+		key, ok := b.keys[c.RawOwner]
+		if !ok {
+			c.Status = Gen2CoinFailed
+			c.Error = "Gen2 key is missing in the dump file"
+			continue
+		}
+
+		claims = append(claims, &batchClaim{
+			index: i,
+			coin: &Gen2Coin{
+				ItemID:   c.ItemID,
+				RawOwner: c.RawOwner,
+				Owner:    c.Owner,
+				Amount:   c.Amount,
+			},
+			key: key,
+		})
+	}
+
+	return claims
+}
+
+// finishBatchClaim updates the coin with the outcome of its claim.
+func (m *MigrationAPI) finishBatchClaim(c *Gen2BatchCoin, claim *batchClaim, now time.Time) {
+	switch {
+	case errors.Is(claim.err, core.ErrZeroFeeDoS):
+		log.Debug("Gen2 claim is throttled", "item", c.ItemID)
+		c.NextAttempt = uint64(now.Add(migrationBatchRetryPeriod).Unix())
+	case claim.err != nil:
+		c.Attempts++
+		m.failBatchClaim(c, now, claim.err)
+	case claim.txhash == (common.Hash{}):
+		c.Status = Gen2CoinClaimed
+		c.Error = ""
+	default:
+		txhash := claim.txhash
+		c.Attempts++
+		c.Status = Gen2CoinPending
+		c.TxHash = &txhash
+		c.Error = ""
+	}
+}
+
+func (m *MigrationAPI) checkBatchClaim(c *Gen2BatchCoin, now time.Time) {
+	tx, block_hash, _, index := rawdb.ReadTransaction(m.backend.ChainDb(), *c.TxHash)
+
+	if tx != nil {
+		receipts, err := m.backend.GetReceipts(context.Background(), block_hash)
+		if err != nil || uint64(len(receipts)) <= index {
+			return
+		}
+
+		if receipts[index].Status == types.ReceiptStatusSuccessful {
+			c.Status = Gen2CoinClaimed
+		} else {
+			m.failBatchClaim(c, now, errors.New("Claim transaction has failed"))
+		}
+		return
+	}
+
+	// Zero-fee transactions get dropped from the pool on timeout
+	if m.backend.GetPoolTransaction(*c.TxHash) == nil {
+		log.Debug("Gen2 claim is dropped", "item", c.ItemID, "tx", c.TxHash.Hex())
+		c.Status = Gen2CoinUnclaimed
+		c.NextAttempt = uint64(now.Add(migrationBatchRetryPeriod).Unix())
+	}
+}
+
+func (m *MigrationAPI) failBatchClaim(c *Gen2BatchCoin, now time.Time, err error) {
+	log.Warn("Gen2 claim has failed", "item", c.ItemID, "attempt", c.Attempts, "err", err)
+
+	c.Error = err.Error()
+
+	if c.Attempts >= migrationBatchMaxAttempts {
+		c.Status = Gen2CoinFailed
+		return
+	}
+
+	c.Status = Gen2CoinUnclaimed
+	c.NextAttempt = uint64(now.Add(migrationBatchRetryPeriod).Unix())
+}
+
+func (m *MigrationAPI) readBatchState(dst common.Address) (*migrationBatchState, error) {
+	data, err := m.backend.ChainDb().Get(migrationBatchKey(dst))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &migrationBatchState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (m *MigrationAPI) writeBatchState(state *migrationBatchState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return m.backend.ChainDb().Put(migrationBatchKey(state.Destination), data)
+}
+
+func migrationBatchKey(dst common.Address) []byte {
+	key := make([]byte, 0, len(migrationBatchPrefix)+common.AddressLength)
+	key = append(key, migrationBatchPrefix...)
+	return append(key, dst.Bytes()...)
+}
+
+func (b *migrationBatch) isRunning() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.running
+}
+
+func (b *migrationBatch) stop() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.running {
+		return false
+	}
+
+	b.running = false
+	close(b.quit)
+	return true
+}
+
+func (b *migrationBatch) status() *Gen2BatchStatus {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	res := &Gen2BatchStatus{
+		Destination: b.state.Destination,
+		File:        b.state.File,
+		Running:     b.running,
+		Coins:       make([]Gen2BatchCoin, len(b.state.Coins)),
+	}
+	copy(res.Coins, b.state.Coins)
+
+	for _, c := range res.Coins {
+		switch c.Status {
+		case Gen2CoinUnclaimed:
+			res.Unclaimed++
+		case Gen2CoinPending:
+			res.Pending++
+		case Gen2CoinClaimed:
+			res.Claimed++
+		case Gen2CoinFailed:
+			res.Failed++
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchClaimSelection(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m     = &MigrationAPI{}
+		now   = time.Unix(1000, 0)
+		owner = common.HexToAddress("0x1")
+		key   = &Gen2Key{RawOwner: owner}
+		b     = &migrationBatch{keys: map[common.Address]*Gen2Key{owner: key}}
+	)
+	for i := 0; i < migrationBatchMaxPending+4; i++ {
+		b.state.Coins = append(b.state.Coins, Gen2BatchCoin{
+			ItemID:   uint64(i),
+			RawOwner: owner,
+			Amount:   (*hexutil.Big)(big.NewInt(1)),
+			Status:   Gen2CoinUnclaimed,
+		})
+	}
+	// Waiting for a retry, claimed already and without a key
+	b.state.Coins[0].NextAttempt = uint64(now.Unix()) + 1
+	b.state.Coins[1].Status = Gen2CoinClaimed
+	b.state.Coins[2].RawOwner = common.HexToAddress("0x2")
+
+	claims := m.nextBatchClaims(b, now)
+	assert.Equal(t, migrationBatchMaxPending, len(claims))
+	assert.Equal(t, 3, claims[0].index)
+	assert.Equal(t, uint64(3), claims[0].coin.ItemID)
+	assert.Equal(t, key, claims[0].key)
+	assert.Equal(t, Gen2CoinFailed, b.state.Coins[2].Status)
+	assert.Equal(t, Gen2CoinUnclaimed, b.state.Coins[0].Status)
+}
+
+func TestBatchClaimOutcome(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m      = &MigrationAPI{}
+		now    = time.Unix(1000, 0)
+		retry  = uint64(now.Add(migrationBatchRetryPeriod).Unix())
+		txhash = common.HexToHash("0x1")
+	)
+	// Throttling is recognized through wrapping and does not count as attempt
+	c := &Gen2BatchCoin{Status: Gen2CoinUnclaimed}
+	m.finishBatchClaim(c, &batchClaim{err: fmt.Errorf("claim: %w", core.ErrZeroFeeDoS)}, now)
+	assert.Equal(t, Gen2CoinUnclaimed, c.Status)
+	assert.Equal(t, uint64(0), c.Attempts)
+	assert.Equal(t, retry, c.NextAttempt)
+
+	// Failures are retried up to the limit
+	for i := 1; i < migrationBatchMaxAttempts; i++ {
+		m.finishBatchClaim(c, &batchClaim{err: errors.New("failed")}, now)
+		assert.Equal(t, Gen2CoinUnclaimed, c.Status)
+		assert.Equal(t, "failed", c.Error)
+	}
+	m.finishBatchClaim(c, &batchClaim{err: errors.New("failed")}, now)
+	assert.Equal(t, Gen2CoinFailed, c.Status)
+
+	// Sent claims are pending, the ones already claimed are done
+	c = &Gen2BatchCoin{Status: Gen2CoinUnclaimed}
+	m.finishBatchClaim(c, &batchClaim{txhash: txhash}, now)
+	assert.Equal(t, Gen2CoinPending, c.Status)
+	assert.Equal(t, &txhash, c.TxHash)
+	assert.Equal(t, uint64(1), c.Attempts)
+
+	c = &Gen2BatchCoin{Status: Gen2CoinUnclaimed}
+	m.finishBatchClaim(c, &batchClaim{}, now)
+	assert.Equal(t, Gen2CoinClaimed, c.Status)
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
 	"range/core/gen3/crypto"
 	"range/core/gen3/log"
 
@@ -42,7 +43,7 @@ func TestGen2Parse(t *testing.T) {
 	t.Parallel()
 	log.Root().SetHandler(log.StdoutHandler)
 
-	m := NewMigrationAPI(nil)
+	m := &MigrationAPI{}
 	res := m.parseGen2Dump(testWalletDump)
 	assert.Equal(t, 2, len(res))
 	assert.Equal(t,
@@ -63,24 +64,24 @@ func TestSearchCoins(t *testing.T) {
 	t.Parallel()
 	log.Root().SetHandler(log.StdoutHandler)
 
-	m := NewMigrationAPI(nil)
+	m := &MigrationAPI{}
 
 	listCoins := func() ([]Gen2Coin, error) {
 		return []Gen2Coin{
 			{
 				ItemID:   77,
 				RawOwner: common.HexToAddress("0xC94729d0212C2D1074d858EB6c9ee44Fb19D76e6"),
-				Amount:   big.NewInt(0),
+				Amount:   (*hexutil.Big)(big.NewInt(0)),
 			},
 			{
 				ItemID:   78,
 				RawOwner: common.HexToAddress("0xC94729d0212C2D1074d858EB6c9ee44Fb19D76e6"),
-				Amount:   big.NewInt(10),
+				Amount:   (*hexutil.Big)(big.NewInt(10)),
 			},
 			{
 				ItemID:   79,
 				RawOwner: common.HexToAddress("0xDB52E60435e09e998b6077eE65e3719836fA0d2e"),
-				Amount:   big.NewInt(10),
+				Amount:   (*hexutil.Big)(big.NewInt(10)),
 			},
 		}, nil
 	}
@@ -102,17 +103,17 @@ func TestSearchCoins(t *testing.T) {
 			{
 				ItemID:   77,
 				RawOwner: common.HexToAddress("0xC94729d0212C2D1074d858EB6c9ee44Fb19D76e6"),
-				Amount:   big.NewInt(0),
+				Amount:   (*hexutil.Big)(big.NewInt(0)),
 			},
 			{
 				ItemID:   78,
 				RawOwner: common.HexToAddress("0xC94729d0212C2D1074d858EB6c9ee44Fb19D76e6"),
-				Amount:   big.NewInt(10),
+				Amount:   (*hexutil.Big)(big.NewInt(10)),
 			},
 			{
 				ItemID:   79,
 				RawOwner: common.HexToAddress("0xDB52E60435e09e998b6077eE65e3719836fA0d2e"),
-				Amount:   big.NewInt(10),
+				Amount:   (*hexutil.Big)(big.NewInt(10)),
 			},
 		}, nil
 	}
@@ -0,0 +1,396 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/pborman/uuid"
+
+	"range/core/gen3/accounts/abi/bind"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	"range/core/gen3/log"
+	"range/core/gen3/rpc"
+
+	energi_abi "range/core/gen3/energi/abi"
+	energi_params "range/core/gen3/energi/params"
+)
+
+const (
+	// Default and maximal number of superblocks in the timeline.
+	treasuryTimelineCount    uint64 = 5
+	treasuryTimelineCountMax uint64 = 100
+)
+
+//=============================================================================
+// Treasury timeline
+//=============================================================================
+
+type TreasuryPayout struct {
+	Proposal common.Address
+	RefUUID  string
+	Amount   *hexutil.Big
+	Unpaid   *hexutil.Big // Left to pay after the cycle, projected payouts only
+}
+
+type TreasurySuperblock struct {
+	Block     uint64
+	Timestamp uint64
+	Estimated bool
+	Reward    *hexutil.Big
+	Payouts   []TreasuryPayout
+}
+
+type TreasuryTimeline struct {
+	Cycle    uint64
+	Balance  *hexutil.Big
+	Upcoming []TreasurySuperblock
+	History  []TreasurySuperblock
+}
+
+// treasuryUnpaid is an accepted budget proposal with a remaining amount.
+type treasuryUnpaid struct {
+	proposal common.Address
+	ref_uuid *big.Int
+	unpaid   *big.Int
+}
+
+// TreasuryTimeline returns the next superblocks with the estimated time and
+// the projected payouts of the accepted budget proposals, as well as the
+// actual payouts of the past superblocks. The treasury distributes its balance
+// on every block, the payouts of a superblock are the ones of its whole cycle
+// up to the next superblock.
+//
+// NOTE: the projection must match the TreasuryV1 reward distribution.
+func (g *GovernanceAPI) TreasuryTimeline(
+	count *uint64,
+	history *uint64,
+) (*TreasuryTimeline, error) {
+	upcoming_count, history_count := treasuryTimelineCount, treasuryTimelineCount
+	if count != nil {
+		upcoming_count = *count
+	}
+	if history != nil {
+		history_count = *history
+	}
+	if upcoming_count > treasuryTimelineCountMax || history_count > treasuryTimelineCountMax {
+		return nil, errors.New("Too many superblocks requested")
+	}
+
+	cfg := g.backend.ChainConfig()
+	if cfg.SuperblockCycle == nil || cfg.SuperblockCycle.Sign() <= 0 {
+		return nil, errors.New("Superblock cycle is not configured")
+	}
+	cycle := cfg.SuperblockCycle.Uint64()
+
+	header, err := g.backend.HeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if err != nil || header == nil {
+		log.Error("Failed at header", "err", err)
+		return nil, err
+	}
+	head := header.Number.Uint64()
+
+	treasury, err := energi_abi.NewITreasuryCaller(
+		energi_params.Range_Treasury, g.backend.(bind.ContractCaller))
+	if err != nil {
+		log.Error("Failed NewITreasuryCaller", "err", err)
+		return nil, err
+	}
+
+	block_reward, err := energi_abi.NewIBlockRewardCaller(
+		energi_params.Range_Treasury, g.backend.(bind.ContractCaller))
+	if err != nil {
+		log.Error("Failed NewIBlockRewardCaller", "err", err)
+		return nil, err
+	}
+
+	call_opts := &bind.CallOpts{
+		BlockNumber: header.Number,
+		GasLimit:    energi_params.UnlimitedGas,
+	}
+
+	balance, err := treasury.Balance(call_opts)
+	if err != nil {
+		log.Error("Failed Balance", "err", err)
+		return nil, err
+	}
+
+	accepted, err := g.treasuryUnpaid(treasury, call_opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &TreasuryTimeline{
+		Cycle:    cycle,
+		Balance:  (*hexutil.Big)(balance),
+		Upcoming: make([]TreasurySuperblock, 0, upcoming_count),
+		History:  make([]TreasurySuperblock, 0, history_count),
+	}
+
+	// Upcoming superblocks
+	//---
+	projected := new(big.Int).Set(balance)
+	next := (head/cycle + 1) * cycle
+
+	// The catch-up payouts of the current cycle
+	treasuryCycle(projected, accepted, next-head-1)
+
+	for i := uint64(0); i < upcoming_count; i++ {
+		block := next + i*cycle
+
+		reward, err := block_reward.GetReward(call_opts, new(big.Int).SetUint64(block))
+		if err != nil {
+			log.Error("Failed GetReward", "err", err)
+			return nil, err
+		}
+		projected.Add(projected, reward)
+
+		res.Upcoming = append(res.Upcoming, TreasurySuperblock{
+			Block:     block,
+			Timestamp: header.Time + (block-head)*energi_params.TargetBlockGap,
+			Estimated: true,
+			Reward:    (*hexutil.Big)(reward),
+			Payouts:   treasuryCycle(projected, accepted, cycle),
+		})
+	}
+
+	// Past superblocks
+	//---
+	filterer, err := energi_abi.NewTreasuryV1Filterer(
+		energi_params.Range_Treasury, g.backend.(bind.ContractFilterer))
+	if err != nil {
+		log.Error("Failed NewTreasuryV1Filterer", "err", err)
+		return nil, err
+	}
+
+	for i := uint64(0); i < history_count && head/cycle > i; i++ {
+		block := (head/cycle - i) * cycle
+		end := block + cycle - 1
+		if end > head {
+			end = head
+		}
+
+		sb, err := g.treasuryPastSuperblock(filterer, block_reward, block, end)
+		if err != nil {
+			return nil, err
+		}
+
+		res.History = append(res.History, *sb)
+	}
+
+	return res, nil
+}
+
+func (g *GovernanceAPI) treasuryUnpaid(
+	treasury *energi_abi.ITreasuryCaller,
+	call_opts *bind.CallOpts,
+) ([]*treasuryUnpaid, error) {
+	proposals, err := treasury.ListProposals(call_opts)
+	if err != nil {
+		log.Error("Failed ListProposals", "err", err)
+		return nil, err
+	}
+
+	ret := make([]*treasuryUnpaid, 0, len(proposals))
+	for _, p := range proposals {
+		budget_proposal, err := energi_abi.NewIBudgetProposalCaller(
+			p, g.backend.(bind.ContractCaller))
+		if err != nil {
+			log.Error("Failed NewIBudgetProposalCaller", "err", err)
+			return nil, err
+		}
+
+		status, err := budget_proposal.BudgetStatus(call_opts)
+		if err != nil {
+			log.Debug("Failed BudgetStatus", "proposal", p, "err", err)
+			continue
+		}
+
+		if !status.IsAccepted || status.Unpaid.Sign() <= 0 {
+			continue
+		}
+
+		ret = append(ret, &treasuryUnpaid{
+			proposal: p,
+			ref_uuid: status.RefUuid,
+			unpaid:   new(big.Int).Set(status.Unpaid),
+		})
+	}
+
+	return ret, nil
+}
+
+// treasuryCycle projects the distributions of the given number of blocks,
+// merged by proposal. Without new funds, the distribution stops as soon as a
+// block pays nothing.
+func treasuryCycle(
+	balance *big.Int,
+	accepted []*treasuryUnpaid,
+	blocks uint64,
+) []TreasuryPayout {
+	payouts := make([]TreasuryPayout, 0, len(accepted))
+
+	for i := uint64(0); i < blocks; i++ {
+		paid := false
+
+		for _, payout := range treasuryDistribute(balance, accepted) {
+			if payout.Amount.ToInt().Sign() > 0 {
+				payouts = treasuryMerge(payouts, payout)
+				paid = true
+			}
+		}
+
+		if !paid {
+			break
+		}
+	}
+
+	return payouts
+}
+
+// treasuryMerge adds the payout to the one of the same proposal, if any. The
+// unpaid amount of the later payout is kept.
+func treasuryMerge(payouts []TreasuryPayout, payout TreasuryPayout) []TreasuryPayout {
+	for i := range payouts {
+		if payouts[i].Proposal == payout.Proposal {
+			amount := new(big.Int).Add(payouts[i].Amount.ToInt(), payout.Amount.ToInt())
+			payouts[i].Amount = (*hexutil.Big)(amount)
+			payouts[i].Unpaid = payout.Unpaid
+			return payouts
+		}
+	}
+	return append(payouts, payout)
+}
+
+// treasuryDistribute projects the reward distribution of a block. Both
+// the balance and the unpaid amounts get updated for the next block.
+func treasuryDistribute(
+	balance *big.Int,
+	accepted []*treasuryUnpaid,
+) []TreasuryPayout {
+	payouts := make([]TreasuryPayout, 0, len(accepted))
+
+	unpaid_total := new(big.Int)
+	for _, p := range accepted {
+		unpaid_total.Add(unpaid_total, p.unpaid)
+	}
+
+	if balance.Sign() <= 0 || unpaid_total.Sign() <= 0 {
+		return payouts
+	}
+
+	permille := big.NewInt(1000)
+	if unpaid_total.Cmp(balance) > 0 {
+		permille.Mul(balance, permille)
+		permille.Div(permille, unpaid_total)
+	}
+
+	for _, p := range accepted {
+		if p.unpaid.Sign() <= 0 {
+			continue
+		}
+
+		amount := new(big.Int).Mul(p.unpaid, permille)
+		amount.Div(amount, big.NewInt(1000))
+
+		balance.Sub(balance, amount)
+		p.unpaid.Sub(p.unpaid, amount)
+
+		payouts = append(payouts, TreasuryPayout{
+			Proposal: p.proposal,
+			RefUUID:  treasuryUUID(p.ref_uuid),
+			Amount:   (*hexutil.Big)(amount),
+			Unpaid:   (*hexutil.Big)(new(big.Int).Set(p.unpaid)),
+		})
+	}
+
+	return payouts
+}
+
+func (g *GovernanceAPI) treasuryPastSuperblock(
+	filterer *energi_abi.TreasuryV1Filterer,
+	block_reward *energi_abi.IBlockRewardCaller,
+	block uint64,
+	end uint64,
+) (*TreasurySuperblock, error) {
+	header, err := g.backend.HeaderByNumber(context.Background(), rpc.BlockNumber(block))
+	if err != nil || header == nil {
+		log.Error("Failed at header", "block", block, "err", err)
+		return nil, err
+	}
+
+	reward, err := block_reward.GetReward(
+		&bind.CallOpts{
+			BlockNumber: header.Number,
+			GasLimit:    energi_params.UnlimitedGas,
+		},
+		header.Number,
+	)
+	if err != nil {
+		log.Error("Failed GetReward", "err", err)
+		return nil, err
+	}
+
+	filter_opts := &bind.FilterOpts{
+		Context: context.Background(),
+		Start:   block,
+		End:     &end,
+	}
+
+	payouts, err := filterer.FilterPayout(filter_opts, nil)
+	if err != nil {
+		log.Error("Failed FilterPayout", "block", block, "err", err)
+		return nil, err
+	}
+	defer payouts.Close()
+
+	sb := &TreasurySuperblock{
+		Block:     block,
+		Timestamp: header.Time,
+		Reward:    (*hexutil.Big)(reward),
+		Payouts:   make([]TreasuryPayout, 0),
+	}
+
+	for payouts.Next() {
+		ev := payouts.Event
+
+		// NOTE: the filter may match on topic only
+		if ev.Raw.BlockNumber < block || ev.Raw.BlockNumber > end || ev.Amount.Sign() <= 0 {
+			continue
+		}
+
+		sb.Payouts = treasuryMerge(sb.Payouts, TreasuryPayout{
+			Proposal: ev.Proposal,
+			RefUUID:  treasuryUUID(ev.RefUuid),
+			Amount:   (*hexutil.Big)(ev.Amount),
+		})
+	}
+
+	if err = payouts.Error(); err != nil {
+		log.Error("Payouts fetch error", "err", err)
+		return nil, err
+	}
+
+	return sb, nil
+}
+
+func treasuryUUID(ref_uuid *big.Int) string {
+	return uuid.UUID(common.LeftPadBytes(ref_uuid.Bytes(), 16)).String()
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreasuryDistribute(t *testing.T) {
+	t.Parallel()
+
+	accepted := []*treasuryUnpaid{
+		{
+			proposal: common.HexToAddress("0x1"),
+			ref_uuid: big.NewInt(1),
+			unpaid:   big.NewInt(300),
+		},
+		{
+			proposal: common.HexToAddress("0x2"),
+			ref_uuid: big.NewInt(2),
+			unpaid:   big.NewInt(100),
+		},
+	}
+
+	// Partial payout
+	balance := big.NewInt(200)
+	payouts := treasuryDistribute(balance, accepted)
+	assert.Equal(t, 2, len(payouts))
+	assert.Equal(t, int64(150), payouts[0].Amount.ToInt().Int64())
+	assert.Equal(t, int64(50), payouts[1].Amount.ToInt().Int64())
+	assert.Equal(t, int64(150), payouts[0].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(50), payouts[1].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(0), balance.Int64())
+	assert.Equal(t, int64(150), accepted[0].unpaid.Int64())
+	assert.Equal(t, int64(50), accepted[1].unpaid.Int64())
+
+	// Full payout
+	balance = big.NewInt(1000)
+	payouts = treasuryDistribute(balance, accepted)
+	assert.Equal(t, 2, len(payouts))
+	assert.Equal(t, int64(150), payouts[0].Amount.ToInt().Int64())
+	assert.Equal(t, int64(50), payouts[1].Amount.ToInt().Int64())
+	assert.Equal(t, int64(0), payouts[0].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(0), payouts[1].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(800), balance.Int64())
+
+	// Nothing left
+	payouts = treasuryDistribute(balance, accepted)
+	assert.Equal(t, 0, len(payouts))
+	assert.Equal(t, int64(800), balance.Int64())
+}
+
+func TestTreasuryCycle(t *testing.T) {
+	t.Parallel()
+
+	newAccepted := func() []*treasuryUnpaid {
+		return []*treasuryUnpaid{
+			{
+				proposal: common.HexToAddress("0x1"),
+				ref_uuid: big.NewInt(1),
+				unpaid:   big.NewInt(3000),
+			},
+			{
+				proposal: common.HexToAddress("0x2"),
+				ref_uuid: big.NewInt(2),
+				unpaid:   big.NewInt(1000),
+			},
+		}
+	}
+
+	// The superblock block alone
+	balance := big.NewInt(2999)
+	payouts := treasuryCycle(balance, newAccepted(), 1)
+	assert.Equal(t, 2, len(payouts))
+	assert.Equal(t, int64(2247), payouts[0].Amount.ToInt().Int64())
+	assert.Equal(t, int64(749), payouts[1].Amount.ToInt().Int64())
+	assert.Equal(t, int64(753), payouts[0].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(251), payouts[1].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(3), balance.Int64())
+
+	// The following blocks catch up with the rest of the balance
+	balance = big.NewInt(2999)
+	accepted := newAccepted()
+	payouts = treasuryCycle(balance, accepted, 10)
+	assert.Equal(t, 2, len(payouts))
+	assert.Equal(t, int64(2248), payouts[0].Amount.ToInt().Int64())
+	assert.Equal(t, int64(749), payouts[1].Amount.ToInt().Int64())
+	assert.Equal(t, int64(752), payouts[0].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(251), payouts[1].Unpaid.ToInt().Int64())
+	assert.Equal(t, int64(2), balance.Int64())
+	assert.Equal(t, int64(752), accepted[0].unpaid.Int64())
+	assert.Equal(t, int64(251), accepted[1].unpaid.Int64())
+
+	// Nothing is paid without a balance
+	payouts = treasuryCycle(big.NewInt(0), accepted, 10)
+	assert.Equal(t, 0, len(payouts))
+}
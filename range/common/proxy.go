@@ -55,8 +55,8 @@ func GeneralProxyHashGen(blockchain *core.BlockChain) GeneralProxyHashFunc {
 // GeneralProxyHashExtractor retrieves if it exists the proxy hash func passed
 // through the context.
 func GeneralProxyHashExtractor(ctx context.Context, qAddr common.Address, blockNo *uint64) *common.Hash {
-	proxyHashFunc := ctx.Value(energi_params.GeneralProxyCtxKey).(GeneralProxyHashFunc)
-	if proxyHashFunc == nil {
+	proxyHashFunc, ok := ctx.Value(energi_params.GeneralProxyCtxKey).(GeneralProxyHashFunc)
+	if !ok || proxyHashFunc == nil {
 		return nil
 	}
 
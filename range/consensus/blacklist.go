@@ -21,6 +21,7 @@ import (
 	"range/core/gen3/core"
 	"range/core/gen3/core/state"
 	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
 	"range/core/gen3/log"
 
 	energi_params "range/core/gen3/energi/params"
@@ -141,14 +142,27 @@ func (e *Range) createWhitelist(
 ) map[common.Address]bool {
 	whitelist := map[common.Address]bool{}
 
-	for _, addr := range consensusProxies {
+	for addr := range ConsensusWhitelist(statedb) {
 		whitelist[addr] = true
+	}
+
+	return whitelist
+}
+
+// ConsensusWhitelist returns the addresses which are never blacklisted, mapped
+// to the consensus contract they belong to. Proxy implementations map to their
+// governed proxy, while proxies and standalone contracts map to themselves.
+func ConsensusWhitelist(statedb vm.StateDB) map[common.Address]common.Address {
+	whitelist := map[common.Address]common.Address{}
+
+	for _, addr := range consensusProxies {
+		whitelist[addr] = addr
 		impl := statedb.GetState(addr, energi_params.Storage_ProxyImpl)
-		whitelist[common.BytesToAddress(impl[:])] = true
+		whitelist[common.BytesToAddress(impl[:])] = addr
 	}
 
 	for _, addr := range consensusStandalone {
-		whitelist[addr] = true
+		whitelist[addr] = addr
 	}
 
 	return whitelist
@@ -20,15 +20,10 @@ import (
 	"testing"
 
 	"range/core/gen3/common"
-	eth_consensus "range/core/gen3/consensus"
 	"range/core/gen3/core/state"
 	"range/core/gen3/core/types"
 	"range/core/gen3/log"
 	"range/core/gen3/params"
-
-	"github.com/stretchr/testify/assert"
-
-	energi_params "range/core/gen3/energi/params"
 )
 
 type fakeDoSChain struct {
@@ -54,7 +49,7 @@ func (fc *fakeDoSChain) GetHeaderByHash(hash common.Hash) *types.Header {
 func (fc *fakeDoSChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	panic("Not impl")
 }
-func (fc *fakeDoSChain) CalculateBlockState(hash common.Hash, number uint64) *state.StateDB {
+func (fc *fakeDoSChain) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
 	panic("Not impl")
 }
 
@@ -83,6 +78,9 @@ func TestPoSDoS(t *testing.T) {
 	engine := New(nil, nil)
 	engine.now = func() uint64 { return curr_time }
 
+	// Only used by the disabled checks below
+	_, _ = h, fc
+
 	// POS-8 is disabled due to issues with chain splits
 	// POS-8: old fork protection
 	//============================
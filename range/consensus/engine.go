@@ -170,6 +170,9 @@ func (e *Range) createEVM(
 	if bc, ok := chain.(*core.BlockChain); ok {
 		vmc = bc.GetVMConfig()
 	}
+	if tracer, ok := chain.(*finalizationTracer); ok {
+		vmc = tracer.vmConfig(e, msg)
+	}
 
 	// Only From() is used by fact
 	ctx := core.NewEVMContext(msg, header, chain.(core.ChainContext), &header.Coinbase)
@@ -347,10 +350,9 @@ func (e *Range) VerifyUncles(chain ChainReader, block *types.Block) error {
 // the consensus rules of the given engine.
 func (e *Range) VerifySeal(chain ChainReader, header *types.Header) error {
 	parent_number := header.Number.Uint64() - 1
-	blockst := chain.CalculateBlockState(header.ParentHash, parent_number)
-	if blockst == nil {
-		log.Error("PoS state root failure", "header", header.ParentHash)
-		return eth_consensus.ErrMissingState
+	blockst, err := blockState(chain, header.ParentHash, parent_number)
+	if err != nil {
+		return err
 	}
 
 	// DBL-8: blacklist block generation
@@ -521,6 +523,17 @@ func (e *Range) Finalize(
 	return block, receipts, err
 }
 
+// FinalizeTransactions runs the post-transaction state modifications over the
+// regular transactions of a block like Finalize, but returns the block with
+// the consensus transactions created by the finalization appended instead of
+// checking them against the given ones.
+func (e *Range) FinalizeTransactions(
+	chain ChainReader, header *types.Header, state *state.StateDB,
+	txs []*types.Transaction, receipts []*types.Receipt,
+) (*types.Block, []*types.Receipt, error) {
+	return e.finalize(chain, header, state, txs, receipts)
+}
+
 func (e *Range) finalize(
 	chain ChainReader, header *types.Header, state *state.StateDB,
 	txs []*types.Transaction, receipts []*types.Receipt,
@@ -544,21 +557,42 @@ func (e *Range) govFinalize(
 	txs types.Transactions,
 	receipts types.Receipts,
 ) (types.Transactions, types.Receipts, error) {
-	err := e.processConsensusGasLimits(chain, header, state)
-	if err == nil {
-		txs, receipts, err = e.processBlockRewards(chain, header, state, txs, receipts)
-	}
-	if err == nil {
-		err = e.processMasternodes(chain, header, state)
-	}
-	if err == nil {
-		err = e.processBlacklists(chain, header, state)
-	}
-	if err == nil {
-		txs, receipts, err = e.processDrainable(chain, header, state, txs, receipts)
+	steps := []finalizationStep{
+		{"consensusGasLimits", func() error {
+			return e.processConsensusGasLimits(chain, header, state)
+		}},
+		{"blockRewards", func() (err error) {
+			txs, receipts, err = e.processBlockRewards(chain, header, state, txs, receipts)
+			return err
+		}},
+		{"masternodes", func() error {
+			return e.processMasternodes(chain, header, state)
+		}},
+		{"blacklists", func() error {
+			return e.processBlacklists(chain, header, state)
+		}},
+		{"drainable", func() (err error) {
+			txs, receipts, err = e.processDrainable(chain, header, state, txs, receipts)
+			return err
+		}},
+		{"migration", func() error {
+			return e.finalizeMigration(chain, header, state, txs)
+		}},
 	}
-	if err == nil {
-		err = e.finalizeMigration(chain, header, state, txs)
+	tracer, _ := chain.(*finalizationTracer)
+
+	var err error
+	for _, step := range steps {
+		if tracer != nil {
+			tracer.startStep(step.name, state)
+		}
+		err = step.run()
+		if tracer != nil {
+			tracer.endStep(state, err)
+		}
+		if err != nil {
+			break
+		}
 	}
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	return txs, receipts, err
@@ -645,9 +679,9 @@ func (e *Range) recreateBlock(
 		ok bool
 	)
 
-	blstate := chain.CalculateBlockState(header.ParentHash, header.Number.Uint64()-1)
+	blstate, err := blockState(chain, header.ParentHash, header.Number.Uint64()-1)
 	if err != nil {
-		return nil, eth_consensus.ErrUnknownAncestor
+		return nil, err
 	}
 
 	vmc := &vm.Config{}
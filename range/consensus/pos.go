@@ -25,6 +25,8 @@ import (
 
 	"range/core/gen3/common"
 	eth_consensus "range/core/gen3/consensus"
+	"range/core/gen3/core"
+	"range/core/gen3/core/state"
 	"range/core/gen3/core/types"
 	"range/core/gen3/crypto"
 	"range/core/gen3/log"
@@ -329,6 +331,21 @@ func (e *Range) calcPoSHash(
 	return poshash, used_weight
 }
 
+// blockState retrieves the state of a block for the PoS rules. A state which
+// can't be regenerated within bounds is reported as missing, so that the block
+// is only postponed rather than marked bad.
+func blockState(chain ChainReader, hash common.Hash, number uint64) (*state.StateDB, error) {
+	statedb, err := chain.CalculateBlockState(hash, number)
+	if err != nil {
+		if _, ok := err.(*core.StateUnavailableError); ok {
+			log.Warn("PoS state root failure", "header", hash, "err", err)
+			return nil, eth_consensus.ErrMissingState
+		}
+		return nil, err
+	}
+	return statedb, nil
+}
+
 func (e *Range) verifyPoSHash(
 	chain ChainReader,
 	header *types.Header,
@@ -387,13 +404,12 @@ func (e *Range) lookupStakeWeight(
 	weight = 0
 	total_staked := uint64(0)
 	first_run := true
-	blockst := chain.CalculateBlockState(till.Hash(), till.Number.Uint64())
+	blockst, err := blockState(chain, till.Hash(), till.Number.Uint64())
 
 	// NOTE: we need to ensure at least one iteration with the balance condition
 	for (till.Time > since) || first_run {
-		if blockst == nil {
-			log.Warn("PoS state root failure", "header", till.Hash())
-			return 0, eth_consensus.ErrMissingState
+		if err != nil {
+			return 0, err
 		}
 
 		weight_at_block := new(big.Int).Div(
@@ -434,7 +450,7 @@ func (e *Range) lookupStakeWeight(
 			return 0, eth_consensus.ErrUnknownAncestor
 		}
 
-		blockst = chain.CalculateBlockState(curr.ParentHash, parent_number)
+		blockst, err = blockState(chain, curr.ParentHash, parent_number)
 	}
 
 	if weight < total_staked {
@@ -26,6 +26,7 @@ import (
 	eth_consensus "range/core/gen3/consensus"
 	"range/core/gen3/core"
 	"range/core/gen3/core/state"
+	state_snapshot "range/core/gen3/core/state/snapshot"
 	"range/core/gen3/core/types"
 	"range/core/gen3/core/vm"
 	"range/core/gen3/crypto"
@@ -61,8 +62,11 @@ func (cr *mockChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
 func (cr *mockChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
 	panic("Not impl")
 }
-func (cr *mockChainReader) CalculateBlockState(hash common.Hash, number uint64) *state.StateDB {
-	return cr.stateDB
+func (cr *mockChainReader) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	if cr.stateDB == nil {
+		return nil, &core.StateUnavailableError{Hash: hash, Number: number, Err: core.ErrStateRegenLimit}
+	}
+	return cr.stateDB, nil
 }
 
 func generateAddresses(len int) ([]common.Address, map[common.Address]*ecdsa.PrivateKey, core.GenesisAlloc, common.Address) {
@@ -119,6 +123,7 @@ func TestPoSChain(t *testing.T) {
 			return crypto.Sign(hash, signers[addr])
 		},
 		func() int { return 1 },
+		func() bool { return true },
 	)
 
 	chainConfig := *params.RangeTestnetChainConfig
@@ -169,7 +174,8 @@ func TestPoSChain(t *testing.T) {
 			Number:     number,
 			Time:       parent.Time,
 		}
-		blstate := chain.CalculateBlockState(header.ParentHash, parent.Number.Uint64())
+		blstate, err := chain.CalculateBlockState(header.ParentHash, parent.Number.Uint64())
+		assert.Empty(t, err)
 		assert.NotEmpty(t, blstate)
 
 		err = engine.Prepare(chain, header)
@@ -448,7 +454,7 @@ func TestStakeWeightLookup(t *testing.T) {
 		parent.Nonce = types.BlockNonce{255, 255, 255, 255, 255, 255, 255, 255}
 		weight, err = engine.lookupStakeWeight(fakeChain, header.Time, parent, header.Coinbase)
 		assert.Empty(t, err)
-		assert.Equal(t, weight, 0)
+		assert.Equal(t, weight, uint64(0))
 
 		parent.Coinbase = parentCoinbase
 		parent.Nonce = parentNonce
@@ -462,6 +468,99 @@ func TestStakeWeightLookup(t *testing.T) {
 	}
 }
 
+// stateChainReader opens a fresh state of every header, like the block chain
+// does for the historical states.
+type stateChainReader struct {
+	mockChainReader
+	stateCache state.Database
+	roots      map[common.Hash]common.Hash
+}
+
+func (cr *stateChainReader) CalculateBlockState(hash common.Hash, number uint64) (*state.StateDB, error) {
+	return state.New(cr.roots[hash], cr.stateCache)
+}
+
+func BenchmarkStakeWeightLookup_trie(b *testing.B) {
+	benchStakeWeightLookup(b, false)
+}
+func BenchmarkStakeWeightLookup_snapshot(b *testing.B) {
+	benchStakeWeightLookup(b, true)
+}
+
+// benchStakeWeightLookup measures the stake weight lookup over the maturity
+// period of blocks, on top of a state with many accounts.
+func benchStakeWeightLookup(b *testing.B, snap bool) {
+	const accounts = 10000
+
+	db := ethdb.NewMemDatabase()
+	stateCache := state.NewDatabase(db)
+	staker := common.BytesToAddress([]byte("staker"))
+
+	statedb, _ := state.New(common.Hash{}, stateCache)
+	statedb.SetBalance(staker, new(big.Int).Mul(minStake, big.NewInt(100)))
+	for i := 0; i < accounts; i++ {
+		statedb.SetBalance(common.BigToAddress(big.NewInt(int64(i+1))), minStake)
+	}
+	root, _ := statedb.Commit(true)
+	stateCache.TrieDB().Commit(root, false)
+
+	if snap {
+		snaps, err := state_snapshot.New(db, stateCache.TrieDB(), root)
+		if err != nil {
+			b.Fatalf("failed to create snapshot: %v", err)
+		}
+		snaps.WaitGeneration()
+		stateCache = state.WithSnapshots(stateCache, snaps)
+	}
+
+	chain := &stateChainReader{
+		mockChainReader: mockChainReader{
+			headers: make(map[common.Hash]*types.Header),
+		},
+		stateCache: stateCache,
+		roots:      make(map[common.Hash]common.Hash),
+	}
+
+	// Build the blocks of a maturity period, each changing a few balances
+	parent := &types.Header{
+		Number:   big.NewInt(0),
+		Time:     1000,
+		GasLimit: 8000000,
+		Root:     root,
+	}
+	chain.headers[parent.Hash()] = parent
+	chain.roots[parent.Hash()] = root
+
+	for i := uint64(1); i <= MaturityPeriod/energi_params.TargetBlockGap+1; i++ {
+		statedb, _ := state.New(parent.Root, stateCache)
+		statedb.AddBalance(staker, common.Big1)
+		statedb.AddBalance(common.BigToAddress(new(big.Int).SetUint64(i)), common.Big1)
+		root, _ := statedb.Commit(true)
+
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).SetUint64(i),
+			Time:       parent.Time + energi_params.TargetBlockGap,
+			GasLimit:   parent.GasLimit,
+			Root:       root,
+		}
+		chain.headers[header.Hash()] = header
+		chain.roots[header.Hash()] = root
+		parent = header
+	}
+	chain.current = parent
+	engine := New(nil, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		weight, err := engine.lookupStakeWeight(chain, parent.Time, parent, staker)
+		if err != nil || weight != 100 {
+			b.Fatalf("lookup failed: weight=%v err=%v", weight, err)
+		}
+	}
+}
+
 func TestPoSMine(t *testing.T) {
 	t.Parallel()
 	log.Root().SetHandler(log.StdoutHandler)
@@ -489,6 +588,7 @@ func TestPoSMine(t *testing.T) {
 			return crypto.Sign(hash, signers[addr])
 		},
 		func() int { return 1 },
+		func() bool { return true },
 	)
 
 	chainConfig := *params.RangeTestnetChainConfig
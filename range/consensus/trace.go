@@ -0,0 +1,299 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"range/core/gen3/accounts/abi"
+	"range/core/gen3/common"
+	"range/core/gen3/common/hexutil"
+	eth_consensus "range/core/gen3/consensus"
+	"range/core/gen3/core/state"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+)
+
+// finalizationStep is a named step of govFinalize.
+type finalizationStep struct {
+	name string
+	run  func() error
+}
+
+// FinalizationTrace is the record of the finalization of a block.
+type FinalizationTrace struct {
+	Number       uint64                   `json:"number"`
+	Hash         common.Hash              `json:"hash"`
+	BlockRoot    common.Hash              `json:"blockRoot"`    // State root of the block header
+	Root         common.Hash              `json:"root"`         // State root after the traced finalization
+	ConsensusTxs []common.Hash            `json:"consensusTxs"` // Consensus transactions created by the finalization
+	Steps        []*FinalizationStepTrace `json:"steps"`
+	Error        string                   `json:"error,omitempty"`
+}
+
+// FinalizationStepTrace is the record of a single finalization step: the EVM
+// calls it made and the resulting changes of the state.
+type FinalizationStepTrace struct {
+	Name     string                                            `json:"name"`
+	Calls    []*FinalizationCall                               `json:"calls"`
+	GasUsed  hexutil.Uint64                                    `json:"gasUsed"`
+	Balances map[common.Address]*BalanceChange                 `json:"balances"`
+	Storage  map[common.Address]map[common.Hash]*StorageChange `json:"storage"`
+	Error    string                                            `json:"error,omitempty"`
+}
+
+// FinalizationCall is an EVM call of a finalization step. The state changes of
+// the read-only calls are reverted afterwards, as seen in the step changes.
+type FinalizationCall struct {
+	Method  string          `json:"method,omitempty"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output"`
+	Error   string          `json:"error,omitempty"`
+	Trace   json.RawMessage `json:"trace,omitempty"`
+
+	tracer vm.Tracer
+}
+
+// BalanceChange is the balance of an account before and after a step.
+type BalanceChange struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// StorageChange is a storage slot value before and after a step.
+type StorageChange struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// finalizationTracer is passed to govFinalize in place of the chain to record
+// the steps, the EVM calls and the state writes.
+type finalizationTracer struct {
+	ChainReader
+
+	engine    eth_consensus.Engine
+	newTracer func() vm.Tracer
+
+	steps    []*FinalizationStepTrace
+	step     *FinalizationStepTrace
+	balances map[common.Address]*big.Int                    // Balances before the first write of the step
+	storage  map[common.Address]map[common.Hash]common.Hash // Slot values before the first write of the step
+}
+
+// Engine implements core.ChainContext for the EVM of the calls.
+func (t *finalizationTracer) Engine() eth_consensus.Engine {
+	return t.engine
+}
+
+// WatchBalance implements state.WriteWatcher.
+func (t *finalizationTracer) WatchBalance(addr common.Address, prev *big.Int) {
+	if _, ok := t.balances[addr]; !ok {
+		t.balances[addr] = new(big.Int).Set(prev)
+	}
+}
+
+// WatchNonce implements state.WriteWatcher, the nonces are not traced.
+func (t *finalizationTracer) WatchNonce(addr common.Address, prev uint64) {}
+
+// WatchCode implements state.WriteWatcher, the codes are not traced.
+func (t *finalizationTracer) WatchCode(addr common.Address, prev []byte) {}
+
+// WatchStorage implements state.WriteWatcher.
+func (t *finalizationTracer) WatchStorage(addr common.Address, key, prev common.Hash) {
+	slots := t.storage[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]common.Hash)
+		t.storage[addr] = slots
+	}
+	if _, ok := slots[key]; !ok {
+		slots[key] = prev
+	}
+}
+
+func (t *finalizationTracer) startStep(name string, statedb *state.StateDB) {
+	t.step = &FinalizationStepTrace{
+		Name:     name,
+		Calls:    []*FinalizationCall{},
+		Balances: make(map[common.Address]*BalanceChange),
+		Storage:  make(map[common.Address]map[common.Hash]*StorageChange),
+	}
+	t.balances = make(map[common.Address]*big.Int)
+	t.storage = make(map[common.Address]map[common.Hash]common.Hash)
+	statedb.Watch(t)
+}
+
+// endStep compares the written balances and slots against their values before
+// the step, the writes reverted or set back are not reported.
+func (t *finalizationTracer) endStep(statedb *state.StateDB, err error) {
+	statedb.Watch(nil)
+
+	step := t.step
+	for addr, prev := range t.balances {
+		if balance := statedb.GetBalance(addr); balance.Cmp(prev) != 0 {
+			step.Balances[addr] = &BalanceChange{
+				From: (*hexutil.Big)(prev),
+				To:   (*hexutil.Big)(new(big.Int).Set(balance)),
+			}
+		}
+	}
+	for addr, slots := range t.storage {
+		for key, prev := range slots {
+			if value := statedb.GetState(addr, key); value != prev {
+				if step.Storage[addr] == nil {
+					step.Storage[addr] = make(map[common.Hash]*StorageChange)
+				}
+				step.Storage[addr][key] = &StorageChange{From: prev, To: value}
+			}
+		}
+	}
+	for _, call := range step.Calls {
+		step.GasUsed += call.GasUsed
+		if tracer, ok := call.tracer.(interface {
+			GetResult() (json.RawMessage, error)
+		}); ok {
+			if res, err := tracer.GetResult(); err != nil {
+				call.Trace, _ = json.Marshal(map[string]string{"error": err.Error()})
+			} else {
+				call.Trace = res
+			}
+		}
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.steps = append(t.steps, step)
+	t.step = nil
+}
+
+// vmConfig records the call of the message and returns the configuration of
+// its EVM, tracing it.
+func (t *finalizationTracer) vmConfig(e *Range, msg types.Message) *vm.Config {
+	call := &FinalizationCall{
+		From:  msg.From(),
+		To:    msg.To(),
+		Value: (*hexutil.Big)(msg.Value()),
+		Gas:   hexutil.Uint64(msg.Gas()),
+		Input: msg.Data(),
+	}
+	if len(msg.Data()) >= 4 {
+		for _, contract := range []*abi.ABI{
+			&e.rewardAbi, &e.sporkAbi, &e.mnregAbi, &e.blacklistAbi, &e.treasuryAbi, &e.dposAbi,
+		} {
+			if method, err := contract.MethodById(msg.Data()[:4]); err == nil {
+				call.Method = method.Name
+				break
+			}
+		}
+	}
+	if t.newTracer != nil {
+		call.tracer = t.newTracer()
+	}
+	if t.step != nil {
+		t.step.Calls = append(t.step.Calls, call)
+	}
+	return &vm.Config{Debug: true, Tracer: &callRecorder{call: call}}
+}
+
+// callRecorder fills in the outcome of a finalization call, passing the
+// execution on to the tracer of the call.
+type callRecorder struct {
+	call *FinalizationCall
+}
+
+func (r *callRecorder) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if r.call.tracer != nil {
+		return r.call.tracer.CaptureStart(from, to, create, input, gas, value)
+	}
+	return nil
+}
+
+func (r *callRecorder) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if r.call.tracer != nil {
+		return r.call.tracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+	}
+	return nil
+}
+
+func (r *callRecorder) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if r.call.tracer != nil {
+		return r.call.tracer.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+	}
+	return nil
+}
+
+func (r *callRecorder) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	r.call.Output = common.CopyBytes(output)
+	r.call.GasUsed = hexutil.Uint64(gasUsed)
+	if err != nil {
+		r.call.Error = err.Error()
+	}
+	if r.call.tracer != nil {
+		return r.call.tracer.CaptureEnd(output, gasUsed, d, err)
+	}
+	return nil
+}
+
+// TraceFinalization runs the finalization of the block over the state after
+// its regular transactions, recording every step: the EVM calls made, traced
+// by the tracers of newTracer if not nil, and the resulting balance and
+// storage changes. A failed finalization is reported in the trace.
+func (e *Range) TraceFinalization(
+	chain ChainReader,
+	header *types.Header,
+	statedb *state.StateDB,
+	txs types.Transactions,
+	receipts types.Receipts,
+	newTracer func() vm.Tracer,
+) *FinalizationTrace {
+	header = types.CopyHeader(header)
+	trace := &FinalizationTrace{
+		Number:       header.Number.Uint64(),
+		Hash:         header.Hash(),
+		BlockRoot:    header.Root,
+		ConsensusTxs: []common.Hash{},
+		Steps:        []*FinalizationStepTrace{},
+	}
+	// Blocks without a coinbase are not finalized, see finalize
+	if (header.Coinbase == common.Address{}) {
+		trace.Root = statedb.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+		return trace
+	}
+	tracer := &finalizationTracer{
+		ChainReader: chain,
+		engine:      e,
+		newTracer:   newTracer,
+	}
+	all, _, err := e.govFinalize(tracer, header, statedb, txs, receipts)
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	if len(all) > len(txs) {
+		for _, tx := range all[len(txs):] {
+			trace.ConsensusTxs = append(trace.ConsensusTxs, tx.Hash())
+		}
+	}
+	trace.Root = header.Root
+	trace.Steps = tracer.steps
+	return trace
+}
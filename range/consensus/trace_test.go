@@ -0,0 +1,117 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core"
+	"range/core/gen3/core/types"
+	"range/core/gen3/core/vm"
+	"range/core/gen3/crypto"
+	"range/core/gen3/ethdb"
+	"range/core/gen3/params"
+	"github.com/stretchr/testify/assert"
+
+	energi_params "range/core/gen3/energi/params"
+)
+
+// Tests that the traced finalization makes the same writes as the block
+// finalization and records them in the steps making them.
+func TestTraceFinalization(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _   = crypto.GenerateKey()
+		signer   = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.HexToAddress("0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba")
+		testdb   = ethdb.NewMemDatabase()
+
+		chainConfig = *params.RangeTestnetChainConfig
+	)
+	chainConfig.Range = &params.RangeConfig{MigrationSigner: signer}
+
+	gspec := &core.Genesis{
+		Config:     &chainConfig,
+		GasLimit:   8000000,
+		Timestamp:  1000,
+		Difficulty: big.NewInt(1),
+		Coinbase:   energi_params.Range_Treasury,
+		Alloc:      core.GenesisAlloc{signer: {Balance: minStake}},
+		Xfers:      core.DeployRangeGovernance(&chainConfig),
+	}
+	genesis := gspec.MustCommit(testdb)
+
+	engine := New(chainConfig.Range, testdb)
+	chain, err := core.NewBlockChain(testdb, nil, &chainConfig, engine, vm.Config{}, nil)
+	assert.Empty(t, err)
+	defer chain.Stop()
+
+	// Block 1 is the migration block, finalized differently
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Coinbase:   coinbase,
+		Difficulty: big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Number:     big.NewInt(2),
+		Time:       genesis.Time() + 30,
+	}
+	finalized, err := chain.StateAt(genesis.Root())
+	assert.Empty(t, err)
+	traced := finalized.Copy()
+
+	block, _, err := engine.FinalizeTransactions(chain, types.CopyHeader(header), finalized, nil, nil)
+	assert.Empty(t, err)
+
+	var calls int
+	trace := engine.TraceFinalization(chain, header, traced, nil, nil, func() vm.Tracer {
+		calls++
+		return nil
+	})
+	assert.Empty(t, trace.Error)
+	assert.Equal(t, block.Root(), trace.Root)
+	assert.Equal(t, len(block.Transactions()), len(trace.ConsensusTxs))
+	for i, tx := range block.Transactions() {
+		assert.Equal(t, tx.Hash(), trace.ConsensusTxs[i])
+	}
+	// Every call of the steps gets its tracer
+	var (
+		recorded int
+		rewards  *FinalizationStepTrace
+	)
+	for _, step := range trace.Steps {
+		recorded += len(step.Calls)
+		if step.Name == "blockRewards" {
+			rewards = step
+		}
+	}
+	assert.NotEqual(t, 0, calls)
+	assert.Equal(t, calls, recorded)
+
+	// The reward payouts are the balance writes of their step
+	if !assert.NotNil(t, rewards) {
+		return
+	}
+	assert.NotEmpty(t, rewards.Calls)
+	assert.NotEmpty(t, rewards.Balances)
+	for addr, change := range rewards.Balances {
+		assert.Equal(t, finalized.GetBalance(addr), change.To.ToInt(), "balance of %x", addr)
+	}
+	assert.Equal(t, finalized.GetBalance(coinbase), traced.GetBalance(coinbase))
+}
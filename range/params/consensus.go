@@ -26,6 +26,10 @@ const (
 	MaxFutureGap      uint64 = 3
 	TargetPeriodGap   uint64 = AverageTimeBlocks * TargetBlockGap
 
+	// StakeWindow is the number of the most recent blocks, at the minimum
+	// block gap, whose states are looked up for the stake weights.
+	StakeWindow uint64 = MaturityPeriod/MinBlockGap + 1
+
 	// DoS protection
 	OldForkPeriod uint64 = 15 * 60
 	StakeThrottle uint64 = 60
@@ -31,7 +31,6 @@ import (
 	"range/core/gen3/rpc"
 
 	energi_abi "range/core/gen3/energi/abi"
-	energi_common "range/core/gen3/energi/common"
 	energi_params "range/core/gen3/energi/params"
 )
 
@@ -129,11 +128,7 @@ func (c *CheckpointService) loop() {
 	cpChan := make(chan *energi_abi.ICheckpointRegistryCheckpoint, cppChanBufferSize)
 
 	watchOpts := &bind.WatchOpts{
-		Context: context.WithValue(
-			context.Background(),
-			energi_params.GeneralProxyCtxKey,
-			energi_common.GeneralProxyHashGen(c.eth.BlockChain()),
-		),
+		Context: context.Background(),
 	}
 
 	subscribe, err := c.cpRegistry.WatchCheckpoint(watchOpts, cpChan, []*big.Int{})
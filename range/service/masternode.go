@@ -19,6 +19,7 @@ package service
 import (
 	"errors"
 	"math/big"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -76,6 +77,22 @@ type MasternodeService struct {
 	features *big.Int
 
 	validator *peerValidator
+
+	statusMtx sync.RWMutex
+	status    MasternodeStatus
+}
+
+// MasternodeStatus is a snapshot of the duties performed by the running
+// masternode, as last seen on chain head processing.
+type MasternodeStatus struct {
+	Masternode       common.Address
+	Owner            common.Address
+	InSync           bool
+	Active           bool
+	LastHeartbeat    uint64
+	LastHeartbeatTx  common.Hash
+	NextHeartbeat    uint64
+	ValidationTarget common.Address
 }
 
 func NewMasternodeService(ethServ *eth.Ethereum, owner common.Address) (node.Service, error) {
@@ -157,6 +174,13 @@ func (m *MasternodeService) Start(server *p2p.Server) error {
 
 	m.server = server
 	m.validator = newPeerValidator(common.Address{}, m)
+
+	m.statusMtx.Lock()
+	m.status.Masternode = address
+	m.status.Owner = m.owner
+	m.status.NextHeartbeat = uint64(m.nextHB.Unix())
+	m.statusMtx.Unlock()
+
 	go m.loop()
 
 	log.Info("Started Range Masternode", "addr", address)
@@ -169,6 +193,16 @@ func (m *MasternodeService) Stop() error {
 	return nil
 }
 
+// Status returns the current heartbeat and validation state of the masternode.
+func (m *MasternodeService) Status() MasternodeStatus {
+	m.statusMtx.RLock()
+	defer m.statusMtx.RUnlock()
+
+	status := m.status
+	status.InSync = atomic.LoadInt32(&m.inSync) != 0
+	return status
+}
+
 func (m *MasternodeService) listenDownloader() {
 	events := m.eth.EventMux().Subscribe(
 		downloader.StartEvent{},
@@ -344,9 +378,16 @@ func (m *MasternodeService) voteOnCheckpoints() {
 }
 
 func (m *MasternodeService) onChainHead(block *types.Block) {
-	if !m.isActive() {
+	is_active := m.isActive()
+
+	m.statusMtx.Lock()
+	m.status.Active = is_active
+	m.statusMtx.Unlock()
+
+	if !is_active {
 		do_cleanup := m.validator.target != common.Address{}
 		m.validator.cancel()
+		m.setValidationTarget(common.Address{})
 
 		if do_cleanup {
 			m.eth.TxPool().RemoveBySender(m.address)
@@ -372,13 +413,18 @@ func (m *MasternodeService) onChainHead(block *types.Block) {
 			current := m.eth.BlockChain().CurrentHeader()
 			tx, err := m.registry.Heartbeat(current.Number, current.Hash(), m.features)
 
+			m.statusMtx.Lock()
 			if err == nil {
 				log.Info("Masternode Heartbeat", "tx", tx.Hash())
 				m.nextHB = now.Add(heartbeatInterval)
+				m.status.LastHeartbeat = uint64(now.Unix())
+				m.status.LastHeartbeatTx = tx.Hash()
 			} else {
 				log.Error("Failed to send Masternode Heartbeat", "err", err)
 				m.nextHB = now.Add(recheckInterval)
 			}
+			m.status.NextHeartbeat = uint64(m.nextHB.Unix())
+			m.statusMtx.Unlock()
 		} else {
 			// NOTE: we need to recover from Nonce mismatch to enable heartbeats
 			//       as soon as possible.
@@ -396,6 +442,7 @@ func (m *MasternodeService) onChainHead(block *types.Block) {
 	if err != nil {
 		log.Warn("MNTarget error", "mn", m.address, "err", err)
 		m.validator.cancel()
+		m.setValidationTarget(common.Address{})
 		return
 	}
 
@@ -403,6 +450,7 @@ func (m *MasternodeService) onChainHead(block *types.Block) {
 	if old_target := m.validator.target; old_target != target {
 		m.validator.cancel()
 		m.validator = newPeerValidator(target, m)
+		m.setValidationTarget(target)
 
 		// Only present in IMasternodeRegistryV2
 		if ok, err := m.registry.CanInvalidate(m.address); err == nil && !ok {
@@ -416,6 +464,12 @@ func (m *MasternodeService) onChainHead(block *types.Block) {
 	}
 }
 
+func (m *MasternodeService) setValidationTarget(target common.Address) {
+	m.statusMtx.Lock()
+	m.status.ValidationTarget = target
+	m.statusMtx.Unlock()
+}
+
 type peerValidator struct {
 	target   common.Address
 	mnsvc    *MasternodeService
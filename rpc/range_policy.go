@@ -0,0 +1,403 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"range/core/gen3/metrics"
+)
+
+// policySweepInterval is how often the idle clients are dropped.
+const policySweepInterval = time.Minute
+
+var (
+	rateRejectMeter        = metrics.NewRegisteredMeter("rpc/policy/rejected/rate", nil)
+	methodRejectMeter      = metrics.NewRegisteredMeter("rpc/policy/rejected/method", nil)
+	concurrencyRejectMeter = metrics.NewRegisteredMeter("rpc/policy/rejected/concurrency", nil)
+	batchRejectMeter       = metrics.NewRegisteredMeter("rpc/policy/rejected/batch", nil)
+	rangeRejectMeter       = metrics.NewRegisteredMeter("rpc/policy/rejected/range", nil)
+)
+
+// request exceeds a limit of the server policy
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// Policy limits the load the remote clients of a public service node can put
+// on it. The method patterns are either full method names or namespace
+// prefixes ending with '*', e.g. "debug_trace*"; the longest match applies.
+// The methods of the aliased namespaces match under their original namespace.
+type Policy struct {
+	RateLimit         float64            // Request cost allowed per second and client IP
+	RateBurst         int                // Request cost a client IP may spend at once
+	MethodCosts       map[string]int     `toml:",omitempty"` // Weighted cost of the expensive methods, 1 otherwise
+	MethodLimits      map[string]float64 `toml:",omitempty"` // Requests allowed per second and method over all the clients
+	MaxConcurrent     int                // Requests of a client IP served at the same time
+	MethodConcurrency map[string]int     `toml:",omitempty"` // Requests of a method served at the same time over all the clients
+	MaxBatchSize      int                // Requests allowed in a single batch
+	MaxBlockRange     uint64             // Blocks allowed in a single log query
+}
+
+// DefaultPublicPolicy returns the policy applied by the public service nodes.
+func DefaultPublicPolicy() *Policy {
+	return &Policy{
+		RateLimit: 20,
+		RateBurst: 100,
+		MethodCosts: map[string]int{
+			"debug_trace*":               50,
+			"trace_*":                    50,
+			"masternode_listMasternodes": 20,
+			"eth_getLogs":                10,
+			"eth_getFilterLogs":          10,
+			"eth_call":                   2,
+			"eth_estimateGas":            2,
+			"graphql_query":              10,
+		},
+		MethodLimits: map[string]float64{
+			"debug_trace*":               2,
+			"trace_*":                    2,
+			"masternode_listMasternodes": 5,
+		},
+		MaxConcurrent: 16,
+		MethodConcurrency: map[string]int{
+			"debug_trace*":  2,
+			"trace_*":       2,
+			"eth_getLogs":   8,
+			"graphql_query": 4,
+		},
+		MaxBatchSize:  100,
+		MaxBlockRange: 10000,
+	}
+}
+
+// lookup returns the value of the longest pattern matching the method.
+func lookup(patterns map[string]int, method string) (string, int) {
+	if value, ok := patterns[method]; ok {
+		return method, value
+	}
+	var (
+		match string
+		value int
+	)
+	for pattern, v := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern &&
+			strings.HasPrefix(method, prefix) && len(pattern) > len(match) {
+			match, value = pattern, v
+		}
+	}
+	return match, value
+}
+
+// lookupRate is lookup over the rate limits.
+func lookupRate(patterns map[string]float64, method string) (string, float64) {
+	if value, ok := patterns[method]; ok {
+		return method, value
+	}
+	var (
+		match string
+		value float64
+	)
+	for pattern, v := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern &&
+			strings.HasPrefix(method, prefix) && len(pattern) > len(match) {
+			match, value = pattern, v
+		}
+	}
+	return match, value
+}
+
+// tokenBucket refills at a constant rate up to its burst size.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accrued since the last refill.
+func (b *tokenBucket) refill(rate, burst float64, now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else if b.tokens += rate * now.Sub(b.last).Seconds(); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// policyClient is the state of a client IP.
+type policyClient struct {
+	bucket tokenBucket
+	busy   int
+}
+
+// policyEnforcer tracks the clients and methods against the policy.
+type policyEnforcer struct {
+	policy *Policy
+
+	clients map[string]*policyClient
+	methods map[string]*tokenBucket // Rate limited method patterns
+	busy    map[string]int          // Running requests of the concurrency capped patterns
+	swept   time.Time
+	lock    sync.Mutex
+}
+
+func newPolicyEnforcer(policy *Policy) *policyEnforcer {
+	return &policyEnforcer{
+		policy:  policy,
+		clients: make(map[string]*policyClient),
+		methods: make(map[string]*tokenBucket),
+		busy:    make(map[string]int),
+		swept:   time.Now(),
+	}
+}
+
+// admit checks the request of the client against the policy, the returned
+// release function must be called once the request is served.
+func (p *policyEnforcer) admit(ip, method string) (func(), Error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	p.sweep(now)
+
+	client := p.clients[ip]
+	if client == nil {
+		client = new(policyClient)
+		p.clients[ip] = client
+	}
+	// Concurrency caps go first, they don't consume any tokens
+	if max := p.policy.MaxConcurrent; max > 0 && client.busy >= max {
+		concurrencyRejectMeter.Mark(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many concurrent requests, limit %d", max)}
+	}
+	capped, max := lookup(p.policy.MethodConcurrency, method)
+	if capped != "" && max > 0 && p.busy[capped] >= max {
+		concurrencyRejectMeter.Mark(1)
+		return nil, &limitExceededError{fmt.Sprintf("too many concurrent %s requests, limit %d", method, max)}
+	}
+	// Both the client and the method bucket must afford the request
+	cost := 1.0
+	if _, weight := lookup(p.policy.MethodCosts, method); weight > 0 {
+		cost = float64(weight)
+	}
+	if rate := p.policy.RateLimit; rate > 0 {
+		burst := float64(p.policy.RateBurst)
+		if burst < cost {
+			burst = cost
+		}
+		client.bucket.refill(rate, burst, now)
+		if client.bucket.tokens < cost {
+			rateRejectMeter.Mark(1)
+			return nil, &limitExceededError{fmt.Sprintf("rate limit exceeded, %.0f requests per second", rate)}
+		}
+	}
+	var limited *tokenBucket
+	if pattern, rate := lookupRate(p.policy.MethodLimits, method); pattern != "" && rate > 0 {
+		if limited = p.methods[pattern]; limited == nil {
+			limited = new(tokenBucket)
+			p.methods[pattern] = limited
+		}
+		burst := rate
+		if burst < 1 {
+			burst = 1
+		}
+		limited.refill(rate, burst, now)
+		if limited.tokens < 1 {
+			methodRejectMeter.Mark(1)
+			return nil, &limitExceededError{fmt.Sprintf("%s rate limit exceeded, %g requests per second", method, rate)}
+		}
+	}
+	if p.policy.RateLimit > 0 {
+		client.bucket.tokens -= cost
+	}
+	if limited != nil {
+		limited.tokens--
+	}
+	// Admitted, track the running request
+	client.busy++
+	if capped != "" {
+		p.busy[capped]++
+	}
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		client.busy--
+		if capped != "" {
+			p.busy[capped]--
+		}
+	}, nil
+}
+
+// sweep drops the idle clients whose bucket would be full again.
+func (p *policyEnforcer) sweep(now time.Time) {
+	if now.Sub(p.swept) < policySweepInterval {
+		return
+	}
+	p.swept = now
+
+	refill := time.Duration(0)
+	if p.policy.RateLimit > 0 {
+		refill = time.Duration(float64(p.policy.RateBurst) / p.policy.RateLimit * float64(time.Second))
+	}
+	for ip, client := range p.clients {
+		if client.busy == 0 && now.Sub(client.bucket.last) > refill {
+			delete(p.clients, ip)
+		}
+	}
+}
+
+// policyKey is the context key of the policy applied to the request.
+type policyKey struct{}
+
+// SetPolicy applies the policy to the requests of the remote clients, the
+// local IPC and in-process connections are never limited. A nil policy
+// lifts the limits.
+func (s *Server) SetPolicy(policy *Policy) {
+	if policy == nil {
+		s.policy.Store((*policyEnforcer)(nil))
+		return
+	}
+	s.policy.Store(newPolicyEnforcer(policy))
+}
+
+// enforcer returns the policy enforcer of the request context, if any.
+func (s *Server) enforcer(ctx context.Context) (*policyEnforcer, string) {
+	p, _ := s.policy.Load().(*policyEnforcer)
+	if p == nil {
+		return nil, ""
+	}
+	remote, _ := ctx.Value("remote").(string)
+	if remote == "" {
+		return nil, ""
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return p, host
+	}
+	return p, remote
+}
+
+// checkBatch rejects the batches larger than the policy allows.
+func (s *Server) checkBatch(ctx context.Context, size int) Error {
+	if p, _ := s.enforcer(ctx); p != nil && p.policy.MaxBatchSize > 0 && size > p.policy.MaxBatchSize {
+		batchRejectMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("batch too large, limit %d requests", p.policy.MaxBatchSize)}
+	}
+	return nil
+}
+
+// resolveNamespaces assigns the callbacks registered under the name to the
+// namespace their receiver was first registered under. The aliases, e.g. the
+// nrg namespace of the eth APIs, are thus limited as the original methods.
+func (s *Server) resolveNamespaces(name string, methods callbacks, subscriptions subscriptions) {
+	resolve := func(cb *callback) {
+		cb.namespace = name
+		if cb.rcvr.Kind() != reflect.Ptr {
+			return
+		}
+		for _, svc := range s.services {
+			for _, registered := range []map[string]*callback{svc.callbacks, svc.subscriptions} {
+				for _, other := range registered {
+					if other.rcvr.Kind() == reflect.Ptr && other.rcvr.Pointer() == cb.rcvr.Pointer() &&
+						other.rcvr.Type() == cb.rcvr.Type() && other.method.Name == cb.method.Name {
+						cb.namespace = other.namespace
+						return
+					}
+				}
+			}
+		}
+	}
+	for _, cb := range methods {
+		resolve(cb)
+	}
+	for _, cb := range subscriptions {
+		resolve(cb)
+	}
+}
+
+// admit checks the request against the policy, the returned context carries
+// the policy on to the API.
+func (s *Server) admit(ctx context.Context, req *serverRequest) (context.Context, func(), Error) {
+	p, ip := s.enforcer(ctx)
+	if p == nil || req.callb == nil {
+		return ctx, nil, nil
+	}
+	release, err := p.admit(ip, req.callb.namespace+serviceMethodSeparator+req.method)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return context.WithValue(ctx, policyKey{}, p.policy), release, nil
+}
+
+// policyHandler applies a policy to the requests of an HTTP handler.
+type policyHandler struct {
+	enforcer *policyEnforcer
+	method   string
+	handler  http.Handler
+}
+
+// NewPolicyHandler applies the policy to an HTTP handler serving requests
+// outside of the RPC server, e.g. the GraphQL queries. Every request counts
+// as a call of the given method, the context of the admitted ones carries the
+// policy on to the API. A nil policy lifts the limits.
+func NewPolicyHandler(policy *Policy, method string, handler http.Handler) http.Handler {
+	if policy == nil {
+		return handler
+	}
+	return &policyHandler{
+		enforcer: newPolicyEnforcer(policy),
+		method:   method,
+		handler:  handler,
+	}
+}
+
+func (h *policyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	release, err := h.enforcer.admit(ip, h.method)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	h.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), policyKey{}, h.enforcer.policy)))
+}
+
+// CheckBlockRange returns an error if the number of blocks of the log query
+// exceeds the limit of the policy applied to the request.
+func CheckBlockRange(ctx context.Context, begin, end uint64) error {
+	policy, _ := ctx.Value(policyKey{}).(*Policy)
+	if policy == nil || policy.MaxBlockRange == 0 || end < begin {
+		return nil
+	}
+	if blocks := end - begin + 1; blocks > policy.MaxBlockRange {
+		rangeRejectMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("query spans %d blocks, limit %d", blocks, policy.MaxBlockRange)}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// policyCall posts the raw request and returns the error codes of the
+// responses, zero for the successful ones.
+func policyCall(t *testing.T, url, body string) []int {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	assert.Empty(t, err)
+	defer resp.Body.Close()
+
+	var msgs []jsonErrResponse
+	if strings.HasPrefix(body, "[") {
+		assert.Empty(t, json.NewDecoder(resp.Body).Decode(&msgs))
+	} else {
+		msgs = make([]jsonErrResponse, 1)
+		assert.Empty(t, json.NewDecoder(resp.Body).Decode(&msgs[0]))
+	}
+	codes := make([]int, len(msgs))
+	for i, msg := range msgs {
+		codes[i] = msg.Error.Code
+	}
+	return codes
+}
+
+func TestServerPolicy(t *testing.T) {
+	server := NewServer()
+	assert.Empty(t, server.RegisterName("test", new(Service)))
+	server.SetPolicy(&Policy{
+		RateLimit:    0.001,
+		RateBurst:    4,
+		MethodCosts:  map[string]int{"test_echo*": 2},
+		MaxBatchSize: 2,
+	})
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		rets = `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`
+		echo = `{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["x",1,{"S":"y"}]}`
+	)
+	// Oversized batches are rejected without spending tokens
+	assert.Equal(t, []int{-32005, -32005, -32005}, policyCall(t, httpsrv.URL, "["+rets+","+rets+","+rets+"]"))
+
+	// The weighted methods drain the bucket faster
+	assert.Equal(t, []int{0}, policyCall(t, httpsrv.URL, echo))
+	assert.Equal(t, []int{0, 0}, policyCall(t, httpsrv.URL, "["+rets+","+rets+"]"))
+	assert.Equal(t, []int{-32005}, policyCall(t, httpsrv.URL, rets))
+
+	// The in-process clients are never limited
+	client := DialInProc(server)
+	defer client.Close()
+	var result string
+	for i := 0; i < 10; i++ {
+		assert.Empty(t, client.Call(&result, "test_rets"))
+	}
+}
+
+type LogService struct{}
+
+func (s *LogService) GetLogs() []int { return []int{} }
+
+func TestPolicyAliases(t *testing.T) {
+	server := NewServer()
+	logs := new(LogService)
+	assert.Empty(t, server.RegisterName("eth", logs))
+	assert.Empty(t, server.RegisterName("nrg", logs))
+	server.SetPolicy(&Policy{
+		MethodLimits: map[string]float64{"eth_getLogs": 0.001},
+	})
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	// The aliased namespace shares the limits of the original one
+	assert.Equal(t, []int{0}, policyCall(t, httpsrv.URL, `{"jsonrpc":"2.0","id":1,"method":"nrg_getLogs"}`))
+	assert.Equal(t, []int{-32005}, policyCall(t, httpsrv.URL, `{"jsonrpc":"2.0","id":2,"method":"eth_getLogs"}`))
+	assert.Equal(t, []int{-32005}, policyCall(t, httpsrv.URL, `{"jsonrpc":"2.0","id":3,"method":"nrg_getLogs"}`))
+}
+
+func TestPolicyEnforcer(t *testing.T) {
+	p := newPolicyEnforcer(&Policy{
+		MaxConcurrent:     2,
+		MethodConcurrency: map[string]int{"debug_trace*": 1, "debug_traceBlock": 3},
+		MethodLimits:      map[string]float64{"debug_*": 0.001},
+	})
+	// The longest pattern applies
+	release, err := p.admit("1.1.1.1", "debug_traceTransaction")
+	assert.Empty(t, err)
+	_, err = p.admit("2.2.2.2", "debug_traceCall")
+	assert.NotEmpty(t, err)
+	release()
+
+	// The method limit is shared by all the clients
+	_, err = p.admit("2.2.2.2", "debug_traceCall")
+	assert.NotEmpty(t, err)
+
+	// Concurrency per client
+	release, err = p.admit("3.3.3.3", "eth_blockNumber")
+	assert.Empty(t, err)
+	_, err = p.admit("3.3.3.3", "eth_blockNumber")
+	assert.Empty(t, err)
+	_, err = p.admit("3.3.3.3", "eth_blockNumber")
+	assert.Equal(t, -32005, err.ErrorCode())
+	release()
+	_, err = p.admit("3.3.3.3", "eth_blockNumber")
+	assert.Empty(t, err)
+}
+
+func TestCheckBlockRange(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, CheckBlockRange(ctx, 0, 1000000))
+
+	ctx = context.WithValue(ctx, policyKey{}, &Policy{MaxBlockRange: 100})
+	assert.Empty(t, CheckBlockRange(ctx, 100, 199))
+	assert.NotEmpty(t, CheckBlockRange(ctx, 100, 200))
+}
+
+func TestPolicyHandler(t *testing.T) {
+	var blockRange error
+	handler := NewPolicyHandler(&Policy{
+		RateLimit:     0.001,
+		RateBurst:     2,
+		MaxBlockRange: 100,
+	}, "graphql_query", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blockRange = CheckBlockRange(r.Context(), 0, 100)
+	}))
+	httpsrv := httptest.NewServer(handler)
+	defer httpsrv.Close()
+
+	// The admitted requests carry the policy
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(httpsrv.URL)
+		assert.Empty(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, blockRange)
+	}
+	resp, err := http.Get(httpsrv.URL)
+	assert.Empty(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"range/core/gen3/common"
+	"range/core/gen3/core/vm"
+)
+
+// istanbulStateTest stores the chain ID and the own balance of the contract
+// (CHAINID, SELFBALANCE), with and without non-zero call data (EIP-2028).
+const istanbulStateTest = `{
+	"env": {
+		"currentCoinbase": "2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x0f4240",
+		"currentNumber": "0x01",
+		"currentTimestamp": "0x03e8"
+	},
+	"pre": {
+		"a94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x",
+			"nonce": "0x00",
+			"storage": {}
+		},
+		"0000000000000000000000000000000000001000": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x466000554760015500",
+			"nonce": "0x00",
+			"storage": {}
+		}
+	},
+	"transaction": {
+		"data": ["0x", "0x00ff"],
+		"gasLimit": ["0x0186a0"],
+		"gasPrice": "0x01",
+		"nonce": "0x00",
+		"secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8",
+		"to": "0x0000000000000000000000000000000000001000",
+		"value": ["0x00"]
+	},
+	"post": {
+		"ConstantinopleFix": [
+			{"hash": "db8630cec3d72e8426f935c350151e154f92800e721677edb445be3bc212dc1f", "logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347", "indexes": {"data": 0, "gas": 0, "value": 0}},
+			{"hash": "db8630cec3d72e8426f935c350151e154f92800e721677edb445be3bc212dc1f", "logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347", "indexes": {"data": 1, "gas": 0, "value": 0}}
+		],
+		"Istanbul": [
+			{"hash": "245ab8e7d89592d72930341ee07082560c23f03584705d01e2aa447fd7dfacae", "logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347", "indexes": {"data": 0, "gas": 0, "value": 0}},
+			{"hash": "37f39350a9f800e5bdf2017a2a7ab81ba9fdfab3d6c7900e894b8827631a59e6", "logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347", "indexes": {"data": 1, "gas": 0, "value": 0}}
+		]
+	}
+}`
+
+func TestIstanbulState(t *testing.T) {
+	var test StateTest
+	assert.Empty(t, json.Unmarshal([]byte(istanbulStateTest), &test))
+
+	var (
+		ether    = big.NewInt(1000000000000000000)
+		sender   = common.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+		contract = common.HexToAddress("0x1000")
+	)
+	for _, tt := range []struct {
+		fork    string
+		index   int
+		used    int64
+		chainID common.Hash
+		balance common.Hash
+	}{
+		// Invalid opcode before Istanbul, consuming all the gas
+		{"ConstantinopleFix", 0, 100000, common.Hash{}, common.Hash{}},
+		{"ConstantinopleFix", 1, 100000, common.Hash{}, common.Hash{}},
+		// 21000 + 2 + 3 + 20000 + 5 + 3 + 20000, plus 4 + 16 for the data
+		{"Istanbul", 0, 61013, common.BigToHash(big.NewInt(1)), common.BigToHash(ether)},
+		{"Istanbul", 1, 61033, common.BigToHash(big.NewInt(1)), common.BigToHash(ether)},
+	} {
+		statedb, err := test.Run(StateSubtest{tt.fork, tt.index}, vm.Config{})
+		assert.Empty(t, err, "%s/%d", tt.fork, tt.index)
+
+		balance := new(big.Int).Sub(ether, big.NewInt(tt.used))
+		assert.Equal(t, balance, statedb.GetBalance(sender), "%s/%d", tt.fork, tt.index)
+		assert.Equal(t, tt.chainID, statedb.GetState(contract, common.BigToHash(big.NewInt(0))), "%s/%d", tt.fork, tt.index)
+		assert.Equal(t, tt.balance, statedb.GetState(contract, common.BigToHash(big.NewInt(1))), "%s/%d", tt.fork, tt.index)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"range/core/gen3/common"
+)
+
+// CheckNodes walks the whole trie of the given root, unlike the iterators it
+// doesn't stop at the missing nodes: every one of them is reported along with
+// its hex-encoded path, the subtries below them are skipped. The values of
+// the reachable leaves are passed to onLeaf with their keys, a failing onLeaf
+// aborts the walk. It returns the number of the visited nodes.
+func CheckNodes(root common.Hash, db *Database, onLeaf func(key, value []byte) error, onMissing func(*MissingNodeError)) (uint64, error) {
+	if root == emptyRoot || root == (common.Hash{}) {
+		return 0, nil
+	}
+	c := &nodeChecker{db: db, onLeaf: onLeaf, onMissing: onMissing}
+	err := c.check(hashNode(root[:]), nil)
+	return c.nodes, err
+}
+
+type nodeChecker struct {
+	db        *Database
+	onLeaf    func(key, value []byte) error
+	onMissing func(*MissingNodeError)
+	nodes     uint64
+}
+
+func (c *nodeChecker) check(n node, path []byte) error {
+	switch n := n.(type) {
+	case hashNode:
+		hash := common.BytesToHash(n)
+		resolved := c.db.node(hash, 0)
+		if resolved == nil {
+			if c.onMissing != nil {
+				c.onMissing(&MissingNodeError{NodeHash: hash, Path: append([]byte{}, path...)})
+			}
+			return nil
+		}
+		c.nodes++
+		return c.check(resolved, path)
+
+	case *shortNode:
+		return c.check(n.Val, append(path, n.Key...))
+
+	case *fullNode:
+		for i, child := range &n.Children {
+			if child == nil {
+				continue
+			}
+			if err := c.check(child, append(path, byte(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case valueNode:
+		if c.onLeaf != nil && hasTerm(path) {
+			return c.onLeaf(hexToKeybytes(path), n)
+		}
+		return nil
+	}
+	return nil
+}
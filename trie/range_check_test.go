@@ -0,0 +1,76 @@
+// Copyright 2019 The Range Core Authors
+// This file is part of the Range Core library.
+//
+// The Range Core library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Range Core library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Range Core library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"range/core/gen3/common"
+	"range/core/gen3/ethdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNodes(t *testing.T) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+
+	tr, _ := New(common.Hash{}, triedb)
+	for i := 0; i < 100; i++ {
+		updateString(tr, fmt.Sprintf("key-%03d", i), fmt.Sprintf("value-%032d", i))
+	}
+	root, err := tr.Commit(nil)
+	assert.Empty(t, err)
+	assert.Empty(t, triedb.Commit(root, false))
+
+	leaves := 0
+	onLeaf := func(key, value []byte) error {
+		leaves++
+		return nil
+	}
+	var missing []*MissingNodeError
+	onMissing := func(err *MissingNodeError) {
+		missing = append(missing, err)
+	}
+
+	// A complete trie
+	nodes, err := CheckNodes(root, NewDatabase(diskdb), onLeaf, onMissing)
+	assert.Empty(t, err)
+	assert.Equal(t, 100, leaves)
+	assert.Empty(t, missing)
+	assert.Equal(t, uint64(len(diskdb.Keys())), nodes)
+	total := nodes
+
+	// Delete a node below the root, the rest of the trie is still walked
+	var deleted common.Hash
+	for _, key := range diskdb.Keys() {
+		if hash := common.BytesToHash(key); hash != root {
+			deleted = hash
+			break
+		}
+	}
+	assert.Empty(t, diskdb.Delete(deleted[:]))
+
+	leaves = 0
+	nodes, err = CheckNodes(root, NewDatabase(diskdb), onLeaf, onMissing)
+	assert.Empty(t, err)
+	assert.Equal(t, 1, len(missing))
+	assert.Equal(t, deleted, missing[0].NodeHash)
+	assert.True(t, leaves > 0 && leaves < 100)
+	assert.True(t, nodes < total)
+}